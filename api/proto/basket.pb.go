@@ -21,9 +21,71 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// MergeStrategy decides the quantity of a product that is in both baskets.
+// Products only in one basket are always kept.
+type MergeStrategy int32
+
+const (
+	// Use the service's configured default
+	MergeStrategy_MERGE_STRATEGY_UNSPECIFIED MergeStrategy = 0
+	// Add the guest quantity to the user quantity
+	MergeStrategy_MERGE_STRATEGY_SUM MergeStrategy = 1
+	// Keep the larger of the two quantities
+	MergeStrategy_MERGE_STRATEGY_MAX MergeStrategy = 2
+	// Keep the user's line unchanged
+	MergeStrategy_MERGE_STRATEGY_PREFER_USER MergeStrategy = 3
+)
+
+// Enum value maps for MergeStrategy.
+var (
+	MergeStrategy_name = map[int32]string{
+		0: "MERGE_STRATEGY_UNSPECIFIED",
+		1: "MERGE_STRATEGY_SUM",
+		2: "MERGE_STRATEGY_MAX",
+		3: "MERGE_STRATEGY_PREFER_USER",
+	}
+	MergeStrategy_value = map[string]int32{
+		"MERGE_STRATEGY_UNSPECIFIED": 0,
+		"MERGE_STRATEGY_SUM":         1,
+		"MERGE_STRATEGY_MAX":         2,
+		"MERGE_STRATEGY_PREFER_USER": 3,
+	}
+)
+
+func (x MergeStrategy) Enum() *MergeStrategy {
+	p := new(MergeStrategy)
+	*p = x
+	return p
+}
+
+func (x MergeStrategy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MergeStrategy) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_basket_proto_enumTypes[0].Descriptor()
+}
+
+func (MergeStrategy) Type() protoreflect.EnumType {
+	return &file_api_proto_basket_proto_enumTypes[0]
+}
+
+func (x MergeStrategy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MergeStrategy.Descriptor instead.
+func (MergeStrategy) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{0}
+}
+
 type GetBasketRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Optional ISO-4217 code; when set, display_total holds the total
+	// converted to this currency
+	Currency      string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	SessionToken  string `protobuf:"bytes,3,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -65,11 +127,26 @@ func (x *GetBasketRequest) GetUserId() uint32 {
 	return 0
 }
 
+func (x *GetBasketRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *GetBasketRequest) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
 type AddItemRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	ProductId     uint32                 `protobuf:"varint,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
 	Quantity      int32                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	SessionToken  string                 `protobuf:"bytes,4,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -125,11 +202,19 @@ func (x *AddItemRequest) GetQuantity() int32 {
 	return 0
 }
 
+func (x *AddItemRequest) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
 type UpdateItemRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	ProductId     uint32                 `protobuf:"varint,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
 	Quantity      int32                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	SessionToken  string                 `protobuf:"bytes,4,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -185,10 +270,18 @@ func (x *UpdateItemRequest) GetQuantity() int32 {
 	return 0
 }
 
+func (x *UpdateItemRequest) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
 type RemoveItemRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	ProductId     uint32                 `protobuf:"varint,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	SessionToken  string                 `protobuf:"bytes,3,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -237,9 +330,17 @@ func (x *RemoveItemRequest) GetProductId() uint32 {
 	return 0
 }
 
+func (x *RemoveItemRequest) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
 type ClearBasketRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	SessionToken  string                 `protobuf:"bytes,2,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -281,19 +382,511 @@ func (x *ClearBasketRequest) GetUserId() uint32 {
 	return 0
 }
 
-type Basket struct {
+func (x *ClearBasketRequest) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
+type MergeBasketsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionToken  string                 `protobuf:"bytes,1,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
+	UserId        uint32                 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Strategy      MergeStrategy          `protobuf:"varint,3,opt,name=strategy,proto3,enum=basket.MergeStrategy" json:"strategy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MergeBasketsRequest) Reset() {
+	*x = MergeBasketsRequest{}
+	mi := &file_api_proto_basket_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MergeBasketsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MergeBasketsRequest) ProtoMessage() {}
+
+func (x *MergeBasketsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_basket_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MergeBasketsRequest.ProtoReflect.Descriptor instead.
+func (*MergeBasketsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *MergeBasketsRequest) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
+func (x *MergeBasketsRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *MergeBasketsRequest) GetStrategy() MergeStrategy {
+	if x != nil {
+		return x.Strategy
+	}
+	return MergeStrategy_MERGE_STRATEGY_UNSPECIFIED
+}
+
+type ApplyCouponRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	SessionToken  string                 `protobuf:"bytes,2,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
+	Code          string                 `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApplyCouponRequest) Reset() {
+	*x = ApplyCouponRequest{}
+	mi := &file_api_proto_basket_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApplyCouponRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApplyCouponRequest) ProtoMessage() {}
+
+func (x *ApplyCouponRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_basket_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApplyCouponRequest.ProtoReflect.Descriptor instead.
+func (*ApplyCouponRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ApplyCouponRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *ApplyCouponRequest) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
+func (x *ApplyCouponRequest) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+type RemoveCouponRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	SessionToken  string                 `protobuf:"bytes,2,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveCouponRequest) Reset() {
+	*x = RemoveCouponRequest{}
+	mi := &file_api_proto_basket_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveCouponRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveCouponRequest) ProtoMessage() {}
+
+func (x *RemoveCouponRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_basket_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveCouponRequest.ProtoReflect.Descriptor instead.
+func (*RemoveCouponRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RemoveCouponRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *RemoveCouponRequest) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
+type GetWishlistRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetWishlistRequest) Reset() {
+	*x = GetWishlistRequest{}
+	mi := &file_api_proto_basket_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetWishlistRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetWishlistRequest) ProtoMessage() {}
+
+func (x *GetWishlistRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_basket_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetWishlistRequest.ProtoReflect.Descriptor instead.
+func (*GetWishlistRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetWishlistRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type MoveItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ProductId     uint32                 `protobuf:"varint,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MoveItemRequest) Reset() {
+	*x = MoveItemRequest{}
+	mi := &file_api_proto_basket_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MoveItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoveItemRequest) ProtoMessage() {}
+
+func (x *MoveItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_basket_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoveItemRequest.ProtoReflect.Descriptor instead.
+func (*MoveItemRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *MoveItemRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *MoveItemRequest) GetProductId() uint32 {
+	if x != nil {
+		return x.ProductId
+	}
+	return 0
+}
+
+type LockBasketForCheckoutRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Basket.version the customer was shown
+	Version       int64 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LockBasketForCheckoutRequest) Reset() {
+	*x = LockBasketForCheckoutRequest{}
+	mi := &file_api_proto_basket_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockBasketForCheckoutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockBasketForCheckoutRequest) ProtoMessage() {}
+
+func (x *LockBasketForCheckoutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_basket_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockBasketForCheckoutRequest.ProtoReflect.Descriptor instead.
+func (*LockBasketForCheckoutRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *LockBasketForCheckoutRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *LockBasketForCheckoutRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type CompleteCheckoutRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Basket.version of the locked snapshot the order was placed for
+	Version       int64 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompleteCheckoutRequest) Reset() {
+	*x = CompleteCheckoutRequest{}
+	mi := &file_api_proto_basket_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteCheckoutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteCheckoutRequest) ProtoMessage() {}
+
+func (x *CompleteCheckoutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_basket_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteCheckoutRequest.ProtoReflect.Descriptor instead.
+func (*CompleteCheckoutRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CompleteCheckoutRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *CompleteCheckoutRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type CompleteCheckoutResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompleteCheckoutResponse) Reset() {
+	*x = CompleteCheckoutResponse{}
+	mi := &file_api_proto_basket_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteCheckoutResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteCheckoutResponse) ProtoMessage() {}
+
+func (x *CompleteCheckoutResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_basket_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteCheckoutResponse.ProtoReflect.Descriptor instead.
+func (*CompleteCheckoutResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{12}
+}
+
+// Wishlists belong to users only and do not expire
+type Wishlist struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	Items         []*BasketItem          `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
-	Total         float64                `protobuf:"fixed64,3,opt,name=total,proto3" json:"total,omitempty"`
-	UpdatedAt     string                 `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	UpdatedAt     string                 `protobuf:"bytes,3,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Wishlist) Reset() {
+	*x = Wishlist{}
+	mi := &file_api_proto_basket_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Wishlist) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Wishlist) ProtoMessage() {}
+
+func (x *Wishlist) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_basket_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Wishlist.ProtoReflect.Descriptor instead.
+func (*Wishlist) Descriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Wishlist) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *Wishlist) GetItems() []*BasketItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *Wishlist) GetUpdatedAt() string {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return ""
+}
+
+type Basket struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Items  []*BasketItem          `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	// subtotal minus discounts
+	Total     *Money `protobuf:"bytes,12,opt,name=total,proto3" json:"total,omitempty"`
+	UpdatedAt string `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// Only set when GetBasketRequest.currency is given
+	DisplayTotal *Money  `protobuf:"bytes,5,opt,name=display_total,json=displayTotal,proto3" json:"display_total,omitempty"`
+	ExchangeRate float64 `protobuf:"fixed64,6,opt,name=exchange_rate,json=exchangeRate,proto3" json:"exchange_rate,omitempty"`
+	// Set instead of user_id for guest baskets
+	SessionToken string `protobuf:"bytes,7,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
+	// Sum of current catalog prices of the available items
+	Subtotal *Money `protobuf:"bytes,8,opt,name=subtotal,proto3" json:"subtotal,omitempty"`
+	// Discounts of the applied coupon; empty if it no longer applies
+	Discounts  []*DiscountLine `protobuf:"bytes,9,rep,name=discounts,proto3" json:"discounts,omitempty"`
+	CouponCode string          `protobuf:"bytes,10,opt,name=coupon_code,json=couponCode,proto3" json:"coupon_code,omitempty"`
+	// Incremented by every change to the basket's items or coupon. Catalog
+	// price and stock changes do not change the version.
+	Version       int64 `protobuf:"varint,11,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Basket) Reset() {
 	*x = Basket{}
-	mi := &file_api_proto_basket_proto_msgTypes[5]
+	mi := &file_api_proto_basket_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -305,7 +898,7 @@ func (x *Basket) String() string {
 func (*Basket) ProtoMessage() {}
 
 func (x *Basket) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_basket_proto_msgTypes[5]
+	mi := &file_api_proto_basket_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -318,7 +911,7 @@ func (x *Basket) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Basket.ProtoReflect.Descriptor instead.
 func (*Basket) Descriptor() ([]byte, []int) {
-	return file_api_proto_basket_proto_rawDescGZIP(), []int{5}
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *Basket) GetUserId() uint32 {
@@ -335,11 +928,11 @@ func (x *Basket) GetItems() []*BasketItem {
 	return nil
 }
 
-func (x *Basket) GetTotal() float64 {
+func (x *Basket) GetTotal() *Money {
 	if x != nil {
 		return x.Total
 	}
-	return 0
+	return nil
 }
 
 func (x *Basket) GetUpdatedAt() string {
@@ -349,19 +942,136 @@ func (x *Basket) GetUpdatedAt() string {
 	return ""
 }
 
-type BasketItem struct {
+func (x *Basket) GetDisplayTotal() *Money {
+	if x != nil {
+		return x.DisplayTotal
+	}
+	return nil
+}
+
+func (x *Basket) GetExchangeRate() float64 {
+	if x != nil {
+		return x.ExchangeRate
+	}
+	return 0
+}
+
+func (x *Basket) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
+func (x *Basket) GetSubtotal() *Money {
+	if x != nil {
+		return x.Subtotal
+	}
+	return nil
+}
+
+func (x *Basket) GetDiscounts() []*DiscountLine {
+	if x != nil {
+		return x.Discounts
+	}
+	return nil
+}
+
+func (x *Basket) GetCouponCode() string {
+	if x != nil {
+		return x.CouponCode
+	}
+	return ""
+}
+
+func (x *Basket) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type DiscountLine struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ProductId     uint32                 `protobuf:"varint,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
-	Quantity      int32                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
-	Price         float64                `protobuf:"fixed64,3,opt,name=price,proto3" json:"price,omitempty"`
-	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Amount        *Money                 `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiscountLine) Reset() {
+	*x = DiscountLine{}
+	mi := &file_api_proto_basket_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiscountLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiscountLine) ProtoMessage() {}
+
+func (x *DiscountLine) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_basket_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiscountLine.ProtoReflect.Descriptor instead.
+func (*DiscountLine) Descriptor() ([]byte, []int) {
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DiscountLine) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *DiscountLine) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *DiscountLine) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+type BasketItem struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductId uint32                 `protobuf:"varint,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Quantity  int32                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// Price and name as snapshotted when the item was added
+	Price *Money `protobuf:"bytes,9,opt,name=price,proto3" json:"price,omitempty"`
+	Name  string `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	// The catalog price differs from the snapshot; current_price holds it
+	PriceChanged bool   `protobuf:"varint,5,opt,name=price_changed,json=priceChanged,proto3" json:"price_changed,omitempty"`
+	CurrentPrice *Money `protobuf:"bytes,6,opt,name=current_price,json=currentPrice,proto3" json:"current_price,omitempty"`
+	// Fewer units in stock than the requested quantity
+	OutOfStock bool `protobuf:"varint,7,opt,name=out_of_stock,json=outOfStock,proto3" json:"out_of_stock,omitempty"`
+	// The product was deleted or deactivated; the item is excluded from total
+	Unavailable   bool `protobuf:"varint,8,opt,name=unavailable,proto3" json:"unavailable,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *BasketItem) Reset() {
 	*x = BasketItem{}
-	mi := &file_api_proto_basket_proto_msgTypes[6]
+	mi := &file_api_proto_basket_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -373,7 +1083,7 @@ func (x *BasketItem) String() string {
 func (*BasketItem) ProtoMessage() {}
 
 func (x *BasketItem) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_basket_proto_msgTypes[6]
+	mi := &file_api_proto_basket_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -386,7 +1096,7 @@ func (x *BasketItem) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BasketItem.ProtoReflect.Descriptor instead.
 func (*BasketItem) Descriptor() ([]byte, []int) {
-	return file_api_proto_basket_proto_rawDescGZIP(), []int{6}
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *BasketItem) GetProductId() uint32 {
@@ -403,11 +1113,11 @@ func (x *BasketItem) GetQuantity() int32 {
 	return 0
 }
 
-func (x *BasketItem) GetPrice() float64 {
+func (x *BasketItem) GetPrice() *Money {
 	if x != nil {
 		return x.Price
 	}
-	return 0
+	return nil
 }
 
 func (x *BasketItem) GetName() string {
@@ -417,6 +1127,34 @@ func (x *BasketItem) GetName() string {
 	return ""
 }
 
+func (x *BasketItem) GetPriceChanged() bool {
+	if x != nil {
+		return x.PriceChanged
+	}
+	return false
+}
+
+func (x *BasketItem) GetCurrentPrice() *Money {
+	if x != nil {
+		return x.CurrentPrice
+	}
+	return nil
+}
+
+func (x *BasketItem) GetOutOfStock() bool {
+	if x != nil {
+		return x.OutOfStock
+	}
+	return false
+}
+
+func (x *BasketItem) GetUnavailable() bool {
+	if x != nil {
+		return x.Unavailable
+	}
+	return false
+}
+
 type ClearBasketResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -426,7 +1164,7 @@ type ClearBasketResponse struct {
 
 func (x *ClearBasketResponse) Reset() {
 	*x = ClearBasketResponse{}
-	mi := &file_api_proto_basket_proto_msgTypes[7]
+	mi := &file_api_proto_basket_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -438,7 +1176,7 @@ func (x *ClearBasketResponse) String() string {
 func (*ClearBasketResponse) ProtoMessage() {}
 
 func (x *ClearBasketResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_basket_proto_msgTypes[7]
+	mi := &file_api_proto_basket_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -451,7 +1189,7 @@ func (x *ClearBasketResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClearBasketResponse.ProtoReflect.Descriptor instead.
 func (*ClearBasketResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_basket_proto_rawDescGZIP(), []int{7}
+	return file_api_proto_basket_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *ClearBasketResponse) GetSuccess() bool {
@@ -465,40 +1203,98 @@ var File_api_proto_basket_proto protoreflect.FileDescriptor
 
 const file_api_proto_basket_proto_rawDesc = "" +
 	"\n" +
-	"\x16api/proto/basket.proto\x12\x06basket\"+\n" +
+	"\x16api/proto/basket.proto\x12\x06basket\x1a\x15api/proto/money.proto\"l\n" +
 	"\x10GetBasketRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\rR\x06userId\"d\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\x12#\n" +
+	"\rsession_token\x18\x03 \x01(\tR\fsessionToken\"\x89\x01\n" +
 	"\x0eAddItemRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\rR\x06userId\x12\x1d\n" +
 	"\n" +
 	"product_id\x18\x02 \x01(\rR\tproductId\x12\x1a\n" +
-	"\bquantity\x18\x03 \x01(\x05R\bquantity\"g\n" +
+	"\bquantity\x18\x03 \x01(\x05R\bquantity\x12#\n" +
+	"\rsession_token\x18\x04 \x01(\tR\fsessionToken\"\x8c\x01\n" +
 	"\x11UpdateItemRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\rR\x06userId\x12\x1d\n" +
 	"\n" +
 	"product_id\x18\x02 \x01(\rR\tproductId\x12\x1a\n" +
-	"\bquantity\x18\x03 \x01(\x05R\bquantity\"K\n" +
+	"\bquantity\x18\x03 \x01(\x05R\bquantity\x12#\n" +
+	"\rsession_token\x18\x04 \x01(\tR\fsessionToken\"p\n" +
 	"\x11RemoveItemRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\rR\x06userId\x12\x1d\n" +
 	"\n" +
-	"product_id\x18\x02 \x01(\rR\tproductId\"-\n" +
+	"product_id\x18\x02 \x01(\rR\tproductId\x12#\n" +
+	"\rsession_token\x18\x03 \x01(\tR\fsessionToken\"R\n" +
 	"\x12ClearBasketRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\rR\x06userId\"\x80\x01\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\x12#\n" +
+	"\rsession_token\x18\x02 \x01(\tR\fsessionToken\"\x86\x01\n" +
+	"\x13MergeBasketsRequest\x12#\n" +
+	"\rsession_token\x18\x01 \x01(\tR\fsessionToken\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\rR\x06userId\x121\n" +
+	"\bstrategy\x18\x03 \x01(\x0e2\x15.basket.MergeStrategyR\bstrategy\"f\n" +
+	"\x12ApplyCouponRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\x12#\n" +
+	"\rsession_token\x18\x02 \x01(\tR\fsessionToken\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\"S\n" +
+	"\x13RemoveCouponRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\x12#\n" +
+	"\rsession_token\x18\x02 \x01(\tR\fsessionToken\"-\n" +
+	"\x12GetWishlistRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\"I\n" +
+	"\x0fMoveItemRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x02 \x01(\rR\tproductId\"Q\n" +
+	"\x1cLockBasketForCheckoutRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"L\n" +
+	"\x17CompleteCheckoutRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"\x1a\n" +
+	"\x18CompleteCheckoutResponse\"l\n" +
+	"\bWishlist\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\x12(\n" +
+	"\x05items\x18\x02 \x03(\v2\x12.basket.BasketItemR\x05items\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\x03 \x01(\tR\tupdatedAt\"\xaa\x03\n" +
 	"\x06Basket\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\rR\x06userId\x12(\n" +
-	"\x05items\x18\x02 \x03(\v2\x12.basket.BasketItemR\x05items\x12\x14\n" +
-	"\x05total\x18\x03 \x01(\x01R\x05total\x12\x1d\n" +
+	"\x05items\x18\x02 \x03(\v2\x12.basket.BasketItemR\x05items\x12\"\n" +
+	"\x05total\x18\f \x01(\v2\f.money.MoneyR\x05total\x12\x1d\n" +
 	"\n" +
-	"updated_at\x18\x04 \x01(\tR\tupdatedAt\"q\n" +
+	"updated_at\x18\x04 \x01(\tR\tupdatedAt\x121\n" +
+	"\rdisplay_total\x18\x05 \x01(\v2\f.money.MoneyR\fdisplayTotal\x12#\n" +
+	"\rexchange_rate\x18\x06 \x01(\x01R\fexchangeRate\x12#\n" +
+	"\rsession_token\x18\a \x01(\tR\fsessionToken\x12(\n" +
+	"\bsubtotal\x18\b \x01(\v2\f.money.MoneyR\bsubtotal\x122\n" +
+	"\tdiscounts\x18\t \x03(\v2\x14.basket.DiscountLineR\tdiscounts\x12\x1f\n" +
+	"\vcoupon_code\x18\n" +
+	" \x01(\tR\n" +
+	"couponCode\x12\x18\n" +
+	"\aversion\x18\v \x01(\x03R\aversionJ\x04\b\x03\x10\x04\"j\n" +
+	"\fDiscountLine\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12$\n" +
+	"\x06amount\x18\x03 \x01(\v2\f.money.MoneyR\x06amount\"\xa1\x02\n" +
 	"\n" +
 	"BasketItem\x12\x1d\n" +
 	"\n" +
 	"product_id\x18\x01 \x01(\rR\tproductId\x12\x1a\n" +
-	"\bquantity\x18\x02 \x01(\x05R\bquantity\x12\x14\n" +
-	"\x05price\x18\x03 \x01(\x01R\x05price\x12\x12\n" +
-	"\x04name\x18\x04 \x01(\tR\x04name\"/\n" +
+	"\bquantity\x18\x02 \x01(\x05R\bquantity\x12\"\n" +
+	"\x05price\x18\t \x01(\v2\f.money.MoneyR\x05price\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12#\n" +
+	"\rprice_changed\x18\x05 \x01(\bR\fpriceChanged\x121\n" +
+	"\rcurrent_price\x18\x06 \x01(\v2\f.money.MoneyR\fcurrentPrice\x12 \n" +
+	"\fout_of_stock\x18\a \x01(\bR\n" +
+	"outOfStock\x12 \n" +
+	"\vunavailable\x18\b \x01(\bR\vunavailableJ\x04\b\x03\x10\x04\"/\n" +
 	"\x13ClearBasketResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess2\xbd\x02\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess*\x7f\n" +
+	"\rMergeStrategy\x12\x1e\n" +
+	"\x1aMERGE_STRATEGY_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12MERGE_STRATEGY_SUM\x10\x01\x12\x16\n" +
+	"\x12MERGE_STRATEGY_MAX\x10\x02\x12\x1e\n" +
+	"\x1aMERGE_STRATEGY_PREFER_USER\x10\x032\xd9\x06\n" +
 	"\rBasketService\x127\n" +
 	"\tGetBasket\x12\x18.basket.GetBasketRequest\x1a\x0e.basket.Basket\"\x00\x123\n" +
 	"\aAddItem\x12\x16.basket.AddItemRequest\x1a\x0e.basket.Basket\"\x00\x129\n" +
@@ -506,7 +1302,15 @@ const file_api_proto_basket_proto_rawDesc = "" +
 	"UpdateItem\x12\x19.basket.UpdateItemRequest\x1a\x0e.basket.Basket\"\x00\x129\n" +
 	"\n" +
 	"RemoveItem\x12\x19.basket.RemoveItemRequest\x1a\x0e.basket.Basket\"\x00\x12H\n" +
-	"\vClearBasket\x12\x1a.basket.ClearBasketRequest\x1a\x1b.basket.ClearBasketResponse\"\x00B\x13Z\x11gomicro/api/protob\x06proto3"
+	"\vClearBasket\x12\x1a.basket.ClearBasketRequest\x1a\x1b.basket.ClearBasketResponse\"\x00\x12=\n" +
+	"\fMergeBaskets\x12\x1b.basket.MergeBasketsRequest\x1a\x0e.basket.Basket\"\x00\x12;\n" +
+	"\vApplyCoupon\x12\x1a.basket.ApplyCouponRequest\x1a\x0e.basket.Basket\"\x00\x12=\n" +
+	"\fRemoveCoupon\x12\x1b.basket.RemoveCouponRequest\x1a\x0e.basket.Basket\"\x00\x12=\n" +
+	"\vGetWishlist\x12\x1a.basket.GetWishlistRequest\x1a\x10.basket.Wishlist\"\x00\x12;\n" +
+	"\x0eMoveToWishlist\x12\x17.basket.MoveItemRequest\x1a\x0e.basket.Basket\"\x00\x129\n" +
+	"\fMoveToBasket\x12\x17.basket.MoveItemRequest\x1a\x0e.basket.Basket\"\x00\x12O\n" +
+	"\x15LockBasketForCheckout\x12$.basket.LockBasketForCheckoutRequest\x1a\x0e.basket.Basket\"\x00\x12W\n" +
+	"\x10CompleteCheckout\x12\x1f.basket.CompleteCheckoutRequest\x1a .basket.CompleteCheckoutResponse\"\x00B\x13Z\x11gomicro/api/protob\x06proto3"
 
 var (
 	file_api_proto_basket_proto_rawDescOnce sync.Once
@@ -520,34 +1324,72 @@ func file_api_proto_basket_proto_rawDescGZIP() []byte {
 	return file_api_proto_basket_proto_rawDescData
 }
 
-var file_api_proto_basket_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_api_proto_basket_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_proto_basket_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_api_proto_basket_proto_goTypes = []any{
-	(*GetBasketRequest)(nil),    // 0: basket.GetBasketRequest
-	(*AddItemRequest)(nil),      // 1: basket.AddItemRequest
-	(*UpdateItemRequest)(nil),   // 2: basket.UpdateItemRequest
-	(*RemoveItemRequest)(nil),   // 3: basket.RemoveItemRequest
-	(*ClearBasketRequest)(nil),  // 4: basket.ClearBasketRequest
-	(*Basket)(nil),              // 5: basket.Basket
-	(*BasketItem)(nil),          // 6: basket.BasketItem
-	(*ClearBasketResponse)(nil), // 7: basket.ClearBasketResponse
+	(MergeStrategy)(0),                   // 0: basket.MergeStrategy
+	(*GetBasketRequest)(nil),             // 1: basket.GetBasketRequest
+	(*AddItemRequest)(nil),               // 2: basket.AddItemRequest
+	(*UpdateItemRequest)(nil),            // 3: basket.UpdateItemRequest
+	(*RemoveItemRequest)(nil),            // 4: basket.RemoveItemRequest
+	(*ClearBasketRequest)(nil),           // 5: basket.ClearBasketRequest
+	(*MergeBasketsRequest)(nil),          // 6: basket.MergeBasketsRequest
+	(*ApplyCouponRequest)(nil),           // 7: basket.ApplyCouponRequest
+	(*RemoveCouponRequest)(nil),          // 8: basket.RemoveCouponRequest
+	(*GetWishlistRequest)(nil),           // 9: basket.GetWishlistRequest
+	(*MoveItemRequest)(nil),              // 10: basket.MoveItemRequest
+	(*LockBasketForCheckoutRequest)(nil), // 11: basket.LockBasketForCheckoutRequest
+	(*CompleteCheckoutRequest)(nil),      // 12: basket.CompleteCheckoutRequest
+	(*CompleteCheckoutResponse)(nil),     // 13: basket.CompleteCheckoutResponse
+	(*Wishlist)(nil),                     // 14: basket.Wishlist
+	(*Basket)(nil),                       // 15: basket.Basket
+	(*DiscountLine)(nil),                 // 16: basket.DiscountLine
+	(*BasketItem)(nil),                   // 17: basket.BasketItem
+	(*ClearBasketResponse)(nil),          // 18: basket.ClearBasketResponse
+	(*Money)(nil),                        // 19: money.Money
 }
 var file_api_proto_basket_proto_depIdxs = []int32{
-	6, // 0: basket.Basket.items:type_name -> basket.BasketItem
-	0, // 1: basket.BasketService.GetBasket:input_type -> basket.GetBasketRequest
-	1, // 2: basket.BasketService.AddItem:input_type -> basket.AddItemRequest
-	2, // 3: basket.BasketService.UpdateItem:input_type -> basket.UpdateItemRequest
-	3, // 4: basket.BasketService.RemoveItem:input_type -> basket.RemoveItemRequest
-	4, // 5: basket.BasketService.ClearBasket:input_type -> basket.ClearBasketRequest
-	5, // 6: basket.BasketService.GetBasket:output_type -> basket.Basket
-	5, // 7: basket.BasketService.AddItem:output_type -> basket.Basket
-	5, // 8: basket.BasketService.UpdateItem:output_type -> basket.Basket
-	5, // 9: basket.BasketService.RemoveItem:output_type -> basket.Basket
-	7, // 10: basket.BasketService.ClearBasket:output_type -> basket.ClearBasketResponse
-	6, // [6:11] is the sub-list for method output_type
-	1, // [1:6] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: basket.MergeBasketsRequest.strategy:type_name -> basket.MergeStrategy
+	17, // 1: basket.Wishlist.items:type_name -> basket.BasketItem
+	17, // 2: basket.Basket.items:type_name -> basket.BasketItem
+	19, // 3: basket.Basket.total:type_name -> money.Money
+	19, // 4: basket.Basket.display_total:type_name -> money.Money
+	19, // 5: basket.Basket.subtotal:type_name -> money.Money
+	16, // 6: basket.Basket.discounts:type_name -> basket.DiscountLine
+	19, // 7: basket.DiscountLine.amount:type_name -> money.Money
+	19, // 8: basket.BasketItem.price:type_name -> money.Money
+	19, // 9: basket.BasketItem.current_price:type_name -> money.Money
+	1,  // 10: basket.BasketService.GetBasket:input_type -> basket.GetBasketRequest
+	2,  // 11: basket.BasketService.AddItem:input_type -> basket.AddItemRequest
+	3,  // 12: basket.BasketService.UpdateItem:input_type -> basket.UpdateItemRequest
+	4,  // 13: basket.BasketService.RemoveItem:input_type -> basket.RemoveItemRequest
+	5,  // 14: basket.BasketService.ClearBasket:input_type -> basket.ClearBasketRequest
+	6,  // 15: basket.BasketService.MergeBaskets:input_type -> basket.MergeBasketsRequest
+	7,  // 16: basket.BasketService.ApplyCoupon:input_type -> basket.ApplyCouponRequest
+	8,  // 17: basket.BasketService.RemoveCoupon:input_type -> basket.RemoveCouponRequest
+	9,  // 18: basket.BasketService.GetWishlist:input_type -> basket.GetWishlistRequest
+	10, // 19: basket.BasketService.MoveToWishlist:input_type -> basket.MoveItemRequest
+	10, // 20: basket.BasketService.MoveToBasket:input_type -> basket.MoveItemRequest
+	11, // 21: basket.BasketService.LockBasketForCheckout:input_type -> basket.LockBasketForCheckoutRequest
+	12, // 22: basket.BasketService.CompleteCheckout:input_type -> basket.CompleteCheckoutRequest
+	15, // 23: basket.BasketService.GetBasket:output_type -> basket.Basket
+	15, // 24: basket.BasketService.AddItem:output_type -> basket.Basket
+	15, // 25: basket.BasketService.UpdateItem:output_type -> basket.Basket
+	15, // 26: basket.BasketService.RemoveItem:output_type -> basket.Basket
+	18, // 27: basket.BasketService.ClearBasket:output_type -> basket.ClearBasketResponse
+	15, // 28: basket.BasketService.MergeBaskets:output_type -> basket.Basket
+	15, // 29: basket.BasketService.ApplyCoupon:output_type -> basket.Basket
+	15, // 30: basket.BasketService.RemoveCoupon:output_type -> basket.Basket
+	14, // 31: basket.BasketService.GetWishlist:output_type -> basket.Wishlist
+	15, // 32: basket.BasketService.MoveToWishlist:output_type -> basket.Basket
+	15, // 33: basket.BasketService.MoveToBasket:output_type -> basket.Basket
+	15, // 34: basket.BasketService.LockBasketForCheckout:output_type -> basket.Basket
+	13, // 35: basket.BasketService.CompleteCheckout:output_type -> basket.CompleteCheckoutResponse
+	23, // [23:36] is the sub-list for method output_type
+	10, // [10:23] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_basket_proto_init() }
@@ -555,18 +1397,20 @@ func file_api_proto_basket_proto_init() {
 	if File_api_proto_basket_proto != nil {
 		return
 	}
+	file_api_proto_money_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_basket_proto_rawDesc), len(file_api_proto_basket_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   8,
+			NumEnums:      1,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_api_proto_basket_proto_goTypes,
 		DependencyIndexes: file_api_proto_basket_proto_depIdxs,
+		EnumInfos:         file_api_proto_basket_proto_enumTypes,
 		MessageInfos:      file_api_proto_basket_proto_msgTypes,
 	}.Build()
 	File_api_proto_basket_proto = out.File
@@ -19,11 +19,19 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BasketService_GetBasket_FullMethodName   = "/basket.BasketService/GetBasket"
-	BasketService_AddItem_FullMethodName     = "/basket.BasketService/AddItem"
-	BasketService_UpdateItem_FullMethodName  = "/basket.BasketService/UpdateItem"
-	BasketService_RemoveItem_FullMethodName  = "/basket.BasketService/RemoveItem"
-	BasketService_ClearBasket_FullMethodName = "/basket.BasketService/ClearBasket"
+	BasketService_GetBasket_FullMethodName             = "/basket.BasketService/GetBasket"
+	BasketService_AddItem_FullMethodName               = "/basket.BasketService/AddItem"
+	BasketService_UpdateItem_FullMethodName            = "/basket.BasketService/UpdateItem"
+	BasketService_RemoveItem_FullMethodName            = "/basket.BasketService/RemoveItem"
+	BasketService_ClearBasket_FullMethodName           = "/basket.BasketService/ClearBasket"
+	BasketService_MergeBaskets_FullMethodName          = "/basket.BasketService/MergeBaskets"
+	BasketService_ApplyCoupon_FullMethodName           = "/basket.BasketService/ApplyCoupon"
+	BasketService_RemoveCoupon_FullMethodName          = "/basket.BasketService/RemoveCoupon"
+	BasketService_GetWishlist_FullMethodName           = "/basket.BasketService/GetWishlist"
+	BasketService_MoveToWishlist_FullMethodName        = "/basket.BasketService/MoveToWishlist"
+	BasketService_MoveToBasket_FullMethodName          = "/basket.BasketService/MoveToBasket"
+	BasketService_LockBasketForCheckout_FullMethodName = "/basket.BasketService/LockBasketForCheckout"
+	BasketService_CompleteCheckout_FullMethodName      = "/basket.BasketService/CompleteCheckout"
 )
 
 // BasketServiceClient is the client API for BasketService service.
@@ -31,10 +39,43 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type BasketServiceClient interface {
 	GetBasket(ctx context.Context, in *GetBasketRequest, opts ...grpc.CallOption) (*Basket, error)
+	// AddItem adds quantity (> 0) units of the product to its line, creating
+	// the line if needed
 	AddItem(ctx context.Context, in *AddItemRequest, opts ...grpc.CallOption) (*Basket, error)
+	// UpdateItem sets the product's line to exactly quantity (>= 0); 0
+	// removes the line
 	UpdateItem(ctx context.Context, in *UpdateItemRequest, opts ...grpc.CallOption) (*Basket, error)
 	RemoveItem(ctx context.Context, in *RemoveItemRequest, opts ...grpc.CallOption) (*Basket, error)
 	ClearBasket(ctx context.Context, in *ClearBasketRequest, opts ...grpc.CallOption) (*ClearBasketResponse, error)
+	// MergeBaskets folds a guest basket into a user basket, typically on
+	// login, and deletes the guest basket
+	MergeBaskets(ctx context.Context, in *MergeBasketsRequest, opts ...grpc.CallOption) (*Basket, error)
+	// ApplyCoupon puts a coupon on the basket, replacing any previous one. It
+	// fails if the coupon is unknown, outside its validity window, used up or
+	// its conditions (e.g. minimum basket value) are not met. Uses are only
+	// counted by placed orders, see CompleteCheckout.
+	ApplyCoupon(ctx context.Context, in *ApplyCouponRequest, opts ...grpc.CallOption) (*Basket, error)
+	RemoveCoupon(ctx context.Context, in *RemoveCouponRequest, opts ...grpc.CallOption) (*Basket, error)
+	// GetWishlist lists the user's saved-for-later items with current price
+	// and stock from the product service
+	GetWishlist(ctx context.Context, in *GetWishlistRequest, opts ...grpc.CallOption) (*Wishlist, error)
+	// MoveToWishlist moves a basket line to the wishlist
+	MoveToWishlist(ctx context.Context, in *MoveItemRequest, opts ...grpc.CallOption) (*Basket, error)
+	// MoveToBasket adds a saved line back to the basket at the current price,
+	// subject to the basket limits and stock
+	MoveToBasket(ctx context.Context, in *MoveItemRequest, opts ...grpc.CallOption) (*Basket, error)
+	// LockBasketForCheckout freezes the user's basket at version, priced
+	// against the catalog, for checkout. Locking the same version again returns
+	// the same snapshot until it expires, so a retried checkout charges the
+	// same amount. It fails with ABORTED if the basket is no longer at version.
+	LockBasketForCheckout(ctx context.Context, in *LockBasketForCheckoutRequest, opts ...grpc.CallOption) (*Basket, error)
+	// CompleteCheckout counts a use of the snapshot's coupon once the order
+	// for the snapshot of version is placed, and clears the user's basket if it
+	// is still at version. It fails with ABORTED and leaves the basket as it
+	// is if the basket changed since. Locking a snapshot holds a use of its
+	// coupon until the snapshot expires or the basket is cleared with
+	// ClearBasket.
+	CompleteCheckout(ctx context.Context, in *CompleteCheckoutRequest, opts ...grpc.CallOption) (*CompleteCheckoutResponse, error)
 }
 
 type basketServiceClient struct {
@@ -95,15 +136,128 @@ func (c *basketServiceClient) ClearBasket(ctx context.Context, in *ClearBasketRe
 	return out, nil
 }
 
+func (c *basketServiceClient) MergeBaskets(ctx context.Context, in *MergeBasketsRequest, opts ...grpc.CallOption) (*Basket, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Basket)
+	err := c.cc.Invoke(ctx, BasketService_MergeBaskets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *basketServiceClient) ApplyCoupon(ctx context.Context, in *ApplyCouponRequest, opts ...grpc.CallOption) (*Basket, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Basket)
+	err := c.cc.Invoke(ctx, BasketService_ApplyCoupon_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *basketServiceClient) RemoveCoupon(ctx context.Context, in *RemoveCouponRequest, opts ...grpc.CallOption) (*Basket, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Basket)
+	err := c.cc.Invoke(ctx, BasketService_RemoveCoupon_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *basketServiceClient) GetWishlist(ctx context.Context, in *GetWishlistRequest, opts ...grpc.CallOption) (*Wishlist, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Wishlist)
+	err := c.cc.Invoke(ctx, BasketService_GetWishlist_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *basketServiceClient) MoveToWishlist(ctx context.Context, in *MoveItemRequest, opts ...grpc.CallOption) (*Basket, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Basket)
+	err := c.cc.Invoke(ctx, BasketService_MoveToWishlist_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *basketServiceClient) MoveToBasket(ctx context.Context, in *MoveItemRequest, opts ...grpc.CallOption) (*Basket, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Basket)
+	err := c.cc.Invoke(ctx, BasketService_MoveToBasket_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *basketServiceClient) LockBasketForCheckout(ctx context.Context, in *LockBasketForCheckoutRequest, opts ...grpc.CallOption) (*Basket, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Basket)
+	err := c.cc.Invoke(ctx, BasketService_LockBasketForCheckout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *basketServiceClient) CompleteCheckout(ctx context.Context, in *CompleteCheckoutRequest, opts ...grpc.CallOption) (*CompleteCheckoutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompleteCheckoutResponse)
+	err := c.cc.Invoke(ctx, BasketService_CompleteCheckout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BasketServiceServer is the server API for BasketService service.
 // All implementations must embed UnimplementedBasketServiceServer
 // for forward compatibility.
 type BasketServiceServer interface {
 	GetBasket(context.Context, *GetBasketRequest) (*Basket, error)
+	// AddItem adds quantity (> 0) units of the product to its line, creating
+	// the line if needed
 	AddItem(context.Context, *AddItemRequest) (*Basket, error)
+	// UpdateItem sets the product's line to exactly quantity (>= 0); 0
+	// removes the line
 	UpdateItem(context.Context, *UpdateItemRequest) (*Basket, error)
 	RemoveItem(context.Context, *RemoveItemRequest) (*Basket, error)
 	ClearBasket(context.Context, *ClearBasketRequest) (*ClearBasketResponse, error)
+	// MergeBaskets folds a guest basket into a user basket, typically on
+	// login, and deletes the guest basket
+	MergeBaskets(context.Context, *MergeBasketsRequest) (*Basket, error)
+	// ApplyCoupon puts a coupon on the basket, replacing any previous one. It
+	// fails if the coupon is unknown, outside its validity window, used up or
+	// its conditions (e.g. minimum basket value) are not met. Uses are only
+	// counted by placed orders, see CompleteCheckout.
+	ApplyCoupon(context.Context, *ApplyCouponRequest) (*Basket, error)
+	RemoveCoupon(context.Context, *RemoveCouponRequest) (*Basket, error)
+	// GetWishlist lists the user's saved-for-later items with current price
+	// and stock from the product service
+	GetWishlist(context.Context, *GetWishlistRequest) (*Wishlist, error)
+	// MoveToWishlist moves a basket line to the wishlist
+	MoveToWishlist(context.Context, *MoveItemRequest) (*Basket, error)
+	// MoveToBasket adds a saved line back to the basket at the current price,
+	// subject to the basket limits and stock
+	MoveToBasket(context.Context, *MoveItemRequest) (*Basket, error)
+	// LockBasketForCheckout freezes the user's basket at version, priced
+	// against the catalog, for checkout. Locking the same version again returns
+	// the same snapshot until it expires, so a retried checkout charges the
+	// same amount. It fails with ABORTED if the basket is no longer at version.
+	LockBasketForCheckout(context.Context, *LockBasketForCheckoutRequest) (*Basket, error)
+	// CompleteCheckout counts a use of the snapshot's coupon once the order
+	// for the snapshot of version is placed, and clears the user's basket if it
+	// is still at version. It fails with ABORTED and leaves the basket as it
+	// is if the basket changed since. Locking a snapshot holds a use of its
+	// coupon until the snapshot expires or the basket is cleared with
+	// ClearBasket.
+	CompleteCheckout(context.Context, *CompleteCheckoutRequest) (*CompleteCheckoutResponse, error)
 	mustEmbedUnimplementedBasketServiceServer()
 }
 
@@ -129,6 +283,30 @@ func (UnimplementedBasketServiceServer) RemoveItem(context.Context, *RemoveItemR
 func (UnimplementedBasketServiceServer) ClearBasket(context.Context, *ClearBasketRequest) (*ClearBasketResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ClearBasket not implemented")
 }
+func (UnimplementedBasketServiceServer) MergeBaskets(context.Context, *MergeBasketsRequest) (*Basket, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeBaskets not implemented")
+}
+func (UnimplementedBasketServiceServer) ApplyCoupon(context.Context, *ApplyCouponRequest) (*Basket, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyCoupon not implemented")
+}
+func (UnimplementedBasketServiceServer) RemoveCoupon(context.Context, *RemoveCouponRequest) (*Basket, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveCoupon not implemented")
+}
+func (UnimplementedBasketServiceServer) GetWishlist(context.Context, *GetWishlistRequest) (*Wishlist, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWishlist not implemented")
+}
+func (UnimplementedBasketServiceServer) MoveToWishlist(context.Context, *MoveItemRequest) (*Basket, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveToWishlist not implemented")
+}
+func (UnimplementedBasketServiceServer) MoveToBasket(context.Context, *MoveItemRequest) (*Basket, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveToBasket not implemented")
+}
+func (UnimplementedBasketServiceServer) LockBasketForCheckout(context.Context, *LockBasketForCheckoutRequest) (*Basket, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LockBasketForCheckout not implemented")
+}
+func (UnimplementedBasketServiceServer) CompleteCheckout(context.Context, *CompleteCheckoutRequest) (*CompleteCheckoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompleteCheckout not implemented")
+}
 func (UnimplementedBasketServiceServer) mustEmbedUnimplementedBasketServiceServer() {}
 func (UnimplementedBasketServiceServer) testEmbeddedByValue()                       {}
 
@@ -240,6 +418,150 @@ func _BasketService_ClearBasket_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BasketService_MergeBaskets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeBasketsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketServiceServer).MergeBaskets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketService_MergeBaskets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketServiceServer).MergeBaskets(ctx, req.(*MergeBasketsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BasketService_ApplyCoupon_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyCouponRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketServiceServer).ApplyCoupon(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketService_ApplyCoupon_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketServiceServer).ApplyCoupon(ctx, req.(*ApplyCouponRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BasketService_RemoveCoupon_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveCouponRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketServiceServer).RemoveCoupon(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketService_RemoveCoupon_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketServiceServer).RemoveCoupon(ctx, req.(*RemoveCouponRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BasketService_GetWishlist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWishlistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketServiceServer).GetWishlist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketService_GetWishlist_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketServiceServer).GetWishlist(ctx, req.(*GetWishlistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BasketService_MoveToWishlist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketServiceServer).MoveToWishlist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketService_MoveToWishlist_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketServiceServer).MoveToWishlist(ctx, req.(*MoveItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BasketService_MoveToBasket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketServiceServer).MoveToBasket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketService_MoveToBasket_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketServiceServer).MoveToBasket(ctx, req.(*MoveItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BasketService_LockBasketForCheckout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockBasketForCheckoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketServiceServer).LockBasketForCheckout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketService_LockBasketForCheckout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketServiceServer).LockBasketForCheckout(ctx, req.(*LockBasketForCheckoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BasketService_CompleteCheckout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompleteCheckoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketServiceServer).CompleteCheckout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketService_CompleteCheckout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketServiceServer).CompleteCheckout(ctx, req.(*CompleteCheckoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BasketService_ServiceDesc is the grpc.ServiceDesc for BasketService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -267,6 +589,38 @@ var BasketService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ClearBasket",
 			Handler:    _BasketService_ClearBasket_Handler,
 		},
+		{
+			MethodName: "MergeBaskets",
+			Handler:    _BasketService_MergeBaskets_Handler,
+		},
+		{
+			MethodName: "ApplyCoupon",
+			Handler:    _BasketService_ApplyCoupon_Handler,
+		},
+		{
+			MethodName: "RemoveCoupon",
+			Handler:    _BasketService_RemoveCoupon_Handler,
+		},
+		{
+			MethodName: "GetWishlist",
+			Handler:    _BasketService_GetWishlist_Handler,
+		},
+		{
+			MethodName: "MoveToWishlist",
+			Handler:    _BasketService_MoveToWishlist_Handler,
+		},
+		{
+			MethodName: "MoveToBasket",
+			Handler:    _BasketService_MoveToBasket_Handler,
+		},
+		{
+			MethodName: "LockBasketForCheckout",
+			Handler:    _BasketService_LockBasketForCheckout_Handler,
+		},
+		{
+			MethodName: "CompleteCheckout",
+			Handler:    _BasketService_CompleteCheckout_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/basket.proto",
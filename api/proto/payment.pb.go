@@ -22,30 +22,506 @@ const (
 )
 
 type ProcessPaymentRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// The currency must be a known ISO-4217 code
+	Amount        *Money `protobuf:"bytes,6,opt,name=amount,proto3" json:"amount,omitempty"`
+	PaymentMethod string `protobuf:"bytes,4,opt,name=payment_method,json=paymentMethod,proto3" json:"payment_method,omitempty"`
+	// Optional client-generated key. Replaying a request with the same key
+	// returns the original payment instead of charging again.
+	IdempotencyKey string `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ProcessPaymentRequest) Reset() {
+	*x = ProcessPaymentRequest{}
+	mi := &file_api_proto_payment_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessPaymentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessPaymentRequest) ProtoMessage() {}
+
+func (x *ProcessPaymentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessPaymentRequest.ProtoReflect.Descriptor instead.
+func (*ProcessPaymentRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProcessPaymentRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *ProcessPaymentRequest) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *ProcessPaymentRequest) GetPaymentMethod() string {
+	if x != nil {
+		return x.PaymentMethod
+	}
+	return ""
+}
+
+func (x *ProcessPaymentRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type CheckoutRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Currency to charge in; defaults to the merchant's base currency. Catalog
+	// prices are converted at the current exchange rate.
+	Currency      string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	PaymentMethod string `protobuf:"bytes,3,opt,name=payment_method,json=paymentMethod,proto3" json:"payment_method,omitempty"`
+	// Basket.version the customer confirmed; 0 checks out the basket as it is
+	BasketVersion int64 `protobuf:"varint,4,opt,name=basket_version,json=basketVersion,proto3" json:"basket_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckoutRequest) Reset() {
+	*x = CheckoutRequest{}
+	mi := &file_api_proto_payment_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckoutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckoutRequest) ProtoMessage() {}
+
+func (x *CheckoutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckoutRequest.ProtoReflect.Descriptor instead.
+func (*CheckoutRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CheckoutRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *CheckoutRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *CheckoutRequest) GetPaymentMethod() string {
+	if x != nil {
+		return x.PaymentMethod
+	}
+	return ""
+}
+
+func (x *CheckoutRequest) GetBasketVersion() int64 {
+	if x != nil {
+		return x.BasketVersion
+	}
+	return 0
+}
+
+type ConfirmPaymentRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Amount        float64                `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount,omitempty"`
-	Currency      string                 `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
-	PaymentMethod string                 `protobuf:"bytes,4,opt,name=payment_method,json=paymentMethod,proto3" json:"payment_method,omitempty"`
+	PaymentId     uint32                 `protobuf:"varint,1,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfirmPaymentRequest) Reset() {
+	*x = ConfirmPaymentRequest{}
+	mi := &file_api_proto_payment_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfirmPaymentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfirmPaymentRequest) ProtoMessage() {}
+
+func (x *ConfirmPaymentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfirmPaymentRequest.ProtoReflect.Descriptor instead.
+func (*ConfirmPaymentRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ConfirmPaymentRequest) GetPaymentId() uint32 {
+	if x != nil {
+		return x.PaymentId
+	}
+	return 0
+}
+
+type RefundPaymentRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	PaymentId uint32                 `protobuf:"varint,1,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
+	// Amount to refund in the payment's currency; unset or zero refunds the
+	// remaining captured amount
+	Amount *Money `protobuf:"bytes,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	Reason string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	// Items returned to stock. When omitted, a refund that settles the
+	// payment restocks every item not returned yet.
+	Items         []*RefundItem `protobuf:"bytes,4,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RefundPaymentRequest) Reset() {
+	*x = RefundPaymentRequest{}
+	mi := &file_api_proto_payment_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefundPaymentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefundPaymentRequest) ProtoMessage() {}
+
+func (x *RefundPaymentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefundPaymentRequest.ProtoReflect.Descriptor instead.
+func (*RefundPaymentRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RefundPaymentRequest) GetPaymentId() uint32 {
+	if x != nil {
+		return x.PaymentId
+	}
+	return 0
+}
+
+func (x *RefundPaymentRequest) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *RefundPaymentRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *RefundPaymentRequest) GetItems() []*RefundItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type RefundItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProductId     uint32                 `protobuf:"varint,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Quantity      int32                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RefundItem) Reset() {
+	*x = RefundItem{}
+	mi := &file_api_proto_payment_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefundItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefundItem) ProtoMessage() {}
+
+func (x *RefundItem) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefundItem.ProtoReflect.Descriptor instead.
+func (*RefundItem) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RefundItem) GetProductId() uint32 {
+	if x != nil {
+		return x.ProductId
+	}
+	return 0
+}
+
+func (x *RefundItem) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+type GetPaymentHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PaymentId     uint32                 `protobuf:"varint,1,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPaymentHistoryRequest) Reset() {
+	*x = GetPaymentHistoryRequest{}
+	mi := &file_api_proto_payment_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPaymentHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPaymentHistoryRequest) ProtoMessage() {}
+
+func (x *GetPaymentHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPaymentHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetPaymentHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetPaymentHistoryRequest) GetPaymentId() uint32 {
+	if x != nil {
+		return x.PaymentId
+	}
+	return 0
+}
+
+type GetPaymentHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*PaymentEvent        `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPaymentHistoryResponse) Reset() {
+	*x = GetPaymentHistoryResponse{}
+	mi := &file_api_proto_payment_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPaymentHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPaymentHistoryResponse) ProtoMessage() {}
+
+func (x *GetPaymentHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPaymentHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetPaymentHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetPaymentHistoryResponse) GetEvents() []*PaymentEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type PaymentEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromStatus    string                 `protobuf:"bytes,1,opt,name=from_status,json=fromStatus,proto3" json:"from_status,omitempty"`
+	ToStatus      string                 `protobuf:"bytes,2,opt,name=to_status,json=toStatus,proto3" json:"to_status,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	CreatedAt     string                 `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaymentEvent) Reset() {
+	*x = PaymentEvent{}
+	mi := &file_api_proto_payment_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaymentEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaymentEvent) ProtoMessage() {}
+
+func (x *PaymentEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaymentEvent.ProtoReflect.Descriptor instead.
+func (*PaymentEvent) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PaymentEvent) GetFromStatus() string {
+	if x != nil {
+		return x.FromStatus
+	}
+	return ""
+}
+
+func (x *PaymentEvent) GetToStatus() string {
+	if x != nil {
+		return x.ToStatus
+	}
+	return ""
+}
+
+func (x *PaymentEvent) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *PaymentEvent) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+// Unset (zero) filters are ignored. Results are ordered newest first.
+type ListPaymentsRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	UserId   uint32                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Status   string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Currency string                 `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
+	// RFC 3339 timestamps; created_from is inclusive, created_to exclusive
+	CreatedFrom string `protobuf:"bytes,4,opt,name=created_from,json=createdFrom,proto3" json:"created_from,omitempty"`
+	CreatedTo   string `protobuf:"bytes,5,opt,name=created_to,json=createdTo,proto3" json:"created_to,omitempty"`
+	// Amount bounds in minor units, inclusive; they require currency
+	MinAmount int64 `protobuf:"varint,10,opt,name=min_amount,json=minAmount,proto3" json:"min_amount,omitempty"`
+	MaxAmount int64 `protobuf:"varint,11,opt,name=max_amount,json=maxAmount,proto3" json:"max_amount,omitempty"`
+	// Defaults to 20, capped at 100
+	PageSize int32 `protobuf:"varint,8,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token from a previous response
+	PageToken     string `protobuf:"bytes,9,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProcessPaymentRequest) Reset() {
-	*x = ProcessPaymentRequest{}
-	mi := &file_api_proto_payment_proto_msgTypes[0]
+func (x *ListPaymentsRequest) Reset() {
+	*x = ListPaymentsRequest{}
+	mi := &file_api_proto_payment_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProcessPaymentRequest) String() string {
+func (x *ListPaymentsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProcessPaymentRequest) ProtoMessage() {}
+func (*ListPaymentsRequest) ProtoMessage() {}
 
-func (x *ProcessPaymentRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_payment_proto_msgTypes[0]
+func (x *ListPaymentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -56,35 +532,122 @@ func (x *ProcessPaymentRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProcessPaymentRequest.ProtoReflect.Descriptor instead.
-func (*ProcessPaymentRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_payment_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use ListPaymentsRequest.ProtoReflect.Descriptor instead.
+func (*ListPaymentsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *ProcessPaymentRequest) GetUserId() uint32 {
+func (x *ListPaymentsRequest) GetUserId() uint32 {
 	if x != nil {
 		return x.UserId
 	}
 	return 0
 }
 
-func (x *ProcessPaymentRequest) GetAmount() float64 {
+func (x *ListPaymentsRequest) GetStatus() string {
 	if x != nil {
-		return x.Amount
+		return x.Status
 	}
-	return 0
+	return ""
 }
 
-func (x *ProcessPaymentRequest) GetCurrency() string {
+func (x *ListPaymentsRequest) GetCurrency() string {
 	if x != nil {
 		return x.Currency
 	}
 	return ""
 }
 
-func (x *ProcessPaymentRequest) GetPaymentMethod() string {
+func (x *ListPaymentsRequest) GetCreatedFrom() string {
 	if x != nil {
-		return x.PaymentMethod
+		return x.CreatedFrom
+	}
+	return ""
+}
+
+func (x *ListPaymentsRequest) GetCreatedTo() string {
+	if x != nil {
+		return x.CreatedTo
+	}
+	return ""
+}
+
+func (x *ListPaymentsRequest) GetMinAmount() int64 {
+	if x != nil {
+		return x.MinAmount
+	}
+	return 0
+}
+
+func (x *ListPaymentsRequest) GetMaxAmount() int64 {
+	if x != nil {
+		return x.MaxAmount
+	}
+	return 0
+}
+
+func (x *ListPaymentsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListPaymentsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListPaymentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payments      []*PaymentResponse     `protobuf:"bytes,1,rep,name=payments,proto3" json:"payments,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPaymentsResponse) Reset() {
+	*x = ListPaymentsResponse{}
+	mi := &file_api_proto_payment_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPaymentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPaymentsResponse) ProtoMessage() {}
+
+func (x *ListPaymentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPaymentsResponse.ProtoReflect.Descriptor instead.
+func (*ListPaymentsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListPaymentsResponse) GetPayments() []*PaymentResponse {
+	if x != nil {
+		return x.Payments
+	}
+	return nil
+}
+
+func (x *ListPaymentsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
 	}
 	return ""
 }
@@ -98,7 +661,7 @@ type GetPaymentRequest struct {
 
 func (x *GetPaymentRequest) Reset() {
 	*x = GetPaymentRequest{}
-	mi := &file_api_proto_payment_proto_msgTypes[1]
+	mi := &file_api_proto_payment_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -110,7 +673,7 @@ func (x *GetPaymentRequest) String() string {
 func (*GetPaymentRequest) ProtoMessage() {}
 
 func (x *GetPaymentRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_payment_proto_msgTypes[1]
+	mi := &file_api_proto_payment_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -123,7 +686,7 @@ func (x *GetPaymentRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetPaymentRequest.ProtoReflect.Descriptor instead.
 func (*GetPaymentRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_payment_proto_rawDescGZIP(), []int{1}
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *GetPaymentRequest) GetPaymentId() uint32 {
@@ -137,17 +700,25 @@ type PaymentResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PaymentId     uint32                 `protobuf:"varint,1,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
 	UserId        uint32                 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Amount        float64                `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount,omitempty"`
-	Currency      string                 `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	Amount        *Money                 `protobuf:"bytes,14,opt,name=amount,proto3" json:"amount,omitempty"`
 	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
 	CreatedAt     string                 `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Items         []*PaymentItem         `protobuf:"bytes,7,rep,name=items,proto3" json:"items,omitempty"`
+	FailureReason string                 `protobuf:"bytes,8,opt,name=failure_reason,json=failureReason,proto3" json:"failure_reason,omitempty"`
+	// Set when status is "requires_action", e.g. a 3-D Secure challenge
+	NextActionUrl  string    `protobuf:"bytes,9,opt,name=next_action_url,json=nextActionUrl,proto3" json:"next_action_url,omitempty"`
+	RefundedAmount *Money    `protobuf:"bytes,15,opt,name=refunded_amount,json=refundedAmount,proto3" json:"refunded_amount,omitempty"`
+	Refunds        []*Refund `protobuf:"bytes,11,rep,name=refunds,proto3" json:"refunds,omitempty"`
+	// amount converted to the merchant's base currency at exchange_rate
+	SettledAmount *Money  `protobuf:"bytes,12,opt,name=settled_amount,json=settledAmount,proto3" json:"settled_amount,omitempty"`
+	ExchangeRate  float64 `protobuf:"fixed64,13,opt,name=exchange_rate,json=exchangeRate,proto3" json:"exchange_rate,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PaymentResponse) Reset() {
 	*x = PaymentResponse{}
-	mi := &file_api_proto_payment_proto_msgTypes[2]
+	mi := &file_api_proto_payment_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -159,7 +730,7 @@ func (x *PaymentResponse) String() string {
 func (*PaymentResponse) ProtoMessage() {}
 
 func (x *PaymentResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_payment_proto_msgTypes[2]
+	mi := &file_api_proto_payment_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -172,7 +743,7 @@ func (x *PaymentResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PaymentResponse.ProtoReflect.Descriptor instead.
 func (*PaymentResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_payment_proto_rawDescGZIP(), []int{2}
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *PaymentResponse) GetPaymentId() uint32 {
@@ -189,60 +760,322 @@ func (x *PaymentResponse) GetUserId() uint32 {
 	return 0
 }
 
-func (x *PaymentResponse) GetAmount() float64 {
+func (x *PaymentResponse) GetAmount() *Money {
 	if x != nil {
 		return x.Amount
 	}
-	return 0
+	return nil
 }
 
-func (x *PaymentResponse) GetCurrency() string {
+func (x *PaymentResponse) GetStatus() string {
 	if x != nil {
-		return x.Currency
+		return x.Status
 	}
 	return ""
 }
 
-func (x *PaymentResponse) GetStatus() string {
+func (x *PaymentResponse) GetCreatedAt() string {
 	if x != nil {
-		return x.Status
+		return x.CreatedAt
 	}
 	return ""
 }
 
-func (x *PaymentResponse) GetCreatedAt() string {
+func (x *PaymentResponse) GetItems() []*PaymentItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *PaymentResponse) GetFailureReason() string {
+	if x != nil {
+		return x.FailureReason
+	}
+	return ""
+}
+
+func (x *PaymentResponse) GetNextActionUrl() string {
+	if x != nil {
+		return x.NextActionUrl
+	}
+	return ""
+}
+
+func (x *PaymentResponse) GetRefundedAmount() *Money {
+	if x != nil {
+		return x.RefundedAmount
+	}
+	return nil
+}
+
+func (x *PaymentResponse) GetRefunds() []*Refund {
+	if x != nil {
+		return x.Refunds
+	}
+	return nil
+}
+
+func (x *PaymentResponse) GetSettledAmount() *Money {
+	if x != nil {
+		return x.SettledAmount
+	}
+	return nil
+}
+
+func (x *PaymentResponse) GetExchangeRate() float64 {
+	if x != nil {
+		return x.ExchangeRate
+	}
+	return 0
+}
+
+type Refund struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RefundId  uint32                 `protobuf:"varint,1,opt,name=refund_id,json=refundId,proto3" json:"refund_id,omitempty"`
+	Amount    *Money                 `protobuf:"bytes,7,opt,name=amount,proto3" json:"amount,omitempty"`
+	Reason    string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	CreatedAt string                 `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Items     []*RefundItem          `protobuf:"bytes,5,rep,name=items,proto3" json:"items,omitempty"`
+	// pending while the provider pays it out, then succeeded or failed
+	Status        string `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Refund) Reset() {
+	*x = Refund{}
+	mi := &file_api_proto_payment_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Refund) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Refund) ProtoMessage() {}
+
+func (x *Refund) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Refund.ProtoReflect.Descriptor instead.
+func (*Refund) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Refund) GetRefundId() uint32 {
+	if x != nil {
+		return x.RefundId
+	}
+	return 0
+}
+
+func (x *Refund) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *Refund) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Refund) GetCreatedAt() string {
 	if x != nil {
 		return x.CreatedAt
 	}
 	return ""
 }
 
+func (x *Refund) GetItems() []*RefundItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *Refund) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type PaymentItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProductId     uint32                 `protobuf:"varint,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Quantity      int32                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	UnitPrice     *Money                 `protobuf:"bytes,4,opt,name=unit_price,json=unitPrice,proto3" json:"unit_price,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaymentItem) Reset() {
+	*x = PaymentItem{}
+	mi := &file_api_proto_payment_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaymentItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaymentItem) ProtoMessage() {}
+
+func (x *PaymentItem) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_payment_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaymentItem.ProtoReflect.Descriptor instead.
+func (*PaymentItem) Descriptor() ([]byte, []int) {
+	return file_api_proto_payment_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PaymentItem) GetProductId() uint32 {
+	if x != nil {
+		return x.ProductId
+	}
+	return 0
+}
+
+func (x *PaymentItem) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *PaymentItem) GetUnitPrice() *Money {
+	if x != nil {
+		return x.UnitPrice
+	}
+	return nil
+}
+
 var File_api_proto_payment_proto protoreflect.FileDescriptor
 
 const file_api_proto_payment_proto_rawDesc = "" +
 	"\n" +
-	"\x17api/proto/payment.proto\x12\apayment\"\x8b\x01\n" +
+	"\x17api/proto/payment.proto\x12\apayment\x1a\x15api/proto/money.proto\"\xb2\x01\n" +
 	"\x15ProcessPaymentRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\x12$\n" +
+	"\x06amount\x18\x06 \x01(\v2\f.money.MoneyR\x06amount\x12%\n" +
+	"\x0epayment_method\x18\x04 \x01(\tR\rpaymentMethod\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKeyJ\x04\b\x02\x10\x03J\x04\b\x03\x10\x04\"\x94\x01\n" +
+	"\x0fCheckoutRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\x12%\n" +
+	"\x0epayment_method\x18\x03 \x01(\tR\rpaymentMethod\x12%\n" +
+	"\x0ebasket_version\x18\x04 \x01(\x03R\rbasketVersion\"6\n" +
+	"\x15ConfirmPaymentRequest\x12\x1d\n" +
+	"\n" +
+	"payment_id\x18\x01 \x01(\rR\tpaymentId\"\xa4\x01\n" +
+	"\x14RefundPaymentRequest\x12\x1d\n" +
+	"\n" +
+	"payment_id\x18\x01 \x01(\rR\tpaymentId\x12$\n" +
+	"\x06amount\x18\x05 \x01(\v2\f.money.MoneyR\x06amount\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12)\n" +
+	"\x05items\x18\x04 \x03(\v2\x13.payment.RefundItemR\x05itemsJ\x04\b\x02\x10\x03\"G\n" +
+	"\n" +
+	"RefundItem\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x01 \x01(\rR\tproductId\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x05R\bquantity\"9\n" +
+	"\x18GetPaymentHistoryRequest\x12\x1d\n" +
+	"\n" +
+	"payment_id\x18\x01 \x01(\rR\tpaymentId\"J\n" +
+	"\x19GetPaymentHistoryResponse\x12-\n" +
+	"\x06events\x18\x01 \x03(\v2\x15.payment.PaymentEventR\x06events\"\x83\x01\n" +
+	"\fPaymentEvent\x12\x1f\n" +
+	"\vfrom_status\x18\x01 \x01(\tR\n" +
+	"fromStatus\x12\x1b\n" +
+	"\tto_status\x18\x02 \x01(\tR\btoStatus\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\tR\tcreatedAt\"\xaa\x02\n" +
+	"\x13ListPaymentsRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\rR\x06userId\x12\x16\n" +
-	"\x06amount\x18\x02 \x01(\x01R\x06amount\x12\x1a\n" +
-	"\bcurrency\x18\x03 \x01(\tR\bcurrency\x12%\n" +
-	"\x0epayment_method\x18\x04 \x01(\tR\rpaymentMethod\"2\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1a\n" +
+	"\bcurrency\x18\x03 \x01(\tR\bcurrency\x12!\n" +
+	"\fcreated_from\x18\x04 \x01(\tR\vcreatedFrom\x12\x1d\n" +
+	"\n" +
+	"created_to\x18\x05 \x01(\tR\tcreatedTo\x12\x1d\n" +
+	"\n" +
+	"min_amount\x18\n" +
+	" \x01(\x03R\tminAmount\x12\x1d\n" +
+	"\n" +
+	"max_amount\x18\v \x01(\x03R\tmaxAmount\x12\x1b\n" +
+	"\tpage_size\x18\b \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\t \x01(\tR\tpageTokenJ\x04\b\x06\x10\aJ\x04\b\a\x10\b\"t\n" +
+	"\x14ListPaymentsResponse\x124\n" +
+	"\bpayments\x18\x01 \x03(\v2\x18.payment.PaymentResponseR\bpayments\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"2\n" +
 	"\x11GetPaymentRequest\x12\x1d\n" +
 	"\n" +
-	"payment_id\x18\x01 \x01(\rR\tpaymentId\"\xb4\x01\n" +
+	"payment_id\x18\x01 \x01(\rR\tpaymentId\"\xef\x03\n" +
 	"\x0fPaymentResponse\x12\x1d\n" +
 	"\n" +
 	"payment_id\x18\x01 \x01(\rR\tpaymentId\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\rR\x06userId\x12\x16\n" +
-	"\x06amount\x18\x03 \x01(\x01R\x06amount\x12\x1a\n" +
-	"\bcurrency\x18\x04 \x01(\tR\bcurrency\x12\x16\n" +
+	"\auser_id\x18\x02 \x01(\rR\x06userId\x12$\n" +
+	"\x06amount\x18\x0e \x01(\v2\f.money.MoneyR\x06amount\x12\x16\n" +
 	"\x06status\x18\x05 \x01(\tR\x06status\x12\x1d\n" +
 	"\n" +
-	"created_at\x18\x06 \x01(\tR\tcreatedAt2\xa4\x01\n" +
+	"created_at\x18\x06 \x01(\tR\tcreatedAt\x12*\n" +
+	"\x05items\x18\a \x03(\v2\x14.payment.PaymentItemR\x05items\x12%\n" +
+	"\x0efailure_reason\x18\b \x01(\tR\rfailureReason\x12&\n" +
+	"\x0fnext_action_url\x18\t \x01(\tR\rnextActionUrl\x125\n" +
+	"\x0frefunded_amount\x18\x0f \x01(\v2\f.money.MoneyR\x0erefundedAmount\x12)\n" +
+	"\arefunds\x18\v \x03(\v2\x0f.payment.RefundR\arefunds\x123\n" +
+	"\x0esettled_amount\x18\f \x01(\v2\f.money.MoneyR\rsettledAmount\x12#\n" +
+	"\rexchange_rate\x18\r \x01(\x01R\fexchangeRateJ\x04\b\x03\x10\x04J\x04\b\x04\x10\x05J\x04\b\n" +
+	"\x10\v\"\xcb\x01\n" +
+	"\x06Refund\x12\x1b\n" +
+	"\trefund_id\x18\x01 \x01(\rR\brefundId\x12$\n" +
+	"\x06amount\x18\a \x01(\v2\f.money.MoneyR\x06amount\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\tR\tcreatedAt\x12)\n" +
+	"\x05items\x18\x05 \x03(\v2\x13.payment.RefundItemR\x05items\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06statusJ\x04\b\x02\x10\x03\"{\n" +
+	"\vPaymentItem\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x01 \x01(\rR\tproductId\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x05R\bquantity\x12+\n" +
+	"\n" +
+	"unit_price\x18\x04 \x01(\v2\f.money.MoneyR\tunitPriceJ\x04\b\x03\x10\x042\xad\x04\n" +
 	"\x0ePaymentService\x12L\n" +
 	"\x0eProcessPayment\x12\x1e.payment.ProcessPaymentRequest\x1a\x18.payment.PaymentResponse\"\x00\x12D\n" +
 	"\n" +
-	"GetPayment\x12\x1a.payment.GetPaymentRequest\x1a\x18.payment.PaymentResponse\"\x00B\x13Z\x11gomicro/api/protob\x06proto3"
+	"GetPayment\x12\x1a.payment.GetPaymentRequest\x1a\x18.payment.PaymentResponse\"\x00\x12@\n" +
+	"\bCheckout\x12\x18.payment.CheckoutRequest\x1a\x18.payment.PaymentResponse\"\x00\x12L\n" +
+	"\x0eConfirmPayment\x12\x1e.payment.ConfirmPaymentRequest\x1a\x18.payment.PaymentResponse\"\x00\x12J\n" +
+	"\rRefundPayment\x12\x1d.payment.RefundPaymentRequest\x1a\x18.payment.PaymentResponse\"\x00\x12\\\n" +
+	"\x11GetPaymentHistory\x12!.payment.GetPaymentHistoryRequest\x1a\".payment.GetPaymentHistoryResponse\"\x00\x12M\n" +
+	"\fListPayments\x12\x1c.payment.ListPaymentsRequest\x1a\x1d.payment.ListPaymentsResponse\"\x00B\x13Z\x11gomicro/api/protob\x06proto3"
 
 var (
 	file_api_proto_payment_proto_rawDescOnce sync.Once
@@ -256,22 +1089,57 @@ func file_api_proto_payment_proto_rawDescGZIP() []byte {
 	return file_api_proto_payment_proto_rawDescData
 }
 
-var file_api_proto_payment_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_api_proto_payment_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_api_proto_payment_proto_goTypes = []any{
-	(*ProcessPaymentRequest)(nil), // 0: payment.ProcessPaymentRequest
-	(*GetPaymentRequest)(nil),     // 1: payment.GetPaymentRequest
-	(*PaymentResponse)(nil),       // 2: payment.PaymentResponse
+	(*ProcessPaymentRequest)(nil),     // 0: payment.ProcessPaymentRequest
+	(*CheckoutRequest)(nil),           // 1: payment.CheckoutRequest
+	(*ConfirmPaymentRequest)(nil),     // 2: payment.ConfirmPaymentRequest
+	(*RefundPaymentRequest)(nil),      // 3: payment.RefundPaymentRequest
+	(*RefundItem)(nil),                // 4: payment.RefundItem
+	(*GetPaymentHistoryRequest)(nil),  // 5: payment.GetPaymentHistoryRequest
+	(*GetPaymentHistoryResponse)(nil), // 6: payment.GetPaymentHistoryResponse
+	(*PaymentEvent)(nil),              // 7: payment.PaymentEvent
+	(*ListPaymentsRequest)(nil),       // 8: payment.ListPaymentsRequest
+	(*ListPaymentsResponse)(nil),      // 9: payment.ListPaymentsResponse
+	(*GetPaymentRequest)(nil),         // 10: payment.GetPaymentRequest
+	(*PaymentResponse)(nil),           // 11: payment.PaymentResponse
+	(*Refund)(nil),                    // 12: payment.Refund
+	(*PaymentItem)(nil),               // 13: payment.PaymentItem
+	(*Money)(nil),                     // 14: money.Money
 }
 var file_api_proto_payment_proto_depIdxs = []int32{
-	0, // 0: payment.PaymentService.ProcessPayment:input_type -> payment.ProcessPaymentRequest
-	1, // 1: payment.PaymentService.GetPayment:input_type -> payment.GetPaymentRequest
-	2, // 2: payment.PaymentService.ProcessPayment:output_type -> payment.PaymentResponse
-	2, // 3: payment.PaymentService.GetPayment:output_type -> payment.PaymentResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	14, // 0: payment.ProcessPaymentRequest.amount:type_name -> money.Money
+	14, // 1: payment.RefundPaymentRequest.amount:type_name -> money.Money
+	4,  // 2: payment.RefundPaymentRequest.items:type_name -> payment.RefundItem
+	7,  // 3: payment.GetPaymentHistoryResponse.events:type_name -> payment.PaymentEvent
+	11, // 4: payment.ListPaymentsResponse.payments:type_name -> payment.PaymentResponse
+	14, // 5: payment.PaymentResponse.amount:type_name -> money.Money
+	13, // 6: payment.PaymentResponse.items:type_name -> payment.PaymentItem
+	14, // 7: payment.PaymentResponse.refunded_amount:type_name -> money.Money
+	12, // 8: payment.PaymentResponse.refunds:type_name -> payment.Refund
+	14, // 9: payment.PaymentResponse.settled_amount:type_name -> money.Money
+	14, // 10: payment.Refund.amount:type_name -> money.Money
+	4,  // 11: payment.Refund.items:type_name -> payment.RefundItem
+	14, // 12: payment.PaymentItem.unit_price:type_name -> money.Money
+	0,  // 13: payment.PaymentService.ProcessPayment:input_type -> payment.ProcessPaymentRequest
+	10, // 14: payment.PaymentService.GetPayment:input_type -> payment.GetPaymentRequest
+	1,  // 15: payment.PaymentService.Checkout:input_type -> payment.CheckoutRequest
+	2,  // 16: payment.PaymentService.ConfirmPayment:input_type -> payment.ConfirmPaymentRequest
+	3,  // 17: payment.PaymentService.RefundPayment:input_type -> payment.RefundPaymentRequest
+	5,  // 18: payment.PaymentService.GetPaymentHistory:input_type -> payment.GetPaymentHistoryRequest
+	8,  // 19: payment.PaymentService.ListPayments:input_type -> payment.ListPaymentsRequest
+	11, // 20: payment.PaymentService.ProcessPayment:output_type -> payment.PaymentResponse
+	11, // 21: payment.PaymentService.GetPayment:output_type -> payment.PaymentResponse
+	11, // 22: payment.PaymentService.Checkout:output_type -> payment.PaymentResponse
+	11, // 23: payment.PaymentService.ConfirmPayment:output_type -> payment.PaymentResponse
+	11, // 24: payment.PaymentService.RefundPayment:output_type -> payment.PaymentResponse
+	6,  // 25: payment.PaymentService.GetPaymentHistory:output_type -> payment.GetPaymentHistoryResponse
+	9,  // 26: payment.PaymentService.ListPayments:output_type -> payment.ListPaymentsResponse
+	20, // [20:27] is the sub-list for method output_type
+	13, // [13:20] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_payment_proto_init() }
@@ -279,13 +1147,14 @@ func file_api_proto_payment_proto_init() {
 	if File_api_proto_payment_proto != nil {
 		return
 	}
+	file_api_proto_money_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_payment_proto_rawDesc), len(file_api_proto_payment_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
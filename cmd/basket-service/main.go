@@ -5,42 +5,104 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/url"
 	"os"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"google.golang.org/grpc"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
 	pb "gomicro/api/proto"
 	"gomicro/internal/basket/handler"
+	"gomicro/internal/basket/model"
 	"gomicro/internal/basket/repository"
 	"gomicro/internal/basket/service"
+	"gomicro/internal/money"
 )
 
 func main() {
-	// Redis connection
-	redisHost := getEnv("REDIS_HOST", "localhost")
-	redisPort := getEnv("REDIS_PORT", "6379")
+	ctx := context.Background()
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
-		Password: "", // no password set
-		DB:       0,  // use default DB
-	})
+	// Baskets expire after BASKET_TTL without updates
+	basketTTL := getDurationEnv("BASKET_TTL", repository.DefaultBasketTTL)
+	abandonedAfter := getDurationEnv("BASKET_ABANDONED_AFTER", 2*time.Hour)
+	if abandonedAfter >= basketTTL {
+		log.Fatalf("BASKET_ABANDONED_AFTER (%v) must be shorter than BASKET_TTL (%v)", abandonedAfter, basketTTL)
+	}
 
-	// Test Redis connection
-	ctx := context.Background()
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+	// Baskets saved before prices were stored in minor units hold bare
+	// amounts in the merchant's base currency
+	money.LegacyCurrency = getEnv("MERCHANT_BASE_CURRENCY", "TRY")
+	if !money.IsKnownCurrency(money.LegacyCurrency) {
+		log.Fatalf("Unknown merchant base currency %q", money.LegacyCurrency)
+	}
+
+	// Coupon definitions come from configuration; their use counts live in
+	// the basket store
+	couponDefinitions, err := repository.LoadCoupons(getEnv("COUPONS_FILE", "deployments/coupons.json"))
+	if err != nil {
+		log.Fatalf("Failed to load coupons: %v", err)
+	}
+
+	// Initialize repositories on the configured store
+	var repo repository.BasketRepository
+	var coupons repository.CouponRepository
+	switch store := getEnv("BASKET_STORE", "redis"); store {
+	case "redis":
+		rdb := connectRedis(ctx)
+		repo = repository.NewBasketRepository(rdb, basketTTL)
+		coupons = repository.NewCouponRepository(rdb, couponDefinitions)
+	case "postgres":
+		db := connectPostgres()
+		repo = repository.NewPostgresBasketRepository(db, basketTTL)
+		coupons = repository.NewPostgresCouponRepository(db, couponDefinitions)
+	case "memory":
+		log.Println("Storing baskets in memory; they are lost on restart")
+		repo = repository.NewMemoryBasketRepository(basketTTL)
+		coupons = repository.NewMemoryCouponRepository(couponDefinitions)
+	default:
+		log.Fatalf("Invalid BASKET_STORE %q: must be redis, postgres or memory", store)
 	}
-	log.Println("Connected to Redis successfully")
 
-	// Initialize repository
-	repo := repository.NewBasketRepository(rdb)
+	// Exchange rates used to display basket totals in other currencies
+	rates, err := money.LoadStaticRates(getEnv("EXCHANGE_RATES_FILE", "deployments/exchange_rates.json"))
+	if err != nil {
+		log.Fatalf("Failed to load exchange rates: %v", err)
+	}
+
+	// Product service is used to validate items and price the basket
+	productClient, err := service.NewProductClient(getEnv("PRODUCT_SERVICE_ADDR", "localhost:8081"))
+	if err != nil {
+		log.Fatalf("Failed to create product client: %v", err)
+	}
 
 	// Initialize service
-	basketService := service.NewBasketService(repo)
+	basketService := service.NewBasketService(repo, rates, productClient, coupons)
+
+	// Publish BasketAbandoned events for baskets idle past the threshold
+	rabbitmqURL := url.URL{
+		Scheme: "amqp",
+		User:   url.UserPassword(getEnv("RABBITMQ_USER", "guest"), getEnv("RABBITMQ_PASSWORD", "guest")),
+		Host:   net.JoinHostPort(getEnv("RABBITMQ_HOST", "localhost"), getEnv("RABBITMQ_PORT", "5672")),
+		Path:   "/",
+	}
+	publisher, err := service.NewRabbitMQPublisher(rabbitmqURL.String(), "basket-events")
+	if err != nil {
+		log.Fatalf("Failed to create RabbitMQ publisher: %v", err)
+	}
+	defer publisher.Close()
+	abandonedJob := service.NewAbandonedBasketJob(repo, basketService, publisher, abandonedAfter, getDurationEnv("BASKET_ABANDONED_CHECK_INTERVAL", time.Minute))
+	go abandonedJob.Run(ctx)
+
+	// Conflict rule for MergeBaskets requests that do not choose one
+	mergeStrategy, err := service.ParseMergeStrategy(getEnv("BASKET_MERGE_STRATEGY", string(service.MergeSum)))
+	if err != nil {
+		log.Fatalf("Invalid BASKET_MERGE_STRATEGY: %v", err)
+	}
 
 	// Initialize gRPC handler
-	basketHandler := handler.NewBasketGRPCHandler(basketService)
+	basketHandler := handler.NewBasketGRPCHandler(basketService, mergeStrategy)
 
 	// Create gRPC server
 	server := grpc.NewServer()
@@ -61,10 +123,56 @@ func main() {
 	}
 }
 
+func connectRedis(ctx context.Context) *redis.Client {
+	redisHost := getEnv("REDIS_HOST", "localhost")
+	redisPort := getEnv("REDIS_PORT", "6379")
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
+		Password: "", // no password set
+		DB:       0,  // use default DB
+	})
+
+	// Test Redis connection
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	log.Println("Connected to Redis successfully")
+	return rdb
+}
+
+func connectPostgres() *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		getEnv("DB_HOST", "localhost"), getEnv("DB_USER", "postgres"), getEnv("DB_PASSWORD", "postgres"),
+		getEnv("DB_NAME", "gomicro"), getEnv("DB_PORT", "5432"))
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.AutoMigrate(&model.BasketRecord{}, &model.CouponUse{}, &model.CouponHold{}); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	log.Println("Database migration completed successfully")
+	return db
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
 		return defaultValue
 	}
 	return value
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("Invalid %s %q: must be a positive duration such as 24h", key, value)
+	}
+	return duration
 } 
\ No newline at end of file
@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
 	"os"
+	"time"
 
 	"google.golang.org/grpc"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
 	pb "gomicro/api/proto"
+	"gomicro/internal/money"
 	"gomicro/internal/payment/handler"
 	"gomicro/internal/payment/model"
 	"gomicro/internal/payment/repository"
@@ -41,8 +44,13 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Convert float amounts from before amounts were stored in minor units
+	if err := repository.MigrateLegacyAmounts(db); err != nil {
+		log.Fatalf("Failed to migrate legacy amounts: %v", err)
+	}
+
 	// Auto migrate the schema
-	if err := db.AutoMigrate(&model.Payment{}); err != nil {
+	if err := db.AutoMigrate(&model.Payment{}, &model.PaymentItem{}, &model.Refund{}, &model.RefundItem{}, &model.PaymentEvent{}, &model.OutboxEvent{}); err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 	log.Println("Database migration completed successfully")
@@ -58,9 +66,40 @@ func main() {
 	}
 	defer publisher.Close()
 
+	// Relay outbox events to RabbitMQ
+	outboxRelay := service.NewOutboxRelay(repository.NewOutboxRepository(db), publisher, time.Second)
+	go outboxRelay.Run(context.Background())
+
+	// Initialize gRPC clients for checkout
+	basketClient, err := service.NewBasketClient(getEnv("BASKET_SERVICE_ADDR", "localhost:8082"))
+	if err != nil {
+		log.Fatalf("Failed to create basket client: %v", err)
+	}
+	productClient, err := service.NewProductClient(getEnv("PRODUCT_SERVICE_ADDR", "localhost:8081"))
+	if err != nil {
+		log.Fatalf("Failed to create product client: %v", err)
+	}
+
+	// Only the simulated provider is available until a real PSP is integrated
+	provider := service.NewSimulatedProvider(service.DefaultSimulatorRules()...)
+
+	// Payments settle in the merchant's base currency
+	rates, err := money.LoadStaticRates(getEnv("EXCHANGE_RATES_FILE", "deployments/exchange_rates.json"))
+	if err != nil {
+		log.Fatalf("Failed to load exchange rates: %v", err)
+	}
+	baseCurrency := getEnv("MERCHANT_BASE_CURRENCY", "TRY")
+	if !money.IsKnownCurrency(baseCurrency) {
+		log.Fatalf("Unknown merchant base currency %q", baseCurrency)
+	}
+
 	// Initialize repository and service
 	paymentRepo := repository.NewPaymentRepository(db)
-	paymentService := service.NewPaymentService(paymentRepo, publisher)
+	paymentService := service.NewPaymentService(paymentRepo, provider, basketClient, productClient, rates, baseCurrency)
+
+	// Settle refunds left pending by provider timeouts
+	refundReconciler := service.NewRefundReconciler(paymentService, time.Minute, 5*time.Minute)
+	go refundReconciler.Run(context.Background())
 
 	// Initialize gRPC server
 	port := 8083
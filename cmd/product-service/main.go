@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"time"
 
 	"google.golang.org/grpc"
 	"gorm.io/driver/postgres"
@@ -40,8 +45,18 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Product search matches names by trigram similarity
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Fatalf("Failed to enable pg_trgm: %v", err)
+	}
+
+	// Convert float prices from before prices were stored in minor units
+	if err := repository.MigrateLegacyPrices(db, getEnv("MERCHANT_BASE_CURRENCY", "TRY")); err != nil {
+		log.Fatalf("Failed to migrate legacy prices: %v", err)
+	}
+
 	// Auto Migrate the schema
-	if err := db.AutoMigrate(&model.Product{}); err != nil {
+	if err := db.AutoMigrate(&model.Product{}, &model.StockReservation{}, &model.ProcessedStockUpdate{}); err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 	log.Println("Database migration completed successfully")
@@ -52,8 +67,35 @@ func main() {
 	// Initialize service
 	productService := service.NewProductService(repo)
 
+	// Initialize RabbitMQ consumer for stock updates
+	rabbitmqURL := url.URL{
+		Scheme: "amqp",
+		User:   url.UserPassword(getEnv("RABBITMQ_USER", "guest"), getEnv("RABBITMQ_PASSWORD", "guest")),
+		Host:   net.JoinHostPort(getEnv("RABBITMQ_HOST", "localhost"), getEnv("RABBITMQ_PORT", "5672")),
+		Path:   "/",
+	}
+	consumer, err := service.NewRabbitMQConsumer(rabbitmqURL.String(), "stock-updates", "product-service.stock-updates", "stock.update")
+	if err != nil {
+		log.Fatalf("Failed to create RabbitMQ consumer: %v", err)
+	}
+
+	// The first of the consumer and the servers to fail stops the service
+	errs := make(chan error, 3)
+
+	stockConsumer := service.NewStockUpdateConsumer(repo, consumer)
+	go func() {
+		if err := stockConsumer.Run(context.Background()); err != nil {
+			errs <- fmt.Errorf("stock update consumer stopped: %w", err)
+		}
+	}()
+
+	// Stock reservations for checkouts in progress; expired ones are reaped
+	reservationRepo := repository.NewReservationRepository(db)
+	reservationService := service.NewReservationService(reservationRepo)
+	go service.NewReservationReaper(reservationRepo, time.Minute).Run(context.Background())
+
 	// Initialize gRPC handler
-	productHandler := handler.NewProductGRPCHandler(productService)
+	productHandler := handler.NewProductGRPCHandler(productService, reservationService)
 
 	// Create gRPC server
 	server := grpc.NewServer()
@@ -69,7 +111,25 @@ func main() {
 	}
 
 	fmt.Printf("Product service is starting on port %d...\n", port)
-	if err := server.Serve(lis); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
-	}
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			errs <- fmt.Errorf("failed to serve: %w", err)
+		}
+	}()
+
+	// The REST API is served next to gRPC on its own port
+	httpPort := 8084
+	httpServer := &http.Server{Addr: fmt.Sprintf(":%d", httpPort), Handler: handler.NewRouter(productService)}
+	fmt.Printf("Product HTTP API is starting on port %d...\n", httpPort)
+	go func() {
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("failed to serve HTTP: %w", err)
+		}
+	}()
+
+	err = <-errs
+	httpServer.Shutdown(context.Background())
+	server.GracefulStop()
+	consumer.Close()
+	log.Fatalf("Product service stopped: %v", err)
 } 
\ No newline at end of file
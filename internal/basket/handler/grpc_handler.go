@@ -3,21 +3,30 @@ package handler
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	pb "gomicro/api/proto"
 	"gomicro/internal/basket/model"
+	"gomicro/internal/basket/repository"
 	"gomicro/internal/basket/service"
+	"gomicro/internal/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type BasketGRPCHandler struct {
 	pb.UnimplementedBasketServiceServer
-	basketService service.IBasketService
+	basketService        service.IBasketService
+	defaultMergeStrategy service.MergeStrategy
 }
 
-func NewBasketGRPCHandler(basketService service.IBasketService) *BasketGRPCHandler {
+// NewBasketGRPCHandler creates the handler; defaultMergeStrategy is used for
+// MergeBaskets requests that do not name a strategy
+func NewBasketGRPCHandler(basketService service.IBasketService, defaultMergeStrategy service.MergeStrategy) *BasketGRPCHandler {
 	return &BasketGRPCHandler{
-		basketService: basketService,
+		basketService:        basketService,
+		defaultMergeStrategy: defaultMergeStrategy,
 	}
 }
 
@@ -25,85 +34,309 @@ func (h *BasketGRPCHandler) GetBasket(ctx context.Context, req *pb.GetBasketRequ
 	if req == nil {
 		return nil, errors.New("request is nil")
 	}
-	basket, err := h.basketService.GetBasket(ctx, uint(req.UserId))
+	basket, err := h.getBasket(ctx, req.UserId, req.SessionToken)
 	if err != nil {
 		return nil, err
 	}
-	return convertToProtoBasket(basket), nil
+	protoBasket := convertToProtoBasket(basket)
+	if req.Currency != "" {
+		total, rate, err := h.basketService.ConvertTotal(ctx, basket, req.Currency)
+		if err != nil {
+			return nil, toGRPCError(err)
+		}
+		protoBasket.DisplayTotal = total.ToProto()
+		protoBasket.ExchangeRate = rate
+	}
+	return protoBasket, nil
 }
 
 func (h *BasketGRPCHandler) AddItem(ctx context.Context, req *pb.AddItemRequest) (*pb.Basket, error) {
 	if req == nil {
 		return nil, errors.New("request is nil")
 	}
-	err := h.basketService.AddItemToBasket(ctx, uint(req.UserId), uint(req.ProductId), int(req.Quantity))
+	return h.setItem(ctx, req.UserId, req.SessionToken, req.ProductId, req.Quantity, false)
+}
+
+func (h *BasketGRPCHandler) UpdateItem(ctx context.Context, req *pb.UpdateItemRequest) (*pb.Basket, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	return h.setItem(ctx, req.UserId, req.SessionToken, req.ProductId, req.Quantity, true)
+}
+
+// setItem adds quantity units of the product, or sets the line to quantity
+// when absolute is true
+func (h *BasketGRPCHandler) setItem(ctx context.Context, userID uint32, sessionToken string, productID uint32, quantity int32, absolute bool) (*pb.Basket, error) {
+	var err error
+	switch {
+	case isGuest(userID, sessionToken) && absolute:
+		err = h.basketService.UpdateGuestItemQuantity(ctx, sessionToken, uint(productID), int(quantity))
+	case isGuest(userID, sessionToken):
+		err = h.basketService.AddItemToGuestBasket(ctx, sessionToken, uint(productID), int(quantity))
+	case absolute:
+		err = h.basketService.UpdateItemQuantity(ctx, uint(userID), uint(productID), int(quantity))
+	default:
+		err = h.basketService.AddItemToBasket(ctx, uint(userID), uint(productID), int(quantity))
+	}
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	basket, err := h.getBasket(ctx, userID, sessionToken)
 	if err != nil {
 		return nil, err
 	}
-	basket, err := h.basketService.GetBasket(ctx, uint(req.UserId))
+	return convertToProtoBasket(basket), nil
+}
+
+func (h *BasketGRPCHandler) RemoveItem(ctx context.Context, req *pb.RemoveItemRequest) (*pb.Basket, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	var err error
+	if isGuest(req.UserId, req.SessionToken) {
+		err = h.basketService.RemoveItemFromGuestBasket(ctx, req.SessionToken, uint(req.ProductId))
+	} else {
+		err = h.basketService.RemoveItemFromBasket(ctx, uint(req.UserId), uint(req.ProductId))
+	}
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	basket, err := h.getBasket(ctx, req.UserId, req.SessionToken)
 	if err != nil {
 		return nil, err
 	}
 	return convertToProtoBasket(basket), nil
 }
 
-func (h *BasketGRPCHandler) UpdateItem(ctx context.Context, req *pb.UpdateItemRequest) (*pb.Basket, error) {
+func (h *BasketGRPCHandler) ClearBasket(ctx context.Context, req *pb.ClearBasketRequest) (*pb.ClearBasketResponse, error) {
 	if req == nil {
 		return nil, errors.New("request is nil")
 	}
-	err := h.basketService.AddItemToBasket(ctx, uint(req.UserId), uint(req.ProductId), int(req.Quantity))
+	var err error
+	if isGuest(req.UserId, req.SessionToken) {
+		err = h.basketService.ClearGuestBasket(ctx, req.SessionToken)
+	} else {
+		err = h.basketService.ClearBasket(ctx, uint(req.UserId))
+	}
+	if err != nil {
+		return &pb.ClearBasketResponse{Success: false}, toGRPCError(err)
+	}
+	return &pb.ClearBasketResponse{Success: true}, nil
+}
+
+func (h *BasketGRPCHandler) MergeBaskets(ctx context.Context, req *pb.MergeBasketsRequest) (*pb.Basket, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	strategy, err := h.mergeStrategy(req.Strategy)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	basket, err := h.basketService.MergeBaskets(ctx, req.SessionToken, uint(req.UserId), strategy)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	return convertToProtoBasket(basket), nil
+}
+
+func (h *BasketGRPCHandler) ApplyCoupon(ctx context.Context, req *pb.ApplyCouponRequest) (*pb.Basket, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	var err error
+	if isGuest(req.UserId, req.SessionToken) {
+		err = h.basketService.ApplyCouponToGuestBasket(ctx, req.SessionToken, req.Code)
+	} else {
+		err = h.basketService.ApplyCoupon(ctx, uint(req.UserId), req.Code)
+	}
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	basket, err := h.getBasket(ctx, req.UserId, req.SessionToken)
 	if err != nil {
 		return nil, err
 	}
-	basket, err := h.basketService.GetBasket(ctx, uint(req.UserId))
+	return convertToProtoBasket(basket), nil
+}
+
+func (h *BasketGRPCHandler) RemoveCoupon(ctx context.Context, req *pb.RemoveCouponRequest) (*pb.Basket, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	var err error
+	if isGuest(req.UserId, req.SessionToken) {
+		err = h.basketService.RemoveCouponFromGuestBasket(ctx, req.SessionToken)
+	} else {
+		err = h.basketService.RemoveCoupon(ctx, uint(req.UserId))
+	}
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	basket, err := h.getBasket(ctx, req.UserId, req.SessionToken)
 	if err != nil {
 		return nil, err
 	}
 	return convertToProtoBasket(basket), nil
 }
 
-func (h *BasketGRPCHandler) RemoveItem(ctx context.Context, req *pb.RemoveItemRequest) (*pb.Basket, error) {
+func (h *BasketGRPCHandler) GetWishlist(ctx context.Context, req *pb.GetWishlistRequest) (*pb.Wishlist, error) {
 	if req == nil {
 		return nil, errors.New("request is nil")
 	}
-	err := h.basketService.RemoveItemFromBasket(ctx, uint(req.UserId), uint(req.ProductId))
+	wishlist, err := h.basketService.GetWishlist(ctx, uint(req.UserId))
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	return &pb.Wishlist{
+		UserId:    uint32(wishlist.UserID),
+		Items:     convertToProtoItems(wishlist.Items),
+		UpdatedAt: wishlist.UpdatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+func (h *BasketGRPCHandler) MoveToWishlist(ctx context.Context, req *pb.MoveItemRequest) (*pb.Basket, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	if err := h.basketService.MoveToWishlist(ctx, uint(req.UserId), uint(req.ProductId)); err != nil {
+		return nil, toGRPCError(err)
+	}
+	basket, err := h.getBasket(ctx, req.UserId, "")
 	if err != nil {
 		return nil, err
 	}
-	basket, err := h.basketService.GetBasket(ctx, uint(req.UserId))
+	return convertToProtoBasket(basket), nil
+}
+
+func (h *BasketGRPCHandler) MoveToBasket(ctx context.Context, req *pb.MoveItemRequest) (*pb.Basket, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	if err := h.basketService.MoveToBasket(ctx, uint(req.UserId), uint(req.ProductId)); err != nil {
+		return nil, toGRPCError(err)
+	}
+	basket, err := h.getBasket(ctx, req.UserId, "")
 	if err != nil {
 		return nil, err
 	}
 	return convertToProtoBasket(basket), nil
 }
 
-func (h *BasketGRPCHandler) ClearBasket(ctx context.Context, req *pb.ClearBasketRequest) (*pb.ClearBasketResponse, error) {
+func (h *BasketGRPCHandler) LockBasketForCheckout(ctx context.Context, req *pb.LockBasketForCheckoutRequest) (*pb.Basket, error) {
 	if req == nil {
 		return nil, errors.New("request is nil")
 	}
-	err := h.basketService.ClearBasket(ctx, uint(req.UserId))
+	basket, err := h.basketService.LockBasketForCheckout(ctx, uint(req.UserId), req.Version)
 	if err != nil {
-		return &pb.ClearBasketResponse{Success: false}, err
+		return nil, toGRPCError(err)
+	}
+	return convertToProtoBasket(basket), nil
+}
+
+func (h *BasketGRPCHandler) CompleteCheckout(ctx context.Context, req *pb.CompleteCheckoutRequest) (*pb.CompleteCheckoutResponse, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	if err := h.basketService.CompleteCheckout(ctx, uint(req.UserId), req.Version); err != nil {
+		return nil, toGRPCError(err)
+	}
+	return &pb.CompleteCheckoutResponse{}, nil
+}
+
+// getBasket reads the guest basket when only a session token is given and
+// the user basket otherwise
+func (h *BasketGRPCHandler) getBasket(ctx context.Context, userID uint32, sessionToken string) (*model.Basket, error) {
+	var basket *model.Basket
+	var err error
+	if isGuest(userID, sessionToken) {
+		basket, err = h.basketService.GetGuestBasket(ctx, sessionToken)
+	} else {
+		basket, err = h.basketService.GetBasket(ctx, uint(userID))
+	}
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	return basket, nil
+}
+
+func isGuest(userID uint32, sessionToken string) bool {
+	return userID == 0 && sessionToken != ""
+}
+
+func (h *BasketGRPCHandler) mergeStrategy(strategy pb.MergeStrategy) (service.MergeStrategy, error) {
+	switch strategy {
+	case pb.MergeStrategy_MERGE_STRATEGY_UNSPECIFIED:
+		return h.defaultMergeStrategy, nil
+	case pb.MergeStrategy_MERGE_STRATEGY_SUM:
+		return service.MergeSum, nil
+	case pb.MergeStrategy_MERGE_STRATEGY_MAX:
+		return service.MergeMax, nil
+	case pb.MergeStrategy_MERGE_STRATEGY_PREFER_USER:
+		return service.MergePreferUser, nil
+	default:
+		return "", fmt.Errorf("%w: %v", service.ErrUnknownMergeStrategy, strategy)
+	}
+}
+
+func toGRPCError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrProductNotFound), errors.Is(err, service.ErrBasketNotFound),
+		errors.Is(err, service.ErrCouponNotFound), errors.Is(err, service.ErrItemNotInBasket),
+		errors.Is(err, service.ErrItemNotInWishlist):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrConcurrentModification), errors.Is(err, repository.ErrBasketChanged):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, service.ErrProductInactive), errors.Is(err, service.ErrInsufficientStock),
+		errors.Is(err, service.ErrCouponNotActive), errors.Is(err, service.ErrCouponNotApplicable),
+		errors.Is(err, repository.ErrCouponUsageLimit), errors.Is(err, service.ErrQuantityLimitExceeded):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, money.ErrUnknownCurrency), errors.Is(err, service.ErrInvalidSessionToken),
+		errors.Is(err, service.ErrUnknownMergeStrategy), errors.Is(err, service.ErrInvalidQuantity):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, money.ErrRateUnavailable):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return err
 	}
-	return &pb.ClearBasketResponse{Success: true}, nil
 }
 
 func convertToProtoBasket(basket *model.Basket) *pb.Basket {
 	protoBasket := &pb.Basket{
-		UserId:    uint32(basket.UserID),
-		Total:     basket.Total,
-		UpdatedAt: basket.UpdatedAt.Format(time.RFC3339),
-		Items:     make([]*pb.BasketItem, len(basket.Items)),
-	}
-
-	for i, item := range basket.Items {
-		protoBasket.Items[i] = &pb.BasketItem{
-			ProductId: uint32(item.ProductID),
-			Quantity:  int32(item.Quantity),
-			Price:     item.Price,
-			Name:      item.Name,
+		UserId:       uint32(basket.UserID),
+		SessionToken: basket.SessionToken,
+		CouponCode:   basket.CouponCode,
+		Version:      basket.Version,
+		Subtotal:     basket.Subtotal.ToProto(),
+		Discounts:    make([]*pb.DiscountLine, len(basket.Discounts)),
+		Total:        basket.Total.ToProto(),
+		UpdatedAt:    basket.UpdatedAt.Format(time.RFC3339),
+		Items:        convertToProtoItems(basket.Items),
+	}
+
+	for i, discount := range basket.Discounts {
+		protoBasket.Discounts[i] = &pb.DiscountLine{
+			Code:        discount.Code,
+			Description: discount.Description,
+			Amount:      discount.Amount.ToProto(),
 		}
 	}
 
 	return protoBasket
+}
+
+func convertToProtoItems(items []model.BasketItem) []*pb.BasketItem {
+	protoItems := make([]*pb.BasketItem, len(items))
+	for i, item := range items {
+		protoItems[i] = &pb.BasketItem{
+			ProductId:    uint32(item.ProductID),
+			Quantity:     int32(item.Quantity),
+			Price:        item.Price.ToProto(),
+			Name:         item.Name,
+			PriceChanged: item.PriceChanged,
+			CurrentPrice: item.CurrentPrice.ToProto(),
+			OutOfStock:   item.OutOfStock,
+			Unavailable:  item.Unavailable,
+		}
+	}
+	return protoItems
 } 
\ No newline at end of file
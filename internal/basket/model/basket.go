@@ -3,22 +3,39 @@ package model
 import (
 	"encoding/json"
 	"time"
+
+	"gomicro/internal/money"
 )
 
+// BasketItem snapshots the product's name and price when it is added. The
+// remaining fields are recomputed from the catalog on every read.
 type BasketItem struct {
-	ProductID uint    `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
-	Name      string  `json:"name"`
+	ProductID    uint        `json:"product_id"`
+	Quantity     int         `json:"quantity"`
+	Price        money.Money `json:"price"`
+	Name         string      `json:"name"`
+	PriceChanged bool        `json:"price_changed,omitempty"`
+	CurrentPrice money.Money `json:"current_price"`
+	OutOfStock   bool        `json:"out_of_stock,omitempty"`
+	Unavailable  bool        `json:"unavailable,omitempty"`
 }
 
+// Basket belongs to a user or, for anonymous shoppers, to a guest session
+// identified by SessionToken
 type Basket struct {
-	ID        uint         `json:"id"`
-	UserID    uint         `json:"user_id"`
-	Items     []BasketItem `json:"items"`
-	Total     float64      `json:"total"`
-	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
+	ID           uint         `json:"id"`
+	UserID       uint         `json:"user_id"`
+	SessionToken string       `json:"session_token,omitempty"`
+	Items        []BasketItem `json:"items"`
+	CouponCode   string       `json:"coupon_code,omitempty"`
+	// Version is incremented by every saved change
+	Version int64 `json:"version"`
+	// Subtotal, Discounts and Total are recomputed on every read
+	Subtotal  money.Money    `json:"subtotal"`
+	Discounts []DiscountLine `json:"discounts,omitempty"`
+	Total     money.Money    `json:"total"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
 }
 
 // Redis için JSON dönüşüm metodları
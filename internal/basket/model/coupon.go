@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"gomicro/internal/money"
+)
+
+// PromotionType selects how a coupon discounts a basket
+type PromotionType string
+
+const (
+	// PromotionPercentage takes Percent percent off the subtotal
+	PromotionPercentage PromotionType = "percentage"
+	// PromotionFixedAmount takes Amount off the subtotal
+	PromotionFixedAmount PromotionType = "fixed_amount"
+	// PromotionBuyXGetY makes FreeQuantity of every BuyQuantity+FreeQuantity
+	// units of ProductID free
+	PromotionBuyXGetY PromotionType = "buy_x_get_y"
+)
+
+// Coupon is a promotion redeemable by code. MinBasketValue, the validity
+// window and MaxUses restrict every promotion type; zero values mean no
+// restriction.
+type Coupon struct {
+	Code         string        `json:"code"`
+	Description  string        `json:"description"`
+	Type         PromotionType `json:"type"`
+	Percent      int64         `json:"percent,omitempty"`
+	Amount       money.Money   `json:"amount,omitzero"`
+	ProductID    uint          `json:"product_id,omitempty"`
+	BuyQuantity  int           `json:"buy_quantity,omitempty"`
+	FreeQuantity int           `json:"free_quantity,omitempty"`
+
+	MinBasketValue money.Money `json:"min_basket_value,omitzero"`
+	ValidFrom      time.Time   `json:"valid_from,omitzero"`
+	ValidUntil     time.Time   `json:"valid_until,omitzero"`
+	MaxUses        int64       `json:"max_uses,omitempty"`
+}
+
+// DiscountLine is one discount applied to a basket
+type DiscountLine struct {
+	Code        string      `json:"code"`
+	Description string      `json:"description"`
+	Amount      money.Money `json:"amount"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"gomicro/internal/money"
+)
+
+// BasketAbandonedEvent is published when a user's basket has not been
+// updated for the configured idle period
+type BasketAbandonedEvent struct {
+	UserID       uint                  `json:"user_id"`
+	Items        []AbandonedBasketItem `json:"items"`
+	Total        money.Money           `json:"total"`
+	LastActivity time.Time             `json:"last_activity"`
+}
+
+type AbandonedBasketItem struct {
+	ProductID uint        `json:"product_id"`
+	Name      string      `json:"name"`
+	Quantity  int         `json:"quantity"`
+	Price     money.Money `json:"price"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// BasketRecord is a basket, wishlist or checkout snapshot as stored by the
+// PostgreSQL repository: JSON under the same key the Redis repository uses.
+// The version counter of a user basket is a record whose revision is the
+// last version.
+type BasketRecord struct {
+	Key  string `gorm:"primaryKey"`
+	Data string `gorm:"type:jsonb;not null"`
+	// Revision is incremented by every write and guards optimistic updates
+	Revision int64 `gorm:"not null"`
+	// ExpiresAt is nil for values that do not expire
+	ExpiresAt *time.Time `gorm:"index"`
+	// ActiveAt is the unix millisecond time of the last update of a basket
+	// in the activity index, 0 otherwise
+	ActiveAt int64 `gorm:"index;not null"`
+}
+
+// CouponUse counts the uses of a coupon when baskets are stored in
+// PostgreSQL
+type CouponUse struct {
+	Code string `gorm:"primaryKey"`
+	Uses int64  `gorm:"not null"`
+}
+
+// CouponHold is a use of a coupon held by a basket in checkout when baskets
+// are stored in PostgreSQL
+type CouponHold struct {
+	Code      string    `gorm:"primaryKey"`
+	Holder    string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// Wishlist holds items a user saved for later. Items keep the snapshot taken
+// when they were in the basket; the catalog fields are recomputed on every
+// read, as for baskets.
+type Wishlist struct {
+	UserID    uint         `json:"user_id"`
+	Items     []BasketItem `json:"items"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
@@ -3,17 +3,95 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gomicro/internal/basket/model"
 )
 
+// ErrConcurrentModification is returned when a basket kept changing under
+// ModifyBasket and the update could not be applied
+var ErrConcurrentModification = errors.New("basket was modified concurrently")
+
+// ErrBasketChanged is returned when a basket is no longer at the version a
+// checkout snapshot was requested for
+var ErrBasketChanged = errors.New("basket changed since it was displayed")
+
+const (
+	// DefaultBasketTTL is how long a basket is kept after its last update
+	DefaultBasketTTL = 24 * time.Hour
+	// maxModifyAttempts bounds the optimistic retries of ModifyBasket
+	maxModifyAttempts = 20
+	// activityKey is a sorted set of basket keys scored by the unix
+	// millisecond time of their last update
+	activityKey = "baskets:activity"
+)
+
+// forgetIdleScript removes a basket from the activity index only if it was
+// not updated since it was listed as idle
+var forgetIdleScript = redis.NewScript(`
+if tonumber(redis.call('ZSCORE', KEYS[1], ARGV[1])) == tonumber(ARGV[2]) then
+	return redis.call('ZREM', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// nextVersionScript increments a basket version counter to at least ARGV[1]
+// and returns it, for writes outside a transaction watching the counter
+var nextVersionScript = redis.NewScript(`
+local version = redis.call('INCR', KEYS[1])
+if version < tonumber(ARGV[1]) then
+	version = tonumber(ARGV[1])
+	redis.call('SET', KEYS[1], version)
+end
+return version
+`)
+
+// BasketRepository stores baskets, wishlists and checkout snapshots. It is
+// implemented on Redis, PostgreSQL and process memory; all implementations
+// share the semantics documented here.
 type BasketRepository interface {
 	GetBasket(ctx context.Context, userID uint) (*model.Basket, error)
 	SaveBasket(ctx context.Context, basket *model.Basket) error
 	DeleteBasket(ctx context.Context, userID uint) error
+	// ModifyBasket atomically applies fn to the stored basket and saves the
+	// result. fn may be called more than once and must not have side effects
+	// outside the basket; if it returns an error nothing is saved.
+	ModifyBasket(ctx context.Context, userID uint, fn func(basket *model.Basket) error) error
+
+	// Guest baskets are keyed by an opaque session token instead of a user
+	GetGuestBasket(ctx context.Context, sessionToken string) (*model.Basket, error)
+	ModifyGuestBasket(ctx context.Context, sessionToken string, fn func(basket *model.Basket) error) error
+	// MergeGuestBasket atomically applies fn to the guest and user baskets,
+	// saves the user basket and deletes the guest basket. Missing baskets are
+	// passed to fn as empty baskets, never nil.
+	MergeGuestBasket(ctx context.Context, sessionToken string, userID uint, fn func(guest, user *model.Basket) error) error
+
+	// GetWishlist returns the user's saved-for-later list. Wishlists do not
+	// expire.
+	GetWishlist(ctx context.Context, userID uint) (*model.Wishlist, error)
+	// ModifyBasketAndWishlist atomically applies fn to the user's basket and
+	// wishlist and saves both, so items can move between them
+	ModifyBasketAndWishlist(ctx context.Context, userID uint, fn func(basket *model.Basket, wishlist *model.Wishlist) error) error
+
+	// IdleBaskets returns up to limit baskets whose last update is before
+	// cutoff, oldest first. UpdatedAt holds the time of that update.
+	IdleBaskets(ctx context.Context, cutoff time.Time, limit int) ([]*model.Basket, error)
+	// ForgetIdleBasket stops reporting basket as idle until it is updated
+	// again. It does nothing if the basket was updated after it was listed.
+	ForgetIdleBasket(ctx context.Context, basket *model.Basket) error
+
+	// LockCheckoutSnapshot stores snapshot, a priced copy of the user basket,
+	// as the checkout snapshot of its version for ttl and returns it. If that
+	// version was already locked the stored snapshot is returned instead. It
+	// fails with ErrBasketChanged if the basket is at another version.
+	LockCheckoutSnapshot(ctx context.Context, snapshot *model.Basket, ttl time.Duration) (*model.Basket, error)
+	// GetCheckoutSnapshot returns the snapshot locked for version, or nil if
+	// it was never locked or has expired
+	GetCheckoutSnapshot(ctx context.Context, userID uint, version int64) (*model.Basket, error)
 
 	// New methods for test/service compatibility
 	Create(ctx context.Context, basket *model.Basket) error
@@ -21,26 +99,77 @@ type BasketRepository interface {
 	Update(ctx context.Context, basket *model.Basket) error
 }
 
+// basketRepository is the Redis implementation
 type basketRepository struct {
 	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewBasketRepository stores baskets in Redis; a basket expires ttl after
+// its last update
+func NewBasketRepository(client *redis.Client, ttl time.Duration) BasketRepository {
+	return &basketRepository{client: client, ttl: ttl}
+}
+
+func basketKey(userID uint) string {
+	return fmt.Sprintf("basket:%d", userID)
+}
+
+// basketVersionKey holds the version counter of a user basket. It never
+// expires, so a basket recreated after it expired or was deleted continues
+// from the last version instead of reusing the versions of earlier checkout
+// snapshots.
+func basketVersionKey(userID uint) string {
+	return fmt.Sprintf("basket:version:%d", userID)
 }
 
-func NewBasketRepository(client *redis.Client) BasketRepository {
-	return &basketRepository{client: client}
+func guestBasketKey(sessionToken string) string {
+	return "basket:guest:" + sessionToken
+}
+
+func wishlistKey(userID uint) string {
+	return fmt.Sprintf("wishlist:%d", userID)
+}
+
+func checkoutSnapshotKey(userID uint, version int64) string {
+	return fmt.Sprintf("basket:checkout:%d:%d", userID, version)
+}
+
+// keyOf returns the key a basket is stored under
+func keyOf(basket *model.Basket) string {
+	if basket.SessionToken != "" {
+		return guestBasketKey(basket.SessionToken)
+	}
+	return basketKey(basket.UserID)
 }
 
 func (r *basketRepository) GetBasket(ctx context.Context, userID uint) (*model.Basket, error) {
-	key := fmt.Sprintf("basket:%d", userID)
-	data, err := r.client.Get(ctx, key).Bytes()
+	return getBasket(ctx, r.client, &model.Basket{UserID: userID})
+}
+
+func (r *basketRepository) GetGuestBasket(ctx context.Context, sessionToken string) (*model.Basket, error) {
+	return getBasket(ctx, r.client, &model.Basket{SessionToken: sessionToken})
+}
+
+// getBasket reads the basket stored under the key of owner through client,
+// which is either the shared client or a transaction watching that key. A
+// missing basket is returned as owner with no items, at the last version of
+// a user basket.
+func getBasket(ctx context.Context, client redis.Cmdable, owner *model.Basket) (*model.Basket, error) {
+	data, err := client.Get(ctx, keyOf(owner)).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			// Return empty basket if not found
-			return &model.Basket{
-				UserID:    userID,
-				Items:     []model.BasketItem{},
-				Total:     0,
-				UpdatedAt: time.Now(),
-			}, nil
+			owner.Items = []model.BasketItem{}
+			owner.UpdatedAt = time.Now()
+			if owner.SessionToken == "" {
+				version, err := client.Get(ctx, basketVersionKey(owner.UserID)).Int64()
+				if err != nil && err != redis.Nil {
+					return nil, err
+				}
+				owner.Version = version
+			}
+			return owner, nil
 		}
 		return nil, err
 	}
@@ -54,18 +183,321 @@ func (r *basketRepository) GetBasket(ctx context.Context, userID uint) (*model.B
 }
 
 func (r *basketRepository) SaveBasket(ctx context.Context, basket *model.Basket) error {
-	key := fmt.Sprintf("basket:%d", basket.UserID)
+	touch(basket)
+	if basket.SessionToken == "" {
+		version, err := nextVersionScript.Run(ctx, r.client, []string{basketVersionKey(basket.UserID)}, basket.Version).Int64()
+		if err != nil {
+			return err
+		}
+		basket.Version = version
+	}
 	data, err := json.Marshal(basket)
 	if err != nil {
 		return err
 	}
 
-	return r.client.Set(ctx, key, data, 24*time.Hour).Err()
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		r.save(ctx, pipe, keyOf(basket), data, basket.UpdatedAt)
+		return nil
+	})
+	return err
+}
+
+// touch records an update of basket before it is saved
+func touch(basket *model.Basket) {
+	basket.UpdatedAt = time.Now()
+	basket.Version++
+}
+
+// nextVersion gives a touched user basket the next value of its version
+// counter, read through tx, which must watch the counter. Guest baskets are
+// never checked out, so they keep their version in the basket alone.
+func nextVersion(ctx context.Context, tx *redis.Tx, basket *model.Basket) (int64, error) {
+	if basket.SessionToken != "" {
+		return 0, nil
+	}
+	counter, err := tx.Get(ctx, basketVersionKey(basket.UserID)).Int64()
+	if err != nil && err != redis.Nil {
+		return 0, err
+	}
+	basket.Version = max(counter+1, basket.Version)
+	return basket.Version - counter, nil
+}
+
+// saveVersion moves the version counter of basket by the increment returned
+// by nextVersion
+func saveVersion(ctx context.Context, pipe redis.Pipeliner, basket *model.Basket, increment int64) {
+	if basket.SessionToken == "" {
+		pipe.IncrBy(ctx, basketVersionKey(basket.UserID), increment)
+	}
+}
+
+// save writes a basket and records its update in the activity index
+func (r *basketRepository) save(ctx context.Context, pipe redis.Pipeliner, key string, data []byte, updatedAt time.Time) {
+	pipe.Set(ctx, key, data, r.ttl)
+	pipe.ZAdd(ctx, activityKey, redis.Z{Score: float64(updatedAt.UnixMilli()), Member: key})
 }
 
 func (r *basketRepository) DeleteBasket(ctx context.Context, userID uint) error {
-	key := fmt.Sprintf("basket:%d", userID)
-	return r.client.Del(ctx, key).Err()
+	key := basketKey(userID)
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.ZRem(ctx, activityKey, key)
+		pipe.Incr(ctx, basketVersionKey(userID))
+		return nil
+	})
+	return err
+}
+
+func (r *basketRepository) ModifyBasket(ctx context.Context, userID uint, fn func(basket *model.Basket) error) error {
+	return r.modify(ctx, &model.Basket{UserID: userID}, fn)
+}
+
+func (r *basketRepository) ModifyGuestBasket(ctx context.Context, sessionToken string, fn func(basket *model.Basket) error) error {
+	return r.modify(ctx, &model.Basket{SessionToken: sessionToken}, fn)
+}
+
+// modify reads the basket under WATCH and writes it back in a MULTI block, so
+// a concurrent write aborts the transaction instead of being overwritten
+func (r *basketRepository) modify(ctx context.Context, owner *model.Basket, fn func(basket *model.Basket) error) error {
+	key := keyOf(owner)
+	keys := []string{key}
+	if owner.SessionToken == "" {
+		keys = append(keys, basketVersionKey(owner.UserID))
+	}
+	return r.watch(ctx, func(tx *redis.Tx) error {
+		basket, err := getBasket(ctx, tx, &model.Basket{UserID: owner.UserID, SessionToken: owner.SessionToken})
+		if err != nil {
+			return err
+		}
+		if err := fn(basket); err != nil {
+			return err
+		}
+		touch(basket)
+		increment, err := nextVersion(ctx, tx, basket)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(basket)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			r.save(ctx, pipe, key, data, basket.UpdatedAt)
+			saveVersion(ctx, pipe, basket, increment)
+			return nil
+		})
+		return err
+	}, keys...)
+}
+
+func (r *basketRepository) MergeGuestBasket(ctx context.Context, sessionToken string, userID uint, fn func(guest, user *model.Basket) error) error {
+	guestKey, userKey := guestBasketKey(sessionToken), basketKey(userID)
+	return r.watch(ctx, func(tx *redis.Tx) error {
+		guest, err := getBasket(ctx, tx, &model.Basket{SessionToken: sessionToken})
+		if err != nil {
+			return err
+		}
+		user, err := getBasket(ctx, tx, &model.Basket{UserID: userID})
+		if err != nil {
+			return err
+		}
+		if err := fn(guest, user); err != nil {
+			return err
+		}
+		touch(user)
+		increment, err := nextVersion(ctx, tx, user)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(user)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			r.save(ctx, pipe, userKey, data, user.UpdatedAt)
+			saveVersion(ctx, pipe, user, increment)
+			pipe.Del(ctx, guestKey)
+			pipe.ZRem(ctx, activityKey, guestKey)
+			return nil
+		})
+		return err
+	}, guestKey, userKey, basketVersionKey(userID))
+}
+
+func (r *basketRepository) GetWishlist(ctx context.Context, userID uint) (*model.Wishlist, error) {
+	return getWishlist(ctx, r.client, userID)
+}
+
+func getWishlist(ctx context.Context, client redis.Cmdable, userID uint) (*model.Wishlist, error) {
+	data, err := client.Get(ctx, wishlistKey(userID)).Bytes()
+	if err == redis.Nil {
+		return &model.Wishlist{UserID: userID, Items: []model.BasketItem{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var wishlist model.Wishlist
+	if err := json.Unmarshal(data, &wishlist); err != nil {
+		return nil, err
+	}
+	return &wishlist, nil
+}
+
+func (r *basketRepository) ModifyBasketAndWishlist(ctx context.Context, userID uint, fn func(basket *model.Basket, wishlist *model.Wishlist) error) error {
+	userKey, savedKey := basketKey(userID), wishlistKey(userID)
+	return r.watch(ctx, func(tx *redis.Tx) error {
+		basket, err := getBasket(ctx, tx, &model.Basket{UserID: userID})
+		if err != nil {
+			return err
+		}
+		wishlist, err := getWishlist(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if err := fn(basket, wishlist); err != nil {
+			return err
+		}
+
+		touch(basket)
+		increment, err := nextVersion(ctx, tx, basket)
+		if err != nil {
+			return err
+		}
+		wishlist.UpdatedAt = basket.UpdatedAt
+		basketData, err := json.Marshal(basket)
+		if err != nil {
+			return err
+		}
+		wishlistData, err := json.Marshal(wishlist)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			r.save(ctx, pipe, userKey, basketData, basket.UpdatedAt)
+			saveVersion(ctx, pipe, basket, increment)
+			pipe.Set(ctx, savedKey, wishlistData, 0)
+			return nil
+		})
+		return err
+	}, userKey, savedKey, basketVersionKey(userID))
+}
+
+// watch runs txf as an optimistic transaction on keys. Transactions aborted
+// by a concurrent write are retried.
+func (r *basketRepository) watch(ctx context.Context, txf func(tx *redis.Tx) error, keys ...string) error {
+	return retry(ctx, redis.TxFailedErr, func() error {
+		return r.client.Watch(ctx, txf, keys...)
+	})
+}
+
+// retry runs attempt until it returns anything but conflict, with a short
+// jittered backoff between attempts. It gives up with
+// ErrConcurrentModification after maxModifyAttempts.
+func retry(ctx context.Context, conflict error, attempt func() error) error {
+	for i := 0; i < maxModifyAttempts; i++ {
+		err := attempt()
+		if !errors.Is(err, conflict) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(rand.Intn(i+1)+1) * time.Millisecond):
+		}
+	}
+	return ErrConcurrentModification
+}
+
+// IdleBaskets reads the activity index instead of scanning the keyspace.
+// Entries whose basket has already expired are removed from the index.
+func (r *basketRepository) IdleBaskets(ctx context.Context, cutoff time.Time, limit int) ([]*model.Basket, error) {
+	entries, err := r.client.ZRangeByScoreWithScores(ctx, activityKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   fmt.Sprintf("(%d", cutoff.UnixMilli()),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	baskets := make([]*model.Basket, 0, len(entries))
+	for _, entry := range entries {
+		key := entry.Member.(string)
+		data, err := r.client.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			if err := r.client.ZRem(ctx, activityKey, key).Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var basket model.Basket
+		if err := json.Unmarshal(data, &basket); err != nil {
+			return nil, err
+		}
+		basket.UpdatedAt = time.UnixMilli(int64(entry.Score))
+		baskets = append(baskets, &basket)
+	}
+	return baskets, nil
+}
+
+func (r *basketRepository) ForgetIdleBasket(ctx context.Context, basket *model.Basket) error {
+	return forgetIdleScript.Run(ctx, r.client, []string{activityKey}, keyOf(basket), basket.UpdatedAt.UnixMilli()).Err()
+}
+
+func (r *basketRepository) LockCheckoutSnapshot(ctx context.Context, snapshot *model.Basket, ttl time.Duration) (*model.Basket, error) {
+	userKey, lockKey := basketKey(snapshot.UserID), checkoutSnapshotKey(snapshot.UserID, snapshot.Version)
+	locked := snapshot
+	err := r.watch(ctx, func(tx *redis.Tx) error {
+		basket, err := getBasket(ctx, tx, &model.Basket{UserID: snapshot.UserID})
+		if err != nil {
+			return err
+		}
+		if basket.Version != snapshot.Version {
+			return ErrBasketChanged
+		}
+
+		data, err := tx.Get(ctx, lockKey).Bytes()
+		if err == nil {
+			locked = &model.Basket{}
+			return json.Unmarshal(data, locked)
+		}
+		if err != redis.Nil {
+			return err
+		}
+		if data, err = json.Marshal(snapshot); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, lockKey, data, ttl)
+			return nil
+		})
+		return err
+	}, userKey, lockKey)
+	if err != nil {
+		return nil, err
+	}
+	return locked, nil
+}
+
+func (r *basketRepository) GetCheckoutSnapshot(ctx context.Context, userID uint, version int64) (*model.Basket, error) {
+	data, err := r.client.Get(ctx, checkoutSnapshotKey(userID, version)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot model.Basket
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
 }
 
 // New methods for test/service compatibility
@@ -79,4 +511,4 @@ func (r *basketRepository) GetByID(ctx context.Context, basketID uint) (*model.B
 
 func (r *basketRepository) Update(ctx context.Context, basket *model.Basket) error {
 	return r.SaveBasket(ctx, basket)
-} 
\ No newline at end of file
+}
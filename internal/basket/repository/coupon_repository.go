@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gomicro/internal/basket/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrCouponUsageLimit is returned when a coupon has been used MaxUses times
+var ErrCouponUsageLimit = errors.New("coupon usage limit reached")
+
+// reserveCouponScript holds a use for the holder in ARGV[2] until the unix
+// millisecond time in ARGV[4], unless counted and held uses have reached the
+// limit in ARGV[1] (0 for none). Holds that expired before ARGV[3] are
+// dropped first; a holder that already holds a use only moves its expiry.
+var reserveCouponScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', ARGV[3])
+if not redis.call('ZSCORE', KEYS[2], ARGV[2]) then
+	local used = tonumber(redis.call('GET', KEYS[1]) or '0') + redis.call('ZCARD', KEYS[2])
+	if tonumber(ARGV[1]) > 0 and used >= tonumber(ARGV[1]) then
+		return 0
+	end
+end
+redis.call('ZADD', KEYS[2], ARGV[4], ARGV[2])
+return 1
+`)
+
+// couponAvailableScript reports whether counted and held uses are below the
+// limit in ARGV[1], dropping holds that expired before ARGV[2]
+var couponAvailableScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', ARGV[2])
+local used = tonumber(redis.call('GET', KEYS[1]) or '0') + redis.call('ZCARD', KEYS[2])
+if tonumber(ARGV[1]) > 0 and used >= tonumber(ARGV[1]) then
+	return 0
+end
+return 1
+`)
+
+// redeemCouponScript turns the hold of ARGV[1] into a counted use
+var redeemCouponScript = redis.NewScript(`
+redis.call('ZREM', KEYS[2], ARGV[1])
+return redis.call('INCR', KEYS[1])
+`)
+
+// CouponRepository looks up coupons and counts their uses. Codes are case
+// insensitive. A use is held while a basket with the coupon is checked out
+// and counted once the order is placed, so coupons sitting on baskets do not
+// use up the limit.
+type CouponRepository interface {
+	GetCoupon(ctx context.Context, code string) (*model.Coupon, error)
+	// CheckAvailable returns ErrCouponUsageLimit if counted and held uses
+	// have reached MaxUses
+	CheckAvailable(ctx context.Context, coupon *model.Coupon) error
+	// Reserve holds one use of the coupon for holder until ttl has passed,
+	// or returns ErrCouponUsageLimit. Reserving again for the same holder
+	// only moves the expiry.
+	Reserve(ctx context.Context, coupon *model.Coupon, holder string, ttl time.Duration) error
+	// Release gives back the use held for holder, if any
+	Release(ctx context.Context, coupon *model.Coupon, holder string) error
+	// Redeem counts the use held for holder. An order placed with the coupon
+	// is counted even if its hold has expired.
+	Redeem(ctx context.Context, coupon *model.Coupon, holder string) error
+}
+
+// couponCatalog serves coupon definitions from configuration by upper-cased
+// code. The repositories embed it and differ only in where uses are counted.
+type couponCatalog map[string]*model.Coupon
+
+func newCouponCatalog(coupons []*model.Coupon) couponCatalog {
+	catalog := make(couponCatalog, len(coupons))
+	for _, coupon := range coupons {
+		catalog[strings.ToUpper(coupon.Code)] = coupon
+	}
+	return catalog
+}
+
+func (c couponCatalog) GetCoupon(ctx context.Context, code string) (*model.Coupon, error) {
+	coupon, ok := c[strings.ToUpper(code)]
+	if !ok {
+		return nil, nil
+	}
+	return coupon, nil
+}
+
+// couponRepository keeps use counts in Redis, so limits hold across
+// basket-service instances
+type couponRepository struct {
+	couponCatalog
+	client *redis.Client
+}
+
+func NewCouponRepository(client *redis.Client, coupons []*model.Coupon) CouponRepository {
+	return &couponRepository{couponCatalog: newCouponCatalog(coupons), client: client}
+}
+
+// LoadCoupons reads coupon definitions from a JSON array
+func LoadCoupons(path string) ([]*model.Coupon, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var coupons []*model.Coupon
+	if err := json.Unmarshal(data, &coupons); err != nil {
+		return nil, fmt.Errorf("failed to parse coupons %s: %v", path, err)
+	}
+	return coupons, nil
+}
+
+func couponUsageKey(code string) string {
+	return "coupon:uses:" + strings.ToUpper(code)
+}
+
+// couponHoldsKey is a sorted set of holders scored by the unix millisecond
+// time their hold expires
+func couponHoldsKey(code string) string {
+	return "coupon:holds:" + strings.ToUpper(code)
+}
+
+func couponKeys(coupon *model.Coupon) []string {
+	return []string{couponUsageKey(coupon.Code), couponHoldsKey(coupon.Code)}
+}
+
+func (r *couponRepository) CheckAvailable(ctx context.Context, coupon *model.Coupon) error {
+	available, err := couponAvailableScript.Run(ctx, r.client, couponKeys(coupon), coupon.MaxUses, time.Now().UnixMilli()).Int()
+	if err != nil {
+		return err
+	}
+	if available == 0 {
+		return ErrCouponUsageLimit
+	}
+	return nil
+}
+
+func (r *couponRepository) Reserve(ctx context.Context, coupon *model.Coupon, holder string, ttl time.Duration) error {
+	now := time.Now()
+	reserved, err := reserveCouponScript.Run(ctx, r.client, couponKeys(coupon),
+		coupon.MaxUses, holder, now.UnixMilli(), now.Add(ttl).UnixMilli()).Int()
+	if err != nil {
+		return err
+	}
+	if reserved == 0 {
+		return ErrCouponUsageLimit
+	}
+	return nil
+}
+
+func (r *couponRepository) Release(ctx context.Context, coupon *model.Coupon, holder string) error {
+	return r.client.ZRem(ctx, couponHoldsKey(coupon.Code), holder).Err()
+}
+
+func (r *couponRepository) Redeem(ctx context.Context, coupon *model.Coupon, holder string) error {
+	return redeemCouponScript.Run(ctx, r.client, couponKeys(coupon), holder).Err()
+}
+
+// memoryCouponRepository counts uses in process memory, for the memory basket
+// store
+type memoryCouponRepository struct {
+	couponCatalog
+	mu    sync.Mutex
+	uses  map[string]int64
+	holds map[string]map[string]time.Time
+}
+
+func NewMemoryCouponRepository(coupons []*model.Coupon) CouponRepository {
+	return &memoryCouponRepository{
+		couponCatalog: newCouponCatalog(coupons),
+		uses:          make(map[string]int64),
+		holds:         make(map[string]map[string]time.Time),
+	}
+}
+
+// liveHolds drops the expired holds of code and returns the others. The
+// caller must hold r.mu.
+func (r *memoryCouponRepository) liveHolds(code string) map[string]time.Time {
+	holds, ok := r.holds[code]
+	if !ok {
+		holds = make(map[string]time.Time)
+		r.holds[code] = holds
+	}
+	now := time.Now()
+	for holder, expiresAt := range holds {
+		if !now.Before(expiresAt) {
+			delete(holds, holder)
+		}
+	}
+	return holds
+}
+
+func (r *memoryCouponRepository) CheckAvailable(ctx context.Context, coupon *model.Coupon) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code := strings.ToUpper(coupon.Code)
+	if coupon.MaxUses > 0 && r.uses[code]+int64(len(r.liveHolds(code))) >= coupon.MaxUses {
+		return ErrCouponUsageLimit
+	}
+	return nil
+}
+
+func (r *memoryCouponRepository) Reserve(ctx context.Context, coupon *model.Coupon, holder string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code := strings.ToUpper(coupon.Code)
+	holds := r.liveHolds(code)
+	if _, held := holds[holder]; !held && coupon.MaxUses > 0 && r.uses[code]+int64(len(holds)) >= coupon.MaxUses {
+		return ErrCouponUsageLimit
+	}
+	holds[holder] = time.Now().Add(ttl)
+	return nil
+}
+
+func (r *memoryCouponRepository) Release(ctx context.Context, coupon *model.Coupon, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.holds[strings.ToUpper(coupon.Code)], holder)
+	return nil
+}
+
+func (r *memoryCouponRepository) Redeem(ctx context.Context, coupon *model.Coupon, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code := strings.ToUpper(coupon.Code)
+	delete(r.holds[code], holder)
+	r.uses[code]++
+	return nil
+}
+
+// postgresCouponRepository counts uses in model.CouponUse rows, for the
+// PostgreSQL basket store
+type postgresCouponRepository struct {
+	couponCatalog
+	db *gorm.DB
+}
+
+func NewPostgresCouponRepository(db *gorm.DB, coupons []*model.Coupon) CouponRepository {
+	return &postgresCouponRepository{couponCatalog: newCouponCatalog(coupons), db: db}
+}
+
+// lockCouponUse creates the use count of code if needed and locks it, so
+// concurrent reservations of the coupon are serialized
+func lockCouponUse(tx *gorm.DB, code string) (*model.CouponUse, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.CouponUse{Code: code}).Error; err != nil {
+		return nil, err
+	}
+	var use model.CouponUse
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).Take(&use).Error; err != nil {
+		return nil, err
+	}
+	return &use, nil
+}
+
+// liveHolds drops the expired holds of code and counts the others
+func liveHolds(tx *gorm.DB, code string) (int64, error) {
+	if err := tx.Where("code = ? AND expires_at <= ?", code, time.Now()).Delete(&model.CouponHold{}).Error; err != nil {
+		return 0, err
+	}
+	var held int64
+	err := tx.Model(&model.CouponHold{}).Where("code = ?", code).Count(&held).Error
+	return held, err
+}
+
+func (r *postgresCouponRepository) CheckAvailable(ctx context.Context, coupon *model.Coupon) error {
+	code := strings.ToUpper(coupon.Code)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		use, err := lockCouponUse(tx, code)
+		if err != nil {
+			return err
+		}
+		held, err := liveHolds(tx, code)
+		if err != nil {
+			return err
+		}
+		if coupon.MaxUses > 0 && use.Uses+held >= coupon.MaxUses {
+			return ErrCouponUsageLimit
+		}
+		return nil
+	})
+}
+
+// Reserve counts uses and holds under a lock on the use count, so concurrent
+// reservations cannot exceed the limit
+func (r *postgresCouponRepository) Reserve(ctx context.Context, coupon *model.Coupon, holder string, ttl time.Duration) error {
+	code := strings.ToUpper(coupon.Code)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		use, err := lockCouponUse(tx, code)
+		if err != nil {
+			return err
+		}
+		held, err := liveHolds(tx, code)
+		if err != nil {
+			return err
+		}
+		var existing int64
+		if err := tx.Model(&model.CouponHold{}).Where("code = ? AND holder = ?", code, holder).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing == 0 && coupon.MaxUses > 0 && use.Uses+held >= coupon.MaxUses {
+			return ErrCouponUsageLimit
+		}
+		hold := model.CouponHold{Code: code, Holder: holder, ExpiresAt: time.Now().Add(ttl)}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "code"}, {Name: "holder"}},
+			DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+		}).Create(&hold).Error
+	})
+}
+
+func (r *postgresCouponRepository) Release(ctx context.Context, coupon *model.Coupon, holder string) error {
+	return r.db.WithContext(ctx).
+		Where("code = ? AND holder = ?", strings.ToUpper(coupon.Code), holder).
+		Delete(&model.CouponHold{}).Error
+}
+
+func (r *postgresCouponRepository) Redeem(ctx context.Context, coupon *model.Coupon, holder string) error {
+	code := strings.ToUpper(coupon.Code)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCouponUse(tx, code); err != nil {
+			return err
+		}
+		if err := tx.Where("code = ? AND holder = ?", code, holder).Delete(&model.CouponHold{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.CouponUse{}).Where("code = ?", code).Update("uses", gorm.Expr("uses + 1")).Error
+	})
+}
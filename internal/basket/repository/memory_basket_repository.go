@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"gomicro/internal/basket/model"
+)
+
+// memoryEntry is a stored basket, wishlist or checkout snapshot. Values are
+// kept as JSON so callers never share memory with the store.
+type memoryEntry struct {
+	data []byte
+	// expiresAt is zero for values that do not expire
+	expiresAt time.Time
+	// activity is the unix millisecond time of the last update of a basket
+	// in the activity index, 0 otherwise
+	activity int64
+}
+
+// memoryBasketRepository keeps everything in process memory behind a single
+// lock, for tests and single-node development. Nothing survives a restart.
+type memoryBasketRepository struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*memoryEntry
+	// versions holds the last version of each user basket; unlike the
+	// baskets themselves they never expire
+	versions map[uint]int64
+}
+
+// NewMemoryBasketRepository stores baskets in memory; a basket expires ttl
+// after its last update
+func NewMemoryBasketRepository(ttl time.Duration) BasketRepository {
+	return &memoryBasketRepository{
+		ttl:      ttl,
+		entries:  make(map[string]*memoryEntry),
+		versions: make(map[uint]int64),
+	}
+}
+
+// load decodes the live value under key into v and reports whether there was
+// one. Expired values are dropped. The caller must hold the lock.
+func (r *memoryBasketRepository) load(key string, v interface{}) (bool, error) {
+	entry, ok := r.entries[key]
+	if !ok {
+		return false, nil
+	}
+	if !entry.expiresAt.IsZero() && !time.Now().Before(entry.expiresAt) {
+		delete(r.entries, key)
+		return false, nil
+	}
+	return true, json.Unmarshal(entry.data, v)
+}
+
+// getBasket returns the basket stored under the key of owner, or owner with
+// no items at the last version of a user basket. The caller must hold the
+// lock.
+func (r *memoryBasketRepository) getBasket(owner *model.Basket) (*model.Basket, error) {
+	var basket model.Basket
+	found, err := r.load(keyOf(owner), &basket)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		owner.Items = []model.BasketItem{}
+		owner.UpdatedAt = time.Now()
+		if owner.SessionToken == "" {
+			owner.Version = r.versions[owner.UserID]
+		}
+		return owner, nil
+	}
+	return &basket, nil
+}
+
+// saveBasket stores a touched basket and records its update in the activity
+// index. A user basket gets the next version of its counter first. The caller
+// must hold the lock.
+func (r *memoryBasketRepository) saveBasket(basket *model.Basket) error {
+	if basket.SessionToken == "" {
+		basket.Version = max(r.versions[basket.UserID]+1, basket.Version)
+		r.versions[basket.UserID] = basket.Version
+	}
+	data, err := json.Marshal(basket)
+	if err != nil {
+		return err
+	}
+	r.entries[keyOf(basket)] = &memoryEntry{
+		data:      data,
+		expiresAt: basket.UpdatedAt.Add(r.ttl),
+		activity:  basket.UpdatedAt.UnixMilli(),
+	}
+	return nil
+}
+
+func (r *memoryBasketRepository) GetBasket(ctx context.Context, userID uint) (*model.Basket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getBasket(&model.Basket{UserID: userID})
+}
+
+func (r *memoryBasketRepository) GetGuestBasket(ctx context.Context, sessionToken string) (*model.Basket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getBasket(&model.Basket{SessionToken: sessionToken})
+}
+
+func (r *memoryBasketRepository) SaveBasket(ctx context.Context, basket *model.Basket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	touch(basket)
+	return r.saveBasket(basket)
+}
+
+func (r *memoryBasketRepository) DeleteBasket(ctx context.Context, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, basketKey(userID))
+	r.versions[userID]++
+	return nil
+}
+
+func (r *memoryBasketRepository) ModifyBasket(ctx context.Context, userID uint, fn func(basket *model.Basket) error) error {
+	return r.modify(&model.Basket{UserID: userID}, fn)
+}
+
+func (r *memoryBasketRepository) ModifyGuestBasket(ctx context.Context, sessionToken string, fn func(basket *model.Basket) error) error {
+	return r.modify(&model.Basket{SessionToken: sessionToken}, fn)
+}
+
+// modify holds the lock while fn runs, so updates never interleave
+func (r *memoryBasketRepository) modify(owner *model.Basket, fn func(basket *model.Basket) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	basket, err := r.getBasket(owner)
+	if err != nil {
+		return err
+	}
+	if err := fn(basket); err != nil {
+		return err
+	}
+	touch(basket)
+	return r.saveBasket(basket)
+}
+
+func (r *memoryBasketRepository) MergeGuestBasket(ctx context.Context, sessionToken string, userID uint, fn func(guest, user *model.Basket) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	guest, err := r.getBasket(&model.Basket{SessionToken: sessionToken})
+	if err != nil {
+		return err
+	}
+	user, err := r.getBasket(&model.Basket{UserID: userID})
+	if err != nil {
+		return err
+	}
+	if err := fn(guest, user); err != nil {
+		return err
+	}
+	touch(user)
+	if err := r.saveBasket(user); err != nil {
+		return err
+	}
+	delete(r.entries, guestBasketKey(sessionToken))
+	return nil
+}
+
+func (r *memoryBasketRepository) GetWishlist(ctx context.Context, userID uint) (*model.Wishlist, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getWishlist(userID)
+}
+
+// getWishlist returns the user's wishlist, or an empty one. The caller must
+// hold the lock.
+func (r *memoryBasketRepository) getWishlist(userID uint) (*model.Wishlist, error) {
+	wishlist := &model.Wishlist{UserID: userID, Items: []model.BasketItem{}}
+	if _, err := r.load(wishlistKey(userID), wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (r *memoryBasketRepository) ModifyBasketAndWishlist(ctx context.Context, userID uint, fn func(basket *model.Basket, wishlist *model.Wishlist) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	basket, err := r.getBasket(&model.Basket{UserID: userID})
+	if err != nil {
+		return err
+	}
+	wishlist, err := r.getWishlist(userID)
+	if err != nil {
+		return err
+	}
+	if err := fn(basket, wishlist); err != nil {
+		return err
+	}
+
+	touch(basket)
+	wishlist.UpdatedAt = basket.UpdatedAt
+	data, err := json.Marshal(wishlist)
+	if err != nil {
+		return err
+	}
+	if err := r.saveBasket(basket); err != nil {
+		return err
+	}
+	r.entries[wishlistKey(userID)] = &memoryEntry{data: data}
+	return nil
+}
+
+// IdleBaskets also drops every expired value, so memory does not grow with
+// baskets that were never read again
+func (r *memoryBasketRepository) IdleBaskets(ctx context.Context, cutoff time.Time, limit int) ([]*model.Basket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	keys := make([]string, 0)
+	for key, entry := range r.entries {
+		if !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt) {
+			delete(r.entries, key)
+			continue
+		}
+		if entry.activity != 0 && entry.activity < cutoff.UnixMilli() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := r.entries[keys[i]], r.entries[keys[j]]
+		if a.activity != b.activity {
+			return a.activity < b.activity
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	baskets := make([]*model.Basket, 0, len(keys))
+	for _, key := range keys {
+		var basket model.Basket
+		if err := json.Unmarshal(r.entries[key].data, &basket); err != nil {
+			return nil, err
+		}
+		basket.UpdatedAt = time.UnixMilli(r.entries[key].activity)
+		baskets = append(baskets, &basket)
+	}
+	return baskets, nil
+}
+
+func (r *memoryBasketRepository) ForgetIdleBasket(ctx context.Context, basket *model.Basket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.entries[keyOf(basket)]; ok && entry.activity == basket.UpdatedAt.UnixMilli() {
+		entry.activity = 0
+	}
+	return nil
+}
+
+func (r *memoryBasketRepository) LockCheckoutSnapshot(ctx context.Context, snapshot *model.Basket, ttl time.Duration) (*model.Basket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	basket, err := r.getBasket(&model.Basket{UserID: snapshot.UserID})
+	if err != nil {
+		return nil, err
+	}
+	if basket.Version != snapshot.Version {
+		return nil, ErrBasketChanged
+	}
+
+	key := checkoutSnapshotKey(snapshot.UserID, snapshot.Version)
+	var locked model.Basket
+	found, err := r.load(key, &locked)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &locked, nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	r.entries[key] = &memoryEntry{data: data, expiresAt: time.Now().Add(ttl)}
+	return snapshot, nil
+}
+
+func (r *memoryBasketRepository) GetCheckoutSnapshot(ctx context.Context, userID uint, version int64) (*model.Basket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var snapshot model.Basket
+	found, err := r.load(checkoutSnapshotKey(userID, version), &snapshot)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// New methods for test/service compatibility
+func (r *memoryBasketRepository) Create(ctx context.Context, basket *model.Basket) error {
+	return r.SaveBasket(ctx, basket)
+}
+
+func (r *memoryBasketRepository) GetByID(ctx context.Context, basketID uint) (*model.Basket, error) {
+	return r.GetBasket(ctx, basketID)
+}
+
+func (r *memoryBasketRepository) Update(ctx context.Context, basket *model.Basket) error {
+	return r.SaveBasket(ctx, basket)
+}
@@ -0,0 +1,397 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gomicro/internal/basket/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errRevisionConflict aborts an optimistic write whose record changed after
+// it was read
+var errRevisionConflict = errors.New("record revision changed")
+
+// postgresBasketRepository stores baskets durably as model.BasketRecord rows.
+// Updates are optimistic like the Redis WATCH transactions: a record is
+// written only if its revision is still the one that was read.
+type postgresBasketRepository struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewPostgresBasketRepository stores baskets in PostgreSQL; a basket expires
+// ttl after its last update. The model.BasketRecord table must be migrated.
+func NewPostgresBasketRepository(db *gorm.DB, ttl time.Duration) BasketRepository {
+	return &postgresBasketRepository{db: db, ttl: ttl}
+}
+
+// read returns the record under key, or a new record with revision 0. The
+// second result reports whether it holds a live value; an expired record
+// keeps its revision so that writing it back is still guarded.
+func (r *postgresBasketRepository) read(ctx context.Context, key string) (*model.BasketRecord, bool, error) {
+	var record model.BasketRecord
+	err := r.db.WithContext(ctx).Where("key = ?", key).Take(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.BasketRecord{Key: key}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	live := record.ExpiresAt == nil || time.Now().Before(*record.ExpiresAt)
+	return &record, live, nil
+}
+
+// readBasket returns the record of owner's basket and the basket, or owner
+// with no items at the last version of a user basket
+func (r *postgresBasketRepository) readBasket(ctx context.Context, owner *model.Basket) (*model.BasketRecord, *model.Basket, error) {
+	record, live, err := r.read(ctx, keyOf(owner))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !live {
+		owner.Items = []model.BasketItem{}
+		owner.UpdatedAt = time.Now()
+		if owner.SessionToken == "" {
+			counter, _, err := r.read(ctx, basketVersionKey(owner.UserID))
+			if err != nil {
+				return nil, nil, err
+			}
+			owner.Version = counter.Revision
+		}
+		return record, owner, nil
+	}
+	var basket model.Basket
+	if err := json.Unmarshal([]byte(record.Data), &basket); err != nil {
+		return nil, nil, err
+	}
+	return record, &basket, nil
+}
+
+func (r *postgresBasketRepository) readWishlist(ctx context.Context, userID uint) (*model.BasketRecord, *model.Wishlist, error) {
+	record, live, err := r.read(ctx, wishlistKey(userID))
+	if err != nil {
+		return nil, nil, err
+	}
+	wishlist := &model.Wishlist{UserID: userID, Items: []model.BasketItem{}}
+	if live {
+		if err := json.Unmarshal([]byte(record.Data), wishlist); err != nil {
+			return nil, nil, err
+		}
+	}
+	return record, wishlist, nil
+}
+
+// setBasket puts a touched basket into its record and the activity index
+func (r *postgresBasketRepository) setBasket(record *model.BasketRecord, basket *model.Basket) error {
+	data, err := json.Marshal(basket)
+	if err != nil {
+		return err
+	}
+	expiresAt := basket.UpdatedAt.Add(r.ttl)
+	record.Data = string(data)
+	record.ExpiresAt = &expiresAt
+	record.ActiveAt = basket.UpdatedAt.UnixMilli()
+	return nil
+}
+
+// recordWrite is one step of commit
+type recordWrite func(tx *gorm.DB) error
+
+// nextVersionSQL increments the version counter of a user basket, kept as the
+// revision of a record that never expires, to at least the given version
+const nextVersionSQL = `INSERT INTO basket_records (key, data, revision, active_at) VALUES (?, '{}', ?, 0)
+ON CONFLICT (key) DO UPDATE SET revision = GREATEST(basket_records.revision + 1, EXCLUDED.revision)
+RETURNING revision`
+
+// storeBasket gives a touched user basket the next value of its version
+// counter, then stores it in record at its read revision
+func (r *postgresBasketRepository) storeBasket(record *model.BasketRecord, basket *model.Basket) recordWrite {
+	return func(tx *gorm.DB) error {
+		if basket.SessionToken == "" {
+			if err := tx.Raw(nextVersionSQL, basketVersionKey(basket.UserID), basket.Version).Scan(&basket.Version).Error; err != nil {
+				return err
+			}
+		}
+		if err := r.setBasket(record, basket); err != nil {
+			return err
+		}
+		return store(record)(tx)
+	}
+}
+
+// commit applies writes in one transaction. It fails with
+// errRevisionConflict if any record changed since it was read.
+func (r *postgresBasketRepository) commit(ctx context.Context, writes ...recordWrite) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, write := range writes {
+			if err := write(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// store inserts or updates record at its read revision
+func store(record *model.BasketRecord) recordWrite {
+	return func(tx *gorm.DB) error {
+		var result *gorm.DB
+		if record.Revision == 0 {
+			record.Revision = 1
+			result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		} else {
+			result = tx.Model(&model.BasketRecord{}).
+				Where("key = ? AND revision = ?", record.Key, record.Revision).
+				Updates(map[string]interface{}{
+					"data":       record.Data,
+					"revision":   record.Revision + 1,
+					"expires_at": record.ExpiresAt,
+					"active_at":  record.ActiveAt,
+				})
+		}
+		return checkWritten(result)
+	}
+}
+
+// remove deletes record at its read revision
+func remove(record *model.BasketRecord) recordWrite {
+	return func(tx *gorm.DB) error {
+		if record.Revision == 0 {
+			return nil
+		}
+		return checkWritten(tx.Where("key = ? AND revision = ?", record.Key, record.Revision).Delete(&model.BasketRecord{}))
+	}
+}
+
+// unchanged asserts record is still at its read revision. The no-op update
+// locks the row until the transaction ends.
+func unchanged(record *model.BasketRecord) recordWrite {
+	return func(tx *gorm.DB) error {
+		if record.Revision == 0 {
+			var count int64
+			if err := tx.Model(&model.BasketRecord{}).Where("key = ?", record.Key).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return errRevisionConflict
+			}
+			return nil
+		}
+		return checkWritten(tx.Model(&model.BasketRecord{}).
+			Where("key = ? AND revision = ?", record.Key, record.Revision).
+			Update("revision", gorm.Expr("revision")))
+	}
+}
+
+func checkWritten(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errRevisionConflict
+	}
+	return nil
+}
+
+func (r *postgresBasketRepository) GetBasket(ctx context.Context, userID uint) (*model.Basket, error) {
+	_, basket, err := r.readBasket(ctx, &model.Basket{UserID: userID})
+	return basket, err
+}
+
+func (r *postgresBasketRepository) GetGuestBasket(ctx context.Context, sessionToken string) (*model.Basket, error) {
+	_, basket, err := r.readBasket(ctx, &model.Basket{SessionToken: sessionToken})
+	return basket, err
+}
+
+// SaveBasket overwrites the stored basket whatever its revision
+func (r *postgresBasketRepository) SaveBasket(ctx context.Context, basket *model.Basket) error {
+	touch(basket)
+	return retry(ctx, errRevisionConflict, func() error {
+		record, _, err := r.read(ctx, keyOf(basket))
+		if err != nil {
+			return err
+		}
+		return r.commit(ctx, r.storeBasket(record, basket))
+	})
+}
+
+// DeleteBasket also bumps the version counter, so a snapshot locked before
+// the delete never matches the basket that replaces it
+func (r *postgresBasketRepository) DeleteBasket(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("key = ?", basketKey(userID)).Delete(&model.BasketRecord{}).Error; err != nil {
+			return err
+		}
+		var version int64
+		return tx.Raw(nextVersionSQL, basketVersionKey(userID), 1).Scan(&version).Error
+	})
+}
+
+func (r *postgresBasketRepository) ModifyBasket(ctx context.Context, userID uint, fn func(basket *model.Basket) error) error {
+	return r.modify(ctx, &model.Basket{UserID: userID}, fn)
+}
+
+func (r *postgresBasketRepository) ModifyGuestBasket(ctx context.Context, sessionToken string, fn func(basket *model.Basket) error) error {
+	return r.modify(ctx, &model.Basket{SessionToken: sessionToken}, fn)
+}
+
+func (r *postgresBasketRepository) modify(ctx context.Context, owner *model.Basket, fn func(basket *model.Basket) error) error {
+	return retry(ctx, errRevisionConflict, func() error {
+		record, basket, err := r.readBasket(ctx, &model.Basket{UserID: owner.UserID, SessionToken: owner.SessionToken})
+		if err != nil {
+			return err
+		}
+		if err := fn(basket); err != nil {
+			return err
+		}
+		touch(basket)
+		return r.commit(ctx, r.storeBasket(record, basket))
+	})
+}
+
+func (r *postgresBasketRepository) MergeGuestBasket(ctx context.Context, sessionToken string, userID uint, fn func(guest, user *model.Basket) error) error {
+	return retry(ctx, errRevisionConflict, func() error {
+		guestRecord, guest, err := r.readBasket(ctx, &model.Basket{SessionToken: sessionToken})
+		if err != nil {
+			return err
+		}
+		userRecord, user, err := r.readBasket(ctx, &model.Basket{UserID: userID})
+		if err != nil {
+			return err
+		}
+		if err := fn(guest, user); err != nil {
+			return err
+		}
+		touch(user)
+		return r.commit(ctx, r.storeBasket(userRecord, user), remove(guestRecord))
+	})
+}
+
+func (r *postgresBasketRepository) GetWishlist(ctx context.Context, userID uint) (*model.Wishlist, error) {
+	_, wishlist, err := r.readWishlist(ctx, userID)
+	return wishlist, err
+}
+
+func (r *postgresBasketRepository) ModifyBasketAndWishlist(ctx context.Context, userID uint, fn func(basket *model.Basket, wishlist *model.Wishlist) error) error {
+	return retry(ctx, errRevisionConflict, func() error {
+		basketRecord, basket, err := r.readBasket(ctx, &model.Basket{UserID: userID})
+		if err != nil {
+			return err
+		}
+		wishlistRecord, wishlist, err := r.readWishlist(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := fn(basket, wishlist); err != nil {
+			return err
+		}
+
+		touch(basket)
+		wishlist.UpdatedAt = basket.UpdatedAt
+		data, err := json.Marshal(wishlist)
+		if err != nil {
+			return err
+		}
+		wishlistRecord.Data = string(data)
+		wishlistRecord.ExpiresAt = nil
+		return r.commit(ctx, r.storeBasket(basketRecord, basket), store(wishlistRecord))
+	})
+}
+
+// IdleBaskets also deletes every expired record, so the table does not grow
+// with baskets that were never read again
+func (r *postgresBasketRepository) IdleBaskets(ctx context.Context, cutoff time.Time, limit int) ([]*model.Basket, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Where("expires_at <= ?", time.Now()).Delete(&model.BasketRecord{}).Error; err != nil {
+		return nil, err
+	}
+
+	var records []model.BasketRecord
+	err := db.Where("active_at > 0 AND active_at < ?", cutoff.UnixMilli()).
+		Order("active_at, key").
+		Limit(limit).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	baskets := make([]*model.Basket, 0, len(records))
+	for _, record := range records {
+		var basket model.Basket
+		if err := json.Unmarshal([]byte(record.Data), &basket); err != nil {
+			return nil, err
+		}
+		basket.UpdatedAt = time.UnixMilli(record.ActiveAt)
+		baskets = append(baskets, &basket)
+	}
+	return baskets, nil
+}
+
+func (r *postgresBasketRepository) ForgetIdleBasket(ctx context.Context, basket *model.Basket) error {
+	return r.db.WithContext(ctx).Model(&model.BasketRecord{}).
+		Where("key = ? AND active_at = ?", keyOf(basket), basket.UpdatedAt.UnixMilli()).
+		Update("active_at", 0).Error
+}
+
+func (r *postgresBasketRepository) LockCheckoutSnapshot(ctx context.Context, snapshot *model.Basket, ttl time.Duration) (*model.Basket, error) {
+	locked := snapshot
+	err := retry(ctx, errRevisionConflict, func() error {
+		basketRecord, basket, err := r.readBasket(ctx, &model.Basket{UserID: snapshot.UserID})
+		if err != nil {
+			return err
+		}
+		if basket.Version != snapshot.Version {
+			return ErrBasketChanged
+		}
+
+		record, live, err := r.read(ctx, checkoutSnapshotKey(snapshot.UserID, snapshot.Version))
+		if err != nil {
+			return err
+		}
+		if live {
+			locked = &model.Basket{}
+			return json.Unmarshal([]byte(record.Data), locked)
+		}
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		expiresAt := time.Now().Add(ttl)
+		record.Data = string(data)
+		record.ExpiresAt = &expiresAt
+		return r.commit(ctx, unchanged(basketRecord), store(record))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return locked, nil
+}
+
+func (r *postgresBasketRepository) GetCheckoutSnapshot(ctx context.Context, userID uint, version int64) (*model.Basket, error) {
+	record, live, err := r.read(ctx, checkoutSnapshotKey(userID, version))
+	if err != nil || !live {
+		return nil, err
+	}
+	var snapshot model.Basket
+	if err := json.Unmarshal([]byte(record.Data), &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// New methods for test/service compatibility
+func (r *postgresBasketRepository) Create(ctx context.Context, basket *model.Basket) error {
+	return r.SaveBasket(ctx, basket)
+}
+
+func (r *postgresBasketRepository) GetByID(ctx context.Context, basketID uint) (*model.Basket, error) {
+	return r.GetBasket(ctx, basketID)
+}
+
+func (r *postgresBasketRepository) Update(ctx context.Context, basket *model.Basket) error {
+	return r.SaveBasket(ctx, basket)
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gomicro/internal/basket/model"
+	"gomicro/internal/basket/repository"
+)
+
+// AbandonedBasketJob publishes a BasketAbandonedEvent for every user basket
+// that has not been updated for idleAfter. A basket is reported once per idle
+// period: it is reported again only after it has been updated and left idle
+// again. Guest baskets and empty baskets are dropped from the index without
+// an event, since there is nobody to remind.
+type AbandonedBasketJob struct {
+	repo          repository.BasketRepository
+	basketService IBasketService
+	publisher     IRabbitMQPublisher
+	idleAfter     time.Duration
+	interval      time.Duration
+	batchSize     int
+}
+
+func NewAbandonedBasketJob(repo repository.BasketRepository, basketService IBasketService, publisher IRabbitMQPublisher, idleAfter, interval time.Duration) *AbandonedBasketJob {
+	return &AbandonedBasketJob{
+		repo:          repo,
+		basketService: basketService,
+		publisher:     publisher,
+		idleAfter:     idleAfter,
+		interval:      interval,
+		batchSize:     100,
+	}
+}
+
+// Run checks for abandoned baskets until the context is cancelled
+func (j *AbandonedBasketJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.PublishAbandoned(ctx); err != nil {
+			log.Printf("Failed to publish abandoned baskets: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishAbandoned publishes one batch of abandoned baskets and returns how
+// many events were sent. A basket whose publish fails stays in the index and
+// is retried on the next run.
+func (j *AbandonedBasketJob) PublishAbandoned(ctx context.Context) (int, error) {
+	idle, err := j.repo.IdleBaskets(ctx, time.Now().Add(-j.idleAfter), j.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, basket := range idle {
+		if basket.UserID != 0 && len(basket.Items) > 0 {
+			event, err := j.abandonedEvent(ctx, basket)
+			if err != nil {
+				log.Printf("Failed to price abandoned basket of user %d: %v", basket.UserID, err)
+				continue
+			}
+			if event == nil {
+				// Updated since it was listed
+				continue
+			}
+			// Nothing to remind about if every product is gone from the catalog
+			if len(event.Items) > 0 {
+				if err := j.publisher.SendBasketAbandonedEvent(event); err != nil {
+					log.Printf("Failed to publish abandoned basket of user %d: %v", basket.UserID, err)
+					continue
+				}
+				published++
+			}
+		}
+		if err := j.repo.ForgetIdleBasket(ctx, basket); err != nil {
+			return published, err
+		}
+	}
+	return published, nil
+}
+
+// abandonedEvent prices the basket at current catalog prices. It returns nil
+// if the basket was updated after idle was listed.
+func (j *AbandonedBasketJob) abandonedEvent(ctx context.Context, idle *model.Basket) (*model.BasketAbandonedEvent, error) {
+	basket, err := j.basketService.GetBasket(ctx, idle.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if basket.UpdatedAt.UnixMilli() != idle.UpdatedAt.UnixMilli() {
+		return nil, nil
+	}
+
+	event := &model.BasketAbandonedEvent{
+		UserID:       basket.UserID,
+		Items:        make([]model.AbandonedBasketItem, 0, len(basket.Items)),
+		Total:        basket.Total,
+		LastActivity: idle.UpdatedAt,
+	}
+	for _, item := range basket.Items {
+		if item.Unavailable {
+			continue
+		}
+		event.Items = append(event.Items, model.AbandonedBasketItem{
+			ProductID: item.ProductID,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			Price:     item.CurrentPrice,
+		})
+	}
+	return event, nil
+}
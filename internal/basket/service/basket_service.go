@@ -3,26 +3,143 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
 
+	pb "gomicro/api/proto"
 	"gomicro/internal/basket/model"
 	"gomicro/internal/basket/repository"
+	"gomicro/internal/money"
 )
 
+var (
+	// ErrProductNotFound is returned when adding a product that does not exist
+	ErrProductNotFound = errors.New("product not found")
+	// ErrProductInactive is returned when adding a product that is no longer sold
+	ErrProductInactive = errors.New("product is not active")
+	// ErrInsufficientStock is returned when the requested quantity exceeds the available stock
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrBasketNotFound is returned when modifying a basket that does not exist
+	ErrBasketNotFound = errors.New("basket not found")
+	// ErrInvalidSessionToken is returned for a malformed guest session token
+	ErrInvalidSessionToken = errors.New("invalid session token")
+	// ErrUnknownMergeStrategy is returned for a merge strategy this service does not know
+	ErrUnknownMergeStrategy = errors.New("unknown merge strategy")
+	// ErrInvalidQuantity is returned for a quantity that is not allowed in the request
+	ErrInvalidQuantity = errors.New("invalid quantity")
+	// ErrQuantityLimitExceeded is returned when a line or the whole basket
+	// would exceed MaxLineQuantity or MaxBasketQuantity, or the wishlist
+	// MaxWishlistItems
+	ErrQuantityLimitExceeded = errors.New("quantity limit exceeded")
+	// ErrItemNotInBasket is returned when moving a product the basket does not hold
+	ErrItemNotInBasket = errors.New("item not in basket")
+	// ErrItemNotInWishlist is returned when moving a product the wishlist does not hold
+	ErrItemNotInWishlist = errors.New("item not in wishlist")
+)
+
+const (
+	// MaxLineQuantity is the most units of one product a basket may hold
+	MaxLineQuantity = 50
+	// MaxBasketQuantity is the most units a basket may hold in total
+	MaxBasketQuantity = 200
+	// MaxWishlistItems is the most products a wishlist may hold
+	MaxWishlistItems = 100
+	// CheckoutSnapshotTTL is how long a locked checkout snapshot is kept,
+	// enough for a payment that awaits customer action
+	CheckoutSnapshotTTL = 15 * time.Minute
+)
+
+// sessionTokenPattern keeps guest tokens opaque but safe to embed in a key
+var sessionTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
+// MergeStrategy decides the quantity of a product that is both in the guest
+// and in the user basket when they are merged
+type MergeStrategy string
+
+const (
+	// MergeSum adds the guest quantity to the user quantity
+	MergeSum MergeStrategy = "sum"
+	// MergeMax keeps the larger of the two quantities
+	MergeMax MergeStrategy = "max"
+	// MergePreferUser keeps the user's line unchanged
+	MergePreferUser MergeStrategy = "prefer_user"
+)
+
+// ParseMergeStrategy validates a merge strategy read from configuration
+func ParseMergeStrategy(value string) (MergeStrategy, error) {
+	switch strategy := MergeStrategy(value); strategy {
+	case MergeSum, MergeMax, MergePreferUser:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownMergeStrategy, value)
+	}
+}
+
 type IBasketService interface {
 	CreateBasket(ctx context.Context, userID uint) (*model.Basket, error)
 	GetBasket(ctx context.Context, basketID uint) (*model.Basket, error)
+	// AddItemToBasket adds quantity units of the product to the basket
 	AddItemToBasket(ctx context.Context, basketID, productID uint, quantity int) error
+	// UpdateItemQuantity sets the product's line to quantity; zero removes it
+	UpdateItemQuantity(ctx context.Context, basketID, productID uint, quantity int) error
 	RemoveItemFromBasket(ctx context.Context, basketID, productID uint) error
 	ClearBasket(ctx context.Context, basketID uint) error
+	ConvertTotal(ctx context.Context, basket *model.Basket, currency string) (money.Money, float64, error)
+
+	// Guest baskets are addressed by an opaque session token and are created
+	// on first use
+	GetGuestBasket(ctx context.Context, sessionToken string) (*model.Basket, error)
+	AddItemToGuestBasket(ctx context.Context, sessionToken string, productID uint, quantity int) error
+	UpdateGuestItemQuantity(ctx context.Context, sessionToken string, productID uint, quantity int) error
+	RemoveItemFromGuestBasket(ctx context.Context, sessionToken string, productID uint) error
+	ClearGuestBasket(ctx context.Context, sessionToken string) error
+	// MergeBaskets folds the guest basket into the user basket and deletes
+	// the guest basket
+	MergeBaskets(ctx context.Context, sessionToken string, userID uint, strategy MergeStrategy) (*model.Basket, error)
+
+	// A basket holds at most one coupon. Applying or removing it does not
+	// count a use: the use is held when the basket is locked for checkout
+	// and counted by CompleteCheckout.
+	ApplyCoupon(ctx context.Context, basketID uint, code string) error
+	RemoveCoupon(ctx context.Context, basketID uint) error
+	ApplyCouponToGuestBasket(ctx context.Context, sessionToken, code string) error
+	RemoveCouponFromGuestBasket(ctx context.Context, sessionToken string) error
+
+	// Users can save basket lines for later. GetWishlist refreshes the saved
+	// items from the catalog like GetBasket does.
+	GetWishlist(ctx context.Context, userID uint) (*model.Wishlist, error)
+	MoveToWishlist(ctx context.Context, userID, productID uint) error
+	MoveToBasket(ctx context.Context, userID, productID uint) error
+
+	// LockBasketForCheckout freezes the user basket at version and returns
+	// the priced snapshot. It fails with repository.ErrBasketChanged if the
+	// basket is no longer at version.
+	LockBasketForCheckout(ctx context.Context, userID uint, version int64) (*model.Basket, error)
+	// CompleteCheckout counts the coupon use of the order placed for the
+	// snapshot of version and clears the user basket if it is still at
+	// version. A basket changed since fails with repository.ErrBasketChanged
+	// and is left as it is.
+	CompleteCheckout(ctx context.Context, userID uint, version int64) error
 }
 
 type basketService struct {
-	repo repository.BasketRepository
+	repo          repository.BasketRepository
+	rates         money.RateProvider
+	productClient IProductClient
+	coupons       repository.CouponRepository
+	promotions    *PromotionEngine
 }
 
-func NewBasketService(repo repository.BasketRepository) IBasketService {
+func NewBasketService(repo repository.BasketRepository, rates money.RateProvider, productClient IProductClient, coupons repository.CouponRepository) IBasketService {
 	return &basketService{
-		repo: repo,
+		repo:          repo,
+		rates:         rates,
+		productClient: productClient,
+		coupons:       coupons,
+		promotions:    NewPromotionEngine(rates),
 	}
 }
 
@@ -44,72 +161,651 @@ func (s *basketService) CreateBasket(ctx context.Context, userID uint) (*model.B
 	return basket, nil
 }
 
+// GetBasket returns the basket with its total recomputed from current
+// catalog prices and the applied coupon. Items whose price changed since
+// they were added, that are out of stock or that are no longer sold are
+// flagged.
 func (s *basketService) GetBasket(ctx context.Context, basketID uint) (*model.Basket, error) {
-	return s.repo.GetByID(ctx, basketID)
+	basket, err := s.repo.GetByID(ctx, basketID)
+	if err != nil || basket == nil {
+		return basket, err
+	}
+	if err := s.refresh(ctx, basket); err != nil {
+		return nil, err
+	}
+	return basket, nil
+}
+
+// refresh compares every item with the catalog and recomputes the subtotal
+// in the currency of the first item, then applies the coupon. If the product
+// service cannot be reached the snapshotted prices are used and no item is
+// flagged.
+func (s *basketService) refresh(ctx context.Context, basket *model.Basket) error {
+	if err := s.enrich(ctx, basket.Items); err != nil {
+		return err
+	}
+
+	var total money.Money
+	for _, item := range basket.Items {
+		if item.Unavailable {
+			continue
+		}
+		if total.Currency == "" {
+			total.Currency = item.CurrentPrice.Currency
+		}
+		line, _, err := money.Exchange(ctx, s.rates, item.CurrentPrice.Mul(int64(item.Quantity)), total.Currency)
+		if err != nil {
+			return err
+		}
+		if total, err = total.Add(line); err != nil {
+			return err
+		}
+	}
+	basket.Subtotal = total
+	basket.Discounts = nil
+	basket.Total = total
+
+	// A coupon that no longer applies stays on the basket without discount
+	if basket.CouponCode == "" {
+		return nil
+	}
+	coupon, err := s.coupons.GetCoupon(ctx, basket.CouponCode)
+	if err != nil || coupon == nil {
+		return err
+	}
+	discount, err := s.promotions.Discount(ctx, coupon, basket, time.Now())
+	if err != nil {
+		if errors.Is(err, ErrCouponNotActive) || errors.Is(err, ErrCouponNotApplicable) {
+			return nil
+		}
+		return err
+	}
+	basket.Discounts = []model.DiscountLine{discount}
+	basket.Total, err = total.Sub(discount.Amount)
+	return err
+}
+
+// enrich sets the current price and the price change, stock and availability
+// flags of items from the catalog. If the product service cannot be reached
+// the snapshotted prices are used and no item is flagged.
+func (s *basketService) enrich(ctx context.Context, items []model.BasketItem) error {
+	productIDs := make([]uint32, len(items))
+	for i, item := range items {
+		productIDs[i] = uint32(item.ProductID)
+	}
+
+	var catalog map[uint]*pb.Product
+	if len(productIDs) > 0 {
+		products, err := s.productClient.GetProducts(ctx, productIDs)
+		if err != nil {
+			log.Printf("Failed to refresh items from product service: %v", err)
+		} else {
+			catalog = make(map[uint]*pb.Product, len(products))
+			for _, p := range products {
+				catalog[uint(p.Id)] = p
+			}
+		}
+	}
+
+	for i := range items {
+		item := &items[i]
+		item.CurrentPrice = item.Price
+		item.PriceChanged, item.OutOfStock, item.Unavailable = false, false, false
+		if catalog == nil {
+			continue
+		}
+
+		product, ok := catalog[item.ProductID]
+		if !ok || !product.IsActive {
+			item.Unavailable = true
+			continue
+		}
+		price, err := money.FromProto(product.Price)
+		if err != nil {
+			return fmt.Errorf("invalid price for product %d: %w", product.Id, err)
+		}
+		item.CurrentPrice = price
+		item.PriceChanged = price != item.Price
+		item.OutOfStock = availableStock(product) < item.Quantity
+	}
+	return nil
+}
+
+// basketModifier atomically applies fn to one stored basket
+type basketModifier func(ctx context.Context, fn func(basket *model.Basket) error) error
+
+func (s *basketService) userBasket(basketID uint) basketModifier {
+	return func(ctx context.Context, fn func(basket *model.Basket) error) error {
+		return s.repo.ModifyBasket(ctx, basketID, fn)
+	}
+}
+
+// userBasketAt modifies the user basket only while it is at version and
+// fails with repository.ErrBasketChanged otherwise
+func (s *basketService) userBasketAt(userID uint, version int64) basketModifier {
+	return func(ctx context.Context, fn func(basket *model.Basket) error) error {
+		return s.repo.ModifyBasket(ctx, userID, func(basket *model.Basket) error {
+			if basket != nil && basket.Version != version {
+				return fmt.Errorf("%w: version is %d, not %d", repository.ErrBasketChanged, basket.Version, version)
+			}
+			return fn(basket)
+		})
+	}
+}
+
+func (s *basketService) guestBasket(sessionToken string) basketModifier {
+	return func(ctx context.Context, fn func(basket *model.Basket) error) error {
+		return s.repo.ModifyGuestBasket(ctx, sessionToken, fn)
+	}
 }
 
 func (s *basketService) AddItemToBasket(ctx context.Context, basketID, productID uint, quantity int) error {
+	return s.setItem(ctx, s.userBasket(basketID), productID, quantity, false)
+}
+
+func (s *basketService) UpdateItemQuantity(ctx context.Context, basketID, productID uint, quantity int) error {
+	return s.updateItem(ctx, s.userBasket(basketID), productID, quantity)
+}
+
+func (s *basketService) RemoveItemFromBasket(ctx context.Context, basketID, productID uint) error {
+	return s.removeItem(ctx, s.userBasket(basketID), productID)
+}
+
+// ClearBasket empties the basket. A coupon use held by a checkout of the
+// cleared version is given back, since that checkout can no longer complete
+// an order the customer still wants.
+func (s *basketService) ClearBasket(ctx context.Context, basketID uint) error {
+	cleared, err := s.clear(ctx, s.userBasket(basketID))
+	if err != nil {
+		return err
+	}
+	s.releaseCoupon(ctx, cleared.CouponCode, checkoutHolder(basketID, cleared.Version))
+	return nil
+}
+
+// setItem validates the product against the catalog before touching the
+// basket, so the basket itself is only modified inside the repository's
+// atomic update. The line is incremented by quantity, or set to quantity when
+// absolute is true; limits and stock are checked against the resulting line.
+func (s *basketService) setItem(ctx context.Context, modify basketModifier, productID uint, quantity int, absolute bool) error {
 	if quantity <= 0 {
-		return errors.New("quantity must be positive")
+		return fmt.Errorf("%w: quantity must be positive", ErrInvalidQuantity)
+	}
+	if quantity > MaxLineQuantity {
+		return fmt.Errorf("%w: at most %d units per product", ErrQuantityLimitExceeded, MaxLineQuantity)
 	}
 
-	basket, err := s.repo.GetByID(ctx, basketID)
+	product, err := s.productClient.GetProduct(ctx, uint32(productID))
 	if err != nil {
 		return err
 	}
-
-	if basket == nil {
-		return errors.New("basket not found")
+	if product == nil {
+		return ErrProductNotFound
+	}
+	if !product.IsActive {
+		return ErrProductInactive
 	}
+	price, err := money.FromProto(product.Price)
+	if err != nil {
+		return err
+	}
+
+	return modify(ctx, func(basket *model.Basket) error {
+		if basket == nil {
+			return ErrBasketNotFound
+		}
+		return putItem(basket, product, price, quantity, absolute)
+	})
+}
 
-	// Check if item already exists
+// putItem adds quantity units of product to the basket line, or sets the
+// line to quantity when absolute is true, and refreshes its snapshot. Limits
+// and stock are checked against the resulting line.
+func putItem(basket *model.Basket, product *pb.Product, price money.Money, quantity int, absolute bool) error {
+	productID := uint(product.Id)
+	line, units := -1, 0
 	for i, item := range basket.Items {
 		if item.ProductID == productID {
-			// Update quantity
-			basket.Items[i].Quantity = quantity
-			return s.repo.Update(ctx, basket)
+			line = i
+		} else {
+			units += item.Quantity
 		}
 	}
 
+	newQuantity := quantity
+	if line >= 0 && !absolute {
+		newQuantity += basket.Items[line].Quantity
+	}
+	if newQuantity > MaxLineQuantity {
+		return fmt.Errorf("%w: at most %d units per product", ErrQuantityLimitExceeded, MaxLineQuantity)
+	}
+	if units+newQuantity > MaxBasketQuantity {
+		return fmt.Errorf("%w: at most %d units per basket", ErrQuantityLimitExceeded, MaxBasketQuantity)
+	}
+	if newQuantity > availableStock(product) {
+		return ErrInsufficientStock
+	}
+
+	if line >= 0 {
+		// Update quantity and refresh the snapshot
+		basket.Items[line].Quantity = newQuantity
+		basket.Items[line].Name = product.Name
+		basket.Items[line].Price = price
+		return nil
+	}
+
 	// Add new item
 	basket.Items = append(basket.Items, model.BasketItem{
 		ProductID: productID,
-		Quantity:  quantity,
+		Quantity:  newQuantity,
+		Price:     price,
+		Name:      product.Name,
 	})
+	return nil
+}
 
-	return s.repo.Update(ctx, basket)
+// updateItem sets the line to quantity; zero removes it
+func (s *basketService) updateItem(ctx context.Context, modify basketModifier, productID uint, quantity int) error {
+	if quantity < 0 {
+		return fmt.Errorf("%w: quantity must not be negative", ErrInvalidQuantity)
+	}
+	if quantity == 0 {
+		return s.removeItem(ctx, modify, productID)
+	}
+	return s.setItem(ctx, modify, productID, quantity, true)
 }
 
-func (s *basketService) RemoveItemFromBasket(ctx context.Context, basketID, productID uint) error {
-	basket, err := s.repo.GetByID(ctx, basketID)
+func (s *basketService) removeItem(ctx context.Context, modify basketModifier, productID uint) error {
+	return modify(ctx, func(basket *model.Basket) error {
+		if basket == nil {
+			return ErrBasketNotFound
+		}
+
+		// Find and remove item
+		for i, item := range basket.Items {
+			if item.ProductID == productID {
+				basket.Items = append(basket.Items[:i], basket.Items[i+1:]...)
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// clear empties the basket and returns its coupon and version from before
+func (s *basketService) clear(ctx context.Context, modify basketModifier) (model.Basket, error) {
+	var cleared model.Basket
+	err := modify(ctx, func(basket *model.Basket) error {
+		if basket == nil {
+			return ErrBasketNotFound
+		}
+		cleared = model.Basket{CouponCode: basket.CouponCode, Version: basket.Version}
+		basket.Items = []model.BasketItem{}
+		basket.CouponCode = ""
+		basket.Total = money.Money{Currency: basket.Total.Currency}
+		return nil
+	})
+	return cleared, err
+}
+
+func (s *basketService) GetGuestBasket(ctx context.Context, sessionToken string) (*model.Basket, error) {
+	if err := validateSessionToken(sessionToken); err != nil {
+		return nil, err
+	}
+	basket, err := s.repo.GetGuestBasket(ctx, sessionToken)
 	if err != nil {
+		return nil, err
+	}
+	if err := s.refresh(ctx, basket); err != nil {
+		return nil, err
+	}
+	return basket, nil
+}
+
+func (s *basketService) AddItemToGuestBasket(ctx context.Context, sessionToken string, productID uint, quantity int) error {
+	if err := validateSessionToken(sessionToken); err != nil {
 		return err
 	}
+	return s.setItem(ctx, s.guestBasket(sessionToken), productID, quantity, false)
+}
 
-	if basket == nil {
-		return errors.New("basket not found")
+func (s *basketService) UpdateGuestItemQuantity(ctx context.Context, sessionToken string, productID uint, quantity int) error {
+	if err := validateSessionToken(sessionToken); err != nil {
+		return err
 	}
+	return s.updateItem(ctx, s.guestBasket(sessionToken), productID, quantity)
+}
 
-	// Find and remove item
-	for i, item := range basket.Items {
-		if item.ProductID == productID {
-			basket.Items = append(basket.Items[:i], basket.Items[i+1:]...)
-			return s.repo.Update(ctx, basket)
+func (s *basketService) RemoveItemFromGuestBasket(ctx context.Context, sessionToken string, productID uint) error {
+	if err := validateSessionToken(sessionToken); err != nil {
+		return err
+	}
+	return s.removeItem(ctx, s.guestBasket(sessionToken), productID)
+}
+
+func (s *basketService) ClearGuestBasket(ctx context.Context, sessionToken string) error {
+	if err := validateSessionToken(sessionToken); err != nil {
+		return err
+	}
+	_, err := s.clear(ctx, s.guestBasket(sessionToken))
+	return err
+}
+
+// MergeBaskets adds the guest lines to the user basket. Products in both
+// baskets are resolved by strategy and keep the user's price snapshot; summed
+// lines are capped at MaxLineQuantity. Stock and MaxBasketQuantity are not
+// checked here so that logging in never loses items; lines that exceed the
+// stock are flagged when the basket is read.
+func (s *basketService) MergeBaskets(ctx context.Context, sessionToken string, userID uint, strategy MergeStrategy) (*model.Basket, error) {
+	if err := validateSessionToken(sessionToken); err != nil {
+		return nil, err
+	}
+	if userID == 0 {
+		return nil, errors.New("invalid user ID")
+	}
+	if _, err := ParseMergeStrategy(string(strategy)); err != nil {
+		return nil, err
+	}
+
+	// The guest's coupon moves to the user basket unless it has its own
+	err := s.repo.MergeGuestBasket(ctx, sessionToken, userID, func(guest, user *model.Basket) error {
+		if user.CouponCode == "" {
+			user.CouponCode = guest.CouponCode
 		}
+		for _, guestItem := range guest.Items {
+			merged := false
+			for i := range user.Items {
+				item := &user.Items[i]
+				if item.ProductID != guestItem.ProductID {
+					continue
+				}
+				switch strategy {
+				case MergeSum:
+					item.Quantity = min(item.Quantity+guestItem.Quantity, MaxLineQuantity)
+				case MergeMax:
+					if guestItem.Quantity > item.Quantity {
+						item.Quantity = guestItem.Quantity
+					}
+				}
+				merged = true
+				break
+			}
+			if !merged {
+				user.Items = append(user.Items, guestItem)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return s.GetBasket(ctx, userID)
+}
 
-	return nil
+func (s *basketService) ApplyCoupon(ctx context.Context, basketID uint, code string) error {
+	get := func(ctx context.Context) (*model.Basket, error) { return s.GetBasket(ctx, basketID) }
+	return s.applyCoupon(ctx, get, s.userBasket(basketID), code)
 }
 
-func (s *basketService) ClearBasket(ctx context.Context, basketID uint) error {
-	basket, err := s.repo.GetByID(ctx, basketID)
+func (s *basketService) RemoveCoupon(ctx context.Context, basketID uint) error {
+	return s.removeCoupon(ctx, s.userBasket(basketID))
+}
+
+func (s *basketService) ApplyCouponToGuestBasket(ctx context.Context, sessionToken, code string) error {
+	if err := validateSessionToken(sessionToken); err != nil {
+		return err
+	}
+	get := func(ctx context.Context) (*model.Basket, error) { return s.GetGuestBasket(ctx, sessionToken) }
+	return s.applyCoupon(ctx, get, s.guestBasket(sessionToken), code)
+}
+
+func (s *basketService) RemoveCouponFromGuestBasket(ctx context.Context, sessionToken string) error {
+	if err := validateSessionToken(sessionToken); err != nil {
+		return err
+	}
+	return s.removeCoupon(ctx, s.guestBasket(sessionToken))
+}
+
+// applyCoupon checks the coupon against the current basket and its usage
+// limit, and stores the code
+func (s *basketService) applyCoupon(ctx context.Context, get func(ctx context.Context) (*model.Basket, error), modify basketModifier, code string) error {
+	coupon, err := s.coupons.GetCoupon(ctx, strings.TrimSpace(code))
+	if err != nil {
+		return err
+	}
+	if coupon == nil {
+		return ErrCouponNotFound
+	}
+
+	basket, err := get(ctx)
 	if err != nil {
 		return err
 	}
 	if basket == nil {
-		return errors.New("basket not found")
+		return ErrBasketNotFound
+	}
+	if basket.CouponCode == coupon.Code {
+		return nil
+	}
+	if _, err := s.promotions.Discount(ctx, coupon, basket, time.Now()); err != nil {
+		return err
+	}
+
+	if err := s.coupons.CheckAvailable(ctx, coupon); err != nil {
+		return err
+	}
+	return modify(ctx, func(basket *model.Basket) error {
+		if basket == nil {
+			return ErrBasketNotFound
+		}
+		basket.CouponCode = coupon.Code
+		return nil
+	})
+}
+
+func (s *basketService) removeCoupon(ctx context.Context, modify basketModifier) error {
+	return modify(ctx, func(basket *model.Basket) error {
+		if basket == nil {
+			return ErrBasketNotFound
+		}
+		basket.CouponCode = ""
+		return nil
+	})
+}
+
+// checkoutHolder identifies the checkout of the user basket at version as
+// the holder of a coupon use
+func checkoutHolder(userID uint, version int64) string {
+	return fmt.Sprintf("checkout:%d:%d", userID, version)
+}
+
+// discountedCoupon returns the coupon that discounts the priced basket, or
+// nil if it holds none or its coupon no longer applies
+func (s *basketService) discountedCoupon(ctx context.Context, basket *model.Basket) (*model.Coupon, error) {
+	for _, discount := range basket.Discounts {
+		if discount.Code == basket.CouponCode {
+			return s.coupons.GetCoupon(ctx, basket.CouponCode)
+		}
+	}
+	return nil, nil
+}
+
+// releaseCoupon gives back the use of code held by holder. A use that is not
+// given back is released when its hold expires, so failures are logged
+// rather than failing the basket update.
+func (s *basketService) releaseCoupon(ctx context.Context, code, holder string) {
+	if code == "" {
+		return
+	}
+	coupon, err := s.coupons.GetCoupon(ctx, code)
+	if err == nil && coupon != nil {
+		err = s.coupons.Release(ctx, coupon, holder)
+	}
+	if err != nil {
+		log.Printf("Failed to release coupon %s held by %s: %v", code, holder, err)
+	}
+}
+
+func (s *basketService) GetWishlist(ctx context.Context, userID uint) (*model.Wishlist, error) {
+	wishlist, err := s.repo.GetWishlist(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.enrich(ctx, wishlist.Items); err != nil {
+		return nil, err
 	}
-	basket.Items = []model.BasketItem{}
-	basket.Total = 0
-	return s.repo.Update(ctx, basket)
-} 
\ No newline at end of file
+	return wishlist, nil
+}
+
+// MoveToWishlist moves the basket line to the wishlist, replacing a saved
+// line for the same product
+func (s *basketService) MoveToWishlist(ctx context.Context, userID, productID uint) error {
+	return s.repo.ModifyBasketAndWishlist(ctx, userID, func(basket *model.Basket, wishlist *model.Wishlist) error {
+		line := indexOfItem(basket.Items, productID)
+		if line < 0 {
+			return ErrItemNotInBasket
+		}
+		item := basket.Items[line]
+
+		if saved := indexOfItem(wishlist.Items, productID); saved >= 0 {
+			wishlist.Items[saved] = item
+		} else if len(wishlist.Items) >= MaxWishlistItems {
+			return fmt.Errorf("%w: at most %d saved products", ErrQuantityLimitExceeded, MaxWishlistItems)
+		} else {
+			wishlist.Items = append(wishlist.Items, item)
+		}
+		basket.Items = append(basket.Items[:line], basket.Items[line+1:]...)
+		return nil
+	})
+}
+
+// MoveToBasket adds the saved quantity back to the basket at the current
+// price. The product must still be sold and in stock, and the basket limits
+// apply as for AddItemToBasket.
+func (s *basketService) MoveToBasket(ctx context.Context, userID, productID uint) error {
+	product, err := s.productClient.GetProduct(ctx, uint32(productID))
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return ErrProductNotFound
+	}
+	if !product.IsActive {
+		return ErrProductInactive
+	}
+	price, err := money.FromProto(product.Price)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.ModifyBasketAndWishlist(ctx, userID, func(basket *model.Basket, wishlist *model.Wishlist) error {
+		saved := indexOfItem(wishlist.Items, productID)
+		if saved < 0 {
+			return ErrItemNotInWishlist
+		}
+		if err := putItem(basket, product, price, wishlist.Items[saved].Quantity, false); err != nil {
+			return err
+		}
+		wishlist.Items = append(wishlist.Items[:saved], wishlist.Items[saved+1:]...)
+		return nil
+	})
+}
+
+// LockBasketForCheckout prices the basket and stores it as the snapshot of
+// version. A snapshot that is already locked keeps the prices it was locked
+// at, so retrying a checkout does not change the amount charged. A use of
+// the snapshot's coupon is held for as long as the snapshot is kept; locking
+// fails with repository.ErrCouponUsageLimit if none is left.
+func (s *basketService) LockBasketForCheckout(ctx context.Context, userID uint, version int64) (*model.Basket, error) {
+	basket, err := s.GetBasket(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if basket.Version != version {
+		return nil, fmt.Errorf("%w: version is %d, not %d", repository.ErrBasketChanged, basket.Version, version)
+	}
+	snapshot, err := s.repo.LockCheckoutSnapshot(ctx, basket, CheckoutSnapshotTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	coupon, err := s.discountedCoupon(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if coupon != nil {
+		if err := s.coupons.Reserve(ctx, coupon, checkoutHolder(userID, version), CheckoutSnapshotTTL); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// CompleteCheckout counts the coupon use of the snapshot locked for version
+// and clears the basket if it is still at version. Lines added since the
+// checkout was locked were not paid for, so a changed basket is kept. The
+// order is already placed, so the use is counted even if its hold has
+// expired or the basket has changed; without a snapshot the coupon of the
+// basket, if it is still at version, is counted instead.
+func (s *basketService) CompleteCheckout(ctx context.Context, userID uint, version int64) error {
+	snapshot, err := s.repo.GetCheckoutSnapshot(ctx, userID, version)
+	if err != nil {
+		return err
+	}
+	cleared, clearErr := s.clear(ctx, s.userBasketAt(userID, version))
+	if clearErr != nil && !errors.Is(clearErr, repository.ErrBasketChanged) {
+		return clearErr
+	}
+
+	var coupon *model.Coupon
+	switch {
+	case snapshot != nil:
+		coupon, err = s.discountedCoupon(ctx, snapshot)
+	case clearErr == nil && cleared.CouponCode != "":
+		coupon, err = s.coupons.GetCoupon(ctx, cleared.CouponCode)
+	}
+	if err != nil {
+		return err
+	}
+	if coupon != nil {
+		if err := s.coupons.Redeem(ctx, coupon, checkoutHolder(userID, version)); err != nil {
+			return err
+		}
+	}
+	return clearErr
+}
+
+// indexOfItem returns the index of the product's line, or -1
+func indexOfItem(items []model.BasketItem, productID uint) int {
+	for i, item := range items {
+		if item.ProductID == productID {
+			return i
+		}
+	}
+	return -1
+}
+
+func validateSessionToken(sessionToken string) error {
+	if !sessionTokenPattern.MatchString(sessionToken) {
+		return ErrInvalidSessionToken
+	}
+	return nil
+}
+
+// ConvertTotal returns the basket total in the requested currency and the
+// exchange rate used. It is for display only; the basket keeps its prices.
+func (s *basketService) ConvertTotal(ctx context.Context, basket *model.Basket, currency string) (money.Money, float64, error) {
+	if !money.IsKnownCurrency(currency) {
+		return money.Money{}, 0, fmt.Errorf("%w: %q", money.ErrUnknownCurrency, currency)
+	}
+	// An empty basket has no currency yet
+	if basket.Total.Currency == "" {
+		return money.Money{Currency: currency}, 1, nil
+	}
+	return money.Exchange(ctx, s.rates, basket.Total, currency)
+}
+
+// availableStock is the product's stock not held by checkout reservations
+func availableStock(product *pb.Product) int {
+	return int(product.Stock - product.ReservedStock)
+}
@@ -6,9 +6,17 @@ import (
 
 	pb "gomicro/api/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
+// IProductClient looks up products in the product service
+type IProductClient interface {
+	GetProduct(ctx context.Context, productID uint32) (*pb.Product, error)
+	GetProducts(ctx context.Context, productIDs []uint32) ([]*pb.Product, error)
+}
+
 type ProductClient struct {
 	client pb.ProductServiceClient
 }
@@ -24,10 +32,14 @@ func NewProductClient(address string) (*ProductClient, error) {
 	return &ProductClient{client: client}, nil
 }
 
+// GetProduct returns nil when the product does not exist
 func (c *ProductClient) GetProduct(ctx context.Context, productID uint32) (*pb.Product, error) {
 	resp, err := c.client.GetProduct(ctx, &pb.GetProductRequest{
 		ProductId: productID,
 	})
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
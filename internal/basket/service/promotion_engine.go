@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gomicro/internal/basket/model"
+	"gomicro/internal/money"
+)
+
+var (
+	// ErrCouponNotFound is returned for an unknown coupon code
+	ErrCouponNotFound = errors.New("coupon not found")
+	// ErrCouponNotActive is returned outside the coupon's validity window
+	ErrCouponNotActive = errors.New("coupon is not active")
+	// ErrCouponNotApplicable is returned when the basket does not meet the
+	// coupon's conditions
+	ErrCouponNotApplicable = errors.New("coupon does not apply to this basket")
+)
+
+// PromotionEngine prices coupons against baskets
+type PromotionEngine struct {
+	rates money.RateProvider
+}
+
+func NewPromotionEngine(rates money.RateProvider) *PromotionEngine {
+	return &PromotionEngine{rates: rates}
+}
+
+// Discount returns the discount line coupon gives basket at now. The basket
+// subtotal must already be computed; amounts configured in another currency
+// are converted to the subtotal's. Only items that are still sold count.
+func (e *PromotionEngine) Discount(ctx context.Context, coupon *model.Coupon, basket *model.Basket, now time.Time) (model.DiscountLine, error) {
+	line := model.DiscountLine{
+		Code:        coupon.Code,
+		Description: coupon.Description,
+		Amount:      money.Money{Currency: basket.Subtotal.Currency},
+	}
+	if !coupon.ValidFrom.IsZero() && now.Before(coupon.ValidFrom) ||
+		!coupon.ValidUntil.IsZero() && !now.Before(coupon.ValidUntil) {
+		return line, ErrCouponNotActive
+	}
+	if basket.Subtotal.Currency == "" {
+		return line, fmt.Errorf("%w: basket is empty", ErrCouponNotApplicable)
+	}
+
+	if coupon.MinBasketValue.Currency != "" {
+		minimum, _, err := money.Exchange(ctx, e.rates, coupon.MinBasketValue, basket.Subtotal.Currency)
+		if err != nil {
+			return line, err
+		}
+		if basket.Subtotal.MinorUnits < minimum.MinorUnits {
+			return line, fmt.Errorf("%w: minimum basket value is %s", ErrCouponNotApplicable, minimum)
+		}
+	}
+
+	switch coupon.Type {
+	case model.PromotionPercentage:
+		if coupon.Percent <= 0 || coupon.Percent > 100 {
+			return line, fmt.Errorf("invalid percentage %d for coupon %s", coupon.Percent, coupon.Code)
+		}
+		line.Amount.MinorUnits = basket.Subtotal.MinorUnits * coupon.Percent / 100
+
+	case model.PromotionFixedAmount:
+		amount, _, err := money.Exchange(ctx, e.rates, coupon.Amount, basket.Subtotal.Currency)
+		if err != nil {
+			return line, err
+		}
+		line.Amount.MinorUnits = min(amount.MinorUnits, basket.Subtotal.MinorUnits)
+
+	case model.PromotionBuyXGetY:
+		if coupon.BuyQuantity <= 0 || coupon.FreeQuantity <= 0 {
+			return line, fmt.Errorf("invalid buy-x-get-y quantities for coupon %s", coupon.Code)
+		}
+		for _, item := range basket.Items {
+			if item.Unavailable || item.ProductID != coupon.ProductID {
+				continue
+			}
+			free := item.Quantity / (coupon.BuyQuantity + coupon.FreeQuantity) * coupon.FreeQuantity
+			price, _, err := money.Exchange(ctx, e.rates, item.CurrentPrice, basket.Subtotal.Currency)
+			if err != nil {
+				return line, err
+			}
+			line.Amount.MinorUnits += price.MinorUnits * int64(free)
+		}
+		if line.Amount.MinorUnits == 0 {
+			return line, fmt.Errorf("%w: add %d of product %d", ErrCouponNotApplicable, coupon.BuyQuantity+coupon.FreeQuantity, coupon.ProductID)
+		}
+
+	default:
+		return line, fmt.Errorf("unknown promotion type %q for coupon %s", coupon.Type, coupon.Code)
+	}
+	return line, nil
+}
@@ -0,0 +1,73 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// LegacyColumn is a float column holding an amount in major units that was
+// replaced by a Money field embedded with Prefix
+type LegacyColumn struct {
+	Table  string
+	Column string
+	Prefix string
+	// Currency is the currency of every row: a code, or a gorm.Expr
+	// evaluated per row
+	Currency interface{}
+}
+
+// MigrateLegacyColumn moves the amounts of a legacy column into the
+// <prefix>minor_units and <prefix>currency columns and drops it. The new
+// columns are added as nullable, backfilled, then made NOT NULL, so run it
+// inside a transaction before AutoMigrate. It does nothing once the legacy
+// column is gone.
+func MigrateLegacyColumn(tx *gorm.DB, c LegacyColumn) error {
+	if !tx.Migrator().HasColumn(c.Table, c.Column) {
+		return nil
+	}
+	if code, ok := c.Currency.(string); ok && !IsKnownCurrency(code) {
+		return fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+
+	minorUnits, currency := c.Prefix+"minor_units", c.Prefix+"currency"
+	steps := []struct {
+		sql  string
+		vars []interface{}
+	}{
+		{sql: fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s bigint, ADD COLUMN IF NOT EXISTS %s varchar(3)", c.Table, minorUnits, currency)},
+		{sql: fmt.Sprintf("UPDATE %s SET %s = ?", c.Table, currency), vars: []interface{}{c.Currency}},
+		{sql: fmt.Sprintf("UPDATE %s SET %s = ROUND(%s * %s)", c.Table, minorUnits, c.Column, minorUnitFactorSQL(currency))},
+		{sql: fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL, ALTER COLUMN %s SET NOT NULL", c.Table, minorUnits, currency)},
+		{sql: fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", c.Table, c.Column)},
+	}
+	for _, step := range steps {
+		if err := tx.Exec(step.sql, step.vars...).Error; err != nil {
+			return fmt.Errorf("failed to migrate %s.%s: %w", c.Table, c.Column, err)
+		}
+	}
+	return nil
+}
+
+// minorUnitFactorSQL returns a SQL expression giving the number of minor
+// units per major unit of the currency in column
+func minorUnitFactorSQL(column string) string {
+	codes := make([]string, 0, len(currencies))
+	for code, exponent := range currencies {
+		if exponent != 2 {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	var sql strings.Builder
+	fmt.Fprintf(&sql, "CASE %s", column)
+	for _, code := range codes {
+		fmt.Fprintf(&sql, " WHEN '%s' THEN %d", code, int64(math.Pow10(currencies[code])))
+	}
+	sql.WriteString(" ELSE 100 END")
+	return sql.String()
+}
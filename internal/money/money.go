@@ -0,0 +1,155 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	// ErrUnknownCurrency is returned for currency codes that are not ISO-4217
+	ErrUnknownCurrency = errors.New("unknown currency code")
+	// ErrCurrencyMismatch is returned when combining amounts in different currencies
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// currencies maps supported ISO-4217 codes to the number of digits after the
+// decimal separator (the exponent of their minor unit)
+var currencies = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BGN": 2, "BHD": 3, "BRL": 2, "CAD": 2,
+	"CHF": 2, "CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EGP": 2,
+	"EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2,
+	"ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2,
+	"NOK": 2, "NZD": 2, "OMR": 3, "PHP": 2, "PLN": 2, "QAR": 2, "RON": 2,
+	"RSD": 2, "RUB": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3,
+	"TRY": 2, "TWD": 2, "UAH": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// Money is an amount in the minor unit of its currency (cents, kuruş, yen),
+// so sums and products never accumulate floating point rounding errors
+type Money struct {
+	MinorUnits int64  `gorm:"not null" json:"minor_units"`
+	Currency   string `gorm:"size:3;not null" json:"currency"`
+}
+
+// LegacyCurrency is the currency of amounts stored as bare numbers in major
+// units before they were Money, such as baskets saved by earlier releases.
+// Services storing such data set it to the merchant's base currency.
+var LegacyCurrency = "TRY"
+
+// UnmarshalJSON decodes the object form of Money, and a bare number as a
+// legacy amount in LegacyCurrency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && (data[0] == '-' || data[0] >= '0' && data[0] <= '9') {
+		var amount float64
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return err
+		}
+		legacy, err := FromMajor(amount, LegacyCurrency)
+		if err != nil {
+			return err
+		}
+		*m = legacy
+		return nil
+	}
+	// plain has no methods, so decoding into it does not recurse
+	type plain Money
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// Exponent returns the number of minor unit digits of a currency
+func Exponent(currency string) (int, error) {
+	exponent, ok := currencies[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return exponent, nil
+}
+
+// IsKnownCurrency reports whether the code is a supported ISO-4217 currency
+func IsKnownCurrency(currency string) bool {
+	_, ok := currencies[currency]
+	return ok
+}
+
+// New returns an amount of minor units in the given currency
+func New(minorUnits int64, currency string) (Money, error) {
+	m := Money{MinorUnits: minorUnits, Currency: currency}
+	if err := m.Validate(); err != nil {
+		return Money{}, err
+	}
+	return m, nil
+}
+
+// FromMajor converts an amount in major units (e.g. 12.345 EUR) to Money,
+// rounding half away from zero to the currency's minor unit
+func FromMajor(amount float64, currency string) (Money, error) {
+	exponent, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{MinorUnits: int64(math.Round(amount * math.Pow10(exponent))), Currency: currency}, nil
+}
+
+// Validate checks that the currency is a known ISO-4217 code
+func (m Money) Validate() error {
+	_, err := Exponent(m.Currency)
+	return err
+}
+
+// Major returns the amount in major units. Use it only for display.
+func (m Money) Major() float64 {
+	exponent := currencies[m.Currency]
+	return float64(m.MinorUnits) / math.Pow10(exponent)
+}
+
+func (m Money) IsZero() bool {
+	return m.MinorUnits == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.MinorUnits > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.MinorUnits < 0
+}
+
+// Add returns m + other. Both amounts must be in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{MinorUnits: m.MinorUnits + other.MinorUnits, Currency: m.Currency}, nil
+}
+
+// Sub returns m - other. Both amounts must be in the same currency.
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{MinorUnits: m.MinorUnits - other.MinorUnits, Currency: m.Currency}, nil
+}
+
+// Mul returns the amount multiplied by a quantity
+func (m Money) Mul(quantity int64) Money {
+	return Money{MinorUnits: m.MinorUnits * quantity, Currency: m.Currency}
+}
+
+// String formats the amount with its currency's precision, e.g. "12.50 TRY"
+func (m Money) String() string {
+	exponent := currencies[m.Currency]
+	units := m.MinorUnits
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	if exponent == 0 {
+		return fmt.Sprintf("%s%d %s", sign, units, m.Currency)
+	}
+	scale := int64(math.Pow10(exponent))
+	fraction := fmt.Sprintf("%0*d", exponent, units%scale)
+	return fmt.Sprintf("%s%d.%s %s", sign, units/scale, fraction, m.Currency)
+}
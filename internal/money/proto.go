@@ -0,0 +1,21 @@
+package money
+
+import (
+	"fmt"
+
+	pb "gomicro/api/proto"
+)
+
+// FromProto converts and validates a Money message. A nil message is an
+// error because every amount needs a currency.
+func FromProto(m *pb.Money) (Money, error) {
+	if m == nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, "")
+	}
+	return New(m.MinorUnits, m.Currency)
+}
+
+// ToProto converts the amount to its Money message
+func (m Money) ToProto() *pb.Money {
+	return &pb.Money{MinorUnits: m.MinorUnits, Currency: m.Currency}
+}
@@ -0,0 +1,112 @@
+package money
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// ErrRateUnavailable is returned when no exchange rate is known for a currency pair
+var ErrRateUnavailable = errors.New("exchange rate unavailable")
+
+// RateProvider quotes exchange rates. Rate returns how many units of the
+// target currency one unit of the source currency buys.
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// StaticRateProvider quotes fixed rates relative to a single base currency.
+// Cross rates are derived through the base.
+type StaticRateProvider struct {
+	base  string
+	rates map[string]float64
+}
+
+// staticRatesFile is the on-disk format read by LoadStaticRates, e.g.
+// {"base": "EUR", "rates": {"TRY": 36.5, "USD": 1.08}}
+type staticRatesFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// NewStaticRateProvider creates a provider from units of each currency per
+// one unit of base. The base itself is always quoted at 1.
+func NewStaticRateProvider(base string, rates map[string]float64) (*StaticRateProvider, error) {
+	if !IsKnownCurrency(base) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, base)
+	}
+	quoted := map[string]float64{base: 1}
+	for currency, rate := range rates {
+		if !IsKnownCurrency(currency) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("invalid rate %v for %s", rate, currency)
+		}
+		quoted[currency] = rate
+	}
+	return &StaticRateProvider{base: base, rates: quoted}, nil
+}
+
+// LoadStaticRates reads a StaticRateProvider from a JSON file
+func LoadStaticRates(path string) (*StaticRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file staticRatesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates %s: %v", path, err)
+	}
+	return NewStaticRateProvider(file.Base, file.Rates)
+}
+
+func (p *StaticRateProvider) Rate(ctx context.Context, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	fromRate, ok := p.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s to %s", ErrRateUnavailable, from, to)
+	}
+	toRate, ok := p.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s to %s", ErrRateUnavailable, from, to)
+	}
+	return toRate / fromRate, nil
+}
+
+// Convert applies a rate to the amount, rounding half away from zero to the
+// minor unit of the target currency
+func Convert(m Money, to string, rate float64) (Money, error) {
+	fromExponent, err := Exponent(m.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	toExponent, err := Exponent(to)
+	if err != nil {
+		return Money{}, err
+	}
+	minorUnits := float64(m.MinorUnits) * rate * math.Pow10(toExponent-fromExponent)
+	return Money{MinorUnits: int64(math.Round(minorUnits)), Currency: to}, nil
+}
+
+// Exchange converts the amount at the provider's current rate and returns
+// the rate used, so callers can record it
+func Exchange(ctx context.Context, rates RateProvider, m Money, to string) (Money, float64, error) {
+	if m.Currency == to {
+		return m, 1, nil
+	}
+	rate, err := rates.Rate(ctx, m.Currency, to)
+	if err != nil {
+		return Money{}, 0, err
+	}
+	converted, err := Convert(m, to, rate)
+	if err != nil {
+		return Money{}, 0, err
+	}
+	return converted, rate, nil
+}
@@ -0,0 +1,72 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Page sizes used when a request asks for none, and at most
+const (
+	DefaultSize = 20
+	MaxSize     = 100
+)
+
+// ErrInvalidToken is returned when a page token cannot be decoded
+var ErrInvalidToken = errors.New("invalid page token")
+
+// Size returns the page size to use for a requested size: DefaultSize when
+// none is requested, capped at MaxSize
+func Size(requested int) int {
+	if requested <= 0 {
+		return DefaultSize
+	}
+	if requested > MaxSize {
+		return MaxSize
+	}
+	return requested
+}
+
+// EncodeToken returns the opaque page token for a cursor
+func EncodeToken(cursor interface{}) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeToken decodes a token made by EncodeToken into cursor. It fails
+// with ErrInvalidToken on malformed tokens; callers check the decoded cursor
+// makes sense.
+func DecodeToken(token string, cursor interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// Fetch returns a page of at most size items and the token for the next
+// page, which is empty on the last page. fetch is asked for one extra item to
+// learn whether another page exists; the token encodes the cursor of the last
+// item on the page.
+func Fetch[T any](size int, fetch func(limit int) ([]T, error), cursor func(last T) interface{}) ([]T, string, error) {
+	items, err := fetch(size + 1)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(items) <= size {
+		return items, "", nil
+	}
+
+	items = items[:size]
+	token, err := EncodeToken(cursor(items[size-1]))
+	if err != nil {
+		return nil, "", err
+	}
+	return items, token, nil
+}
@@ -2,10 +2,16 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	pb "gomicro/api/proto"
+	"gomicro/internal/money"
+	"gomicro/internal/payment/model"
+	"gomicro/internal/payment/repository"
 	"gomicro/internal/payment/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type PaymentHandler struct {
@@ -20,19 +26,17 @@ func NewPaymentHandler(service service.PaymentService) *PaymentHandler {
 }
 
 func (h *PaymentHandler) ProcessPayment(ctx context.Context, req *pb.ProcessPaymentRequest) (*pb.PaymentResponse, error) {
-	payment, err := h.service.ProcessPayment(ctx, uint(req.UserId), req.Amount, req.Currency, req.PaymentMethod)
+	amount, err := money.FromProto(req.Amount)
 	if err != nil {
-		return nil, err
+		return nil, toGRPCError(err)
+	}
+
+	payment, err := h.service.ProcessPayment(ctx, uint(req.UserId), amount, req.PaymentMethod, req.IdempotencyKey)
+	if err != nil {
+		return nil, toGRPCError(err)
 	}
 
-	return &pb.PaymentResponse{
-		PaymentId: uint32(payment.ID),
-		UserId:    uint32(payment.UserID),
-		Amount:    payment.Amount,
-		Currency:  payment.Currency,
-		Status:    payment.Status,
-		CreatedAt: payment.CreatedAt.Format(time.RFC3339),
-	}, nil
+	return convertToProtoPayment(payment), nil
 }
 
 func (h *PaymentHandler) GetPayment(ctx context.Context, req *pb.GetPaymentRequest) (*pb.PaymentResponse, error) {
@@ -40,13 +44,179 @@ func (h *PaymentHandler) GetPayment(ctx context.Context, req *pb.GetPaymentReque
 	if err != nil {
 		return nil, err
 	}
+	if payment == nil {
+		return nil, status.Error(codes.NotFound, "payment not found")
+	}
+
+	return convertToProtoPayment(payment), nil
+}
+
+func (h *PaymentHandler) Checkout(ctx context.Context, req *pb.CheckoutRequest) (*pb.PaymentResponse, error) {
+	payment, err := h.service.Checkout(ctx, uint(req.UserId), req.BasketVersion, req.Currency, req.PaymentMethod)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return convertToProtoPayment(payment), nil
+}
+
+func (h *PaymentHandler) ConfirmPayment(ctx context.Context, req *pb.ConfirmPaymentRequest) (*pb.PaymentResponse, error) {
+	payment, err := h.service.ConfirmPayment(ctx, uint(req.PaymentId))
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return convertToProtoPayment(payment), nil
+}
+
+func (h *PaymentHandler) RefundPayment(ctx context.Context, req *pb.RefundPaymentRequest) (*pb.PaymentResponse, error) {
+	items := make([]model.RefundItem, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = model.RefundItem{
+			ProductID: uint(item.ProductId),
+			Quantity:  int(item.Quantity),
+		}
+	}
+
+	// An unset or zero amount refunds whatever is left
+	var amount money.Money
+	if req.Amount != nil && req.Amount.MinorUnits != 0 {
+		var err error
+		if amount, err = money.FromProto(req.Amount); err != nil {
+			return nil, toGRPCError(err)
+		}
+	}
 
-	return &pb.PaymentResponse{
-		PaymentId: uint32(payment.ID),
-		UserId:    uint32(payment.UserID),
-		Amount:    payment.Amount,
-		Currency:  payment.Currency,
-		Status:    payment.Status,
-		CreatedAt: payment.CreatedAt.Format(time.RFC3339),
-	}, nil
-} 
\ No newline at end of file
+	payment, err := h.service.RefundPayment(ctx, uint(req.PaymentId), amount, req.Reason, items)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return convertToProtoPayment(payment), nil
+}
+
+func (h *PaymentHandler) GetPaymentHistory(ctx context.Context, req *pb.GetPaymentHistoryRequest) (*pb.GetPaymentHistoryResponse, error) {
+	events, err := h.service.GetPaymentHistory(ctx, uint(req.PaymentId))
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	resp := &pb.GetPaymentHistoryResponse{
+		Events: make([]*pb.PaymentEvent, len(events)),
+	}
+	for i, event := range events {
+		resp.Events[i] = &pb.PaymentEvent{
+			FromStatus: event.FromStatus,
+			ToStatus:   event.ToStatus,
+			Reason:     event.Reason,
+			CreatedAt:  event.CreatedAt.Format(time.RFC3339),
+		}
+	}
+	return resp, nil
+}
+
+func (h *PaymentHandler) ListPayments(ctx context.Context, req *pb.ListPaymentsRequest) (*pb.ListPaymentsResponse, error) {
+	filter := repository.PaymentFilter{
+		UserID:    uint(req.UserId),
+		Status:    req.Status,
+		Currency:  req.Currency,
+		MinAmount: req.MinAmount,
+		MaxAmount: req.MaxAmount,
+	}
+	// Minor units of different currencies cannot be compared
+	if (req.MinAmount != 0 || req.MaxAmount != 0) && req.Currency == "" {
+		return nil, status.Error(codes.InvalidArgument, "min_amount and max_amount require a currency")
+	}
+	var err error
+	if req.CreatedFrom != "" {
+		if filter.CreatedFrom, err = time.Parse(time.RFC3339, req.CreatedFrom); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "created_from must be an RFC 3339 timestamp")
+		}
+	}
+	if req.CreatedTo != "" {
+		if filter.CreatedTo, err = time.Parse(time.RFC3339, req.CreatedTo); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "created_to must be an RFC 3339 timestamp")
+		}
+	}
+
+	payments, nextPageToken, err := h.service.ListPayments(ctx, filter, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	resp := &pb.ListPaymentsResponse{
+		Payments:      make([]*pb.PaymentResponse, len(payments)),
+		NextPageToken: nextPageToken,
+	}
+	for i, payment := range payments {
+		resp.Payments[i] = convertToProtoPayment(payment)
+	}
+	return resp, nil
+}
+
+// toGRPCError maps service errors to gRPC status codes
+func toGRPCError(err error) error {
+	var transitionErr *model.InvalidTransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrPaymentNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrBasketChanged):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, service.ErrIdempotencyKeyConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, model.ErrRefundExceedsPayment), errors.Is(err, money.ErrRateUnavailable):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrInvalidRefundItems), errors.Is(err, service.ErrInvalidPageToken),
+		errors.Is(err, money.ErrUnknownCurrency), errors.Is(err, money.ErrCurrencyMismatch):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return err
+	}
+}
+
+func convertToProtoPayment(payment *model.Payment) *pb.PaymentResponse {
+	protoPayment := &pb.PaymentResponse{
+		PaymentId:      uint32(payment.ID),
+		UserId:         uint32(payment.UserID),
+		Amount:         payment.Amount.ToProto(),
+		Status:         payment.Status,
+		CreatedAt:      payment.CreatedAt.Format(time.RFC3339),
+		Items:          make([]*pb.PaymentItem, len(payment.Items)),
+		FailureReason:  payment.FailureReason,
+		NextActionUrl:  payment.NextActionURL,
+		RefundedAmount: payment.RefundedAmount.ToProto(),
+		Refunds:        make([]*pb.Refund, len(payment.Refunds)),
+		SettledAmount:  payment.SettledAmount.ToProto(),
+		ExchangeRate:   payment.ExchangeRate,
+	}
+
+	for i, item := range payment.Items {
+		protoPayment.Items[i] = &pb.PaymentItem{
+			ProductId: uint32(item.ProductID),
+			Quantity:  int32(item.Quantity),
+			UnitPrice: item.UnitPrice.ToProto(),
+		}
+	}
+
+	for i, refund := range payment.Refunds {
+		protoRefund := &pb.Refund{
+			RefundId:  uint32(refund.ID),
+			Amount:    refund.Amount.ToProto(),
+			Reason:    refund.Reason,
+			CreatedAt: refund.CreatedAt.Format(time.RFC3339),
+			Items:     make([]*pb.RefundItem, len(refund.Items)),
+			Status:    refund.Status,
+		}
+		for j, item := range refund.Items {
+			protoRefund.Items[j] = &pb.RefundItem{
+				ProductId: uint32(item.ProductID),
+				Quantity:  int32(item.Quantity),
+			}
+		}
+		protoPayment.Refunds[i] = protoRefund
+	}
+
+	return protoPayment
+}
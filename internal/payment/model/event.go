@@ -1,6 +1,12 @@
 package model
 
 type StockUpdateEvent struct {
+	// EventID is the ID of the outbox row the event was relayed from, so
+	// product-service can skip events published more than once
+	EventID   uint `json:"event_id,omitempty"`
 	ProductID uint `json:"product_id"`
 	Quantity  int  `json:"quantity"`
+	// ReservationID is the stock reservation product-service releases once
+	// it has applied the decrement
+	ReservationID string `json:"reservation_id,omitempty"`
 } 
\ No newline at end of file
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+
+	EventTypeStockUpdate = "stock.update"
+)
+
+// OutboxEvent is an event waiting to be published to RabbitMQ. It is written
+// in the same transaction as the payment change that produced it.
+type OutboxEvent struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	PaymentID   uint       `gorm:"index;not null" json:"payment_id"`
+	EventType   string     `gorm:"not null" json:"event_type"`
+	Payload     string     `gorm:"type:text;not null" json:"payload"`
+	Status      string     `gorm:"index;not null;default:pending" json:"status"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	LastError   string     `json:"last_error"`
+	PublishedAt *time.Time `json:"published_at"`
+}
@@ -3,17 +3,54 @@ package model
 import (
 	"time"
 
+	"gomicro/internal/money"
 	"gorm.io/gorm"
 )
 
+const (
+	StatusPending           = "pending"
+	StatusRequiresAction    = "requires_action"
+	StatusAuthorized        = "authorized"
+	StatusCaptured          = "captured"
+	StatusFailed            = "failed"
+	StatusVoided            = "voided"
+	StatusPartiallyRefunded = "partially_refunded"
+	StatusRefunded          = "refunded"
+)
+
 type Payment struct {
-	ID            uint           `gorm:"primarykey" json:"id"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
-	UserID        uint          `gorm:"not null" json:"user_id"`
-	Amount        float64       `gorm:"not null" json:"amount"`
-	Currency      string        `gorm:"not null" json:"currency"`
-	Status        string        `gorm:"not null" json:"status"`
-	PaymentMethod string        `gorm:"not null" json:"payment_method"`
-} 
\ No newline at end of file
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `gorm:"index:idx_payments_user_created,priority:2" json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	UserID    uint           `gorm:"not null;index:idx_payments_user_created,priority:1" json:"user_id"`
+	Amount    money.Money    `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	// SettledAmount is Amount converted to the merchant's base currency at ExchangeRate
+	SettledAmount  money.Money `gorm:"embedded;embeddedPrefix:settled_" json:"settled_amount"`
+	ExchangeRate   float64     `gorm:"not null;default:1" json:"exchange_rate"`
+	Status         string      `gorm:"not null" json:"status"`
+	PaymentMethod  string      `gorm:"not null" json:"payment_method"`
+	IdempotencyKey *string     `gorm:"uniqueIndex" json:"idempotency_key,omitempty"`
+	ProviderRef    string      `json:"provider_ref"`
+	FailureReason  string      `json:"failure_reason,omitempty"`
+	NextActionURL  string      `json:"next_action_url,omitempty"`
+	// StockReservationID is the stock held for a checkout. It is released
+	// when the payment fails, or by product-service when it applies the
+	// stock decrement of the captured payment.
+	StockReservationID string `json:"-"`
+	// BasketVersion is the locked basket snapshot the payment was charged
+	// for; the checkout is completed against it once the payment is captured
+	BasketVersion  int64         `gorm:"not null;default:0" json:"-"`
+	RefundedAmount money.Money   `gorm:"embedded;embeddedPrefix:refunded_" json:"refunded_amount"`
+	Items          []PaymentItem `gorm:"foreignKey:PaymentID" json:"items"`
+	Refunds        []Refund      `gorm:"foreignKey:PaymentID" json:"refunds"`
+}
+
+// PaymentItem is a basket line charged as part of a checkout
+type PaymentItem struct {
+	ID        uint        `gorm:"primarykey" json:"id"`
+	PaymentID uint        `gorm:"index;not null" json:"payment_id"`
+	ProductID uint        `gorm:"not null" json:"product_id"`
+	Quantity  int         `gorm:"not null" json:"quantity"`
+	UnitPrice money.Money `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+}
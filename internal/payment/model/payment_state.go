@@ -0,0 +1,62 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// paymentTransitions lists the statuses reachable from each status
+var paymentTransitions = map[string][]string{
+	StatusPending:           {StatusAuthorized, StatusRequiresAction, StatusFailed},
+	StatusRequiresAction:    {StatusAuthorized, StatusFailed},
+	StatusAuthorized:        {StatusCaptured, StatusVoided, StatusFailed},
+	StatusCaptured:          {StatusPartiallyRefunded, StatusRefunded},
+	StatusPartiallyRefunded: {StatusPartiallyRefunded, StatusRefunded},
+}
+
+// PaymentEvent records a single status transition of a payment
+type PaymentEvent struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	PaymentID  uint      `gorm:"index;not null" json:"payment_id"`
+	FromStatus string    `gorm:"not null" json:"from_status"`
+	ToStatus   string    `gorm:"not null" json:"to_status"`
+	Reason     string    `json:"reason"`
+}
+
+// InvalidTransitionError is returned when a status change is not allowed
+type InvalidTransitionError struct {
+	PaymentID uint
+	From      string
+	To        string
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("payment %d cannot transition from %s to %s", e.PaymentID, e.From, e.To)
+}
+
+// CanTransition reports whether a payment may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range paymentTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the payment to a new status and returns the event to
+// record for it
+func (p *Payment) Transition(to, reason string) (*PaymentEvent, error) {
+	if !CanTransition(p.Status, to) {
+		return nil, &InvalidTransitionError{PaymentID: p.ID, From: p.Status, To: to}
+	}
+	event := &PaymentEvent{
+		PaymentID:  p.ID,
+		FromStatus: p.Status,
+		ToStatus:   to,
+		Reason:     reason,
+	}
+	p.Status = to
+	return event, nil
+}
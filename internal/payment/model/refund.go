@@ -0,0 +1,122 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gomicro/internal/money"
+)
+
+var (
+	// ErrRefundExceedsPayment is returned when cumulative refunds would exceed the charged amount
+	ErrRefundExceedsPayment = errors.New("refund exceeds refundable amount")
+	// ErrInvalidRefundItems is returned when refund items do not match the payment's unreturned items
+	ErrInvalidRefundItems = errors.New("refund items do not match payment items")
+)
+
+// Refund statuses. A refund is recorded as pending before the provider is
+// asked to pay it out, so concurrent refunds cannot both pass validation.
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// Refund is a full or partial refund of a captured payment
+type Refund struct {
+	ID          uint         `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	PaymentID   uint         `gorm:"index;not null" json:"payment_id"`
+	Amount      money.Money  `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Reason      string       `json:"reason"`
+	Status      string       `gorm:"not null;default:succeeded;index" json:"status"`
+	ProviderRef string       `json:"provider_ref"`
+	Items       []RefundItem `gorm:"foreignKey:RefundID" json:"items"`
+}
+
+// RefundItem is a quantity of a product returned to stock by a refund
+type RefundItem struct {
+	ID        uint `gorm:"primarykey" json:"id"`
+	RefundID  uint `gorm:"index;not null" json:"refund_id"`
+	ProductID uint `gorm:"not null" json:"product_id"`
+	Quantity  int  `gorm:"not null" json:"quantity"`
+}
+
+// RefundableAmount is the captured amount not yet refunded
+func (p *Payment) RefundableAmount() money.Money {
+	return money.Money{MinorUnits: p.Amount.MinorUnits - p.RefundedAmount.MinorUnits, Currency: p.Amount.Currency}
+}
+
+// AvailableRefund is the refundable amount not held by pending refunds
+func (p *Payment) AvailableRefund() money.Money {
+	available := p.RefundableAmount()
+	for _, refund := range p.Refunds {
+		if refund.Status == RefundPending {
+			available.MinorUnits -= refund.Amount.MinorUnits
+		}
+	}
+	return available
+}
+
+// CheckRefund reports whether a new refund fits in what is left of the
+// payment once succeeded and pending refunds are taken into account
+func (p *Payment) CheckRefund(refund *Refund) error {
+	if !CanTransition(p.Status, StatusRefunded) {
+		return &InvalidTransitionError{PaymentID: p.ID, From: p.Status, To: StatusRefunded}
+	}
+	available := p.AvailableRefund()
+	if refund.Amount.Currency != available.Currency {
+		return money.ErrCurrencyMismatch
+	}
+	if !refund.Amount.IsPositive() || refund.Amount.MinorUnits > available.MinorUnits {
+		return ErrRefundExceedsPayment
+	}
+
+	restockable := p.RestockableQuantities()
+	for _, item := range refund.Items {
+		if item.Quantity <= 0 || item.Quantity > restockable[item.ProductID] {
+			return ErrInvalidRefundItems
+		}
+		restockable[item.ProductID] -= item.Quantity
+	}
+	return nil
+}
+
+// ApplyRefund adds a refund to the running total and transitions the
+// payment to the status derived from it
+func (p *Payment) ApplyRefund(amount money.Money, reason string) (*PaymentEvent, error) {
+	if amount.Currency != p.Amount.Currency {
+		return nil, money.ErrCurrencyMismatch
+	}
+	refunded := p.RefundedAmount.MinorUnits + amount.MinorUnits
+	status := StatusPartiallyRefunded
+	if refunded == p.Amount.MinorUnits {
+		status = StatusRefunded
+	}
+	if !CanTransition(p.Status, status) {
+		return nil, &InvalidTransitionError{PaymentID: p.ID, From: p.Status, To: status}
+	}
+	if !amount.IsPositive() || amount.MinorUnits > p.RefundableAmount().MinorUnits {
+		return nil, ErrRefundExceedsPayment
+	}
+	p.RefundedAmount = money.Money{MinorUnits: refunded, Currency: p.Amount.Currency}
+	return p.Transition(status, reason)
+}
+
+// RestockableQuantities returns, per product, the purchased quantity not yet
+// returned to stock by earlier refunds, pending ones included
+func (p *Payment) RestockableQuantities() map[uint]int {
+	quantities := make(map[uint]int, len(p.Items))
+	for _, item := range p.Items {
+		quantities[item.ProductID] += item.Quantity
+	}
+	for _, refund := range p.Refunds {
+		if refund.Status == RefundFailed {
+			continue
+		}
+		for _, item := range refund.Items {
+			quantities[item.ProductID] -= item.Quantity
+		}
+	}
+	return quantities
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"fmt"
+
+	"gomicro/internal/money"
+	"gorm.io/gorm"
+)
+
+// MigrateLegacyAmounts converts the float amount columns of payments, payment
+// items and refunds recorded before amounts were stored in minor units. Every
+// amount keeps the currency of its payment, legacy payments settle at their
+// own amount, and payments that predate refunds have nothing refunded. Run it before AutoMigrate.
+func MigrateLegacyAmounts(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if !tx.Migrator().HasColumn("payments", "currency") {
+			return nil
+		}
+		paymentCurrency := func(table string) interface{} {
+			return gorm.Expr(fmt.Sprintf("(SELECT currency FROM payments WHERE payments.id = %s.payment_id)", table))
+		}
+		columns := []money.LegacyColumn{
+			{Table: "payments", Column: "amount", Prefix: "amount_", Currency: gorm.Expr("currency")},
+			{Table: "payments", Column: "refunded_amount", Prefix: "refunded_", Currency: gorm.Expr("currency")},
+			{Table: "payment_items", Column: "unit_price", Prefix: "unit_price_", Currency: paymentCurrency("payment_items")},
+			{Table: "refunds", Column: "amount", Prefix: "amount_", Currency: paymentCurrency("refunds")},
+		}
+		for _, column := range columns {
+			if err := money.MigrateLegacyColumn(tx, column); err != nil {
+				return err
+			}
+		}
+
+		steps := []string{
+			"ALTER TABLE payments ADD COLUMN IF NOT EXISTS refunded_minor_units bigint, ADD COLUMN IF NOT EXISTS refunded_currency varchar(3), ADD COLUMN IF NOT EXISTS settled_minor_units bigint, ADD COLUMN IF NOT EXISTS settled_currency varchar(3)",
+			"UPDATE payments SET refunded_minor_units = 0, refunded_currency = amount_currency WHERE refunded_minor_units IS NULL",
+			"UPDATE payments SET settled_minor_units = amount_minor_units, settled_currency = amount_currency WHERE settled_minor_units IS NULL",
+			"ALTER TABLE payments ALTER COLUMN refunded_minor_units SET NOT NULL, ALTER COLUMN refunded_currency SET NOT NULL, ALTER COLUMN settled_minor_units SET NOT NULL, ALTER COLUMN settled_currency SET NOT NULL",
+			"ALTER TABLE payments DROP COLUMN currency",
+		}
+		for _, step := range steps {
+			if err := tx.Exec(step).Error; err != nil {
+				return fmt.Errorf("failed to migrate payments.currency: %w", err)
+			}
+		}
+		return nil
+	})
+}
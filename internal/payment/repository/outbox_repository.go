@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gomicro/internal/payment/model"
+	"gorm.io/gorm"
+)
+
+type OutboxRepository interface {
+	GetPending(ctx context.Context, limit int) ([]*model.OutboxEvent, error)
+	MarkSent(ctx context.Context, id uint) error
+	MarkFailed(ctx context.Context, id uint, reason string) error
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) GetPending(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+	err := r.db.WithContext(ctx).
+		Where("status = ?", model.OutboxStatusPending).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) MarkSent(ctx context.Context, id uint) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&model.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.OutboxStatusSent,
+		"published_at": &now,
+	}).Error
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id uint, reason string) error {
+	return r.db.WithContext(ctx).Model(&model.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": reason,
+	}).Error
+}
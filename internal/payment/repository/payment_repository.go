@@ -3,15 +3,45 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gomicro/internal/payment/model"
 )
 
+// PaymentFilter narrows ListPayments results. Zero values are ignored.
+type PaymentFilter struct {
+	UserID      uint
+	Status      string
+	Currency    string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Amount bounds are in minor units of Currency; callers must set
+	// Currency with them
+	MinAmount int64
+	MaxAmount int64
+}
+
+// PaymentCursor is the position of the last payment on a page. Payments are
+// ordered newest first by (created_at, id).
+type PaymentCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        uint      `json:"id"`
+}
+
 type PaymentRepository interface {
 	Create(ctx context.Context, payment *model.Payment) error
 	GetByID(ctx context.Context, id uint) (*model.Payment, error)
+	GetByIdempotencyKey(ctx context.Context, key string) (*model.Payment, error)
 	Update(ctx context.Context, payment *model.Payment) error
+	UpdateStatus(ctx context.Context, payment *model.Payment, event *model.PaymentEvent, outbox []*model.OutboxEvent) error
+	ReserveRefund(ctx context.Context, refund *model.Refund) error
+	CompleteRefund(ctx context.Context, refund *model.Refund, outbox []*model.OutboxEvent) (*model.Payment, error)
+	FailRefund(ctx context.Context, refund *model.Refund) error
+	GetPendingRefunds(ctx context.Context, before time.Time, limit int) ([]*model.Refund, error)
+	GetEvents(ctx context.Context, paymentID uint) ([]*model.PaymentEvent, error)
+	List(ctx context.Context, filter PaymentFilter, after *PaymentCursor, limit int) ([]*model.Payment, error)
 }
 
 type paymentRepository struct {
@@ -28,7 +58,18 @@ func (r *paymentRepository) Create(ctx context.Context, payment *model.Payment)
 
 func (r *paymentRepository) GetByID(ctx context.Context, id uint) (*model.Payment, error) {
 	var payment model.Payment
-	if err := r.db.WithContext(ctx).First(&payment, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Items").Preload("Refunds.Items").First(&payment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) GetByIdempotencyKey(ctx context.Context, key string) (*model.Payment, error) {
+	var payment model.Payment
+	if err := r.db.WithContext(ctx).Preload("Items").Preload("Refunds.Items").Where("idempotency_key = ?", key).First(&payment).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -39,4 +80,150 @@ func (r *paymentRepository) GetByID(ctx context.Context, id uint) (*model.Paymen
 
 func (r *paymentRepository) Update(ctx context.Context, payment *model.Payment) error {
 	return r.db.WithContext(ctx).Save(payment).Error
-} 
\ No newline at end of file
+}
+
+// UpdateStatus saves the payment, records its status transition and
+// enqueues its outbox events in one transaction
+func (r *paymentRepository) UpdateStatus(ctx context.Context, payment *model.Payment, event *model.PaymentEvent, outbox []*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(payment).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		if len(outbox) == 0 {
+			return nil
+		}
+		return tx.Create(&outbox).Error
+	})
+}
+
+// ReserveRefund checks a refund against the payment and records it as
+// pending. The payment row is locked so concurrent refunds cannot both
+// reserve the same amount or items.
+func (r *paymentRepository) ReserveRefund(ctx context.Context, refund *model.Refund) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var payment model.Payment
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").Preload("Refunds.Items").
+			First(&payment, refund.PaymentID).Error
+		if err != nil {
+			return err
+		}
+		if err := payment.CheckRefund(refund); err != nil {
+			return err
+		}
+		refund.Status = model.RefundPending
+		return tx.Create(refund).Error
+	})
+}
+
+// CompleteRefund marks a pending refund succeeded and records the resulting
+// status transition and its outbox events
+func (r *paymentRepository) CompleteRefund(ctx context.Context, refund *model.Refund, outbox []*model.OutboxEvent) (*model.Payment, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var payment model.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, refund.PaymentID).Error; err != nil {
+			return err
+		}
+		event, err := payment.ApplyRefund(refund.Amount, refund.Reason)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&payment).Updates(map[string]interface{}{
+			"refunded_minor_units": payment.RefundedAmount.MinorUnits,
+			"refunded_currency":    payment.RefundedAmount.Currency,
+			"status":               payment.Status,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(refund).Updates(map[string]interface{}{
+			"status":       model.RefundSucceeded,
+			"provider_ref": refund.ProviderRef,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		if len(outbox) == 0 {
+			return nil
+		}
+		return tx.Create(&outbox).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	refund.Status = model.RefundSucceeded
+	return r.GetByID(ctx, refund.PaymentID)
+}
+
+// FailRefund marks a pending refund failed, which releases what it reserved
+func (r *paymentRepository) FailRefund(ctx context.Context, refund *model.Refund) error {
+	err := r.db.WithContext(ctx).Model(refund).
+		Where("status = ?", model.RefundPending).
+		Update("status", model.RefundFailed).Error
+	if err != nil {
+		return err
+	}
+	refund.Status = model.RefundFailed
+	return nil
+}
+
+// GetPendingRefunds returns up to limit refunds created before the given
+// time that are still waiting for the provider's answer, oldest first
+func (r *paymentRepository) GetPendingRefunds(ctx context.Context, before time.Time, limit int) ([]*model.Refund, error) {
+	var refunds []*model.Refund
+	err := r.db.WithContext(ctx).Preload("Items").
+		Where("status = ? AND created_at < ?", model.RefundPending, before).
+		Order("id").Limit(limit).Find(&refunds).Error
+	if err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+func (r *paymentRepository) GetEvents(ctx context.Context, paymentID uint) ([]*model.PaymentEvent, error) {
+	var events []*model.PaymentEvent
+	if err := r.db.WithContext(ctx).Where("payment_id = ?", paymentID).Order("id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// List returns up to limit payments matching the filter that come after the
+// cursor, using keyset pagination over (created_at, id)
+func (r *paymentRepository) List(ctx context.Context, filter PaymentFilter, after *PaymentCursor, limit int) ([]*model.Payment, error) {
+	query := r.db.WithContext(ctx).Model(&model.Payment{}).Preload("Items").Preload("Refunds.Items")
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Currency != "" {
+		query = query.Where("amount_currency = ?", filter.Currency)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedTo)
+	}
+	if filter.MinAmount > 0 {
+		query = query.Where("amount_minor_units >= ?", filter.MinAmount)
+	}
+	if filter.MaxAmount > 0 {
+		query = query.Where("amount_minor_units <= ?", filter.MaxAmount)
+	}
+	if after != nil {
+		query = query.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+
+	var payments []*model.Payment
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	pb "gomicro/api/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// ErrBasketChanged is returned when the basket changed after the customer saw
+// it, so the checkout no longer matches what they confirmed
+var ErrBasketChanged = errors.New("basket changed since it was displayed")
+
+type IBasketClient interface {
+	GetBasket(ctx context.Context, userID uint32) (*pb.Basket, error)
+	// LockBasketForCheckout returns the basket snapshot of version, or
+	// ErrBasketChanged if the basket is at another version
+	LockBasketForCheckout(ctx context.Context, userID uint32, version int64) (*pb.Basket, error)
+	// CompleteCheckout clears the basket once the order for the snapshot of
+	// version is placed, counting the use of its coupon. It returns
+	// ErrBasketChanged, leaving the basket as it is, if the basket is no
+	// longer at version.
+	CompleteCheckout(ctx context.Context, userID uint32, version int64) error
+}
+
+type BasketClient struct {
+	client pb.BasketServiceClient
+}
+
+func NewBasketClient(address string) (*BasketClient, error) {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Printf("Failed to connect to basket service: %v", err)
+		return nil, err
+	}
+
+	client := pb.NewBasketServiceClient(conn)
+	return &BasketClient{client: client}, nil
+}
+
+func (c *BasketClient) GetBasket(ctx context.Context, userID uint32) (*pb.Basket, error) {
+	resp, err := c.client.GetBasket(ctx, &pb.GetBasketRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *BasketClient) LockBasketForCheckout(ctx context.Context, userID uint32, version int64) (*pb.Basket, error) {
+	resp, err := c.client.LockBasketForCheckout(ctx, &pb.LockBasketForCheckoutRequest{
+		UserId:  userID,
+		Version: version,
+	})
+	if status.Code(err) == codes.Aborted {
+		return nil, fmt.Errorf("%w: %s", ErrBasketChanged, status.Convert(err).Message())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *BasketClient) CompleteCheckout(ctx context.Context, userID uint32, version int64) error {
+	_, err := c.client.CompleteCheckout(ctx, &pb.CompleteCheckoutRequest{
+		UserId:  userID,
+		Version: version,
+	})
+	if status.Code(err) == codes.Aborted {
+		return fmt.Errorf("%w: %s", ErrBasketChanged, status.Convert(err).Message())
+	}
+	return err
+}
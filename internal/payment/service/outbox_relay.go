@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"gomicro/internal/payment/model"
+	"gomicro/internal/payment/repository"
+)
+
+// OutboxRelay publishes pending outbox events to RabbitMQ. An event is only
+// marked as sent after a successful publish, so delivery is at-least-once;
+// events carry their outbox ID so consumers can drop duplicates.
+type OutboxRelay struct {
+	repo       repository.OutboxRepository
+	publisher  IRabbitMQPublisher
+	interval   time.Duration
+	batchSize  int
+	maxRetries int
+	backoff    time.Duration
+}
+
+func NewOutboxRelay(repo repository.OutboxRepository, publisher IRabbitMQPublisher, interval time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		repo:       repo,
+		publisher:  publisher,
+		interval:   interval,
+		batchSize:  100,
+		maxRetries: 3,
+		backoff:    100 * time.Millisecond,
+	}
+}
+
+// Run polls the outbox until the context is cancelled
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RelayPending(ctx); err != nil {
+			log.Printf("Failed to relay outbox events: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending publishes one batch of pending events. Events that still fail
+// after all retries stay pending and are picked up again on the next run.
+func (r *OutboxRelay) RelayPending(ctx context.Context) error {
+	events, err := r.repo.GetPending(ctx, r.batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if err := r.publishWithRetry(ctx, event); err != nil {
+			log.Printf("Failed to publish outbox event %d: %v", event.ID, err)
+			if err := r.repo.MarkFailed(ctx, event.ID, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.repo.MarkSent(ctx, event.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *OutboxRelay) publishWithRetry(ctx context.Context, event *model.OutboxEvent) error {
+	var err error
+	backoff := r.backoff
+	for attempt := 0; attempt < r.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		if err = r.publish(event); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (r *OutboxRelay) publish(event *model.OutboxEvent) error {
+	switch event.EventType {
+	case model.EventTypeStockUpdate:
+		var stockEvent model.StockUpdateEvent
+		if err := json.Unmarshal([]byte(event.Payload), &stockEvent); err != nil {
+			return fmt.Errorf("failed to unmarshal payload: %v", err)
+		}
+		stockEvent.EventID = event.ID
+		return r.publisher.SendStockUpdateEvent(&stockEvent)
+	default:
+		return fmt.Errorf("unknown event type %q", event.EventType)
+	}
+}
+
+func newStockUpdateOutboxEvent(paymentID uint, event *model.StockUpdateEvent) (*model.OutboxEvent, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %v", err)
+	}
+	return &model.OutboxEvent{
+		PaymentID: paymentID,
+		EventType: model.EventTypeStockUpdate,
+		Payload:   string(payload),
+		Status:    model.OutboxStatusPending,
+	}, nil
+}
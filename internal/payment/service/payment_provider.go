@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"gomicro/internal/money"
+)
+
+var (
+	// ErrProviderTimeout is returned when the payment provider does not answer in time
+	ErrProviderTimeout = errors.New("payment provider timed out")
+	// ErrUnknownProviderReference is returned for operations on an unknown authorization
+	ErrUnknownProviderReference = errors.New("unknown provider reference")
+	// ErrInvalidProviderOperation is returned when an operation is not allowed in the authorization's current state
+	ErrInvalidProviderOperation = errors.New("operation not allowed for authorization state")
+)
+
+type ProviderStatus string
+
+const (
+	ProviderApproved       ProviderStatus = "approved"
+	ProviderDeclined       ProviderStatus = "declined"
+	ProviderRequiresAction ProviderStatus = "requires_action"
+)
+
+// AuthorizeRequest describes the funds to reserve. PaymentMethod carries the
+// card number or token understood by the provider.
+type AuthorizeRequest struct {
+	Amount        money.Money
+	PaymentMethod string
+}
+
+// ProviderResult is the outcome of a provider operation
+type ProviderResult struct {
+	Status      ProviderStatus
+	Reference   string
+	Reason      string
+	RedirectURL string
+}
+
+// PaymentProvider is the integration point for a payment service provider.
+// Authorize reserves funds, Confirm completes an authorization that required
+// customer action, Capture settles funds, Void releases an uncaptured
+// authorization and Refund returns captured funds.
+//
+// A refund is identified to the provider by a key chosen before it is sent.
+// Refunding again with the same key does not pay out twice, and GetRefund
+// looks the refund up by its key, failing with ErrUnknownProviderReference
+// when the provider never received it.
+type PaymentProvider interface {
+	Authorize(ctx context.Context, req *AuthorizeRequest) (*ProviderResult, error)
+	Confirm(ctx context.Context, reference string) (*ProviderResult, error)
+	Capture(ctx context.Context, reference string, amount money.Money) (*ProviderResult, error)
+	Void(ctx context.Context, reference string) (*ProviderResult, error)
+	Refund(ctx context.Context, reference, key string, amount money.Money) (*ProviderResult, error)
+	GetRefund(ctx context.Context, key string) (*ProviderResult, error)
+}
@@ -3,69 +3,570 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
+	pb "gomicro/api/proto"
+	"gomicro/internal/money"
+	"gomicro/internal/pagination"
 	"gomicro/internal/payment/model"
 	"gomicro/internal/payment/repository"
 )
 
+var (
+	// ErrIdempotencyKeyConflict is returned when an idempotency key is replayed
+	// with parameters that differ from the original request
+	ErrIdempotencyKeyConflict = errors.New("idempotency key was already used with different parameters")
+	// ErrPaymentNotFound is returned when the requested payment does not exist
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrInvalidRefundItems is returned when refund items do not match the payment's unreturned items
+	ErrInvalidRefundItems = model.ErrInvalidRefundItems
+	// ErrInvalidPageToken is returned when a ListPayments page token cannot be decoded
+	ErrInvalidPageToken = pagination.ErrInvalidToken
+)
+
+const (
+	// checkoutReservationTTL bounds how long a checkout holds stock, e.g.
+	// while the customer completes a 3-D Secure challenge
+	checkoutReservationTTL = 15 * time.Minute
+	// reconcileBatchSize bounds how many pending refunds one reconciliation looks up
+	reconcileBatchSize = 100
+)
+
 type PaymentService interface {
-	ProcessPayment(ctx context.Context, userID uint, amount float64, currency, paymentMethod string) (*model.Payment, error)
+	ProcessPayment(ctx context.Context, userID uint, amount money.Money, paymentMethod, idempotencyKey string) (*model.Payment, error)
 	GetPayment(ctx context.Context, paymentID uint) (*model.Payment, error)
+	Checkout(ctx context.Context, userID uint, basketVersion int64, currency, paymentMethod string) (*model.Payment, error)
+	ConfirmPayment(ctx context.Context, paymentID uint) (*model.Payment, error)
+	RefundPayment(ctx context.Context, paymentID uint, amount money.Money, reason string, items []model.RefundItem) (*model.Payment, error)
+	GetPaymentHistory(ctx context.Context, paymentID uint) ([]*model.PaymentEvent, error)
+	ListPayments(ctx context.Context, filter repository.PaymentFilter, pageSize int, pageToken string) ([]*model.Payment, string, error)
+	ReconcileRefunds(ctx context.Context, before time.Time) (int, error)
 }
 
 type paymentService struct {
-	repo      repository.PaymentRepository
-	publisher IRabbitMQPublisher
+	repo          repository.PaymentRepository
+	provider      PaymentProvider
+	basketClient  IBasketClient
+	productClient IProductClient
+	rates         money.RateProvider
+	baseCurrency  string
 }
 
-func NewPaymentService(repo repository.PaymentRepository, publisher IRabbitMQPublisher) PaymentService {
+// NewPaymentService creates a payment service. Events are not published
+// directly; they are written to the outbox and delivered by OutboxRelay.
+// Payments are charged in the customer's currency and settled in
+// baseCurrency at the rate quoted by rates.
+func NewPaymentService(repo repository.PaymentRepository, provider PaymentProvider, basketClient IBasketClient, productClient IProductClient, rates money.RateProvider, baseCurrency string) PaymentService {
 	return &paymentService{
-		repo:      repo,
-		publisher: publisher,
+		repo:          repo,
+		provider:      provider,
+		basketClient:  basketClient,
+		productClient: productClient,
+		rates:         rates,
+		baseCurrency:  baseCurrency,
+	}
+}
+
+// ProcessPayment charges a raw amount. When an idempotency key is given, a
+// replayed request returns the original payment instead of charging twice.
+// Amounts in unknown currencies are rejected with money.ErrUnknownCurrency.
+func (s *paymentService) ProcessPayment(ctx context.Context, userID uint, amount money.Money, paymentMethod, idempotencyKey string) (*model.Payment, error) {
+	if err := amount.Validate(); err != nil {
+		return nil, err
+	}
+	if idempotencyKey == "" {
+		return s.charge(ctx, userID, amount, paymentMethod, nil, "", nil)
+	}
+
+	existing, err := s.repo.GetByIdempotencyKey(ctx, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return replayPayment(existing, userID, amount, paymentMethod)
+	}
+
+	payment, err := s.charge(ctx, userID, amount, paymentMethod, nil, "", &idempotencyKey)
+	if err != nil {
+		// A concurrent request with the same key may have won the unique index
+		if existing, lookupErr := s.repo.GetByIdempotencyKey(ctx, idempotencyKey); lookupErr == nil && existing != nil {
+			return replayPayment(existing, userID, amount, paymentMethod)
+		}
+		return nil, err
+	}
+	return payment, nil
+}
+
+func replayPayment(existing *model.Payment, userID uint, amount money.Money, paymentMethod string) (*model.Payment, error) {
+	if existing.UserID != userID || existing.Amount != amount || existing.PaymentMethod != paymentMethod {
+		return nil, ErrIdempotencyKeyConflict
+	}
+	return existing, nil
+}
+
+func (s *paymentService) GetPayment(ctx context.Context, paymentID uint) (*model.Payment, error) {
+	return s.repo.GetByID(ctx, paymentID)
+}
+
+// GetPaymentHistory returns the status transitions of a payment, oldest first
+func (s *paymentService) GetPaymentHistory(ctx context.Context, paymentID uint) ([]*model.PaymentEvent, error) {
+	payment, err := s.repo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+	return s.repo.GetEvents(ctx, paymentID)
+}
+
+// ListPayments returns one page of payments matching the filter, newest
+// first, and the token for the next page (empty on the last page)
+func (s *paymentService) ListPayments(ctx context.Context, filter repository.PaymentFilter, pageSize int, pageToken string) ([]*model.Payment, string, error) {
+	var after *repository.PaymentCursor
+	if pageToken != "" {
+		after = &repository.PaymentCursor{}
+		if err := pagination.DecodeToken(pageToken, after); err != nil || after.ID == 0 {
+			return nil, "", ErrInvalidPageToken
+		}
+	}
+
+	return pagination.Fetch(pagination.Size(pageSize), func(limit int) ([]*model.Payment, error) {
+		return s.repo.List(ctx, filter, after, limit)
+	}, func(last *model.Payment) interface{} {
+		return &repository.PaymentCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	})
+}
+
+// Checkout charges the basket snapshot locked at basketVersion, or at the
+// basket's current version when basketVersion is 0. Items are charged at the
+// prices the basket service locked, converted to the checkout currency per
+// unit, less the snapshot's coupon discount; the client cannot influence
+// either. The catalog is only consulted for stock.
+func (s *paymentService) Checkout(ctx context.Context, userID uint, basketVersion int64, currency, paymentMethod string) (*model.Payment, error) {
+	if currency == "" {
+		currency = s.baseCurrency
+	}
+	total, err := money.New(0, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if basketVersion == 0 {
+		current, err := s.basketClient.GetBasket(ctx, uint32(userID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get basket: %v", err)
+		}
+		basketVersion = current.Version
+	}
+	basket, err := s.basketClient.LockBasketForCheckout(ctx, uint32(userID), basketVersion)
+	if errors.Is(err, ErrBasketChanged) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock basket: %v", err)
+	}
+	if len(basket.Items) == 0 {
+		return nil, errors.New("basket is empty")
+	}
+
+	productIDs := make([]uint32, len(basket.Items))
+	for i, item := range basket.Items {
+		productIDs[i] = item.ProductId
+	}
+	products, err := s.productClient.GetProducts(ctx, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %v", err)
+	}
+	stock := make(map[uint32]int32, len(products))
+	for _, p := range products {
+		stock[p.Id] = p.Stock - p.ReservedStock
+	}
+
+	items := make([]model.PaymentItem, 0, len(basket.Items))
+	for _, item := range basket.Items {
+		available, ok := stock[item.ProductId]
+		if !ok || item.Unavailable {
+			return nil, fmt.Errorf("product %d not found", item.ProductId)
+		}
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("invalid quantity for product %d", item.ProductId)
+		}
+		if available < item.Quantity {
+			return nil, fmt.Errorf("insufficient stock for product %d", item.ProductId)
+		}
+		price, err := money.FromProto(item.CurrentPrice)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price for product %d: %w", item.ProductId, err)
+		}
+		if price, _, err = money.Exchange(ctx, s.rates, price, currency); err != nil {
+			return nil, err
+		}
+		total, err = total.Add(price.Mul(int64(item.Quantity)))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, model.PaymentItem{
+			ProductID: uint(item.ProductId),
+			Quantity:  int(item.Quantity),
+			UnitPrice: price,
+		})
+	}
+
+	for _, discount := range basket.Discounts {
+		amount, err := money.FromProto(discount.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid discount %s: %w", discount.Code, err)
+		}
+		if amount, _, err = money.Exchange(ctx, s.rates, amount, currency); err != nil {
+			return nil, err
+		}
+		if total, err = total.Sub(amount); err != nil {
+			return nil, err
+		}
+	}
+	// Per-unit conversion may round the lines below a full discount
+	total.MinorUnits = max(total.MinorUnits, 0)
+
+	// Hold the stock while the payment is in flight
+	reservation := make([]*pb.StockReservationItem, len(basket.Items))
+	for i, item := range basket.Items {
+		reservation[i] = &pb.StockReservationItem{ProductId: item.ProductId, Quantity: item.Quantity}
+	}
+	reservationID, err := s.productClient.ReserveStock(ctx, reservation, checkoutReservationTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve stock: %w", err)
+	}
+
+	payment, err := s.charge(ctx, userID, total, paymentMethod, items, reservationID, nil)
+	// A payment awaiting customer action keeps its stock until it settles.
+	// Captured stock stays held until product-service applies the stock
+	// decrement from the outbox, which releases the hold with it.
+	if err != nil || !holdsStock(payment) {
+		if releaseErr := s.productClient.ReleaseStock(ctx, reservationID); releaseErr != nil {
+			log.Printf("Failed to release stock reservation %s for user %d: %v", reservationID, userID, releaseErr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status == model.StatusRequiresAction {
+		payment.BasketVersion = basketVersion
+		if err := s.repo.Update(ctx, payment); err != nil {
+			return nil, err
+		}
+	}
+	if payment.Status != model.StatusCaptured {
+		return payment, nil
+	}
+
+	s.completeCheckout(ctx, payment, basketVersion)
+	return payment, nil
+}
+
+// holdsStock reports whether a checkout payment still needs its stock
+// reservation
+func holdsStock(payment *model.Payment) bool {
+	return payment.Status == model.StatusRequiresAction || payment.Status == model.StatusCaptured
+}
+
+// completeCheckout clears the basket the captured payment was charged for,
+// unless it changed since it was locked at version: lines added in the
+// meantime were not paid for. The payment is already captured, so failures
+// are logged rather than failing it.
+func (s *paymentService) completeCheckout(ctx context.Context, payment *model.Payment, version int64) {
+	err := s.basketClient.CompleteCheckout(ctx, uint32(payment.UserID), version)
+	if errors.Is(err, ErrBasketChanged) {
+		log.Printf("Kept basket of user %d after payment %d: %v", payment.UserID, payment.ID, err)
+	} else if err != nil {
+		log.Printf("Failed to complete checkout for user %d after payment %d: %v", payment.UserID, payment.ID, err)
+	}
+}
+
+// ConfirmPayment completes a payment that was waiting for customer action,
+// such as a 3-D Secure challenge. The provider reports whether the action
+// succeeded: the payment is then captured and the basket checkout completed,
+// or failed if it was declined. Provider errors leave the payment waiting so the call can be retried.
+func (s *paymentService) ConfirmPayment(ctx context.Context, paymentID uint) (*model.Payment, error) {
+	payment, err := s.repo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+	if payment.Status != model.StatusRequiresAction {
+		return nil, &model.InvalidTransitionError{PaymentID: payment.ID, From: payment.Status, To: model.StatusAuthorized}
+	}
+
+	result, err := s.provider.Confirm(ctx, payment.ProviderRef)
+	if err != nil {
+		return nil, fmt.Errorf("confirmation failed: %w", err)
+	}
+
+	payment.NextActionURL = ""
+	if result.Status == ProviderApproved {
+		payment.FailureReason = ""
+		if err := s.transition(ctx, payment, model.StatusAuthorized, "customer action completed", nil); err != nil {
+			return nil, err
+		}
+		payment, err = s.capture(ctx, payment)
+	} else {
+		payment, err = s.fail(ctx, payment, result.Reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A captured payment's hold is released by product-service along with
+	// the stock decrement
+	if payment.StockReservationID != "" && !holdsStock(payment) {
+		if err := s.productClient.ReleaseStock(ctx, payment.StockReservationID); err != nil {
+			log.Printf("Failed to release stock reservation %s for payment %d: %v", payment.StockReservationID, payment.ID, err)
+		}
+	}
+	if payment.Status == model.StatusCaptured {
+		s.completeCheckout(ctx, payment, payment.BasketVersion)
+	}
+	return payment, nil
+}
+
+// RefundPayment refunds part or all of a captured payment. An amount of zero
+// refunds whatever is left. Items returned to stock are emitted as positive
+// stock updates; a refund that settles the payment without explicit items
+// restocks everything not returned yet.
+//
+// The refund is reserved as pending under the payment's lock before the
+// provider pays it out, so concurrent refunds cannot exceed the payment. A
+// declined refund releases its reservation; one whose outcome is unknown
+// because the provider timed out stays pending until ReconcileRefunds asks
+// the provider what became of it.
+func (s *paymentService) RefundPayment(ctx context.Context, paymentID uint, amount money.Money, reason string, items []model.RefundItem) (*model.Payment, error) {
+	payment, err := s.repo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, ErrPaymentNotFound
 	}
+
+	available := payment.AvailableRefund()
+	if amount.IsZero() {
+		amount = available
+	}
+	if len(items) == 0 && amount == available {
+		restockable := payment.RestockableQuantities()
+		for _, item := range payment.Items {
+			if quantity := restockable[item.ProductID]; quantity > 0 {
+				items = append(items, model.RefundItem{ProductID: item.ProductID, Quantity: quantity})
+				restockable[item.ProductID] = 0
+			}
+		}
+	}
+
+	refund := &model.Refund{
+		PaymentID: payment.ID,
+		Amount:    amount,
+		Reason:    reason,
+		Items:     items,
+	}
+	if err := s.repo.ReserveRefund(ctx, refund); err != nil {
+		return nil, err
+	}
+
+	result, err := s.provider.Refund(ctx, payment.ProviderRef, refundKey(refund), amount)
+	if errors.Is(err, ErrProviderTimeout) {
+		return nil, fmt.Errorf("refund %d is pending: %w", refund.ID, err)
+	}
+	if err != nil || result.Status != ProviderApproved {
+		if failErr := s.repo.FailRefund(ctx, refund); failErr != nil {
+			log.Printf("Failed to release refund %d of payment %d: %v", refund.ID, payment.ID, failErr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("refund failed: %v", err)
+		}
+		return nil, fmt.Errorf("refund declined: %s", result.Reason)
+	}
+	return s.completeRefund(ctx, refund, result.Reference)
 }
 
-func (s *paymentService) ProcessPayment(ctx context.Context, userID uint, amount float64, currency, paymentMethod string) (*model.Payment, error) {
-	if amount <= 0 {
+// ReconcileRefunds settles refunds created before the given time that are
+// still pending because the provider timed out. The provider is asked what
+// became of each one by its refund key: refunds it paid out are completed
+// and restock their items, while refunds it declined or never received are
+// failed, which releases the amount they held. Refunds it still cannot
+// answer for stay pending for the next run. It returns how many refunds
+// were settled.
+func (s *paymentService) ReconcileRefunds(ctx context.Context, before time.Time) (int, error) {
+	refunds, err := s.repo.GetPendingRefunds(ctx, before, reconcileBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, refund := range refunds {
+		result, err := s.provider.GetRefund(ctx, refundKey(refund))
+		switch {
+		case errors.Is(err, ErrUnknownProviderReference):
+			err = s.repo.FailRefund(ctx, refund)
+		case err != nil:
+			log.Printf("Failed to look up refund %d of payment %d: %v", refund.ID, refund.PaymentID, err)
+			continue
+		case result.Status == ProviderApproved:
+			_, err = s.completeRefund(ctx, refund, result.Reference)
+		default:
+			err = s.repo.FailRefund(ctx, refund)
+		}
+		if err != nil {
+			log.Printf("Failed to settle refund %d of payment %d: %v", refund.ID, refund.PaymentID, err)
+			continue
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// completeRefund records a refund the provider paid out and compensates the
+// stock decrement made when the payment was captured
+func (s *paymentService) completeRefund(ctx context.Context, refund *model.Refund, providerRef string) (*model.Payment, error) {
+	refund.ProviderRef = providerRef
+
+	events := make([]*model.OutboxEvent, 0, len(refund.Items))
+	for _, item := range refund.Items {
+		event, err := newStockUpdateOutboxEvent(refund.PaymentID, &model.StockUpdateEvent{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return s.repo.CompleteRefund(ctx, refund, events)
+}
+
+// refundKey identifies a refund to the provider. It is known before the
+// provider is called, so a refund whose answer was lost can be looked up.
+func refundKey(refund *model.Refund) string {
+	return fmt.Sprintf("refund_%d", refund.ID)
+}
+
+// charge authorizes and captures the amount through the payment provider.
+// Declines, timeouts and 3-D Secure challenges are not errors: they are
+// recorded on the returned payment's status.
+func (s *paymentService) charge(ctx context.Context, userID uint, amount money.Money, paymentMethod string, items []model.PaymentItem, reservationID string, idempotencyKey *string) (*model.Payment, error) {
+	if !amount.IsPositive() {
 		return nil, errors.New("amount must be greater than zero")
 	}
+	settled, rate, err := money.Exchange(ctx, s.rates, amount, s.baseCurrency)
+	if err != nil {
+		return nil, err
+	}
 
 	payment := &model.Payment{
-		UserID:        userID,
-		Amount:        amount,
-		Currency:      currency,
-		Status:        "pending",
-		PaymentMethod: paymentMethod,
+		UserID:             userID,
+		Amount:             amount,
+		SettledAmount:      settled,
+		ExchangeRate:       rate,
+		RefundedAmount:     money.Money{Currency: amount.Currency},
+		Status:             model.StatusPending,
+		PaymentMethod:      paymentMethod,
+		IdempotencyKey:     idempotencyKey,
+		Items:              items,
+		StockReservationID: reservationID,
 	}
 
 	if err := s.repo.Create(ctx, payment); err != nil {
 		return nil, err
 	}
 
-	// Simulate payment processing
-	time.Sleep(2 * time.Second)
+	result, err := s.provider.Authorize(ctx, &AuthorizeRequest{
+		Amount:        amount,
+		PaymentMethod: paymentMethod,
+	})
+	if err != nil {
+		return s.fail(ctx, payment, err.Error())
+	}
 
-	// Update payment status
-	payment.Status = "completed"
-	if err := s.repo.Update(ctx, payment); err != nil {
+	payment.ProviderRef = result.Reference
+	switch result.Status {
+	case ProviderDeclined:
+		return s.fail(ctx, payment, result.Reason)
+	case ProviderRequiresAction:
+		payment.FailureReason = result.Reason
+		payment.NextActionURL = result.RedirectURL
+		if err := s.transition(ctx, payment, model.StatusRequiresAction, result.Reason, nil); err != nil {
+			return nil, err
+		}
+		return payment, nil
+	}
+
+	if err := s.transition(ctx, payment, model.StatusAuthorized, "authorized by provider", nil); err != nil {
 		return nil, err
 	}
 
-	// Send stock update event
-	event := &model.StockUpdateEvent{
-		ProductID: 1, // This should come from the request
-		Quantity:  -1, // Decrease stock by 1
+	return s.capture(ctx, payment)
+}
+
+// capture settles an authorized payment. If the capture fails the
+// authorization is voided so the customer's funds are released.
+func (s *paymentService) capture(ctx context.Context, payment *model.Payment) (*model.Payment, error) {
+	result, err := s.provider.Capture(ctx, payment.ProviderRef, payment.Amount)
+	if err != nil || result.Status != ProviderApproved {
+		reason := "capture declined"
+		if err != nil {
+			reason = err.Error()
+		}
+		if _, voidErr := s.provider.Void(ctx, payment.ProviderRef); voidErr != nil {
+			log.Printf("Failed to void authorization %s for payment %d: %v", payment.ProviderRef, payment.ID, voidErr)
+			return s.fail(ctx, payment, reason)
+		}
+		payment.FailureReason = reason
+		if err := s.transition(ctx, payment, model.StatusVoided, reason, nil); err != nil {
+			return nil, err
+		}
+		return payment, nil
+	}
+
+	// Enqueue one stock update event per charged item
+	events := make([]*model.OutboxEvent, 0, len(payment.Items))
+	for _, item := range payment.Items {
+		event, err := newStockUpdateOutboxEvent(payment.ID, &model.StockUpdateEvent{
+			ProductID:     item.ProductID,
+			Quantity:      -item.Quantity,
+			ReservationID: payment.StockReservationID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
 	}
-	if err := s.publisher.SendStockUpdateEvent(event); err != nil {
-		// Log the error but don't fail the payment
-		// In a real system, you might want to handle this differently
+
+	// Update payment status together with its outbox events
+	if err := s.transition(ctx, payment, model.StatusCaptured, "captured by provider", events); err != nil {
 		return nil, err
 	}
 
 	return payment, nil
 }
 
-func (s *paymentService) GetPayment(ctx context.Context, paymentID uint) (*model.Payment, error) {
-	return s.repo.GetByID(ctx, paymentID)
-} 
\ No newline at end of file
+func (s *paymentService) fail(ctx context.Context, payment *model.Payment, reason string) (*model.Payment, error) {
+	payment.FailureReason = reason
+	if err := s.transition(ctx, payment, model.StatusFailed, reason, nil); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// transition validates a status change and persists it together with its
+// history entry and any outbox events
+func (s *paymentService) transition(ctx context.Context, payment *model.Payment, to, reason string, outbox []*model.OutboxEvent) error {
+	event, err := payment.Transition(to, reason)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateStatus(ctx, payment, event, outbox)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "gomicro/api/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type IProductClient interface {
+	GetProducts(ctx context.Context, productIDs []uint32) ([]*pb.Product, error)
+	ReserveStock(ctx context.Context, items []*pb.StockReservationItem, ttl time.Duration) (string, error)
+	ReleaseStock(ctx context.Context, reservationID string) error
+}
+
+type ProductClient struct {
+	client pb.ProductServiceClient
+}
+
+func NewProductClient(address string) (*ProductClient, error) {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Printf("Failed to connect to product service: %v", err)
+		return nil, err
+	}
+
+	client := pb.NewProductServiceClient(conn)
+	return &ProductClient{client: client}, nil
+}
+
+func (c *ProductClient) GetProducts(ctx context.Context, productIDs []uint32) ([]*pb.Product, error) {
+	resp, err := c.client.GetProducts(ctx, &pb.GetProductsRequest{
+		ProductIds: productIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Products, nil
+}
+
+func (c *ProductClient) ReserveStock(ctx context.Context, items []*pb.StockReservationItem, ttl time.Duration) (string, error) {
+	resp, err := c.client.ReserveStock(ctx, &pb.ReserveStockRequest{
+		Items:      items,
+		TtlSeconds: int32(ttl / time.Second),
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.ReservationId, nil
+}
+
+func (c *ProductClient) ReleaseStock(ctx context.Context, reservationID string) error {
+	_, err := c.client.ReleaseStock(ctx, &pb.ReleaseStockRequest{
+		ReservationId: reservationID,
+	})
+	return err
+}
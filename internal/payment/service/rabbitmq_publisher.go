@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 
 	"github.com/streadway/amqp"
 	"gomicro/internal/payment/model"
@@ -66,8 +67,10 @@ func (p *RabbitMQPublisher) SendStockUpdateEvent(event *model.StockUpdateEvent)
 		false,            // mandatory
 		false,            // immediate
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    strconv.FormatUint(uint64(event.EventID), 10),
+			Body:         body,
 		},
 	)
 	if err != nil {
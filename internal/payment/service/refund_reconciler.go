@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RefundReconciler periodically settles refunds left pending by a provider
+// timeout. Only refunds older than staleAfter are looked up, so a refund
+// whose provider call is still in flight is not failed by mistake.
+type RefundReconciler struct {
+	service    PaymentService
+	interval   time.Duration
+	staleAfter time.Duration
+}
+
+// NewRefundReconciler creates a reconciler that runs every interval
+func NewRefundReconciler(service PaymentService, interval, staleAfter time.Duration) *RefundReconciler {
+	return &RefundReconciler{
+		service:    service,
+		interval:   interval,
+		staleAfter: staleAfter,
+	}
+}
+
+// Run reconciles pending refunds until the context is cancelled
+func (r *RefundReconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.ReconcilePending(ctx); err != nil {
+				log.Printf("Failed to reconcile pending refunds: %v", err)
+			}
+		}
+	}
+}
+
+// ReconcilePending settles stale pending refunds and returns how many
+func (r *RefundReconciler) ReconcilePending(ctx context.Context) (int, error) {
+	return r.service.ReconcileRefunds(ctx, time.Now().Add(-r.staleAfter))
+}
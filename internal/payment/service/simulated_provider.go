@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gomicro/internal/money"
+)
+
+type SimulatedOutcome string
+
+const (
+	SimulateApprove    SimulatedOutcome = "approve"
+	SimulateDecline    SimulatedOutcome = "decline"
+	SimulateTimeout    SimulatedOutcome = "timeout"
+	SimulateRequire3DS SimulatedOutcome = "require_3ds"
+	// SimulateFail3DS requires 3-D Secure and declines on confirmation
+	SimulateFail3DS SimulatedOutcome = "fail_3ds"
+)
+
+// SimulatorRule selects an outcome for authorizations matching a card number
+// or an exact amount. Empty fields match anything.
+type SimulatorRule struct {
+	CardNumber string
+	Amount     money.Money
+	Outcome    SimulatedOutcome
+}
+
+func (r SimulatorRule) matches(req *AuthorizeRequest) bool {
+	if r.CardNumber == "" && r.Amount.IsZero() {
+		return false
+	}
+	if r.CardNumber != "" && r.CardNumber != req.PaymentMethod {
+		return false
+	}
+	if !r.Amount.IsZero() && r.Amount != req.Amount {
+		return false
+	}
+	return true
+}
+
+// DefaultSimulatorRules mirrors the test cards commonly used by real PSPs
+func DefaultSimulatorRules() []SimulatorRule {
+	return []SimulatorRule{
+		{CardNumber: "4000000000000002", Outcome: SimulateDecline},
+		{CardNumber: "4000000000000119", Outcome: SimulateTimeout},
+		{CardNumber: "4000000000003220", Outcome: SimulateRequire3DS},
+		{CardNumber: "4000008400001629", Outcome: SimulateFail3DS},
+	}
+}
+
+type simulatedAuthorization struct {
+	amount   money.Money
+	captured int64
+	refunded int64
+	voided   bool
+	// pendingAction is set until a 3-D Secure challenge is confirmed
+	pendingAction bool
+	failAction    bool
+}
+
+// SimulatedProvider is a deterministic in-memory PaymentProvider. The first
+// matching rule decides the outcome of Authorize; anything else is approved.
+type SimulatedProvider struct {
+	mu             sync.Mutex
+	rules          []SimulatorRule
+	sequence       int
+	authorizations map[string]*simulatedAuthorization
+	// refunds holds the result of each refund by its key
+	refunds map[string]*ProviderResult
+}
+
+func NewSimulatedProvider(rules ...SimulatorRule) *SimulatedProvider {
+	return &SimulatedProvider{
+		rules:          rules,
+		authorizations: make(map[string]*simulatedAuthorization),
+		refunds:        make(map[string]*ProviderResult),
+	}
+}
+
+func (p *SimulatedProvider) Authorize(ctx context.Context, req *AuthorizeRequest) (*ProviderResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	outcome := SimulateApprove
+	for _, rule := range p.rules {
+		if rule.matches(req) {
+			outcome = rule.Outcome
+			break
+		}
+	}
+
+	switch outcome {
+	case SimulateTimeout:
+		return nil, ErrProviderTimeout
+	case SimulateDecline:
+		return &ProviderResult{Status: ProviderDeclined, Reason: "card declined"}, nil
+	}
+
+	p.sequence++
+	reference := fmt.Sprintf("sim_auth_%06d", p.sequence)
+	auth := &simulatedAuthorization{amount: req.Amount}
+	p.authorizations[reference] = auth
+
+	if outcome == SimulateRequire3DS || outcome == SimulateFail3DS {
+		auth.pendingAction = true
+		auth.failAction = outcome == SimulateFail3DS
+		return &ProviderResult{
+			Status:      ProviderRequiresAction,
+			Reference:   reference,
+			Reason:      "3-D Secure authentication required",
+			RedirectURL: "https://simulator.invalid/3ds/" + reference,
+		}, nil
+	}
+	return &ProviderResult{Status: ProviderApproved, Reference: reference}, nil
+}
+
+// Confirm completes a pending 3-D Secure challenge. A failed challenge
+// declines and voids the authorization.
+func (p *SimulatedProvider) Confirm(ctx context.Context, reference string) (*ProviderResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	auth, ok := p.authorizations[reference]
+	if !ok {
+		return nil, ErrUnknownProviderReference
+	}
+	if !auth.pendingAction {
+		return nil, ErrInvalidProviderOperation
+	}
+	auth.pendingAction = false
+	if auth.failAction {
+		auth.voided = true
+		return &ProviderResult{Status: ProviderDeclined, Reference: reference, Reason: "3-D Secure authentication failed"}, nil
+	}
+	return &ProviderResult{Status: ProviderApproved, Reference: reference}, nil
+}
+
+func (p *SimulatedProvider) Capture(ctx context.Context, reference string, amount money.Money) (*ProviderResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	auth, ok := p.authorizations[reference]
+	if !ok {
+		return nil, ErrUnknownProviderReference
+	}
+	if auth.voided || auth.pendingAction || auth.captured > 0 || amount.Currency != auth.amount.Currency ||
+		!amount.IsPositive() || amount.MinorUnits > auth.amount.MinorUnits {
+		return nil, ErrInvalidProviderOperation
+	}
+	auth.captured = amount.MinorUnits
+	return &ProviderResult{Status: ProviderApproved, Reference: reference}, nil
+}
+
+func (p *SimulatedProvider) Void(ctx context.Context, reference string) (*ProviderResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	auth, ok := p.authorizations[reference]
+	if !ok {
+		return nil, ErrUnknownProviderReference
+	}
+	if auth.voided || auth.captured > 0 {
+		return nil, ErrInvalidProviderOperation
+	}
+	auth.voided = true
+	return &ProviderResult{Status: ProviderApproved, Reference: reference}, nil
+}
+
+// Refund pays out part of a captured authorization. A refund replayed with
+// the same key returns the first result.
+func (p *SimulatedProvider) Refund(ctx context.Context, reference, key string, amount money.Money) (*ProviderResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if result, ok := p.refunds[key]; ok {
+		return result, nil
+	}
+	auth, ok := p.authorizations[reference]
+	if !ok {
+		return nil, ErrUnknownProviderReference
+	}
+	if amount.Currency != auth.amount.Currency || !amount.IsPositive() || auth.refunded+amount.MinorUnits > auth.captured {
+		return nil, ErrInvalidProviderOperation
+	}
+	auth.refunded += amount.MinorUnits
+	p.sequence++
+	result := &ProviderResult{Status: ProviderApproved, Reference: fmt.Sprintf("sim_refund_%06d", p.sequence)}
+	p.refunds[key] = result
+	return result, nil
+}
+
+func (p *SimulatedProvider) GetRefund(ctx context.Context, key string) (*ProviderResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result, ok := p.refunds[key]
+	if !ok {
+		return nil, ErrUnknownProviderReference
+	}
+	return result, nil
+}
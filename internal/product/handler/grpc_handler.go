@@ -3,21 +3,29 @@ package handler
 import (
 	"context"
 	"errors"
+	"time"
 
 	pb "gomicro/api/proto"
+	"gomicro/internal/money"
+	"gomicro/internal/product/model"
+	"gomicro/internal/product/repository"
 	"gomicro/internal/product/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // ProductGRPCHandler handles gRPC requests for products
 type ProductGRPCHandler struct {
 	pb.UnimplementedProductServiceServer
-	productService service.ProductService
+	productService     service.ProductService
+	reservationService service.ReservationService
 }
 
 // NewProductGRPCHandler creates a new gRPC handler for products
-func NewProductGRPCHandler(productService service.ProductService) *ProductGRPCHandler {
+func NewProductGRPCHandler(productService service.ProductService, reservationService service.ReservationService) *ProductGRPCHandler {
 	return &ProductGRPCHandler{
-		productService: productService,
+		productService:     productService,
+		reservationService: reservationService,
 	}
 }
 
@@ -29,16 +37,17 @@ func (h *ProductGRPCHandler) GetProduct(ctx context.Context, req *pb.GetProductR
 
 	product, err := h.productService.GetProduct(ctx, uint(req.ProductId))
 	if err != nil {
-		return nil, err
+		return nil, toGRPCError(err)
+	}
+	if product == nil {
+		return nil, status.Error(codes.NotFound, "product not found")
 	}
 
-	return &pb.Product{
-		Id:          uint32(product.ID),
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       product.Price,
-		Stock:       int32(product.Stock),
-	}, nil
+	pbProduct := convertToProtoProduct(product)
+	if err := h.setReservedStock(ctx, pbProduct); err != nil {
+		return nil, toGRPCError(err)
+	}
+	return pbProduct, nil
 }
 
 // GetProducts implements the GetProducts gRPC method
@@ -50,16 +59,14 @@ func (h *ProductGRPCHandler) GetProducts(ctx context.Context, req *pb.GetProduct
 	var products []*pb.Product
 	for _, id := range req.ProductIds {
 		product, err := h.productService.GetProduct(ctx, uint(id))
-		if err != nil {
+		if err != nil || product == nil {
 			continue
 		}
-		products = append(products, &pb.Product{
-			Id:          uint32(product.ID),
-			Name:        product.Name,
-			Description: product.Description,
-			Price:       product.Price,
-			Stock:       int32(product.Stock),
-		})
+		products = append(products, convertToProtoProduct(product))
+	}
+
+	if err := h.setReservedStock(ctx, products...); err != nil {
+		return nil, toGRPCError(err)
 	}
 
 	return &pb.GetProductsResponse{
@@ -73,18 +80,17 @@ func (h *ProductGRPCHandler) CreateProduct(ctx context.Context, req *pb.CreatePr
 		return nil, errors.New("request is nil")
 	}
 
-	product, err := h.productService.CreateProduct(ctx, req.Name, req.Description, req.Price, int(req.Stock))
+	input, err := convertToProductInput(req, req.IsActive)
 	if err != nil {
-		return nil, err
+		return nil, toGRPCError(err)
 	}
 
-	return &pb.Product{
-		Id:          uint32(product.ID),
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       product.Price,
-		Stock:       int32(product.Stock),
-	}, nil
+	product, err := h.productService.CreateProduct(ctx, input)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return convertToProtoProduct(product), nil
 }
 
 // UpdateProduct implements the UpdateProduct gRPC method
@@ -93,18 +99,23 @@ func (h *ProductGRPCHandler) UpdateProduct(ctx context.Context, req *pb.UpdatePr
 		return nil, errors.New("request is nil")
 	}
 
-	product, err := h.productService.UpdateProduct(ctx, uint(req.Id), req.Name, req.Description, req.Price, int(req.Stock))
+	input, err := convertToProductInput(req, req.IsActive)
 	if err != nil {
-		return nil, err
+		return nil, toGRPCError(err)
 	}
 
-	return &pb.Product{
-		Id:          uint32(product.ID),
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       product.Price,
-		Stock:       int32(product.Stock),
-	}, nil
+	// Without a mask, only fields set to a non-default value change
+	fields := req.GetUpdateMask().GetPaths()
+	if len(fields) == 0 {
+		fields = populatedFields(req)
+	}
+
+	product, err := h.productService.UpdateProduct(ctx, uint(req.Id), req.Version, input, fields)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return convertToProtoProduct(product), nil
 }
 
 // DeleteProduct implements the DeleteProduct gRPC method
@@ -113,9 +124,9 @@ func (h *ProductGRPCHandler) DeleteProduct(ctx context.Context, req *pb.DeletePr
 		return nil, errors.New("request is nil")
 	}
 
-	err := h.productService.DeleteProduct(ctx, uint(req.Id))
+	err := h.productService.DeleteProduct(ctx, uint(req.Id), req.Version)
 	if err != nil {
-		return &pb.DeleteProductResponse{Success: false}, err
+		return &pb.DeleteProductResponse{Success: false}, toGRPCError(err)
 	}
 
 	return &pb.DeleteProductResponse{Success: true}, nil
@@ -127,23 +138,239 @@ func (h *ProductGRPCHandler) ListProducts(ctx context.Context, req *pb.ListProdu
 		return nil, errors.New("request is nil")
 	}
 
-	products, err := h.productService.ListProducts(ctx)
+	filter := repository.ProductFilter{
+		Query:           req.Query,
+		Category:        req.Category,
+		Currency:        req.Currency,
+		MinPrice:        req.MinPrice,
+		MaxPrice:        req.MaxPrice,
+		InStockOnly:     req.InStockOnly,
+		IsActive:        req.IsActive,
+		IncludeInactive: req.IncludeInactive,
+	}
+	products, nextPageToken, err := h.productService.ListProducts(ctx, filter, req.Sort, int(req.PageSize), req.PageToken)
 	if err != nil {
-		return nil, err
+		return nil, toGRPCError(err)
 	}
 
 	var pbProducts []*pb.Product
 	for _, p := range products {
-		pbProducts = append(pbProducts, &pb.Product{
-			Id:          uint32(p.ID),
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-			Stock:       int32(p.Stock),
-		})
+		pbProducts = append(pbProducts, convertToProtoProduct(p))
+	}
+
+	if err := h.setReservedStock(ctx, pbProducts...); err != nil {
+		return nil, toGRPCError(err)
 	}
 
 	return &pb.ListProductsResponse{
-		Products: pbProducts,
+		Products:      pbProducts,
+		NextPageToken: nextPageToken,
 	}, nil
+}
+
+// SearchProducts implements the SearchProducts gRPC method
+func (h *ProductGRPCHandler) SearchProducts(ctx context.Context, req *pb.SearchProductsRequest) (*pb.SearchProductsResponse, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+
+	filter := repository.ProductFilter{
+		Category:    req.Category,
+		Currency:    req.Currency,
+		MinPrice:    req.MinPrice,
+		MaxPrice:    req.MaxPrice,
+		InStockOnly: req.InStockOnly,
+	}
+	result, err := h.productService.SearchProducts(ctx, req.Query, filter, req.PriceBucketBounds, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	resp := &pb.SearchProductsResponse{
+		Hits:          make([]*pb.SearchHit, len(result.Hits)),
+		NextPageToken: result.NextPageToken,
+		Fuzzy:         result.Fuzzy,
+	}
+	pbProducts := make([]*pb.Product, len(result.Hits))
+	for i, hit := range result.Hits {
+		pbProducts[i] = convertToProtoProduct(hit.Product)
+		resp.Hits[i] = &pb.SearchHit{
+			Product:       pbProducts[i],
+			Score:         hit.Rank,
+			NameHighlight: hit.NameHighlight,
+			Snippet:       hit.Snippet,
+		}
+	}
+	if err := h.setReservedStock(ctx, pbProducts...); err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	for _, category := range result.Categories {
+		resp.Categories = append(resp.Categories, &pb.CategoryFacet{
+			Category: category.Category,
+			Count:    category.Count,
+		})
+	}
+	for _, bucket := range result.PriceBuckets {
+		resp.PriceBuckets = append(resp.PriceBuckets, &pb.PriceBucketFacet{
+			MinPrice: bucket.Min,
+			MaxPrice: bucket.Max,
+			Count:    bucket.Count,
+		})
+	}
+	return resp, nil
+}
+
+// ReserveStock implements the ReserveStock gRPC method
+func (h *ProductGRPCHandler) ReserveStock(ctx context.Context, req *pb.ReserveStockRequest) (*pb.ReserveStockResponse, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+
+	items := make([]service.ReservationItem, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = service.ReservationItem{
+			ProductID: uint(item.ProductId),
+			Quantity:  int(item.Quantity),
+		}
+	}
+
+	reservationID, expiresAt, err := h.reservationService.ReserveStock(ctx, items, time.Duration(req.TtlSeconds)*time.Second)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return &pb.ReserveStockResponse{
+		ReservationId: reservationID,
+		ExpiresAt:     expiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// ReleaseStock implements the ReleaseStock gRPC method
+func (h *ProductGRPCHandler) ReleaseStock(ctx context.Context, req *pb.ReleaseStockRequest) (*pb.ReleaseStockResponse, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+
+	if err := h.reservationService.ReleaseStock(ctx, req.ReservationId); err != nil {
+		return &pb.ReleaseStockResponse{Success: false}, toGRPCError(err)
+	}
+
+	return &pb.ReleaseStockResponse{Success: true}, nil
+}
+
+// setReservedStock fills in the units held by active reservations
+func (h *ProductGRPCHandler) setReservedStock(ctx context.Context, products ...*pb.Product) error {
+	ids := make([]uint, len(products))
+	for i, p := range products {
+		ids[i] = uint(p.Id)
+	}
+	reserved, err := h.reservationService.ReservedStock(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range products {
+		p.ReservedStock = int32(reserved[uint(p.Id)])
+	}
+	return nil
+}
+
+// productFields are the fields shared by CreateProductRequest and
+// UpdateProductRequest
+type productFields interface {
+	GetName() string
+	GetDescription() string
+	GetPrice() *pb.Money
+	GetStock() int32
+	GetCategory() string
+	GetImageUrl() string
+}
+
+// convertToProductInput converts the fields of a create or update request.
+// isActive is passed separately because an unset value must stay nil.
+func convertToProductInput(req productFields, isActive *bool) (service.ProductInput, error) {
+	input := service.ProductInput{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		Stock:       int(req.GetStock()),
+		Category:    req.GetCategory(),
+		ImageURL:    req.GetImageUrl(),
+		IsActive:    isActive,
+	}
+	// A missing price is left zero; the service rejects it if it is used
+	if req.GetPrice() != nil {
+		price, err := money.FromProto(req.GetPrice())
+		if err != nil {
+			return service.ProductInput{}, err
+		}
+		input.Price = price
+	}
+	return input, nil
+}
+
+// populatedFields names the fields of an update request that are set to a
+// non-default value
+func populatedFields(req *pb.UpdateProductRequest) []string {
+	fields := make([]string, 0)
+	if req.Name != "" {
+		fields = append(fields, service.FieldName)
+	}
+	if req.Description != "" {
+		fields = append(fields, service.FieldDescription)
+	}
+	if req.Price != nil {
+		fields = append(fields, service.FieldPrice)
+	}
+	if req.Stock != 0 {
+		fields = append(fields, service.FieldStock)
+	}
+	if req.Category != "" {
+		fields = append(fields, service.FieldCategory)
+	}
+	if req.ImageUrl != "" {
+		fields = append(fields, service.FieldImageURL)
+	}
+	if req.IsActive != nil {
+		fields = append(fields, service.FieldIsActive)
+	}
+	return fields
+}
+
+// convertToProtoProduct converts a product; reserved stock is filled in by
+// setReservedStock
+func convertToProtoProduct(product *model.Product) *pb.Product {
+	return &pb.Product{
+		Id:          uint32(product.ID),
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price.ToProto(),
+		Stock:       int32(product.Stock),
+		IsActive:    product.IsActive,
+		Category:    product.Category,
+		ImageUrl:    product.ImageURL,
+		CreatedAt:   product.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   product.UpdatedAt.Format(time.RFC3339),
+		Version:     product.Version,
+	}
+}
+
+// toGRPCError maps a service or repository error to a gRPC status error.
+// Every RPC returns its errors through it.
+func toGRPCError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound), errors.Is(err, repository.ErrReservationNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrInsufficientStock):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, repository.ErrVersionConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, service.ErrInvalidReservation), errors.Is(err, service.ErrInvalidPageToken),
+		errors.Is(err, service.ErrInvalidSort), errors.Is(err, service.ErrInvalidSearchQuery),
+		errors.Is(err, service.ErrInvalidPriceBuckets), errors.Is(err, service.ErrInvalidPriceFilter),
+		errors.Is(err, service.ErrInvalidFieldMask), errors.Is(err, service.ErrInvalidProduct),
+		errors.Is(err, service.ErrVersionRequired), errors.Is(err, money.ErrUnknownCurrency):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return err
+	}
 } 
\ No newline at end of file
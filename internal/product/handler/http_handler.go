@@ -1,10 +1,18 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gomicro/internal/money"
+	"gomicro/internal/product/model"
+	"gomicro/internal/product/repository"
 	"gomicro/internal/product/service"
 )
 
@@ -20,6 +28,13 @@ func NewProductHTTPHandler(service service.ProductService) *ProductHTTPHandler {
 	}
 }
 
+// NewRouter returns the engine serving the product REST API
+func NewRouter(service service.ProductService) *gin.Engine {
+	router := gin.Default()
+	NewProductHTTPHandler(service).RegisterRoutes(router)
+	return router
+}
+
 // RegisterRoutes registers the HTTP routes for products
 func (h *ProductHTTPHandler) RegisterRoutes(router *gin.Engine) {
 	products := router.Group("/products")
@@ -27,6 +42,7 @@ func (h *ProductHTTPHandler) RegisterRoutes(router *gin.Engine) {
 		products.GET("/:id", h.GetProduct)
 		products.POST("/", h.CreateProduct)
 		products.PUT("/:id", h.UpdateProduct)
+		products.PATCH("/:id", h.PatchProduct)
 		products.DELETE("/:id", h.DeleteProduct)
 		products.GET("/", h.ListProducts)
 	}
@@ -42,20 +58,28 @@ func (h *ProductHTTPHandler) GetProduct(c *gin.Context) {
 
 	product, err := h.service.GetProduct(c.Request.Context(), uint(id))
 	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if product == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
 
+	setETag(c, product)
 	c.JSON(http.StatusOK, product)
 }
 
 // CreateProduct handles POST /products
 func (h *ProductHTTPHandler) CreateProduct(c *gin.Context) {
 	var product struct {
-		Name        string  `json:"name" binding:"required"`
-		Description string  `json:"description"`
-		Price       float64 `json:"price" binding:"required"`
-		Stock       int     `json:"stock" binding:"required"`
+		Name        string      `json:"name" binding:"required"`
+		Description string      `json:"description"`
+		Price       money.Money `json:"price"`
+		Stock       int         `json:"stock" binding:"required"`
+		Category    string      `json:"category"`
+		ImageURL    string      `json:"image_url"`
+		IsActive    *bool       `json:"is_active"`
 	}
 
 	if err := c.ShouldBindJSON(&product); err != nil {
@@ -63,67 +87,301 @@ func (h *ProductHTTPHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
-	createdProduct, err := h.service.CreateProduct(c.Request.Context(), product.Name, product.Description, product.Price, product.Stock)
+	createdProduct, err := h.service.CreateProduct(c.Request.Context(), service.ProductInput{
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Stock:       product.Stock,
+		Category:    product.Category,
+		ImageURL:    product.ImageURL,
+		IsActive:    product.IsActive,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
+	setETag(c, createdProduct)
 	c.JSON(http.StatusCreated, createdProduct)
 }
 
-// UpdateProduct handles PUT /products/:id
+// productBody is the JSON form of the fields a client may change
+type productBody struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`
+	Stock       int         `json:"stock"`
+	Category    string      `json:"category"`
+	ImageURL    string      `json:"image_url"`
+	IsActive    *bool       `json:"is_active"`
+}
+
+func (b productBody) input() service.ProductInput {
+	return service.ProductInput{
+		Name:        b.Name,
+		Description: b.Description,
+		Price:       b.Price,
+		Stock:       b.Stock,
+		Category:    b.Category,
+		ImageURL:    b.ImageURL,
+		IsActive:    b.IsActive,
+	}
+}
+
+// UpdateProduct handles PUT /products/:id, which replaces every field. The
+// If-Match header must hold the product's current ETag.
 func (h *ProductHTTPHandler) UpdateProduct(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
 		return
 	}
-
-	var product struct {
-		Name        string  `json:"name"`
-		Description string  `json:"description"`
-		Price       float64 `json:"price"`
-		Stock       int     `json:"stock"`
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
 	}
 
+	var product productBody
 	if err := c.ShouldBindJSON(&product); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	updatedProduct, err := h.service.UpdateProduct(c.Request.Context(), uint(id), product.Name, product.Description, product.Price, product.Stock)
+	updatedProduct, err := h.service.UpdateProduct(c.Request.Context(), uint(id), version, product.input(), nil)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	setETag(c, updatedProduct)
+	c.JSON(http.StatusOK, updatedProduct)
+}
+
+// PatchProduct handles PATCH /products/:id with a JSON merge patch (RFC
+// 7396): only the fields in the body change, and null resets a field to its
+// zero value. The If-Match header must hold the product's current ETag.
+func (h *ProductHTTPHandler) PatchProduct(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var patch map[string]interface{}
+	if err := decodeJSON(data, &patch); err != nil || patch == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body must be a JSON object"})
+		return
+	}
+
+	current, err := h.service.GetProduct(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if current == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if current.Version != version {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": repository.ErrVersionConflict.Error()})
+		return
+	}
 
+	// Merge the patch into the current fields so that nested objects such as
+	// the price are patched member by member
+	product, err := mergeProductPatch(productBody{
+		Name:        current.Name,
+		Description: current.Description,
+		Price:       current.Price,
+		Stock:       current.Stock,
+		Category:    current.Category,
+		ImageURL:    current.ImageURL,
+		IsActive:    &current.IsActive,
+	}, patch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	updatedProduct, err := h.service.UpdateProduct(c.Request.Context(), uint(id), version, product.input(), fields)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	setETag(c, updatedProduct)
 	c.JSON(http.StatusOK, updatedProduct)
 }
 
-// DeleteProduct handles DELETE /products/:id
+// mergeProductPatch applies a JSON merge patch to the fields of a product
+func mergeProductPatch(product productBody, patch map[string]interface{}) (productBody, error) {
+	data, err := json.Marshal(product)
+	if err != nil {
+		return productBody{}, err
+	}
+	var document interface{}
+	if err := decodeJSON(data, &document); err != nil {
+		return productBody{}, err
+	}
+	if data, err = json.Marshal(mergePatch(document, patch)); err != nil {
+		return productBody{}, err
+	}
+	var merged productBody
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return productBody{}, err
+	}
+	return merged, nil
+}
+
+// decodeJSON decodes numbers as json.Number so large integers survive a
+// round trip through interface{} values
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// mergePatch applies an RFC 7396 merge patch to a decoded JSON document
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// setETag sets the ETag header to the product's version
+func setETag(c *gin.Context, product *model.Product) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(product.Version, 10)))
+}
+
+// ifMatchVersion returns the product version named by the If-Match header.
+// A missing header or "*" does not name a version; any other tag that is not
+// one of ours can never match.
+func ifMatchVersion(c *gin.Context) (int64, error) {
+	tag := strings.TrimSpace(c.GetHeader("If-Match"))
+	if tag == "" || tag == "*" {
+		return 0, service.ErrVersionRequired
+	}
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return 0, repository.ErrVersionConflict
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, repository.ErrVersionConflict
+	}
+	return version, nil
+}
+
+// productErrorStatus maps a product service error to an HTTP status
+func productErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrVersionRequired):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, money.ErrUnknownCurrency), errors.Is(err, service.ErrInvalidFieldMask),
+		errors.Is(err, service.ErrInvalidProduct):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// DeleteProduct handles DELETE /products/:id. The If-Match header must hold
+// the product's current ETag.
 func (h *ProductHTTPHandler) DeleteProduct(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
 		return
 	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := h.service.DeleteProduct(c.Request.Context(), uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteProduct(c.Request.Context(), uint(id), version); err != nil {
+		c.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
-// ListProducts handles GET /products
+// ListProducts handles GET /products. Query parameters mirror the
+// ListProducts gRPC request: q, category, currency, min_price, max_price,
+// in_stock_only, is_active, include_inactive, sort, page_size and page_token.
 func (h *ProductHTTPHandler) ListProducts(c *gin.Context) {
-	products, err := h.service.ListProducts(c.Request.Context())
+	var query struct {
+		Query           string `form:"q"`
+		Category        string `form:"category"`
+		Currency        string `form:"currency"`
+		MinPrice        int64  `form:"min_price"`
+		MaxPrice        int64  `form:"max_price"`
+		InStockOnly     bool   `form:"in_stock_only"`
+		IsActive        *bool  `form:"is_active"`
+		IncludeInactive bool   `form:"include_inactive"`
+		Sort            string `form:"sort"`
+		PageSize        int    `form:"page_size"`
+		PageToken       string `form:"page_token"`
+	}
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := repository.ProductFilter{
+		Query:           query.Query,
+		Category:        query.Category,
+		Currency:        query.Currency,
+		MinPrice:        query.MinPrice,
+		MaxPrice:        query.MaxPrice,
+		InStockOnly:     query.InStockOnly,
+		IsActive:        query.IsActive,
+		IncludeInactive: query.IncludeInactive,
+	}
+	products, nextPageToken, err := h.service.ListProducts(c.Request.Context(), filter, query.Sort, query.PageSize, query.PageToken)
+	if errors.Is(err, service.ErrInvalidPageToken) || errors.Is(err, service.ErrInvalidSort) || errors.Is(err, service.ErrInvalidPriceFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, products)
+	c.JSON(http.StatusOK, gin.H{"products": products, "next_page_token": nextPageToken})
 } 
\ No newline at end of file
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// StockUpdateEvent is published by payment-service on the "stock-updates"
+// exchange. Quantity is a delta: negative values decrease stock, positive
+// values restore it.
+type StockUpdateEvent struct {
+	// EventID identifies the event across redeliveries and republishing;
+	// events without one are applied every time they arrive
+	EventID   uint `json:"event_id,omitempty"`
+	ProductID uint `json:"product_id"`
+	Quantity  int  `json:"quantity"`
+	// ReservationID is the checkout reservation that held the stock being
+	// sold. Its hold on the product is released together with the decrement.
+	ReservationID string `json:"reservation_id,omitempty"`
+}
+
+// ProcessedStockUpdate records a StockUpdateEvent that has been applied. It
+// is written in the same transaction as the stock change, so an event
+// published twice changes the stock once.
+type ProcessedStockUpdate struct {
+	EventID   uint      `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `gorm:"not null"`
+}
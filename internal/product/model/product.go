@@ -3,19 +3,29 @@ package model
 import (
 	"time"
 
+	"gomicro/internal/money"
 	"gorm.io/gorm"
 )
 
+// The created_at and name indexes end with the ID so ListProducts pages in
+// those orders are read straight from the index. SearchVector and the name
+// trigram index back SearchProducts; the trigram index needs the pg_trgm
+// extension.
 type Product struct {
-	ID          uint           `gorm:"primarykey" json:"id"`
-	CreatedAt   time.Time      `json:"created_at"`
+	ID          uint           `gorm:"primarykey;index:idx_products_created_id,priority:2;index:idx_products_name_id,priority:2" json:"id"`
+	CreatedAt   time.Time      `gorm:"index:idx_products_created_id,priority:1" json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
-	Name        string         `gorm:"not null" json:"name"`
+	Name        string         `gorm:"not null;index:idx_products_name_id,priority:1;index:idx_products_name_trgm,type:gin,expression:name gin_trgm_ops" json:"name"`
 	Description string         `json:"description"`
-	Price       float64        `gorm:"not null" json:"price"`
+	Price       money.Money    `gorm:"embedded;embeddedPrefix:price_" json:"price"`
 	Stock       int           `gorm:"not null" json:"stock"`
-	Category    string         `gorm:"not null" json:"category"`
+	Category    string         `gorm:"not null;index:idx_products_category_active,priority:1" json:"category"`
 	ImageURL    string         `json:"image_url"`
-	IsActive    bool          `gorm:"default:true" json:"is_active"`
+	IsActive    bool          `gorm:"default:true;index:idx_products_category_active,priority:2" json:"is_active"`
+	// Version is incremented by every update so concurrent edits are detected
+	Version int64 `gorm:"not null;default:1" json:"version"`
+	// SearchVector is maintained by PostgreSQL from the name (weight A),
+	// category (B) and description (C); it is never read or written
+	SearchVector string `gorm:"type:tsvector GENERATED ALWAYS AS (setweight(to_tsvector('simple', coalesce(name, '')), 'A') || setweight(to_tsvector('simple', coalesce(category, '')), 'B') || setweight(to_tsvector('simple', coalesce(description, '')), 'C')) STORED;index:idx_products_search,type:gin;->:false;<-:false" json:"-"`
 } 
\ No newline at end of file
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// StockReservation holds units of a product for a checkout until ExpiresAt.
+// A reservation of several products is stored as one row per product sharing
+// the same ReservationID. Expired rows no longer count against stock.
+type StockReservation struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	ReservationID string    `gorm:"index;not null" json:"reservation_id"`
+	ProductID     uint      `gorm:"not null;index:idx_reservations_product_expires,priority:1" json:"product_id"`
+	Quantity      int       `gorm:"not null" json:"quantity"`
+	ExpiresAt     time.Time `gorm:"not null;index:idx_reservations_product_expires,priority:2" json:"expires_at"`
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"gomicro/internal/money"
+	"gorm.io/gorm"
+)
+
+// MigrateLegacyPrices converts the float price column of products created
+// before prices were stored in minor units. Those products are priced in
+// currency. Run it before AutoMigrate.
+func MigrateLegacyPrices(db *gorm.DB, currency string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return money.MigrateLegacyColumn(tx, money.LegacyColumn{
+			Table: "products", Column: "price", Prefix: "price_", Currency: currency,
+		})
+	})
+}
@@ -2,18 +2,109 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gomicro/internal/product/model"
 )
 
+var (
+	// ErrProductNotFound is returned when the requested product does not exist
+	ErrProductNotFound = errors.New("product not found")
+	// ErrInsufficientStock is returned when a stock update would make stock negative
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrVersionConflict is returned when a product has changed since the
+	// version the caller read
+	ErrVersionConflict = errors.New("product version conflict")
+	// ErrStockUpdateApplied is returned when a stock update event was
+	// already applied
+	ErrStockUpdateApplied = errors.New("stock update already applied")
+)
+
+// Product sort orders accepted by List. Every order ends with the ID so
+// products that tie on the sort key still page in a stable order.
+const (
+	SortNewest    = "newest"
+	SortName      = "name"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+)
+
+// productSorts maps each sort order to its keyset column and direction
+var productSorts = map[string]struct {
+	column string
+	desc   bool
+}{
+	SortNewest:    {"created_at", true},
+	SortName:      {"name", false},
+	SortPriceAsc:  {"price_minor_units", false},
+	SortPriceDesc: {"price_minor_units", true},
+}
+
+// IsProductSort reports whether List accepts the sort order
+func IsProductSort(sort string) bool {
+	_, ok := productSorts[sort]
+	return ok
+}
+
+// ProductFilter narrows ListProducts results. Zero values are ignored.
+type ProductFilter struct {
+	// Query matches a case-insensitive substring of the name or description
+	Query    string
+	Category string
+	Currency string
+	// Price bounds are in minor units and only meaningful with Currency
+	MinPrice int64
+	MaxPrice int64
+	// InStockOnly keeps products with stock not held by active reservations
+	InStockOnly bool
+	// IsActive keeps only active or only inactive products when set
+	IsActive *bool
+	// IncludeInactive makes ListProducts list inactive products too when
+	// IsActive is not set; otherwise it only lists active products. List
+	// itself ignores it.
+	IncludeInactive bool
+}
+
+// ProductCursor is the position of the last product on a page. Only the
+// field of the page's sort order and the ID are compared.
+type ProductCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Price     int64     `json:"price"`
+	ID        uint      `json:"id"`
+}
+
+// NewProductCursor returns the position of a product in any sort order
+func NewProductCursor(product *model.Product) *ProductCursor {
+	return &ProductCursor{
+		CreatedAt: product.CreatedAt,
+		Name:      product.Name,
+		Price:     product.Price.MinorUnits,
+		ID:        product.ID,
+	}
+}
+
 // ProductRepository defines the interface for product data operations
 type ProductRepository interface {
 	Create(ctx context.Context, product *model.Product) (*model.Product, error)
 	GetByID(ctx context.Context, id uint) (*model.Product, error)
 	Update(ctx context.Context, product *model.Product) (*model.Product, error)
-	Delete(ctx context.Context, id uint) error
-	List(ctx context.Context) ([]*model.Product, error)
+	Delete(ctx context.Context, id uint, version int64) error
+	List(ctx context.Context, filter ProductFilter, sort string, after *ProductCursor, limit int) ([]*model.Product, error)
+	UpdateStock(ctx context.Context, id uint, quantity int) error
+	// ApplyStockUpdate applies the event's delta like UpdateStock and
+	// releases the product's hold of the event's reservation, if any, in the
+	// same transaction. An event whose ID was applied before fails with
+	// ErrStockUpdateApplied and changes nothing.
+	ApplyStockUpdate(ctx context.Context, event *model.StockUpdateEvent) error
+	Search(ctx context.Context, q SearchQuery, after *SearchCursor, limit int) ([]*SearchHit, error)
+	SearchCategoryCounts(ctx context.Context, q SearchQuery) ([]CategoryCount, error)
+	SearchPriceCounts(ctx context.Context, q SearchQuery, bounds []int64) ([]int64, error)
 }
 
 // productRepository implements the ProductRepository interface
@@ -28,9 +119,22 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 	}
 }
 
-// Create creates a new product
+// Create creates a new product at version 1. GORM inserts the column
+// default (active) in place of a false IsActive, so inactive products are
+// deactivated in the same transaction.
 func (r *productRepository) Create(ctx context.Context, product *model.Product) (*model.Product, error) {
-	if err := r.db.WithContext(ctx).Create(product).Error; err != nil {
+	active := product.IsActive
+	product.Version = 1
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		if active {
+			return nil
+		}
+		return tx.Model(product).Update("is_active", false).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return product, nil
@@ -48,24 +152,171 @@ func (r *productRepository) GetByID(ctx context.Context, id uint) (*model.Produc
 	return &product, nil
 }
 
-// Update updates an existing product
+// Update writes every field of an existing product if it is still at
+// product.Version, and increments the version. It fails with
+// ErrVersionConflict if the product has changed since.
 func (r *productRepository) Update(ctx context.Context, product *model.Product) (*model.Product, error) {
-	if err := r.db.WithContext(ctx).Save(product).Error; err != nil {
-		return nil, err
+	version := product.Version
+	updated := *product
+	updated.Version++
+
+	result := r.db.WithContext(ctx).Model(&updated).
+		Where("version = ?", version).
+		Select("*").Omit("id", "created_at", "deleted_at").
+		Updates(&updated)
+	if result.Error != nil {
+		return nil, result.Error
 	}
-	return product, nil
+	if result.RowsAffected == 0 {
+		return nil, r.versionMismatch(ctx, product.ID)
+	}
+	return &updated, nil
 }
 
-// Delete deletes a product by ID
-func (r *productRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&model.Product{}, id).Error
+// Delete deletes a product by ID if it is still at the given version
+func (r *productRepository) Delete(ctx context.Context, id uint, version int64) error {
+	result := r.db.WithContext(ctx).Where("version = ?", version).Delete(&model.Product{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.versionMismatch(ctx, id)
+	}
+	return nil
 }
 
-// List retrieves all products
-func (r *productRepository) List(ctx context.Context) ([]*model.Product, error) {
+// versionMismatch explains why a versioned write matched no row: the product
+// is gone, or it is at another version
+func (r *productRepository) versionMismatch(ctx context.Context, id uint) error {
+	product, err := r.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return ErrProductNotFound
+	}
+	return ErrVersionConflict
+}
+
+// List returns up to limit products matching the filter that come after the
+// cursor in the given sort order, using keyset pagination over the sort
+// column and id
+func (r *productRepository) List(ctx context.Context, filter ProductFilter, sort string, after *ProductCursor, limit int) ([]*model.Product, error) {
+	order, ok := productSorts[sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown product sort %q", sort)
+	}
+
+	query := r.filter(r.db.WithContext(ctx).Model(&model.Product{}), filter)
+
+	direction, compare := "ASC", ">"
+	if order.desc {
+		direction, compare = "DESC", "<"
+	}
+	if after != nil {
+		var value interface{}
+		switch order.column {
+		case "created_at":
+			value = after.CreatedAt
+		case "name":
+			value = after.Name
+		default:
+			value = after.Price
+		}
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", order.column, compare), value, after.ID)
+	}
+
 	var products []*model.Product
-	if err := r.db.WithContext(ctx).Find(&products).Error; err != nil {
+	orderBy := fmt.Sprintf("%s %s, id %s", order.column, direction, direction)
+	if err := query.Order(orderBy).Limit(limit).Find(&products).Error; err != nil {
 		return nil, err
 	}
 	return products, nil
-} 
\ No newline at end of file
+}
+
+// filter narrows a products query to those matching the filter
+func (r *productRepository) filter(query *gorm.DB, filter ProductFilter) *gorm.DB {
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Query) + "%"
+		query = query.Where("(name ILIKE ? OR description ILIKE ?)", pattern, pattern)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Currency != "" {
+		query = query.Where("price_currency = ?", filter.Currency)
+	}
+	if filter.MinPrice > 0 {
+		query = query.Where("price_minor_units >= ?", filter.MinPrice)
+	}
+	if filter.MaxPrice > 0 {
+		query = query.Where("price_minor_units <= ?", filter.MaxPrice)
+	}
+	if filter.InStockOnly {
+		reserved := r.db.Model(&model.StockReservation{}).
+			Select("COALESCE(SUM(quantity), 0)").
+			Where("product_id = products.id AND expires_at > ?", time.Now())
+		query = query.Where("stock > (?)", reserved)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	return query
+}
+
+// likeEscaper escapes LIKE wildcards so a query only matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// UpdateStock atomically applies a quantity delta to a product's stock.
+// The row is locked for the duration of the transaction so concurrent
+// updates cannot drive the stock below zero. The version is incremented
+// too, so an edit based on the old stock fails instead of overwriting it.
+func (r *productRepository) UpdateStock(ctx context.Context, id uint, quantity int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return updateStock(tx, id, quantity)
+	})
+}
+
+func (r *productRepository) ApplyStockUpdate(ctx context.Context, event *model.StockUpdateEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if event.EventID != 0 {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&model.ProcessedStockUpdate{EventID: event.EventID})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrStockUpdateApplied
+			}
+		}
+		if err := updateStock(tx, event.ProductID, event.Quantity); err != nil {
+			return err
+		}
+		if event.ReservationID == "" {
+			return nil
+		}
+		// The hold may have expired already, so releasing nothing is fine
+		return tx.Where("reservation_id = ? AND product_id = ?", event.ReservationID, event.ProductID).
+			Delete(&model.StockReservation{}).Error
+	})
+}
+
+// updateStock locks the product and applies the delta inside tx
+func updateStock(tx *gorm.DB, id uint, quantity int) error {
+	var product model.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProductNotFound
+		}
+		return err
+	}
+
+	if product.Stock+quantity < 0 {
+		return ErrInsufficientStock
+	}
+
+	return tx.Model(&product).Updates(map[string]interface{}{
+		"stock":   gorm.Expr("stock + ?", quantity),
+		"version": gorm.Expr("version + 1"),
+	}).Error
+}
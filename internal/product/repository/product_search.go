@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gomicro/internal/product/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Search highlights wrap matched words in these markers. Product text is not
+// escaped, so clients rendering HTML must escape it around the markers.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// ts_headline options for the name (every match highlighted) and the
+// description snippet (up to two short fragments)
+var (
+	nameHeadlineOptions    = fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", HighlightStart, HighlightStop)
+	snippetHeadlineOptions = fmt.Sprintf(`StartSel=%s, StopSel=%s, MinWords=8, MaxWords=24, MaxFragments=2, FragmentDelimiter=" ... "`, HighlightStart, HighlightStop)
+)
+
+// SearchQuery is a parsed SearchProducts request
+type SearchQuery struct {
+	// Terms are lower-cased words of letters and digits. In full-text mode a
+	// product matches when every term is a prefix of one of its words.
+	Terms []string
+	// Fuzzy matches the terms against product names by trigram word
+	// similarity instead, which tolerates typos
+	Fuzzy  bool
+	Filter ProductFilter
+}
+
+// SearchHit is a product matched by Search
+type SearchHit struct {
+	Product *model.Product
+	// Rank orders hits, best first. Full-text ranks weight name matches over
+	// category matches over description matches; fuzzy ranks are the
+	// similarity of the terms to the name.
+	Rank float64
+	// NameHighlight is the name with matched words highlighted
+	NameHighlight string
+	// Snippet is the part of the description around the matched words
+	Snippet string
+}
+
+// SearchCursor is the position of the last hit on a page. Hits are ordered
+// by (rank, id) descending.
+type SearchCursor struct {
+	Rank float64 `json:"rank"`
+	ID   uint    `json:"id"`
+}
+
+// CategoryCount is the number of hits in a category
+type CategoryCount struct {
+	Category string
+	Count    int64
+}
+
+// searchRow is a hit as scanned from the database
+type searchRow struct {
+	model.Product
+	Rank          float64
+	NameHighlight string
+	Snippet       string
+}
+
+// tsQuery returns the prefix tsquery text for the terms. Terms only hold
+// letters and digits, so they cannot contain tsquery operators.
+func (q SearchQuery) tsQuery() string {
+	prefixes := make([]string, len(q.Terms))
+	for i, term := range q.Terms {
+		prefixes[i] = term + ":*"
+	}
+	return strings.Join(prefixes, " & ")
+}
+
+// matching returns a products query narrowed to the hits of q and the SQL
+// expression of their rank
+func (r *productRepository) matching(ctx context.Context, q SearchQuery) (*gorm.DB, clause.Expr) {
+	query := r.filter(r.db.WithContext(ctx).Model(&model.Product{}), q.Filter)
+	if q.Fuzzy {
+		text := strings.Join(q.Terms, " ")
+		return query.Where("? <% name", text), gorm.Expr("word_similarity(?, name)", text)
+	}
+	tsQuery := q.tsQuery()
+	return query.Where("search_vector @@ to_tsquery('simple', ?)", tsQuery),
+		gorm.Expr("ts_rank(search_vector, to_tsquery('simple', ?))", tsQuery)
+}
+
+// Search returns up to limit hits that come after the cursor, best first.
+// Headlines are only computed for the returned page.
+func (r *productRepository) Search(ctx context.Context, q SearchQuery, after *SearchCursor, limit int) ([]*SearchHit, error) {
+	query, rank := r.matching(ctx, q)
+	hits := query.Select("products.*, ? AS rank", rank)
+
+	page := r.db.Table("(?) AS hits", hits)
+	if after != nil {
+		page = page.Where("(rank, id) < (?, ?)", after.Rank, after.ID)
+	}
+	page = page.Order("rank DESC, id DESC").Limit(limit)
+
+	tsQuery := q.tsQuery()
+	var rows []*searchRow
+	err := r.db.WithContext(ctx).Unscoped().
+		Table("(?) AS page", page.Unscoped()).
+		Select("page.*, ts_headline('simple', name, to_tsquery('simple', ?), ?) AS name_highlight, "+
+			"ts_headline('simple', description, to_tsquery('simple', ?), ?) AS snippet",
+			tsQuery, nameHeadlineOptions, tsQuery, snippetHeadlineOptions).
+		Order("rank DESC, id DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*SearchHit, len(rows))
+	for i, row := range rows {
+		product := row.Product
+		results[i] = &SearchHit{
+			Product:       &product,
+			Rank:          row.Rank,
+			NameHighlight: row.NameHighlight,
+			Snippet:       row.Snippet,
+		}
+	}
+	return results, nil
+}
+
+// SearchCategoryCounts counts the hits of q per category, largest first
+func (r *productRepository) SearchCategoryCounts(ctx context.Context, q SearchQuery) ([]CategoryCount, error) {
+	query, _ := r.matching(ctx, q)
+	var counts []CategoryCount
+	err := query.Select("category, COUNT(*) AS count").
+		Group("category").
+		Order("count DESC, category").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// SearchPriceCounts counts the hits of q in the price buckets delimited by
+// the ascending bounds: below bounds[0], between each pair of bounds and from
+// the last bound up. A bucket includes its lower bound.
+func (r *productRepository) SearchPriceCounts(ctx context.Context, q SearchQuery, bounds []int64) ([]int64, error) {
+	// The bounds are integers, so they are safe to inline into the array
+	literals := make([]string, len(bounds))
+	for i, bound := range bounds {
+		literals[i] = strconv.FormatInt(bound, 10)
+	}
+	bucket := fmt.Sprintf("width_bucket(price_minor_units, ARRAY[%s]::bigint[])", strings.Join(literals, ","))
+
+	query, _ := r.matching(ctx, q)
+	var rows []struct {
+		Bucket int
+		Count  int64
+	}
+	if err := query.Select(bucket + " AS bucket, COUNT(*) AS count").Group("bucket").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make([]int64, len(bounds)+1)
+	for _, row := range rows {
+		counts[row.Bucket] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gomicro/internal/product/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrReservationNotFound is returned when releasing an unknown or expired reservation
+var ErrReservationNotFound = errors.New("reservation not found")
+
+// ReservationRepository stores stock reservations
+type ReservationRepository interface {
+	// Reserve stores all reservations or none. It fails with
+	// ErrInsufficientStock when a product's stock minus its active
+	// reservations cannot cover the requested quantity.
+	Reserve(ctx context.Context, reservations []*model.StockReservation) error
+	Release(ctx context.Context, reservationID string) error
+	// ReservedQuantities sums the active reservations per product
+	ReservedQuantities(ctx context.Context, productIDs []uint) (map[uint]int, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type reservationRepository struct {
+	db *gorm.DB
+}
+
+// NewReservationRepository creates a new reservation repository
+func NewReservationRepository(db *gorm.DB) ReservationRepository {
+	return &reservationRepository{db: db}
+}
+
+func (r *reservationRepository) Reserve(ctx context.Context, reservations []*model.StockReservation) error {
+	// Lock products in a fixed order so concurrent reservations cannot deadlock
+	sorted := make([]*model.StockReservation, len(reservations))
+	copy(sorted, reservations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ProductID < sorted[j].ProductID })
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, reservation := range sorted {
+			var product model.Product
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, reservation.ProductID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrProductNotFound
+				}
+				return err
+			}
+
+			var reserved int
+			if err := tx.Model(&model.StockReservation{}).
+				Where("product_id = ? AND expires_at > ?", reservation.ProductID, now).
+				Select("COALESCE(SUM(quantity), 0)").
+				Scan(&reserved).Error; err != nil {
+				return err
+			}
+			if product.Stock-reserved < reservation.Quantity {
+				return ErrInsufficientStock
+			}
+		}
+		return tx.Create(&reservations).Error
+	})
+}
+
+func (r *reservationRepository) Release(ctx context.Context, reservationID string) error {
+	result := r.db.WithContext(ctx).
+		Where("reservation_id = ? AND expires_at > ?", reservationID, time.Now()).
+		Delete(&model.StockReservation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReservationNotFound
+	}
+	return nil
+}
+
+func (r *reservationRepository) ReservedQuantities(ctx context.Context, productIDs []uint) (map[uint]int, error) {
+	var rows []struct {
+		ProductID uint
+		Quantity  int
+	}
+	if err := r.db.WithContext(ctx).Model(&model.StockReservation{}).
+		Select("product_id, SUM(quantity) AS quantity").
+		Where("product_id IN ? AND expires_at > ?", productIDs, time.Now()).
+		Group("product_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	reserved := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		reserved[row.ProductID] = row.Quantity
+	}
+	return reserved, nil
+}
+
+func (r *reservationRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&model.StockReservation{})
+	return result.RowsAffected, result.Error
+}
@@ -3,18 +3,106 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
 
+	"gomicro/internal/money"
+	"gomicro/internal/pagination"
 	"gomicro/internal/product/model"
 	"gomicro/internal/product/repository"
 )
 
+var (
+	// ErrInvalidPageToken is returned when a ListProducts or SearchProducts
+	// page token cannot be decoded, or belongs to a different sort order
+	ErrInvalidPageToken = pagination.ErrInvalidToken
+	// ErrInvalidSort is returned for an unknown ListProducts sort order
+	ErrInvalidSort = errors.New("invalid sort order")
+	// ErrInvalidSearchQuery is returned when a search query has no words
+	ErrInvalidSearchQuery = errors.New("search query has no words")
+	// ErrInvalidPriceBuckets is returned when price facet bounds are not
+	// positive and ascending
+	ErrInvalidPriceBuckets = errors.New("price bucket bounds must be positive and ascending")
+	// ErrInvalidPriceFilter is returned when price bounds, price buckets or a
+	// price sort are used without a currency, since prices in different
+	// currencies cannot be compared
+	ErrInvalidPriceFilter = errors.New("price filters and sorts require a currency")
+	// ErrInvalidFieldMask is returned when an update names a field that does
+	// not exist or cannot be changed
+	ErrInvalidFieldMask = errors.New("invalid product update field")
+	// ErrVersionRequired is returned when an update or delete does not say
+	// which version of the product it expects
+	ErrVersionRequired = errors.New("product version is required")
+	// ErrInvalidProduct is returned when a created or updated product fails
+	// validation; the wrapping error says which field is wrong
+	ErrInvalidProduct = errors.New("invalid product")
+)
+
+// Product fields UpdateProduct can change, named as in the API
+const (
+	FieldName        = "name"
+	FieldDescription = "description"
+	FieldPrice       = "price"
+	FieldStock       = "stock"
+	FieldCategory    = "category"
+	FieldImageURL    = "image_url"
+	FieldIsActive    = "is_active"
+)
+
+// defaultPriceBuckets are the SearchProducts price facet bounds in major
+// units of the filtered currency
+var defaultPriceBuckets = []int64{100, 250, 500, 1000, 2500}
+
+// ProductInput holds the fields callers set when creating or updating a
+// product
+type ProductInput struct {
+	Name        string
+	Description string
+	Price       money.Money
+	Stock       int
+	Category    string
+	// ImageURL must be an absolute http or https URL when set
+	ImageURL string
+	// IsActive hides the product from customers when false; nil keeps the
+	// default (active on create, unchanged on a full update)
+	IsActive *bool
+}
+
+// SearchResult is one page of SearchProducts hits and the facet counts over
+// all hits
+type SearchResult struct {
+	Hits          []*repository.SearchHit
+	NextPageToken string
+	// Fuzzy is set when nothing matched the terms exactly and the hits are
+	// typo-tolerant matches on the product name
+	Fuzzy bool
+	// Categories counts the hits per category as if no category was filtered,
+	// so customers can see where else their query matches
+	Categories []repository.CategoryCount
+	// PriceBuckets counts the hits per price range as if no price range was
+	// filtered. Prices in different currencies cannot be bucketed together,
+	// so they are only counted when the search filters by currency.
+	PriceBuckets []PriceBucket
+}
+
+// PriceBucket is the number of hits priced from Min up to, but excluding, Max
+// minor units. Max is 0 for the most expensive bucket.
+type PriceBucket struct {
+	Min   int64
+	Max   int64
+	Count int64
+}
+
 // ProductService defines the interface for product operations
 type ProductService interface {
 	GetProduct(ctx context.Context, id uint) (*model.Product, error)
-	CreateProduct(ctx context.Context, name, description string, price float64, stock int) (*model.Product, error)
-	UpdateProduct(ctx context.Context, id uint, name, description string, price float64, stock int) (*model.Product, error)
-	DeleteProduct(ctx context.Context, id uint) error
-	ListProducts(ctx context.Context) ([]*model.Product, error)
+	CreateProduct(ctx context.Context, input ProductInput) (*model.Product, error)
+	UpdateProduct(ctx context.Context, id uint, version int64, input ProductInput, fields []string) (*model.Product, error)
+	DeleteProduct(ctx context.Context, id uint, version int64) error
+	ListProducts(ctx context.Context, filter repository.ProductFilter, sort string, pageSize int, pageToken string) ([]*model.Product, string, error)
+	SearchProducts(ctx context.Context, text string, filter repository.ProductFilter, priceBounds []int64, pageSize int, pageToken string) (*SearchResult, error)
 }
 
 // productService implements the ProductService interface
@@ -34,53 +122,282 @@ func (s *productService) GetProduct(ctx context.Context, id uint) (*model.Produc
 	return s.repo.GetByID(ctx, id)
 }
 
-// CreateProduct creates a new product
-func (s *productService) CreateProduct(ctx context.Context, name, description string, price float64, stock int) (*model.Product, error) {
-	if price <= 0 {
-		return nil, errors.New("price must be greater than zero")
+// CreateProduct creates a new product. It is active unless input.IsActive
+// says otherwise.
+func (s *productService) CreateProduct(ctx context.Context, input ProductInput) (*model.Product, error) {
+	product := &model.Product{IsActive: true}
+	if err := input.apply(product, nil); err != nil {
+		return nil, err
 	}
-	if stock < 0 {
-		return nil, errors.New("stock cannot be negative")
+	if err := validateProduct(product); err != nil {
+		return nil, err
 	}
 
-	product := &model.Product{
-		Name:        name,
-		Description: description,
-		Price:       price,
-		Stock:       stock,
+	return s.repo.Create(ctx, product)
+}
+
+// UpdateProduct changes the given fields of an existing product to their
+// values in input and leaves the others alone. nil fields changes every
+// field, except that a nil input.IsActive keeps the product's current state.
+// A named is_active field with a nil input.IsActive deactivates the product.
+// The product must still be at the given version, otherwise the update fails
+// with repository.ErrVersionConflict.
+func (s *productService) UpdateProduct(ctx context.Context, id uint, version int64, input ProductInput, fields []string) (*model.Product, error) {
+	if version <= 0 {
+		return nil, ErrVersionRequired
 	}
 
-	return s.repo.Create(ctx, product)
+	product, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, repository.ErrProductNotFound
+	}
+	if product.Version != version {
+		return nil, repository.ErrVersionConflict
+	}
+
+	// Work on a copy so a rejected update leaves the loaded product intact
+	updated := *product
+	if err := input.apply(&updated, fields); err != nil {
+		return nil, err
+	}
+	if err := validateProduct(&updated); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Update(ctx, &updated)
 }
 
-// UpdateProduct updates an existing product
-func (s *productService) UpdateProduct(ctx context.Context, id uint, name, description string, price float64, stock int) (*model.Product, error) {
-	if price <= 0 {
-		return nil, errors.New("price must be greater than zero")
+// validateProduct checks a product as it would be stored. Every error wraps
+// ErrInvalidProduct.
+func validateProduct(product *model.Product) error {
+	if err := product.Price.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProduct, err)
 	}
-	if stock < 0 {
-		return nil, errors.New("stock cannot be negative")
+	if !product.Price.IsPositive() {
+		return fmt.Errorf("%w: price must be greater than zero", ErrInvalidProduct)
 	}
+	if product.Stock < 0 {
+		return fmt.Errorf("%w: stock cannot be negative", ErrInvalidProduct)
+	}
+	if product.ImageURL != "" {
+		u, err := url.Parse(product.ImageURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: image URL must be an absolute http or https URL", ErrInvalidProduct)
+		}
+	}
+	return nil
+}
 
-	product, err := s.repo.GetByID(ctx, id)
+// apply copies the named fields, or every field when fields is nil, from
+// input to product
+func (input ProductInput) apply(product *model.Product, fields []string) error {
+	if fields == nil {
+		fields = []string{FieldName, FieldDescription, FieldPrice, FieldStock, FieldCategory, FieldImageURL}
+		if input.IsActive != nil {
+			fields = append(fields, FieldIsActive)
+		}
+	}
+
+	for _, field := range fields {
+		switch field {
+		case FieldName:
+			product.Name = input.Name
+		case FieldDescription:
+			product.Description = input.Description
+		case FieldPrice:
+			product.Price = input.Price
+		case FieldStock:
+			product.Stock = input.Stock
+		case FieldCategory:
+			product.Category = input.Category
+		case FieldImageURL:
+			product.ImageURL = input.ImageURL
+		case FieldIsActive:
+			product.IsActive = input.IsActive != nil && *input.IsActive
+		default:
+			return fmt.Errorf("%w: %q", ErrInvalidFieldMask, field)
+		}
+	}
+	return nil
+}
+
+// DeleteProduct deletes a product by ID if it is still at the given version
+func (s *productService) DeleteProduct(ctx context.Context, id uint, version int64) error {
+	if version <= 0 {
+		return ErrVersionRequired
+	}
+	return s.repo.Delete(ctx, id, version)
+}
+
+// ListProducts returns one page of products matching the filter in the given
+// sort order (newest first by default), and the token for the next page
+// (empty on the last page). Inactive products are only listed when
+// filter.IsActive or filter.IncludeInactive asks for them.
+func (s *productService) ListProducts(ctx context.Context, filter repository.ProductFilter, sort string, pageSize int, pageToken string) ([]*model.Product, string, error) {
+	if sort == "" {
+		sort = repository.SortNewest
+	}
+	if !repository.IsProductSort(sort) {
+		return nil, "", ErrInvalidSort
+	}
+	if filter.Currency == "" && (hasPriceBounds(filter) || sort == repository.SortPriceAsc || sort == repository.SortPriceDesc) {
+		return nil, "", ErrInvalidPriceFilter
+	}
+	if filter.IsActive == nil && !filter.IncludeInactive {
+		active := true
+		filter.IsActive = &active
+	}
+
+	var after *repository.ProductCursor
+	if pageToken != "" {
+		var token productPageToken
+		if err := pagination.DecodeToken(pageToken, &token); err != nil || token.ID == 0 || token.Sort != sort {
+			return nil, "", ErrInvalidPageToken
+		}
+		after = &token.ProductCursor
+	}
+
+	return pagination.Fetch(pagination.Size(pageSize), func(limit int) ([]*model.Product, error) {
+		return s.repo.List(ctx, filter, sort, after, limit)
+	}, func(last *model.Product) interface{} {
+		return &productPageToken{Sort: sort, ProductCursor: *repository.NewProductCursor(last)}
+	})
+}
+
+// hasPriceBounds reports whether the filter restricts prices
+func hasPriceBounds(filter repository.ProductFilter) bool {
+	return filter.MinPrice != 0 || filter.MaxPrice != 0
+}
+
+// productPageToken is the cursor of a ListProducts page together with its sort
+// order, since a position in one order means nothing in another
+type productPageToken struct {
+	Sort string `json:"sort"`
+	repository.ProductCursor
+}
+
+// SearchProducts returns one page of active products matching the words of
+// text, best match first, with facet counts. When no product matches and no
+// page token is given, the search falls back to typo-tolerant name matching
+// and later pages keep that mode. filter.Query and filter.IsActive are
+// ignored. priceBounds are the ascending price facet bounds in minor units;
+// they default to defaultPriceBuckets in the filtered currency.
+func (s *productService) SearchProducts(ctx context.Context, text string, filter repository.ProductFilter, priceBounds []int64, pageSize int, pageToken string) (*SearchResult, error) {
+	terms := searchTerms(text)
+	if len(terms) == 0 {
+		return nil, ErrInvalidSearchQuery
+	}
+	for i, bound := range priceBounds {
+		if bound <= 0 || (i > 0 && bound <= priceBounds[i-1]) {
+			return nil, ErrInvalidPriceBuckets
+		}
+	}
+	if filter.Currency == "" && (hasPriceBounds(filter) || len(priceBounds) > 0) {
+		return nil, ErrInvalidPriceFilter
+	}
+	if filter.Currency != "" && len(priceBounds) == 0 {
+		bounds, err := defaultPriceBounds(filter.Currency)
+		if err != nil {
+			return nil, err
+		}
+		priceBounds = bounds
+	}
+
+	active := true
+	filter.Query = ""
+	filter.IsActive = &active
+	q := repository.SearchQuery{Terms: terms, Filter: filter}
+
+	var after *repository.SearchCursor
+	if pageToken != "" {
+		var token searchPageToken
+		if err := pagination.DecodeToken(pageToken, &token); err != nil || token.ID == 0 {
+			return nil, ErrInvalidPageToken
+		}
+		q.Fuzzy = token.Fuzzy
+		after = &token.SearchCursor
+	}
+
+	search := func(limit int) ([]*repository.SearchHit, error) {
+		return s.repo.Search(ctx, q, after, limit)
+	}
+	cursor := func(last *repository.SearchHit) interface{} {
+		return &searchPageToken{
+			Fuzzy:        q.Fuzzy,
+			SearchCursor: repository.SearchCursor{Rank: last.Rank, ID: last.Product.ID},
+		}
+	}
+	pageSize = pagination.Size(pageSize)
+	hits, nextPageToken, err := pagination.Fetch(pageSize, search, cursor)
 	if err != nil {
 		return nil, err
 	}
+	if len(hits) == 0 && after == nil && !q.Fuzzy {
+		q.Fuzzy = true
+		if hits, nextPageToken, err = pagination.Fetch(pageSize, search, cursor); err != nil {
+			return nil, err
+		}
+	}
 
-	product.Name = name
-	product.Description = description
-	product.Price = price
-	product.Stock = stock
+	result := &SearchResult{Hits: hits, NextPageToken: nextPageToken, Fuzzy: q.Fuzzy}
+	categoryQuery := q
+	categoryQuery.Filter.Category = ""
+	if result.Categories, err = s.repo.SearchCategoryCounts(ctx, categoryQuery); err != nil {
+		return nil, err
+	}
 
-	return s.repo.Update(ctx, product)
+	if filter.Currency != "" {
+		priceQuery := q
+		priceQuery.Filter.MinPrice, priceQuery.Filter.MaxPrice = 0, 0
+		counts, err := s.repo.SearchPriceCounts(ctx, priceQuery, priceBounds)
+		if err != nil {
+			return nil, err
+		}
+		for i, count := range counts {
+			bucket := PriceBucket{Count: count}
+			if i > 0 {
+				bucket.Min = priceBounds[i-1]
+			}
+			if i < len(priceBounds) {
+				bucket.Max = priceBounds[i]
+			}
+			result.PriceBuckets = append(result.PriceBuckets, bucket)
+		}
+	}
+	return result, nil
 }
 
-// DeleteProduct deletes a product by ID
-func (s *productService) DeleteProduct(ctx context.Context, id uint) error {
-	return s.repo.Delete(ctx, id)
+// searchTerms splits text into lower-cased words of letters and digits;
+// everything else, including tsquery operators, separates words
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
 }
 
-// ListProducts retrieves all products
-func (s *productService) ListProducts(ctx context.Context) ([]*model.Product, error) {
-	return s.repo.List(ctx)
-} 
\ No newline at end of file
+// defaultPriceBounds scales defaultPriceBuckets to minor units of currency
+func defaultPriceBounds(currency string) ([]int64, error) {
+	exponent, err := money.Exponent(currency)
+	if err != nil {
+		return nil, err
+	}
+	scale := int64(1)
+	for i := 0; i < exponent; i++ {
+		scale *= 10
+	}
+	bounds := make([]int64, len(defaultPriceBuckets))
+	for i, bound := range defaultPriceBuckets {
+		bounds[i] = bound * scale
+	}
+	return bounds, nil
+}
+
+// searchPageToken is the cursor of a SearchProducts page together with the
+// matching mode, so that a fuzzy search stays fuzzy on later pages
+type searchPageToken struct {
+	Fuzzy bool `json:"fuzzy"`
+	repository.SearchCursor
+}
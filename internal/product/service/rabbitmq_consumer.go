@@ -0,0 +1,124 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/streadway/amqp"
+)
+
+type IRabbitMQConsumer interface {
+	Consume() (<-chan amqp.Delivery, error)
+	Close()
+}
+
+type RabbitMQConsumer struct {
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	queue   string
+}
+
+// NewRabbitMQConsumer declares a durable queue bound to the given topic
+// exchange. Rejected messages are routed to "<queue>.dlq" through the
+// "<exchange>.dlx" dead-letter exchange.
+func NewRabbitMQConsumer(url, exchange, queue, routingKey string) (*RabbitMQConsumer, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %v", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open a channel: %v", err)
+	}
+
+	consumer := &RabbitMQConsumer{
+		conn:    conn,
+		channel: ch,
+		queue:   queue,
+	}
+
+	if err := consumer.declare(exchange, routingKey); err != nil {
+		consumer.Close()
+		return nil, err
+	}
+
+	return consumer, nil
+}
+
+func (c *RabbitMQConsumer) declare(exchange, routingKey string) error {
+	dlx := exchange + ".dlx"
+	dlq := c.queue + ".dlq"
+
+	err := c.channel.ExchangeDeclare(
+		exchange, // name
+		"topic",  // type
+		true,     // durable
+		false,    // auto-deleted
+		false,    // internal
+		false,    // no-wait
+		nil,      // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare an exchange: %v", err)
+	}
+
+	err = c.channel.ExchangeDeclare(dlx, "fanout", true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead-letter exchange: %v", err)
+	}
+
+	if _, err := c.channel.QueueDeclare(dlq, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %v", err)
+	}
+	if err := c.channel.QueueBind(dlq, "", dlx, false, nil); err != nil {
+		return fmt.Errorf("failed to bind dead-letter queue: %v", err)
+	}
+
+	_, err = c.channel.QueueDeclare(
+		c.queue, // name
+		true,    // durable
+		false,   // delete when unused
+		false,   // exclusive
+		false,   // no-wait
+		amqp.Table{"x-dead-letter-exchange": dlx},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare a queue: %v", err)
+	}
+
+	if err := c.channel.QueueBind(c.queue, routingKey, exchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind a queue: %v", err)
+	}
+
+	if err := c.channel.Qos(10, 0, false); err != nil {
+		return fmt.Errorf("failed to set QoS: %v", err)
+	}
+
+	return nil
+}
+
+func (c *RabbitMQConsumer) Consume() (<-chan amqp.Delivery, error) {
+	deliveries, err := c.channel.Consume(
+		c.queue, // queue
+		"",      // consumer
+		false,   // auto-ack
+		false,   // exclusive
+		false,   // no-local
+		false,   // no-wait
+		nil,     // args
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register a consumer: %v", err)
+	}
+	return deliveries, nil
+}
+
+func (c *RabbitMQConsumer) Close() {
+	if c.channel != nil {
+		c.channel.Close()
+	}
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gomicro/internal/product/repository"
+)
+
+// ReservationReaper periodically deletes expired stock reservations. Expired
+// reservations already stop counting against stock; the reaper only keeps
+// the table small.
+type ReservationReaper struct {
+	repo     repository.ReservationRepository
+	interval time.Duration
+}
+
+// NewReservationReaper creates a reaper that runs every interval
+func NewReservationReaper(repo repository.ReservationRepository, interval time.Duration) *ReservationReaper {
+	return &ReservationReaper{
+		repo:     repo,
+		interval: interval,
+	}
+}
+
+// Run reaps expired reservations until the context is cancelled
+func (r *ReservationReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.ReapExpired(ctx); err != nil {
+				log.Printf("Failed to delete expired stock reservations: %v", err)
+			}
+		}
+	}
+}
+
+// ReapExpired deletes reservations that have expired and returns how many
+func (r *ReservationReaper) ReapExpired(ctx context.Context) (int64, error) {
+	return r.repo.DeleteExpired(ctx, time.Now())
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gomicro/internal/product/model"
+	"gomicro/internal/product/repository"
+)
+
+// ErrInvalidReservation is returned for reservations without items or with non-positive quantities
+var ErrInvalidReservation = errors.New("reservation needs at least one item with a positive quantity")
+
+const (
+	DefaultReservationTTL = 10 * time.Minute
+	MaxReservationTTL     = 30 * time.Minute
+)
+
+// ReservationItem is a quantity of a product to hold
+type ReservationItem struct {
+	ProductID uint
+	Quantity  int
+}
+
+// ReservationService holds stock for checkouts in progress. Reservations
+// expire on their own, so a crashed checkout never holds stock for long.
+type ReservationService interface {
+	ReserveStock(ctx context.Context, items []ReservationItem, ttl time.Duration) (string, time.Time, error)
+	ReleaseStock(ctx context.Context, reservationID string) error
+	ReservedStock(ctx context.Context, productIDs []uint) (map[uint]int, error)
+}
+
+type reservationService struct {
+	repo repository.ReservationRepository
+}
+
+// NewReservationService creates a new reservation service
+func NewReservationService(repo repository.ReservationRepository) ReservationService {
+	return &reservationService{repo: repo}
+}
+
+// ReserveStock holds every item or none and returns the reservation ID and
+// its expiry. A zero ttl uses DefaultReservationTTL; longer ttls are capped
+// at MaxReservationTTL.
+func (s *reservationService) ReserveStock(ctx context.Context, items []ReservationItem, ttl time.Duration) (string, time.Time, error) {
+	if len(items) == 0 {
+		return "", time.Time{}, ErrInvalidReservation
+	}
+	if ttl <= 0 {
+		ttl = DefaultReservationTTL
+	}
+	if ttl > MaxReservationTTL {
+		ttl = MaxReservationTTL
+	}
+
+	reservationID, err := newReservationID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	// Merge repeated products so each is checked against its stock once
+	quantities := make(map[uint]int, len(items))
+	var reservations []*model.StockReservation
+	for _, item := range items {
+		if item.ProductID == 0 || item.Quantity <= 0 {
+			return "", time.Time{}, ErrInvalidReservation
+		}
+		if _, seen := quantities[item.ProductID]; !seen {
+			reservations = append(reservations, &model.StockReservation{
+				ReservationID: reservationID,
+				ProductID:     item.ProductID,
+				ExpiresAt:     expiresAt,
+			})
+		}
+		quantities[item.ProductID] += item.Quantity
+	}
+	for _, reservation := range reservations {
+		reservation.Quantity = quantities[reservation.ProductID]
+	}
+
+	if err := s.repo.Reserve(ctx, reservations); err != nil {
+		return "", time.Time{}, err
+	}
+	return reservationID, expiresAt, nil
+}
+
+func (s *reservationService) ReleaseStock(ctx context.Context, reservationID string) error {
+	return s.repo.Release(ctx, reservationID)
+}
+
+func (s *reservationService) ReservedStock(ctx context.Context, productIDs []uint) (map[uint]int, error) {
+	if len(productIDs) == 0 {
+		return map[uint]int{}, nil
+	}
+	return s.repo.ReservedQuantities(ctx, productIDs)
+}
+
+func newReservationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "res_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/streadway/amqp"
+	"gomicro/internal/product/model"
+	"gomicro/internal/product/repository"
+)
+
+// StockUpdateConsumer applies StockUpdateEvents from RabbitMQ to product stock
+type StockUpdateConsumer struct {
+	repo     repository.ProductRepository
+	consumer IRabbitMQConsumer
+}
+
+// NewStockUpdateConsumer creates a new stock update consumer
+func NewStockUpdateConsumer(repo repository.ProductRepository, consumer IRabbitMQConsumer) *StockUpdateConsumer {
+	return &StockUpdateConsumer{
+		repo:     repo,
+		consumer: consumer,
+	}
+}
+
+// Run consumes deliveries until the context is cancelled or the delivery
+// channel is closed
+func (c *StockUpdateConsumer) Run(ctx context.Context) error {
+	deliveries, err := c.consumer.Consume()
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case d, ok := <-deliveries:
+			if !ok {
+				return errors.New("delivery channel closed")
+			}
+			c.handle(ctx, d)
+		}
+	}
+}
+
+// handle acks a delivery only after the stock update is committed.
+// Malformed and unprocessable events are dead-lettered; transient failures
+// are requeued once and dead-lettered if they fail again.
+func (c *StockUpdateConsumer) handle(ctx context.Context, d amqp.Delivery) {
+	event, err := decodeStockUpdateEvent(d.Body)
+	if err != nil {
+		log.Printf("Dead-lettering malformed stock update event: %v", err)
+		d.Nack(false, false)
+		return
+	}
+
+	err = c.repo.ApplyStockUpdate(ctx, event)
+	switch {
+	case err == nil:
+		d.Ack(false)
+		log.Printf("Applied stock update event: %+v", event)
+	case errors.Is(err, repository.ErrStockUpdateApplied):
+		d.Ack(false)
+		log.Printf("Skipped duplicate stock update event: %+v", event)
+	case errors.Is(err, repository.ErrProductNotFound), errors.Is(err, repository.ErrInsufficientStock):
+		log.Printf("Dead-lettering unprocessable stock update event %+v: %v", event, err)
+		d.Nack(false, false)
+	default:
+		log.Printf("Failed to apply stock update event %+v: %v", event, err)
+		d.Nack(false, !d.Redelivered)
+	}
+}
+
+func decodeStockUpdateEvent(body []byte) (*model.StockUpdateEvent, error) {
+	var event model.StockUpdateEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %v", err)
+	}
+	if event.ProductID == 0 {
+		return nil, errors.New("product_id is required")
+	}
+	if event.Quantity == 0 {
+		return nil, errors.New("quantity must be non-zero")
+	}
+	return &event, nil
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "gomicro/api/proto"
+	"gomicro/internal/basket/model"
+	"gomicro/internal/basket/repository"
+	"gomicro/internal/basket/service"
+)
+
+// MockBasketEventPublisher implements the basket service's IRabbitMQPublisher
+type MockBasketEventPublisher struct {
+	events []*model.BasketAbandonedEvent
+	err    error
+}
+
+func (m *MockBasketEventPublisher) SendBasketAbandonedEvent(event *model.BasketAbandonedEvent) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *MockBasketEventPublisher) Close() {}
+
+func TestBasketTTLIsConfigurable(t *testing.T) {
+	// Setup
+	server, client := startRedis(t)
+	repo := repository.NewBasketRepository(client, 90*time.Minute)
+
+	// Execute
+	err := repo.SaveBasket(context.Background(), &model.Basket{UserID: 1, Items: []model.BasketItem{{ProductID: 1, Quantity: 1}}})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("SaveBasket() unexpected error: %v", err)
+	}
+	if ttl := server.TTL("basket:1"); ttl != 90*time.Minute {
+		t.Errorf("basket TTL = %v, want %v", ttl, 90*time.Minute)
+	}
+}
+
+func TestIdleBasketsSkipsExpiredBaskets(t *testing.T) {
+	// Setup
+	server, client := startRedis(t)
+	repo := repository.NewBasketRepository(client, time.Hour)
+	repo.SaveBasket(context.Background(), &model.Basket{UserID: 1, Items: []model.BasketItem{{ProductID: 1, Quantity: 1}}})
+	server.FastForward(2 * time.Hour)
+
+	// Execute
+	idle, err := repo.IdleBaskets(context.Background(), time.Now().Add(time.Second), 10)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("IdleBaskets() unexpected error: %v", err)
+	}
+	if len(idle) != 0 {
+		t.Errorf("IdleBaskets() = %d baskets, want expired basket skipped", len(idle))
+	}
+	if n := client.ZCard(context.Background(), "baskets:activity").Val(); n != 0 {
+		t.Errorf("activity index has %d entries, want expired basket removed", n)
+	}
+}
+
+func TestAbandonedBasketJob(t *testing.T) {
+	// Setup
+	const idleAfter = 50 * time.Millisecond
+	ctx := context.Background()
+	repo := newRedisBasketRepository(t)
+	catalog := NewMockProductClient(
+		&pb.Product{Id: 1, Name: "Keyboard", Price: tryAmount(4999).ToProto(), Stock: 10, IsActive: true},
+		&pb.Product{Id: 2, Name: "Mouse", Price: tryAmount(1995).ToProto(), Stock: 10, IsActive: true},
+	)
+	basketService := service.NewBasketService(repo, testRates(t), catalog, NewMockCouponRepository())
+	publisher := &MockBasketEventPublisher{}
+	job := service.NewAbandonedBasketJob(repo, basketService, publisher, idleAfter, time.Minute)
+
+	// User 1 leaves two products behind, user 2 empties the basket and a
+	// guest leaves a product behind; user 3 is still shopping
+	basketService.AddItemToBasket(ctx, 1, 1, 2)
+	basketService.AddItemToBasket(ctx, 1, 2, 1)
+	basketService.AddItemToBasket(ctx, 2, 1, 1)
+	basketService.ClearBasket(ctx, 2)
+	basketService.AddItemToGuestBasket(ctx, guestToken, 1, 1)
+	time.Sleep(2 * idleAfter)
+	basketService.AddItemToBasket(ctx, 3, 1, 1)
+
+	// Execute
+	published, err := job.PublishAbandoned(ctx)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("PublishAbandoned() unexpected error: %v", err)
+	}
+	if published != 1 || len(publisher.events) != 1 {
+		t.Fatalf("PublishAbandoned() published %d events (%d sent), want 1", published, len(publisher.events))
+	}
+	event := publisher.events[0]
+	if event.UserID != 1 || len(event.Items) != 2 || event.Total != tryAmount(11993) {
+		t.Errorf("BasketAbandonedEvent = %+v, want user 1 with 2 items totalling %v", event, tryAmount(11993))
+	}
+	if time.Since(event.LastActivity) < 2*idleAfter {
+		t.Errorf("BasketAbandonedEvent last activity = %v, want the time of the last update", event.LastActivity)
+	}
+
+	// A basket is reported once per idle period
+	if published, _ := job.PublishAbandoned(ctx); published != 0 {
+		t.Errorf("PublishAbandoned() again published %d events, want 0", published)
+	}
+
+	// Updating the basket starts a new idle period
+	basketService.AddItemToBasket(ctx, 1, 2, 3)
+	time.Sleep(2 * idleAfter)
+	if published, _ := job.PublishAbandoned(ctx); published != 2 {
+		t.Errorf("PublishAbandoned() after new idle period published %d events, want users 1 and 3", published)
+	}
+}
+
+func TestAbandonedBasketJobRetriesFailedPublish(t *testing.T) {
+	// Setup
+	const idleAfter = 20 * time.Millisecond
+	ctx := context.Background()
+	repo := newRedisBasketRepository(t)
+	basketService := service.NewBasketService(repo, testRates(t), newBasketCatalog(), NewMockCouponRepository())
+	publisher := &MockBasketEventPublisher{err: errors.New("connection closed")}
+	job := service.NewAbandonedBasketJob(repo, basketService, publisher, idleAfter, time.Minute)
+	basketService.AddItemToBasket(ctx, 1, 1, 1)
+	time.Sleep(2 * idleAfter)
+
+	// Execute
+	failed, failedErr := job.PublishAbandoned(ctx)
+	publisher.err = nil
+	retried, retriedErr := job.PublishAbandoned(ctx)
+
+	// Assert
+	if failedErr != nil || retriedErr != nil {
+		t.Fatalf("PublishAbandoned() unexpected errors: %v, %v", failedErr, retriedErr)
+	}
+	if failed != 0 || retried != 1 {
+		t.Errorf("PublishAbandoned() published %d then %d events, want 0 then 1", failed, retried)
+	}
+}
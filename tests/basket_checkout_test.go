@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "gomicro/api/proto"
+	"gomicro/internal/basket/model"
+	"gomicro/internal/basket/repository"
+	"gomicro/internal/basket/service"
+)
+
+func TestBasketVersion(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	_, basketService := newWishlistBasket(t)
+
+	// Execute: 2 adds, then move, update and clear
+	basketService.MoveToWishlist(ctx, 1, 2)
+	basketService.UpdateItemQuantity(ctx, 1, 1, 3)
+	basketService.ClearBasket(ctx, 1)
+	basketService.AddItemToBasket(ctx, 1, 1, 1)
+	failed := basketService.AddItemToBasket(ctx, 1, 1, 100)
+
+	// Assert
+	if failed == nil {
+		t.Fatal("AddItemToBasket() over the limit expected error but got none")
+	}
+	basket, err := basketService.GetBasket(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetBasket() unexpected error: %v", err)
+	}
+	if basket.Version != 6 {
+		t.Errorf("GetBasket() version = %d, want 6", basket.Version)
+	}
+}
+
+func TestLockBasketForCheckout(t *testing.T) {
+	// Setup: 2 keyboards and a mouse, 10% off
+	ctx := context.Background()
+	server, client := startRedis(t)
+	catalog := NewMockProductClient(
+		&pb.Product{Id: 1, Name: "Keyboard", Price: tryAmount(4999).ToProto(), Stock: 10, IsActive: true},
+		&pb.Product{Id: 2, Name: "Mouse", Price: tryAmount(1995).ToProto(), Stock: 10, IsActive: true},
+	)
+	coupons := NewMockCouponRepository(&model.Coupon{Code: "SAVE10", Type: model.PromotionPercentage, Percent: 10})
+	repo := repository.NewBasketRepository(client, repository.DefaultBasketTTL)
+	basketService := service.NewBasketService(repo, testRates(t), catalog, coupons)
+	basketService.AddItemToBasket(ctx, 1, 1, 2)
+	basketService.AddItemToBasket(ctx, 1, 2, 1)
+	basketService.ApplyCoupon(ctx, 1, "SAVE10")
+	shown, _ := basketService.GetBasket(ctx, 1)
+
+	// Execute
+	snapshot, err := basketService.LockBasketForCheckout(ctx, 1, shown.Version)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("LockBasketForCheckout() unexpected error: %v", err)
+	}
+	if snapshot.Version != 3 || snapshot.Subtotal != tryAmount(11993) || snapshot.Total != tryAmount(10794) || len(snapshot.Discounts) != 1 {
+		t.Errorf("LockBasketForCheckout() = version %d subtotal %v total %v discounts %v, want version 3 with 10%% off 119.93",
+			snapshot.Version, snapshot.Subtotal, snapshot.Total, snapshot.Discounts)
+	}
+	if ttl := server.TTL("basket:checkout:1:3"); ttl != service.CheckoutSnapshotTTL {
+		t.Errorf("snapshot TTL = %v, want %v", ttl, service.CheckoutSnapshotTTL)
+	}
+
+	// A retried checkout sees the locked prices
+	catalog.products[1].Price = tryAmount(5999).ToProto()
+	relocked, err := basketService.LockBasketForCheckout(ctx, 1, shown.Version)
+	if err != nil {
+		t.Fatalf("LockBasketForCheckout() again unexpected error: %v", err)
+	}
+	if relocked.Total != snapshot.Total || relocked.Items[0].CurrentPrice != tryAmount(4999) {
+		t.Errorf("LockBasketForCheckout() again total %v, want the locked %v", relocked.Total, snapshot.Total)
+	}
+
+	// Any change to the basket invalidates the version the customer saw
+	basketService.RemoveItemFromBasket(ctx, 1, 2)
+	if _, err := basketService.LockBasketForCheckout(ctx, 1, shown.Version); !errors.Is(err, repository.ErrBasketChanged) {
+		t.Errorf("LockBasketForCheckout() after change error = %v, want %v", err, repository.ErrBasketChanged)
+	}
+}
+
+func TestCheckoutCountsCouponUse(t *testing.T) {
+	// Setup: two baskets hold a keyboard and the single-use coupon
+	ctx := context.Background()
+	_, client := startRedis(t)
+	coupon := &model.Coupon{Code: "ONCE", Type: model.PromotionPercentage, Percent: 10, MaxUses: 1}
+	coupons := repository.NewCouponRepository(client, []*model.Coupon{coupon})
+	repo := repository.NewBasketRepository(client, repository.DefaultBasketTTL)
+	basketService := service.NewBasketService(repo, testRates(t), newBasketCatalog(), coupons)
+	for userID := uint(1); userID <= 2; userID++ {
+		basketService.AddItemToBasket(ctx, userID, 1, 1)
+		if err := basketService.ApplyCoupon(ctx, userID, "ONCE"); err != nil {
+			t.Fatalf("ApplyCoupon() on basket %d unexpected error: %v", userID, err)
+		}
+	}
+
+	// Execute: the first checkout holds the use until its basket is cleared
+	_, firstErr := basketService.LockBasketForCheckout(ctx, 1, 2)
+	_, heldErr := basketService.LockBasketForCheckout(ctx, 2, 2)
+	basketService.ClearBasket(ctx, 1)
+	snapshot, secondErr := basketService.LockBasketForCheckout(ctx, 2, 2)
+	completeErr := basketService.CompleteCheckout(ctx, 2, 2)
+
+	// Assert
+	if firstErr != nil || secondErr != nil || completeErr != nil {
+		t.Fatalf("unexpected errors: lock %v, lock after clear %v, complete %v", firstErr, secondErr, completeErr)
+	}
+	if !errors.Is(heldErr, repository.ErrCouponUsageLimit) {
+		t.Errorf("LockBasketForCheckout() while held error = %v, want %v", heldErr, repository.ErrCouponUsageLimit)
+	}
+	if len(snapshot.Discounts) != 1 {
+		t.Errorf("LockBasketForCheckout() discounts = %v, want the coupon", snapshot.Discounts)
+	}
+	if basket, _ := basketService.GetBasket(ctx, 2); len(basket.Items) != 0 || basket.CouponCode != "" {
+		t.Errorf("CompleteCheckout() left basket %+v, want it cleared", basket)
+	}
+	basketService.AddItemToBasket(ctx, 1, 1, 1)
+	if err := basketService.ApplyCoupon(ctx, 1, "ONCE"); !errors.Is(err, repository.ErrCouponUsageLimit) {
+		t.Errorf("ApplyCoupon() after the order error = %v, want %v", err, repository.ErrCouponUsageLimit)
+	}
+}
+
+func TestCompleteCheckoutKeepsChangedBasket(t *testing.T) {
+	// Setup: the basket is locked with the coupon, then gains a line
+	ctx := context.Background()
+	_, client := startRedis(t)
+	coupon := &model.Coupon{Code: "ONCE", Type: model.PromotionPercentage, Percent: 10, MaxUses: 1}
+	coupons := repository.NewCouponRepository(client, []*model.Coupon{coupon})
+	repo := repository.NewBasketRepository(client, repository.DefaultBasketTTL)
+	catalog := NewMockProductClient(
+		&pb.Product{Id: 1, Name: "Keyboard", Price: tryAmount(4999).ToProto(), Stock: 10, IsActive: true},
+		&pb.Product{Id: 2, Name: "Mouse", Price: tryAmount(1995).ToProto(), Stock: 10, IsActive: true},
+	)
+	basketService := service.NewBasketService(repo, testRates(t), catalog, coupons)
+	basketService.AddItemToBasket(ctx, 1, 1, 1)
+	basketService.ApplyCoupon(ctx, 1, "ONCE")
+	if _, err := basketService.LockBasketForCheckout(ctx, 1, 2); err != nil {
+		t.Fatalf("LockBasketForCheckout() unexpected error: %v", err)
+	}
+	basketService.AddItemToBasket(ctx, 1, 2, 1)
+
+	// Execute
+	err := basketService.CompleteCheckout(ctx, 1, 2)
+
+	// Assert
+	if !errors.Is(err, repository.ErrBasketChanged) {
+		t.Errorf("CompleteCheckout() error = %v, want %v", err, repository.ErrBasketChanged)
+	}
+	if basket, _ := basketService.GetBasket(ctx, 1); len(basket.Items) != 2 || basket.Version != 3 {
+		t.Errorf("CompleteCheckout() left %d items at version %d, want the changed basket kept", len(basket.Items), basket.Version)
+	}
+	if err := coupons.CheckAvailable(ctx, coupon); !errors.Is(err, repository.ErrCouponUsageLimit) {
+		t.Errorf("CheckAvailable() after the order error = %v, want the paid use counted", err)
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	pb "gomicro/api/proto"
+	"gomicro/internal/basket/model"
+	"gomicro/internal/basket/repository"
+	"gomicro/internal/basket/service"
+)
+
+// startRedis runs an in-process Redis server for the duration of the test
+func startRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+// newRedisBasketRepository returns the Redis basket repository backed by an
+// in-process Redis server
+func newRedisBasketRepository(t *testing.T) repository.BasketRepository {
+	t.Helper()
+	_, client := startRedis(t)
+	return repository.NewBasketRepository(client, repository.DefaultBasketTTL)
+}
+
+func TestConcurrentAddItemToBasket(t *testing.T) {
+	// Setup
+	const workers = 25
+	repo := newRedisBasketRepository(t)
+	products := make([]*pb.Product, workers)
+	for i := range products {
+		products[i] = &pb.Product{Id: uint32(i + 1), Name: fmt.Sprintf("Product %d", i+1), Price: tryAmount(1000).ToProto(), Stock: 10, IsActive: true}
+	}
+	basketService := service.NewBasketService(repo, testRates(t), NewMockProductClient(products...), NewMockCouponRepository())
+
+	// Execute
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 1; i <= workers; i++ {
+		wg.Add(1)
+		go func(productID uint) {
+			defer wg.Done()
+			errs <- basketService.AddItemToBasket(context.Background(), 1, productID, 2)
+		}(uint(i))
+	}
+	wg.Wait()
+	close(errs)
+
+	// Assert
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("AddItemToBasket() unexpected error: %v", err)
+		}
+	}
+	basket, err := repo.GetBasket(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetBasket() unexpected error: %v", err)
+	}
+	if len(basket.Items) != workers {
+		t.Fatalf("basket has %d items after %d concurrent adds, want %d", len(basket.Items), workers, workers)
+	}
+	seen := make(map[uint]bool, workers)
+	for _, item := range basket.Items {
+		if seen[item.ProductID] || item.Quantity != 2 {
+			t.Errorf("unexpected basket item %+v", item)
+		}
+		seen[item.ProductID] = true
+	}
+}
+
+func TestConcurrentModifyBasket(t *testing.T) {
+	// Setup
+	const workers = 50
+	repo := newRedisBasketRepository(t)
+	if err := repo.SaveBasket(context.Background(), &model.Basket{UserID: 1, Items: []model.BasketItem{{ProductID: 1}}}); err != nil {
+		t.Fatalf("SaveBasket() unexpected error: %v", err)
+	}
+
+	// Execute: every worker increments the same line
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.ModifyBasket(context.Background(), 1, func(basket *model.Basket) error {
+				basket.Items[0].Quantity++
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// Assert
+	failed := 0
+	for err := range errs {
+		if errors.Is(err, repository.ErrConcurrentModification) {
+			failed++
+		} else if err != nil {
+			t.Fatalf("ModifyBasket() unexpected error: %v", err)
+		}
+	}
+	basket, err := repo.GetBasket(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetBasket() unexpected error: %v", err)
+	}
+	if got := basket.Items[0].Quantity; got != workers-failed {
+		t.Errorf("quantity = %d after %d successful increments, want %d", got, workers-failed, workers-failed)
+	}
+}
@@ -0,0 +1,351 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gomicro/internal/basket/model"
+	"gomicro/internal/basket/repository"
+	"gomicro/internal/basket/service"
+	"gomicro/internal/money"
+)
+
+// MockCouponRepository implements repository.CouponRepository interface
+type MockCouponRepository struct {
+	coupons map[string]*model.Coupon
+	uses    map[string]int64
+	holds   map[string]map[string]bool
+}
+
+func NewMockCouponRepository(coupons ...*model.Coupon) *MockCouponRepository {
+	m := &MockCouponRepository{
+		coupons: make(map[string]*model.Coupon),
+		uses:    make(map[string]int64),
+		holds:   make(map[string]map[string]bool),
+	}
+	for _, coupon := range coupons {
+		m.coupons[strings.ToUpper(coupon.Code)] = coupon
+	}
+	return m
+}
+
+func (m *MockCouponRepository) GetCoupon(ctx context.Context, code string) (*model.Coupon, error) {
+	return m.coupons[strings.ToUpper(code)], nil
+}
+
+// Holds do not expire in the mock; expiry is covered against the real
+// repositories in TestCouponUsageLimit
+func (m *MockCouponRepository) CheckAvailable(ctx context.Context, coupon *model.Coupon) error {
+	if coupon.MaxUses > 0 && m.uses[coupon.Code]+int64(len(m.holds[coupon.Code])) >= coupon.MaxUses {
+		return repository.ErrCouponUsageLimit
+	}
+	return nil
+}
+
+func (m *MockCouponRepository) Reserve(ctx context.Context, coupon *model.Coupon, holder string, ttl time.Duration) error {
+	if m.holds[coupon.Code][holder] {
+		return nil
+	}
+	if err := m.CheckAvailable(ctx, coupon); err != nil {
+		return err
+	}
+	if m.holds[coupon.Code] == nil {
+		m.holds[coupon.Code] = make(map[string]bool)
+	}
+	m.holds[coupon.Code][holder] = true
+	return nil
+}
+
+func (m *MockCouponRepository) Release(ctx context.Context, coupon *model.Coupon, holder string) error {
+	delete(m.holds[coupon.Code], holder)
+	return nil
+}
+
+func (m *MockCouponRepository) Redeem(ctx context.Context, coupon *model.Coupon, holder string) error {
+	delete(m.holds[coupon.Code], holder)
+	m.uses[coupon.Code]++
+	return nil
+}
+
+// couponBasket returns a basket of 3 keyboards at 49.99 TRY and 4 mice at
+// 19.95 TRY with its subtotal computed
+func couponBasket() *model.Basket {
+	return &model.Basket{
+		UserID: 1,
+		Items: []model.BasketItem{
+			{ProductID: 1, Quantity: 3, CurrentPrice: tryAmount(4999)},
+			{ProductID: 2, Quantity: 4, CurrentPrice: tryAmount(1995)},
+		},
+		Subtotal: tryAmount(22977),
+	}
+}
+
+func TestPromotionEngine(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	eur := func(minor int64) money.Money { return money.Money{MinorUnits: minor, Currency: "EUR"} }
+
+	tests := []struct {
+		name         string
+		coupon       *model.Coupon
+		wantDiscount money.Money
+		wantErr      error
+	}{
+		{
+			name:         "percentage off rounds down",
+			coupon:       &model.Coupon{Code: "TEN", Type: model.PromotionPercentage, Percent: 10},
+			wantDiscount: tryAmount(2297),
+		},
+		{
+			name:         "fixed amount off",
+			coupon:       &model.Coupon{Code: "FIFTY", Type: model.PromotionFixedAmount, Amount: tryAmount(5000)},
+			wantDiscount: tryAmount(5000),
+		},
+		{
+			name:         "fixed amount in another currency is converted",
+			coupon:       &model.Coupon{Code: "EURO", Type: model.PromotionFixedAmount, Amount: eur(100)},
+			wantDiscount: tryAmount(4000),
+		},
+		{
+			name:         "fixed amount never exceeds the subtotal",
+			coupon:       &model.Coupon{Code: "HUGE", Type: model.PromotionFixedAmount, Amount: tryAmount(1000000)},
+			wantDiscount: tryAmount(22977),
+		},
+		{
+			name:         "buy 1 get 1 free",
+			coupon:       &model.Coupon{Code: "BOGO", Type: model.PromotionBuyXGetY, ProductID: 1, BuyQuantity: 1, FreeQuantity: 1},
+			wantDiscount: tryAmount(4999),
+		},
+		{
+			name:         "buy 3 get 1 free",
+			coupon:       &model.Coupon{Code: "MICE", Type: model.PromotionBuyXGetY, ProductID: 2, BuyQuantity: 3, FreeQuantity: 1},
+			wantDiscount: tryAmount(1995),
+		},
+		{
+			name:    "buy x get y needs enough units",
+			coupon:  &model.Coupon{Code: "KEYS", Type: model.PromotionBuyXGetY, ProductID: 1, BuyQuantity: 3, FreeQuantity: 1},
+			wantErr: service.ErrCouponNotApplicable,
+		},
+		{
+			name:         "minimum basket value met",
+			coupon:       &model.Coupon{Code: "MIN", Type: model.PromotionPercentage, Percent: 50, MinBasketValue: tryAmount(22977)},
+			wantDiscount: tryAmount(11488),
+		},
+		{
+			name:    "minimum basket value not met",
+			coupon:  &model.Coupon{Code: "MIN", Type: model.PromotionPercentage, Percent: 50, MinBasketValue: eur(1000)},
+			wantErr: service.ErrCouponNotApplicable,
+		},
+		{
+			name:    "not yet valid",
+			coupon:  &model.Coupon{Code: "SOON", Type: model.PromotionPercentage, Percent: 10, ValidFrom: now.Add(time.Hour)},
+			wantErr: service.ErrCouponNotActive,
+		},
+		{
+			name:    "expired",
+			coupon:  &model.Coupon{Code: "OLD", Type: model.PromotionPercentage, Percent: 10, ValidUntil: now},
+			wantErr: service.ErrCouponNotActive,
+		},
+		{
+			name:         "inside validity window",
+			coupon:       &model.Coupon{Code: "NOW", Type: model.PromotionPercentage, Percent: 100, ValidFrom: now.Add(-time.Hour), ValidUntil: now.Add(time.Hour)},
+			wantDiscount: tryAmount(22977),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			engine := service.NewPromotionEngine(testRates(t))
+
+			// Execute
+			line, err := engine.Discount(context.Background(), tt.coupon, couponBasket(), now)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Discount() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (line.Amount != tt.wantDiscount || line.Code != tt.coupon.Code) {
+				t.Errorf("Discount() = %s %v, want %s %v", line.Code, line.Amount, tt.coupon.Code, tt.wantDiscount)
+			}
+		})
+	}
+}
+
+func TestApplyCoupon(t *testing.T) {
+	// Setup: baskets 1 and 2 each hold 2 keyboards
+	ctx := context.Background()
+	repo := NewMockBasketRepository()
+	coupons := NewMockCouponRepository(
+		&model.Coupon{Code: "WELCOME10", Description: "10% off", Type: model.PromotionPercentage, Percent: 10, MaxUses: 1},
+		&model.Coupon{Code: "SAVE20", Type: model.PromotionFixedAmount, Amount: tryAmount(2000)},
+		&model.Coupon{Code: "BIGSPENDER", Type: model.PromotionPercentage, Percent: 20, MinBasketValue: tryAmount(50000)},
+	)
+	basketService := service.NewBasketService(repo, testRates(t), newBasketCatalog(), coupons)
+	for userID := uint(1); userID <= 2; userID++ {
+		repo.Create(ctx, &model.Basket{UserID: userID, Items: []model.BasketItem{}})
+		basketService.AddItemToBasket(ctx, userID, 1, 2)
+	}
+
+	// Execute
+	applyErr := basketService.ApplyCoupon(ctx, 1, "welcome10")
+	basket, _ := basketService.GetBasket(ctx, 1)
+
+	// Assert
+	if applyErr != nil {
+		t.Fatalf("ApplyCoupon() unexpected error: %v", applyErr)
+	}
+	if basket.CouponCode != "WELCOME10" || basket.Subtotal != tryAmount(9998) || basket.Total != tryAmount(8999) {
+		t.Errorf("GetBasket() coupon %q subtotal %v total %v, want WELCOME10 %v %v", basket.CouponCode, basket.Subtotal, basket.Total, tryAmount(9998), tryAmount(8999))
+	}
+	if len(basket.Discounts) != 1 || basket.Discounts[0].Amount != tryAmount(999) || basket.Discounts[0].Description != "10% off" {
+		t.Errorf("GetBasket() discounts = %+v, want one 9.99 TRY line", basket.Discounts)
+	}
+
+	if err := basketService.ApplyCoupon(ctx, 2, "NOPE"); !errors.Is(err, service.ErrCouponNotFound) {
+		t.Errorf("ApplyCoupon() unknown code error = %v, want %v", err, service.ErrCouponNotFound)
+	}
+	if err := basketService.ApplyCoupon(ctx, 2, "BIGSPENDER"); !errors.Is(err, service.ErrCouponNotApplicable) {
+		t.Errorf("ApplyCoupon() below minimum error = %v, want %v", err, service.ErrCouponNotApplicable)
+	}
+
+	// Applying does not count a use, so another basket may apply the coupon
+	// until an order uses it up
+	if err := basketService.ApplyCoupon(ctx, 2, "WELCOME10"); err != nil {
+		t.Errorf("ApplyCoupon() on a second basket unexpected error: %v", err)
+	}
+	coupons.uses["WELCOME10"] = 1
+	if err := basketService.ApplyCoupon(ctx, 2, "SAVE20"); err != nil {
+		t.Fatalf("ApplyCoupon() replacement unexpected error: %v", err)
+	}
+	if err := basketService.ApplyCoupon(ctx, 2, "WELCOME10"); !errors.Is(err, repository.ErrCouponUsageLimit) {
+		t.Errorf("ApplyCoupon() used up coupon error = %v, want %v", err, repository.ErrCouponUsageLimit)
+	}
+	if basket, _ = basketService.GetBasket(ctx, 2); basket.Total != tryAmount(7998) {
+		t.Errorf("GetBasket() total after replacement = %v, want %v", basket.Total, tryAmount(7998))
+	}
+}
+
+func TestRemoveCoupon(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	repo := NewMockBasketRepository()
+	coupons := NewMockCouponRepository(&model.Coupon{Code: "ONCE", Type: model.PromotionPercentage, Percent: 10, MaxUses: 1})
+	basketService := service.NewBasketService(repo, testRates(t), newBasketCatalog(), coupons)
+	repo.Create(ctx, &model.Basket{UserID: 1, Items: []model.BasketItem{}})
+	basketService.AddItemToBasket(ctx, 1, 1, 1)
+	basketService.ApplyCoupon(ctx, 1, "ONCE")
+
+	// Execute
+	err := basketService.RemoveCoupon(ctx, 1)
+	basket, _ := basketService.GetBasket(ctx, 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("RemoveCoupon() unexpected error: %v", err)
+	}
+	if basket.CouponCode != "" || len(basket.Discounts) != 0 || basket.Total != basket.Subtotal {
+		t.Errorf("GetBasket() after RemoveCoupon() = coupon %q discounts %+v total %v", basket.CouponCode, basket.Discounts, basket.Total)
+	}
+	if coupons.uses["ONCE"] != 0 {
+		t.Errorf("RemoveCoupon() left %d uses counted, want 0", coupons.uses["ONCE"])
+	}
+}
+
+func TestCouponStopsApplyingBelowMinimum(t *testing.T) {
+	// Setup: the coupon needs 100.00 TRY and the basket holds 2 keyboards
+	ctx := context.Background()
+	repo := NewMockBasketRepository()
+	coupons := NewMockCouponRepository(&model.Coupon{Code: "MIN100", Type: model.PromotionFixedAmount, Amount: tryAmount(1000), MinBasketValue: tryAmount(9000)})
+	catalog := newBasketCatalog()
+	basketService := service.NewBasketService(repo, testRates(t), catalog, coupons)
+	repo.Create(ctx, &model.Basket{UserID: 1, Items: []model.BasketItem{}})
+	basketService.AddItemToBasket(ctx, 1, 1, 2)
+	if err := basketService.ApplyCoupon(ctx, 1, "MIN100"); err != nil {
+		t.Fatalf("ApplyCoupon() unexpected error: %v", err)
+	}
+
+	// Execute
+	catalog.products[1].Price = tryAmount(3999).ToProto()
+	basket, err := basketService.GetBasket(ctx, 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("GetBasket() unexpected error: %v", err)
+	}
+	if basket.CouponCode != "MIN100" || len(basket.Discounts) != 0 || basket.Total != tryAmount(7998) {
+		t.Errorf("GetBasket() = coupon %q discounts %+v total %v, want coupon kept without discount", basket.CouponCode, basket.Discounts, basket.Total)
+	}
+}
+
+func TestCouponUsageLimit(t *testing.T) {
+	coupon := &model.Coupon{Code: "TWICE", Type: model.PromotionPercentage, Percent: 5, MaxUses: 2}
+	stores := map[string]func(t *testing.T) repository.CouponRepository{
+		"redis": func(t *testing.T) repository.CouponRepository {
+			_, client := startRedis(t)
+			return repository.NewCouponRepository(client, []*model.Coupon{coupon})
+		},
+		"memory": func(t *testing.T) repository.CouponRepository {
+			return repository.NewMemoryCouponRepository([]*model.Coupon{coupon})
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			ctx := context.Background()
+			coupons := open(t)
+
+			// Execute: two holds take both uses until one is released
+			found, _ := coupons.GetCoupon(ctx, "twice")
+			first := coupons.Reserve(ctx, coupon, "checkout:1:1", time.Minute)
+			again := coupons.Reserve(ctx, coupon, "checkout:1:1", time.Minute)
+			short := coupons.Reserve(ctx, coupon, "checkout:2:1", 50*time.Millisecond)
+			third := coupons.Reserve(ctx, coupon, "checkout:3:1", time.Minute)
+			coupons.Release(ctx, coupon, "checkout:1:1")
+			afterRelease := coupons.Reserve(ctx, coupon, "checkout:3:1", time.Minute)
+			redeemErr := coupons.Redeem(ctx, coupon, "checkout:3:1")
+			beforeExpiry := coupons.CheckAvailable(ctx, coupon)
+			time.Sleep(60 * time.Millisecond)
+			afterExpiry := coupons.CheckAvailable(ctx, coupon)
+			last := coupons.Reserve(ctx, coupon, "checkout:4:1", time.Minute)
+			overLimit := coupons.Reserve(ctx, coupon, "checkout:5:1", time.Minute)
+
+			// Assert
+			if found != coupon {
+				t.Errorf("GetCoupon() is not case insensitive")
+			}
+			for _, err := range []error{first, again, short, afterRelease, redeemErr, afterExpiry, last} {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+			if !errors.Is(third, repository.ErrCouponUsageLimit) || !errors.Is(overLimit, repository.ErrCouponUsageLimit) {
+				t.Errorf("Reserve() over limit errors = %v, %v, want %v", third, overLimit, repository.ErrCouponUsageLimit)
+			}
+			if !errors.Is(beforeExpiry, repository.ErrCouponUsageLimit) {
+				t.Errorf("CheckAvailable() with a use and a hold error = %v, want %v", beforeExpiry, repository.ErrCouponUsageLimit)
+			}
+		})
+	}
+}
+
+func TestLoadCoupons(t *testing.T) {
+	// Execute
+	coupons, err := repository.LoadCoupons("../deployments/coupons.json")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("LoadCoupons() unexpected error: %v", err)
+	}
+	if len(coupons) == 0 {
+		t.Fatal("LoadCoupons() returned no coupons")
+	}
+	for _, coupon := range coupons {
+		if coupon.Code == "" || coupon.Type == "" {
+			t.Errorf("LoadCoupons() incomplete coupon %+v", coupon)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "gomicro/api/proto"
+	"gomicro/internal/basket/service"
+)
+
+const guestToken = "guest_3f9c2a7d41b8e605"
+
+// newGuestBasketService returns a Redis backed basket service whose catalog
+// sells products 1 to 3
+func newGuestBasketService(t *testing.T) service.IBasketService {
+	t.Helper()
+	catalog := NewMockProductClient(
+		&pb.Product{Id: 1, Name: "Keyboard", Price: tryAmount(4999).ToProto(), Stock: 10, IsActive: true},
+		&pb.Product{Id: 2, Name: "Mouse", Price: tryAmount(1995).ToProto(), Stock: 10, IsActive: true},
+		&pb.Product{Id: 3, Name: "Monitor", Price: tryAmount(89900).ToProto(), Stock: 10, IsActive: true},
+	)
+	return service.NewBasketService(newRedisBasketRepository(t), testRates(t), catalog, NewMockCouponRepository())
+}
+
+func TestGuestBasket(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	basketService := newGuestBasketService(t)
+
+	// Execute
+	addErr := basketService.AddItemToGuestBasket(ctx, guestToken, 1, 2)
+	guest, getErr := basketService.GetGuestBasket(ctx, guestToken)
+	user, _ := basketService.GetBasket(ctx, 1)
+
+	// Assert
+	if addErr != nil || getErr != nil {
+		t.Fatalf("guest basket unexpected errors: add %v, get %v", addErr, getErr)
+	}
+	if guest.SessionToken != guestToken || guest.UserID != 0 {
+		t.Errorf("GetGuestBasket() owner = %q/%d, want %q/0", guest.SessionToken, guest.UserID, guestToken)
+	}
+	if len(guest.Items) != 1 || guest.Total != tryAmount(9998) {
+		t.Errorf("GetGuestBasket() items = %+v total = %v, want one keyboard line totalling %v", guest.Items, guest.Total, tryAmount(9998))
+	}
+	if len(user.Items) != 0 {
+		t.Errorf("user basket has %d items, want guest items kept apart", len(user.Items))
+	}
+
+	if err := basketService.RemoveItemFromGuestBasket(ctx, guestToken, 1); err != nil {
+		t.Fatalf("RemoveItemFromGuestBasket() unexpected error: %v", err)
+	}
+	if guest, _ = basketService.GetGuestBasket(ctx, guestToken); len(guest.Items) != 0 {
+		t.Errorf("RemoveItemFromGuestBasket() left %d items", len(guest.Items))
+	}
+}
+
+func TestGuestBasketInvalidSessionToken(t *testing.T) {
+	basketService := newGuestBasketService(t)
+
+	for _, token := range []string{"", "too-short", "basket:1:injected-key", string(make([]byte, 129))} {
+		// Execute
+		_, getErr := basketService.GetGuestBasket(context.Background(), token)
+		addErr := basketService.AddItemToGuestBasket(context.Background(), token, 1, 1)
+		_, mergeErr := basketService.MergeBaskets(context.Background(), token, 1, service.MergeSum)
+
+		// Assert
+		for _, err := range []error{getErr, addErr, mergeErr} {
+			if !errors.Is(err, service.ErrInvalidSessionToken) {
+				t.Errorf("token %q error = %v, want %v", token, err, service.ErrInvalidSessionToken)
+			}
+		}
+	}
+}
+
+func TestMergeBaskets(t *testing.T) {
+	tests := []struct {
+		name           string
+		strategy       service.MergeStrategy
+		wantErr        error
+		wantQuantities map[uint]int
+	}{
+		{
+			name:           "sum quantities",
+			strategy:       service.MergeSum,
+			wantQuantities: map[uint]int{1: 5, 2: 1, 3: 1},
+		},
+		{
+			name:           "keep max quantity",
+			strategy:       service.MergeMax,
+			wantQuantities: map[uint]int{1: 3, 2: 1, 3: 1},
+		},
+		{
+			name:           "prefer user basket",
+			strategy:       service.MergePreferUser,
+			wantQuantities: map[uint]int{1: 2, 2: 1, 3: 1},
+		},
+		{
+			name:           "unknown strategy keeps both baskets",
+			strategy:       service.MergeStrategy("min"),
+			wantErr:        service.ErrUnknownMergeStrategy,
+			wantQuantities: map[uint]int{1: 2, 2: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup: the user has 2 keyboards and a mouse, the guest 3
+			// keyboards and a monitor
+			ctx := context.Background()
+			basketService := newGuestBasketService(t)
+			basketService.AddItemToBasket(ctx, 7, 1, 2)
+			basketService.AddItemToBasket(ctx, 7, 2, 1)
+			basketService.AddItemToGuestBasket(ctx, guestToken, 1, 3)
+			basketService.AddItemToGuestBasket(ctx, guestToken, 3, 1)
+
+			// Execute
+			merged, err := basketService.MergeBaskets(ctx, guestToken, 7, tt.strategy)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MergeBaskets() error = %v, want %v", err, tt.wantErr)
+			}
+			guest, _ := basketService.GetGuestBasket(ctx, guestToken)
+			if tt.wantErr != nil {
+				if len(guest.Items) != 2 {
+					t.Errorf("guest basket has %d items after failed merge, want 2", len(guest.Items))
+				}
+				merged, _ = basketService.GetBasket(ctx, 7)
+			} else if len(guest.Items) != 0 {
+				t.Errorf("guest basket has %d items after merge, want it deleted", len(guest.Items))
+			}
+			got := make(map[uint]int, len(merged.Items))
+			for _, item := range merged.Items {
+				got[item.ProductID] = item.Quantity
+			}
+			if len(got) != len(tt.wantQuantities) {
+				t.Fatalf("merged quantities = %v, want %v", got, tt.wantQuantities)
+			}
+			for productID, quantity := range tt.wantQuantities {
+				if got[productID] != quantity {
+					t.Errorf("merged quantities = %v, want %v", got, tt.wantQuantities)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestMergeEmptyGuestBasket(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	basketService := newGuestBasketService(t)
+	basketService.AddItemToBasket(ctx, 7, 1, 2)
+
+	// Execute
+	merged, err := basketService.MergeBaskets(ctx, guestToken, 7, service.MergeSum)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("MergeBaskets() unexpected error: %v", err)
+	}
+	if len(merged.Items) != 1 || merged.Items[0].Quantity != 2 || merged.UserID != 7 {
+		t.Errorf("MergeBaskets() = %+v, want user basket unchanged", merged)
+	}
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "gomicro/api/proto"
+	"gomicro/internal/basket/model"
+	"gomicro/internal/basket/service"
+)
+
+// newQuantityBasket returns a service whose catalog has products 1 to 5 with
+// 100 units each and whose basket 1 holds 3 units of product 1
+func newQuantityBasket(t *testing.T) (*MockBasketRepository, service.IBasketService) {
+	t.Helper()
+	products := make([]*pb.Product, 5)
+	for i := range products {
+		products[i] = &pb.Product{Id: uint32(i + 1), Name: "Product", Price: tryAmount(1000).ToProto(), Stock: 100, IsActive: true}
+	}
+	products[1].Stock = 8
+	repo := NewMockBasketRepository()
+	basketService := service.NewBasketService(repo, testRates(t), NewMockProductClient(products...), NewMockCouponRepository())
+	repo.Create(context.Background(), &model.Basket{UserID: 1, Items: []model.BasketItem{}})
+	if err := basketService.AddItemToBasket(context.Background(), 1, 1, 3); err != nil {
+		t.Fatalf("AddItemToBasket() unexpected error: %v", err)
+	}
+	return repo, basketService
+}
+
+func quantities(basket *model.Basket) map[uint]int {
+	got := make(map[uint]int, len(basket.Items))
+	for _, item := range basket.Items {
+		got[item.ProductID] = item.Quantity
+	}
+	return got
+}
+
+func TestAddItemIncrementsQuantity(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID uint
+		quantity  int
+		wantErr   error
+		wantLine  int
+	}{
+		{name: "existing line is incremented", productID: 1, quantity: 2, wantLine: 5},
+		{name: "new line is created", productID: 3, quantity: 2, wantLine: 2},
+		{name: "zero quantity", productID: 1, quantity: 0, wantErr: service.ErrInvalidQuantity, wantLine: 3},
+		{name: "negative quantity", productID: 1, quantity: -1, wantErr: service.ErrInvalidQuantity, wantLine: 3},
+		{name: "line above maximum", productID: 1, quantity: service.MaxLineQuantity - 2, wantErr: service.ErrQuantityLimitExceeded, wantLine: 3},
+		{name: "request above line maximum", productID: 3, quantity: service.MaxLineQuantity + 1, wantErr: service.ErrQuantityLimitExceeded},
+		{name: "incremented line above stock", productID: 2, quantity: 9, wantErr: service.ErrInsufficientStock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo, basketService := newQuantityBasket(t)
+
+			// Execute
+			err := basketService.AddItemToBasket(context.Background(), 1, tt.productID, tt.quantity)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddItemToBasket() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := quantities(repo.baskets[1])[tt.productID]; got != tt.wantLine {
+				t.Errorf("AddItemToBasket() line quantity = %d, want %d", got, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestUpdateItemQuantity(t *testing.T) {
+	tests := []struct {
+		name           string
+		productID      uint
+		quantity       int
+		wantErr        error
+		wantQuantities map[uint]int
+	}{
+		{name: "sets absolute quantity", productID: 1, quantity: 7, wantQuantities: map[uint]int{1: 7}},
+		{name: "lowers quantity", productID: 1, quantity: 1, wantQuantities: map[uint]int{1: 1}},
+		{name: "zero removes the line", productID: 1, quantity: 0, wantQuantities: map[uint]int{}},
+		{name: "zero for a missing line is a no-op", productID: 4, quantity: 0, wantQuantities: map[uint]int{1: 3}},
+		{name: "creates a missing line", productID: 4, quantity: 2, wantQuantities: map[uint]int{1: 3, 4: 2}},
+		{name: "negative quantity", productID: 1, quantity: -1, wantErr: service.ErrInvalidQuantity, wantQuantities: map[uint]int{1: 3}},
+		{name: "above line maximum", productID: 1, quantity: service.MaxLineQuantity + 1, wantErr: service.ErrQuantityLimitExceeded, wantQuantities: map[uint]int{1: 3}},
+		{name: "line maximum", productID: 1, quantity: service.MaxLineQuantity, wantQuantities: map[uint]int{1: service.MaxLineQuantity}},
+		{name: "above stock", productID: 2, quantity: 9, wantErr: service.ErrInsufficientStock, wantQuantities: map[uint]int{1: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo, basketService := newQuantityBasket(t)
+
+			// Execute
+			err := basketService.UpdateItemQuantity(context.Background(), 1, tt.productID, tt.quantity)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateItemQuantity() error = %v, want %v", err, tt.wantErr)
+			}
+			got := quantities(repo.baskets[1])
+			if len(got) != len(tt.wantQuantities) {
+				t.Fatalf("UpdateItemQuantity() quantities = %v, want %v", got, tt.wantQuantities)
+			}
+			for productID, quantity := range tt.wantQuantities {
+				if got[productID] != quantity {
+					t.Errorf("UpdateItemQuantity() quantities = %v, want %v", got, tt.wantQuantities)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestBasketQuantityLimit(t *testing.T) {
+	// Setup: fill the basket up to the per-basket maximum
+	ctx := context.Background()
+	repo, basketService := newQuantityBasket(t)
+	for _, productID := range []uint{1, 3, 4} {
+		if err := basketService.UpdateItemQuantity(ctx, 1, productID, service.MaxLineQuantity); err != nil {
+			t.Fatalf("UpdateItemQuantity() unexpected error: %v", err)
+		}
+	}
+	if err := basketService.AddItemToBasket(ctx, 1, 5, service.MaxBasketQuantity-3*service.MaxLineQuantity); err != nil {
+		t.Fatalf("AddItemToBasket() up to the limit unexpected error: %v", err)
+	}
+
+	// Execute
+	addErr := basketService.AddItemToBasket(ctx, 1, 2, 1)
+	updateErr := basketService.UpdateItemQuantity(ctx, 1, 5, service.MaxBasketQuantity-3*service.MaxLineQuantity+1)
+	lowerErr := basketService.UpdateItemQuantity(ctx, 1, 5, 1)
+
+	// Assert
+	if !errors.Is(addErr, service.ErrQuantityLimitExceeded) {
+		t.Errorf("AddItemToBasket() over basket limit error = %v, want %v", addErr, service.ErrQuantityLimitExceeded)
+	}
+	if !errors.Is(updateErr, service.ErrQuantityLimitExceeded) {
+		t.Errorf("UpdateItemQuantity() over basket limit error = %v, want %v", updateErr, service.ErrQuantityLimitExceeded)
+	}
+	if lowerErr != nil {
+		t.Errorf("UpdateItemQuantity() lowering a line unexpected error: %v", lowerErr)
+	}
+	if got := quantities(repo.baskets[1])[5]; got != 1 {
+		t.Errorf("line quantity = %d, want 1", got)
+	}
+}
+
+func TestMergeBasketsCapsLineQuantity(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	repo, basketService := newQuantityBasket(t)
+	basketService.UpdateItemQuantity(ctx, 1, 1, service.MaxLineQuantity-1)
+	basketService.AddItemToGuestBasket(ctx, guestToken, 1, 5)
+
+	// Execute
+	_, err := basketService.MergeBaskets(ctx, guestToken, 1, service.MergeSum)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("MergeBaskets() unexpected error: %v", err)
+	}
+	if got := quantities(repo.baskets[1])[1]; got != service.MaxLineQuantity {
+		t.Errorf("merged line quantity = %d, want %d", got, service.MaxLineQuantity)
+	}
+}
@@ -0,0 +1,439 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gomicro/internal/basket/model"
+	"gomicro/internal/basket/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// basketStore opens a BasketRepository implementation for the contract tests
+// in this file. The returned expire function makes the store behave as if d
+// had passed.
+type basketStore struct {
+	name string
+	open func(t *testing.T, ttl time.Duration) (repository.BasketRepository, func(d time.Duration))
+}
+
+var basketStores = []basketStore{
+	{
+		name: "memory",
+		open: func(t *testing.T, ttl time.Duration) (repository.BasketRepository, func(d time.Duration)) {
+			return repository.NewMemoryBasketRepository(ttl), time.Sleep
+		},
+	},
+	{
+		name: "redis",
+		open: func(t *testing.T, ttl time.Duration) (repository.BasketRepository, func(d time.Duration)) {
+			server, client := startRedis(t)
+			return repository.NewBasketRepository(client, ttl), server.FastForward
+		},
+	},
+	{
+		name: "postgres",
+		open: openPostgresBasketStore,
+	},
+}
+
+// openPostgresBasketStore uses the database in BASKET_TEST_POSTGRES_DSN and
+// skips the test without one. Every test starts with an empty table.
+func openPostgresBasketStore(t *testing.T, ttl time.Duration) (repository.BasketRepository, func(d time.Duration)) {
+	dsn := os.Getenv("BASKET_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("BASKET_TEST_POSTGRES_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.AutoMigrate(&model.BasketRecord{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	if err := db.Where("1 = 1").Delete(&model.BasketRecord{}).Error; err != nil {
+		t.Fatalf("Failed to empty basket records: %v", err)
+	}
+	return repository.NewPostgresBasketRepository(db, ttl), time.Sleep
+}
+
+// testBasketStores runs test against every basket store
+func testBasketStores(t *testing.T, ttl time.Duration, test func(t *testing.T, repo repository.BasketRepository, expire func(d time.Duration))) {
+	for _, store := range basketStores {
+		t.Run(store.name, func(t *testing.T) {
+			repo, expire := store.open(t, ttl)
+			test(t, repo, expire)
+		})
+	}
+}
+
+// addLine returns a modification adding quantity units of the product
+func addLine(productID uint, quantity int) func(basket *model.Basket) error {
+	return func(basket *model.Basket) error {
+		if i := indexOfLine(basket, productID); i >= 0 {
+			basket.Items[i].Quantity += quantity
+			return nil
+		}
+		basket.Items = append(basket.Items, model.BasketItem{ProductID: productID, Quantity: quantity, Price: tryAmount(1000)})
+		return nil
+	}
+}
+
+func indexOfLine(basket *model.Basket, productID uint) int {
+	for i, item := range basket.Items {
+		if item.ProductID == productID {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestBasketStoreMissingBasket(t *testing.T) {
+	testBasketStores(t, repository.DefaultBasketTTL, func(t *testing.T, repo repository.BasketRepository, _ func(time.Duration)) {
+		// Execute
+		basket, err := repo.GetBasket(context.Background(), 1)
+		guest, guestErr := repo.GetGuestBasket(context.Background(), guestToken)
+
+		// Assert
+		if err != nil || guestErr != nil {
+			t.Fatalf("GetBasket() errors = %v, %v", err, guestErr)
+		}
+		if basket.UserID != 1 || basket.Items == nil || len(basket.Items) != 0 || basket.Version != 0 {
+			t.Errorf("GetBasket() = %+v, want empty basket of user 1 at version 0", basket)
+		}
+		if guest.SessionToken != guestToken || len(guest.Items) != 0 {
+			t.Errorf("GetGuestBasket() = %+v, want empty guest basket", guest)
+		}
+	})
+}
+
+func TestBasketStoreModifyBasket(t *testing.T) {
+	testBasketStores(t, repository.DefaultBasketTTL, func(t *testing.T, repo repository.BasketRepository, _ func(time.Duration)) {
+		// Setup
+		ctx := context.Background()
+		failure := errors.New("rejected")
+
+		// Execute
+		repo.ModifyBasket(ctx, 1, addLine(1, 2))
+		repo.ModifyBasket(ctx, 1, addLine(1, 1))
+		err := repo.ModifyBasket(ctx, 1, func(basket *model.Basket) error {
+			basket.Items = nil
+			return failure
+		})
+		repo.ModifyGuestBasket(ctx, guestToken, addLine(2, 5))
+
+		// Assert
+		if !errors.Is(err, failure) {
+			t.Errorf("ModifyBasket() error = %v, want %v", err, failure)
+		}
+		basket, _ := repo.GetBasket(ctx, 1)
+		if len(basket.Items) != 1 || basket.Items[0].Quantity != 3 || basket.Items[0].Price != tryAmount(1000) {
+			t.Errorf("GetBasket() items = %+v, want 3 units of product 1", basket.Items)
+		}
+		if basket.Version != 2 || time.Since(basket.UpdatedAt) > time.Minute {
+			t.Errorf("GetBasket() version %d updated %v, want version 2 updated now", basket.Version, basket.UpdatedAt)
+		}
+		guest, _ := repo.GetGuestBasket(ctx, guestToken)
+		if len(guest.Items) != 1 || guest.Items[0].ProductID != 2 || guest.SessionToken != guestToken {
+			t.Errorf("GetGuestBasket() = %+v, want only product 2", guest)
+		}
+	})
+}
+
+func TestBasketStoreSaveAndDeleteBasket(t *testing.T) {
+	testBasketStores(t, repository.DefaultBasketTTL, func(t *testing.T, repo repository.BasketRepository, _ func(time.Duration)) {
+		// Setup
+		ctx := context.Background()
+		repo.ModifyBasket(ctx, 2, addLine(1, 1))
+
+		// Execute
+		saveErr := repo.SaveBasket(ctx, &model.Basket{UserID: 1, Items: []model.BasketItem{{ProductID: 3, Quantity: 4}}})
+		deleteErr := repo.DeleteBasket(ctx, 2)
+
+		// Assert
+		if saveErr != nil || deleteErr != nil {
+			t.Fatalf("SaveBasket(), DeleteBasket() errors = %v, %v", saveErr, deleteErr)
+		}
+		saved, _ := repo.GetBasket(ctx, 1)
+		if len(saved.Items) != 1 || saved.Items[0].Quantity != 4 || saved.Version != 1 {
+			t.Errorf("GetBasket() after save = %+v, want 4 units at version 1", saved)
+		}
+		deleted, _ := repo.GetBasket(ctx, 2)
+		if len(deleted.Items) != 0 {
+			t.Errorf("GetBasket() after delete has %d items, want 0", len(deleted.Items))
+		}
+	})
+}
+
+func TestBasketStoreMergeGuestBasket(t *testing.T) {
+	testBasketStores(t, repository.DefaultBasketTTL, func(t *testing.T, repo repository.BasketRepository, _ func(time.Duration)) {
+		// Setup
+		ctx := context.Background()
+		repo.ModifyBasket(ctx, 1, addLine(1, 1))
+		repo.ModifyGuestBasket(ctx, guestToken, addLine(2, 3))
+
+		// Execute
+		err := repo.MergeGuestBasket(ctx, guestToken, 1, func(guest, user *model.Basket) error {
+			user.Items = append(user.Items, guest.Items...)
+			return nil
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("MergeGuestBasket() unexpected error: %v", err)
+		}
+		user, _ := repo.GetBasket(ctx, 1)
+		if got := quantities(user); len(got) != 2 || got[1] != 1 || got[2] != 3 || user.Version != 2 {
+			t.Errorf("user basket = %v at version %d, want both lines at version 2", got, user.Version)
+		}
+		guest, _ := repo.GetGuestBasket(ctx, guestToken)
+		if len(guest.Items) != 0 {
+			t.Errorf("guest basket has %d items after merge, want 0", len(guest.Items))
+		}
+	})
+}
+
+func TestBasketStoreWishlist(t *testing.T) {
+	testBasketStores(t, repository.DefaultBasketTTL, func(t *testing.T, repo repository.BasketRepository, _ func(time.Duration)) {
+		// Setup
+		ctx := context.Background()
+		repo.ModifyBasket(ctx, 1, addLine(1, 2))
+		failure := errors.New("rejected")
+
+		// Execute
+		err := repo.ModifyBasketAndWishlist(ctx, 1, func(basket *model.Basket, wishlist *model.Wishlist) error {
+			wishlist.Items = append(wishlist.Items, basket.Items...)
+			basket.Items = basket.Items[:0]
+			return nil
+		})
+		rejected := repo.ModifyBasketAndWishlist(ctx, 1, func(basket *model.Basket, wishlist *model.Wishlist) error {
+			wishlist.Items = nil
+			return failure
+		})
+
+		// Assert
+		if err != nil || !errors.Is(rejected, failure) {
+			t.Fatalf("ModifyBasketAndWishlist() errors = %v, %v", err, rejected)
+		}
+		wishlist, _ := repo.GetWishlist(ctx, 1)
+		if len(wishlist.Items) != 1 || wishlist.Items[0].Quantity != 2 || wishlist.UserID != 1 {
+			t.Errorf("GetWishlist() = %+v, want 2 units of product 1", wishlist)
+		}
+		basket, _ := repo.GetBasket(ctx, 1)
+		if len(basket.Items) != 0 || basket.Version != 2 {
+			t.Errorf("GetBasket() = %+v, want empty at version 2", basket)
+		}
+		empty, _ := repo.GetWishlist(ctx, 2)
+		if empty.UserID != 2 || empty.Items == nil || len(empty.Items) != 0 {
+			t.Errorf("GetWishlist() of user without one = %+v, want empty", empty)
+		}
+	})
+}
+
+func TestBasketStoreExpiry(t *testing.T) {
+	const ttl = 200 * time.Millisecond
+	testBasketStores(t, ttl, func(t *testing.T, repo repository.BasketRepository, expire func(time.Duration)) {
+		// Setup
+		ctx := context.Background()
+		repo.ModifyBasket(ctx, 1, addLine(1, 1))
+		repo.ModifyBasketAndWishlist(ctx, 1, func(basket *model.Basket, wishlist *model.Wishlist) error {
+			wishlist.Items = append(wishlist.Items, model.BasketItem{ProductID: 2, Quantity: 1})
+			return nil
+		})
+
+		// Execute
+		expire(ttl + 100*time.Millisecond)
+
+		// Assert
+		basket, err := repo.GetBasket(ctx, 1)
+		if err != nil {
+			t.Fatalf("GetBasket() unexpected error: %v", err)
+		}
+		if len(basket.Items) != 0 {
+			t.Errorf("GetBasket() after TTL has %d items, want 0", len(basket.Items))
+		}
+		if wishlist, _ := repo.GetWishlist(ctx, 1); len(wishlist.Items) != 1 {
+			t.Errorf("GetWishlist() after TTL has %d items, want 1", len(wishlist.Items))
+		}
+		if err := repo.ModifyBasket(ctx, 1, addLine(3, 1)); err != nil {
+			t.Fatalf("ModifyBasket() after TTL unexpected error: %v", err)
+		}
+		if basket, _ := repo.GetBasket(ctx, 1); len(basket.Items) != 1 || basket.Items[0].ProductID != 3 {
+			t.Errorf("GetBasket() after TTL and update = %+v, want only product 3", basket.Items)
+		}
+	})
+}
+
+func TestBasketStoreIdleBaskets(t *testing.T) {
+	testBasketStores(t, repository.DefaultBasketTTL, func(t *testing.T, repo repository.BasketRepository, _ func(time.Duration)) {
+		// Setup
+		ctx := context.Background()
+		repo.ModifyBasket(ctx, 1, addLine(1, 1))
+		time.Sleep(2 * time.Millisecond)
+		repo.ModifyGuestBasket(ctx, guestToken, addLine(1, 1))
+		time.Sleep(2 * time.Millisecond)
+		repo.ModifyBasket(ctx, 2, addLine(1, 1))
+		cutoff := time.Now().Add(time.Minute)
+
+		// Execute
+		idle, err := repo.IdleBaskets(ctx, cutoff, 2)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("IdleBaskets() unexpected error: %v", err)
+		}
+		if len(idle) != 2 || idle[0].UserID != 1 || idle[1].SessionToken != guestToken {
+			t.Fatalf("IdleBaskets() = %+v, want user 1 then the guest basket", idle)
+		}
+		stored, _ := repo.GetBasket(ctx, 1)
+		if idle[0].UpdatedAt.UnixMilli() != stored.UpdatedAt.UnixMilli() {
+			t.Errorf("IdleBaskets() updated at %v, want %v", idle[0].UpdatedAt, stored.UpdatedAt)
+		}
+
+		// Forgotten baskets are skipped until they are updated again
+		if err := repo.ForgetIdleBasket(ctx, idle[0]); err != nil {
+			t.Fatalf("ForgetIdleBasket() unexpected error: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+		repo.ModifyGuestBasket(ctx, guestToken, addLine(1, 1))
+		repo.ForgetIdleBasket(ctx, idle[1])
+		idle, _ = repo.IdleBaskets(ctx, cutoff, 10)
+		if len(idle) != 2 || idle[0].UserID != 2 || idle[1].SessionToken != guestToken {
+			t.Errorf("IdleBaskets() after forgetting = %+v, want user 2 then the updated guest basket", idle)
+		}
+		if early, _ := repo.IdleBaskets(ctx, time.Now().Add(-time.Minute), 10); len(early) != 0 {
+			t.Errorf("IdleBaskets() before any update = %+v, want none", early)
+		}
+	})
+}
+
+func TestBasketStoreLockCheckoutSnapshot(t *testing.T) {
+	testBasketStores(t, repository.DefaultBasketTTL, func(t *testing.T, repo repository.BasketRepository, expire func(time.Duration)) {
+		// Setup
+		ctx := context.Background()
+		repo.ModifyBasket(ctx, 1, addLine(1, 2))
+		basket, _ := repo.GetBasket(ctx, 1)
+		basket.Total = tryAmount(2000)
+
+		// Execute
+		locked, err := repo.LockCheckoutSnapshot(ctx, basket, time.Minute)
+		repriced := *basket
+		repriced.Total = tryAmount(3000)
+		relocked, relockErr := repo.LockCheckoutSnapshot(ctx, &repriced, time.Minute)
+
+		// Assert
+		if err != nil || relockErr != nil {
+			t.Fatalf("LockCheckoutSnapshot() errors = %v, %v", err, relockErr)
+		}
+		if locked.Total != tryAmount(2000) || relocked.Total != tryAmount(2000) || relocked.Version != 1 {
+			t.Errorf("LockCheckoutSnapshot() totals = %v, %v, want the first lock's 2000", locked.Total, relocked.Total)
+		}
+
+		repo.ModifyBasket(ctx, 1, addLine(1, 1))
+		if _, err := repo.LockCheckoutSnapshot(ctx, basket, time.Minute); !errors.Is(err, repository.ErrBasketChanged) {
+			t.Errorf("LockCheckoutSnapshot() of old version error = %v, want %v", err, repository.ErrBasketChanged)
+		}
+	})
+}
+
+func TestBasketStoreVersionOutlivesBasket(t *testing.T) {
+	const ttl = 200 * time.Millisecond
+	testBasketStores(t, ttl, func(t *testing.T, repo repository.BasketRepository, expire func(time.Duration)) {
+		// Setup
+		ctx := context.Background()
+		repo.ModifyBasket(ctx, 1, addLine(1, 1))
+		basket, _ := repo.GetBasket(ctx, 1)
+		if _, err := repo.LockCheckoutSnapshot(ctx, basket, time.Minute); err != nil {
+			t.Fatalf("LockCheckoutSnapshot() unexpected error: %v", err)
+		}
+
+		// lock re-adds productID to the cleared basket and locks it, which
+		// must not return the snapshot of an earlier basket
+		lock := func(productID uint, after int64) int64 {
+			t.Helper()
+			if err := repo.ModifyBasket(ctx, 1, addLine(productID, 1)); err != nil {
+				t.Fatalf("ModifyBasket() unexpected error: %v", err)
+			}
+			basket, _ := repo.GetBasket(ctx, 1)
+			if basket.Version <= after {
+				t.Fatalf("GetBasket() version = %d, want above %d", basket.Version, after)
+			}
+			locked, err := repo.LockCheckoutSnapshot(ctx, basket, time.Minute)
+			if err != nil {
+				t.Fatalf("LockCheckoutSnapshot() unexpected error: %v", err)
+			}
+			if len(locked.Items) != 1 || locked.Items[0].ProductID != productID {
+				t.Errorf("LockCheckoutSnapshot() items = %+v, want only product %d", locked.Items, productID)
+			}
+			return basket.Version
+		}
+
+		// Execute & Assert: the basket expires, then is deleted
+		expire(ttl + 100*time.Millisecond)
+		if missing, _ := repo.GetBasket(ctx, 1); missing.Version != basket.Version {
+			t.Errorf("GetBasket() after TTL version = %d, want %d", missing.Version, basket.Version)
+		}
+		version := lock(2, basket.Version)
+
+		if err := repo.DeleteBasket(ctx, 1); err != nil {
+			t.Fatalf("DeleteBasket() unexpected error: %v", err)
+		}
+		lock(3, version)
+	})
+}
+
+func TestBasketStoreLegacyPrices(t *testing.T) {
+	// Setup: a basket saved before prices were stored in minor units
+	server, client := startRedis(t)
+	repo := repository.NewBasketRepository(client, repository.DefaultBasketTTL)
+	server.Set("basket:1", `{"id":0,"user_id":1,"items":[{"product_id":7,"quantity":2,"price":19.99,"name":"Mouse"}],"total":39.98,"updated_at":"2026-01-01T00:00:00Z"}`)
+
+	// Execute
+	basket, err := repo.GetBasket(context.Background(), 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("GetBasket() unexpected error: %v", err)
+	}
+	if len(basket.Items) != 1 || basket.Items[0].Price != tryAmount(1999) || basket.Total != tryAmount(3998) {
+		t.Errorf("GetBasket() = %+v, want the legacy prices in TRY", basket)
+	}
+}
+
+func TestBasketStoreConcurrentModify(t *testing.T) {
+	testBasketStores(t, repository.DefaultBasketTTL, func(t *testing.T, repo repository.BasketRepository, _ func(time.Duration)) {
+		// Setup
+		const workers = 10
+		ctx := context.Background()
+
+		// Execute
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- repo.ModifyBasket(ctx, 1, addLine(1, 1))
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		// Assert
+		for err := range errs {
+			if err != nil {
+				t.Errorf("ModifyBasket() unexpected error: %v", err)
+			}
+		}
+		basket, _ := repo.GetBasket(ctx, 1)
+		if len(basket.Items) != 1 || basket.Items[0].Quantity != workers || basket.Version != workers {
+			t.Errorf("GetBasket() = %+v, want %d units at version %d", basket, workers, workers)
+		}
+	})
+}
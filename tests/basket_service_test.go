@@ -2,21 +2,31 @@ package tests
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	pb "gomicro/api/proto"
 	"gomicro/internal/basket/model"
+	"gomicro/internal/basket/repository"
 	"gomicro/internal/basket/service"
 )
 
 // MockBasketRepository implements repository.BasketRepository interface
 type MockBasketRepository struct {
-	baskets map[uint]*model.Basket
+	baskets   map[uint]*model.Basket
+	guests    map[string]*model.Basket
+	wishlists map[uint]*model.Wishlist
+	snapshots map[string]*model.Basket
 }
 
 func NewMockBasketRepository() *MockBasketRepository {
 	return &MockBasketRepository{
-		baskets: make(map[uint]*model.Basket),
+		baskets:   make(map[uint]*model.Basket),
+		guests:    make(map[string]*model.Basket),
+		wishlists: make(map[uint]*model.Wishlist),
+		snapshots: make(map[string]*model.Basket),
 	}
 }
 
@@ -75,6 +85,98 @@ func (m *MockBasketRepository) SaveBasket(ctx context.Context, basket *model.Bas
 	return nil
 }
 
+func (m *MockBasketRepository) GetGuestBasket(ctx context.Context, sessionToken string) (*model.Basket, error) {
+	if basket, exists := m.guests[sessionToken]; exists {
+		return basket, nil
+	}
+	return &model.Basket{SessionToken: sessionToken, Items: []model.BasketItem{}}, nil
+}
+
+func (m *MockBasketRepository) ModifyGuestBasket(ctx context.Context, sessionToken string, fn func(basket *model.Basket) error) error {
+	basket, _ := m.GetGuestBasket(ctx, sessionToken)
+	if err := fn(basket); err != nil {
+		return err
+	}
+	basket.UpdatedAt = time.Now()
+	m.guests[sessionToken] = basket
+	return nil
+}
+
+func (m *MockBasketRepository) MergeGuestBasket(ctx context.Context, sessionToken string, userID uint, fn func(guest, user *model.Basket) error) error {
+	guest, _ := m.GetGuestBasket(ctx, sessionToken)
+	if err := fn(guest, m.baskets[userID]); err != nil {
+		return err
+	}
+	delete(m.guests, sessionToken)
+	return nil
+}
+
+func (m *MockBasketRepository) GetWishlist(ctx context.Context, userID uint) (*model.Wishlist, error) {
+	if wishlist, exists := m.wishlists[userID]; exists {
+		return wishlist, nil
+	}
+	return &model.Wishlist{UserID: userID, Items: []model.BasketItem{}}, nil
+}
+
+func (m *MockBasketRepository) ModifyBasketAndWishlist(ctx context.Context, userID uint, fn func(basket *model.Basket, wishlist *model.Wishlist) error) error {
+	basket := m.baskets[userID]
+	if basket == nil {
+		basket = &model.Basket{ID: userID, UserID: userID, Items: []model.BasketItem{}}
+	}
+	wishlist, _ := m.GetWishlist(ctx, userID)
+	if err := fn(basket, wishlist); err != nil {
+		return err
+	}
+	m.baskets[userID] = basket
+	m.wishlists[userID] = wishlist
+	return nil
+}
+
+func (m *MockBasketRepository) LockCheckoutSnapshot(ctx context.Context, snapshot *model.Basket, ttl time.Duration) (*model.Basket, error) {
+	if basket, exists := m.baskets[snapshot.UserID]; exists && basket.Version != snapshot.Version {
+		return nil, repository.ErrBasketChanged
+	}
+	key := fmt.Sprintf("%d:%d", snapshot.UserID, snapshot.Version)
+	if locked, exists := m.snapshots[key]; exists {
+		return locked, nil
+	}
+	m.snapshots[key] = snapshot
+	return snapshot, nil
+}
+
+func (m *MockBasketRepository) GetCheckoutSnapshot(ctx context.Context, userID uint, version int64) (*model.Basket, error) {
+	return m.snapshots[fmt.Sprintf("%d:%d", userID, version)], nil
+}
+
+// Idle basket detection is covered against Redis in basket_abandoned_test.go
+func (m *MockBasketRepository) IdleBaskets(ctx context.Context, cutoff time.Time, limit int) ([]*model.Basket, error) {
+	return nil, nil
+}
+
+func (m *MockBasketRepository) ForgetIdleBasket(ctx context.Context, basket *model.Basket) error {
+	return nil
+}
+
+func (m *MockBasketRepository) ModifyBasket(ctx context.Context, userID uint, fn func(basket *model.Basket) error) error {
+	basket := m.baskets[userID]
+	if err := fn(basket); err != nil {
+		return err
+	}
+	if basket != nil {
+		basket.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// newBasketCatalog returns a product service with an active keyboard and an
+// inactive mouse
+func newBasketCatalog() *MockProductClient {
+	return NewMockProductClient(
+		&pb.Product{Id: 1, Name: "Keyboard", Price: tryAmount(4999).ToProto(), Stock: 10, IsActive: true},
+		&pb.Product{Id: 2, Name: "Mouse", Price: tryAmount(1995).ToProto(), Stock: 10, IsActive: false},
+	)
+}
+
 func TestCreateBasket(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -97,7 +199,7 @@ func TestCreateBasket(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup
 			repo := NewMockBasketRepository()
-			basketService := service.NewBasketService(repo)
+			basketService := service.NewBasketService(repo, testRates(t), newBasketCatalog(), NewMockCouponRepository())
 
 			// Execute
 			basket, err := basketService.CreateBasket(context.Background(), tt.userID)
@@ -134,7 +236,7 @@ func TestCreateBasket(t *testing.T) {
 func TestGetBasket(t *testing.T) {
 	// Setup
 	repo := NewMockBasketRepository()
-	basketService := service.NewBasketService(repo)
+	basketService := service.NewBasketService(repo, testRates(t), newBasketCatalog(), NewMockCouponRepository())
 
 	// Create a test basket
 	testBasket := &model.Basket{
@@ -198,7 +300,7 @@ func TestGetBasket(t *testing.T) {
 func TestAddItemToBasket(t *testing.T) {
 	// Setup
 	repo := NewMockBasketRepository()
-	basketService := service.NewBasketService(repo)
+	basketService := service.NewBasketService(repo, testRates(t), newBasketCatalog(), NewMockCouponRepository())
 
 	// Create a test basket
 	testBasket := &model.Basket{
@@ -282,6 +384,9 @@ func TestAddItemToBasket(t *testing.T) {
 					if item.Quantity != tt.quantity {
 						t.Errorf("AddItemToBasket() quantity = %v, want %v", item.Quantity, tt.quantity)
 					}
+					if item.Name != "Keyboard" || item.Price != tryAmount(4999) {
+						t.Errorf("AddItemToBasket() snapshot = %q %v, want Keyboard %v", item.Name, item.Price, tryAmount(4999))
+					}
 					break
 				}
 			}
@@ -296,7 +401,7 @@ func TestAddItemToBasket(t *testing.T) {
 func TestRemoveItemFromBasket(t *testing.T) {
 	// Setup
 	repo := NewMockBasketRepository()
-	basketService := service.NewBasketService(repo)
+	basketService := service.NewBasketService(repo, testRates(t), newBasketCatalog(), NewMockCouponRepository())
 
 	// Create a test basket with an item
 	testBasket := &model.Basket{
@@ -377,4 +482,114 @@ func TestRemoveItemFromBasket(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+}
+
+func TestAddItemToBasketProductErrors(t *testing.T) {
+	// Setup
+	repo := NewMockBasketRepository()
+	catalog := newBasketCatalog()
+	basketService := service.NewBasketService(repo, testRates(t), catalog, NewMockCouponRepository())
+	repo.Create(context.Background(), &model.Basket{UserID: 1, Items: []model.BasketItem{}})
+
+	// Execute
+	missingErr := basketService.AddItemToBasket(context.Background(), 1, 99, 1)
+	inactiveErr := basketService.AddItemToBasket(context.Background(), 1, 2, 1)
+	catalog.products[1].ReservedStock = 8
+	stockErr := basketService.AddItemToBasket(context.Background(), 1, 1, 3)
+
+	// Assert
+	if !errors.Is(missingErr, service.ErrProductNotFound) {
+		t.Errorf("AddItemToBasket() missing product error = %v, want %v", missingErr, service.ErrProductNotFound)
+	}
+	if !errors.Is(inactiveErr, service.ErrProductInactive) {
+		t.Errorf("AddItemToBasket() inactive product error = %v, want %v", inactiveErr, service.ErrProductInactive)
+	}
+	if !errors.Is(stockErr, service.ErrInsufficientStock) {
+		t.Errorf("AddItemToBasket() reserved stock error = %v, want %v", stockErr, service.ErrInsufficientStock)
+	}
+}
+
+func TestGetBasketRefreshesItems(t *testing.T) {
+	tests := []struct {
+		name             string
+		product          *pb.Product
+		productErr       error
+		wantTotal        int64
+		wantPriceChanged bool
+		wantOutOfStock   bool
+		wantUnavailable  bool
+	}{
+		{
+			name:      "unchanged",
+			product:   &pb.Product{Id: 1, Price: tryAmount(1000).ToProto(), Stock: 10, IsActive: true},
+			wantTotal: 3500,
+		},
+		{
+			name:             "price changed",
+			product:          &pb.Product{Id: 1, Price: tryAmount(1200).ToProto(), Stock: 10, IsActive: true},
+			wantTotal:        4100,
+			wantPriceChanged: true,
+		},
+		{
+			name:           "out of stock",
+			product:        &pb.Product{Id: 1, Price: tryAmount(1000).ToProto(), Stock: 2, IsActive: true},
+			wantTotal:      3500,
+			wantOutOfStock: true,
+		},
+		{
+			name:            "deactivated",
+			product:         &pb.Product{Id: 1, Price: tryAmount(1000).ToProto(), Stock: 10, IsActive: false},
+			wantTotal:       500,
+			wantUnavailable: true,
+		},
+		{
+			name:            "deleted",
+			wantTotal:       500,
+			wantUnavailable: true,
+		},
+		{
+			name:       "product service unavailable",
+			productErr: errors.New("unavailable"),
+			wantTotal:  3500,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup: 3 x product 1 at 10.00 and 1 x product 2 at 5.00
+			repo := NewMockBasketRepository()
+			catalog := NewMockProductClient(&pb.Product{Id: 2, Price: tryAmount(500).ToProto(), Stock: 10, IsActive: true})
+			if tt.product != nil {
+				catalog.products[1] = tt.product
+			}
+			catalog.err = tt.productErr
+			basketService := service.NewBasketService(repo, testRates(t), catalog, NewMockCouponRepository())
+			repo.Create(context.Background(), &model.Basket{UserID: 1, Items: []model.BasketItem{
+				{ProductID: 1, Quantity: 3, Price: tryAmount(1000), Name: "Keyboard"},
+				{ProductID: 2, Quantity: 1, Price: tryAmount(500), Name: "Cable"},
+			}})
+
+			// Execute
+			basket, err := basketService.GetBasket(context.Background(), 1)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("GetBasket() unexpected error: %v", err)
+			}
+			if basket.Total != tryAmount(tt.wantTotal) {
+				t.Errorf("GetBasket() total = %v, want %v", basket.Total, tryAmount(tt.wantTotal))
+			}
+			item := basket.Items[0]
+			if item.PriceChanged != tt.wantPriceChanged || item.OutOfStock != tt.wantOutOfStock || item.Unavailable != tt.wantUnavailable {
+				t.Errorf("GetBasket() flags = changed:%v out_of_stock:%v unavailable:%v, want %v %v %v",
+					item.PriceChanged, item.OutOfStock, item.Unavailable, tt.wantPriceChanged, tt.wantOutOfStock, tt.wantUnavailable)
+			}
+			if item.Price != tryAmount(1000) {
+				t.Errorf("GetBasket() changed the snapshot price to %v", item.Price)
+			}
+			if basket.Items[1].PriceChanged || basket.Items[1].OutOfStock || basket.Items[1].Unavailable {
+				t.Errorf("GetBasket() flagged unchanged item %+v", basket.Items[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "gomicro/api/proto"
+	"gomicro/internal/basket/repository"
+	"gomicro/internal/basket/service"
+)
+
+// newWishlistBasket returns a Redis backed service whose user 1 has 2
+// keyboards and 1 mouse in the basket
+func newWishlistBasket(t *testing.T) (*MockProductClient, service.IBasketService) {
+	t.Helper()
+	_, client := startRedis(t)
+	catalog := NewMockProductClient(
+		&pb.Product{Id: 1, Name: "Keyboard", Price: tryAmount(4999).ToProto(), Stock: 10, IsActive: true},
+		&pb.Product{Id: 2, Name: "Mouse", Price: tryAmount(1995).ToProto(), Stock: 10, IsActive: true},
+	)
+	repo := repository.NewBasketRepository(client, repository.DefaultBasketTTL)
+	basketService := service.NewBasketService(repo, testRates(t), catalog, NewMockCouponRepository())
+	basketService.AddItemToBasket(context.Background(), 1, 1, 2)
+	basketService.AddItemToBasket(context.Background(), 1, 2, 1)
+	return catalog, basketService
+}
+
+func TestMoveToWishlist(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	_, basketService := newWishlistBasket(t)
+
+	// Execute
+	err := basketService.MoveToWishlist(ctx, 1, 1)
+	missingErr := basketService.MoveToWishlist(ctx, 1, 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("MoveToWishlist() unexpected error: %v", err)
+	}
+	if !errors.Is(missingErr, service.ErrItemNotInBasket) {
+		t.Errorf("MoveToWishlist() twice error = %v, want %v", missingErr, service.ErrItemNotInBasket)
+	}
+	basket, _ := basketService.GetBasket(ctx, 1)
+	if got := quantities(basket); len(got) != 1 || got[2] != 1 || basket.Total != tryAmount(1995) {
+		t.Errorf("basket after move = %v total %v, want only the mouse", got, basket.Total)
+	}
+	wishlist, _ := basketService.GetWishlist(ctx, 1)
+	if len(wishlist.Items) != 1 || wishlist.Items[0].ProductID != 1 || wishlist.Items[0].Quantity != 2 || wishlist.Items[0].Name != "Keyboard" {
+		t.Errorf("GetWishlist() = %+v, want 2 keyboards", wishlist.Items)
+	}
+}
+
+func TestWishlistOutlivesBasket(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	server, client := startRedis(t)
+	repo := repository.NewBasketRepository(client, repository.DefaultBasketTTL)
+	basketService := service.NewBasketService(repo, testRates(t), newBasketCatalog(), NewMockCouponRepository())
+	basketService.AddItemToBasket(ctx, 1, 1, 1)
+	basketService.MoveToWishlist(ctx, 1, 1)
+
+	// Execute
+	server.FastForward(2 * repository.DefaultBasketTTL)
+	wishlist, err := basketService.GetWishlist(ctx, 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("GetWishlist() unexpected error: %v", err)
+	}
+	if len(wishlist.Items) != 1 {
+		t.Errorf("GetWishlist() after basket expiry has %d items, want 1", len(wishlist.Items))
+	}
+}
+
+func TestGetWishlistRefreshesItems(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	catalog, basketService := newWishlistBasket(t)
+	basketService.MoveToWishlist(ctx, 1, 1)
+	basketService.MoveToWishlist(ctx, 1, 2)
+	catalog.products[1].Price = tryAmount(3999).ToProto()
+	catalog.products[1].Stock = 1
+	catalog.products[2].IsActive = false
+
+	// Execute
+	wishlist, err := basketService.GetWishlist(ctx, 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("GetWishlist() unexpected error: %v", err)
+	}
+	if len(wishlist.Items) != 2 {
+		t.Fatalf("GetWishlist() has %d items, want 2", len(wishlist.Items))
+	}
+	keyboard, mouse := wishlist.Items[0], wishlist.Items[1]
+	if !keyboard.PriceChanged || keyboard.CurrentPrice != tryAmount(3999) || keyboard.Price != tryAmount(4999) || !keyboard.OutOfStock {
+		t.Errorf("GetWishlist() keyboard = %+v, want new price and out of stock", keyboard)
+	}
+	if !mouse.Unavailable {
+		t.Errorf("GetWishlist() mouse = %+v, want unavailable", mouse)
+	}
+}
+
+func TestMoveToBasket(t *testing.T) {
+	tests := []struct {
+		name           string
+		prepare        func(catalog *MockProductClient, basketService service.IBasketService)
+		productID      uint
+		wantErr        error
+		wantQuantities map[uint]int
+		wantSaved      int
+	}{
+		{
+			name:           "adds the saved quantity to the basket line",
+			prepare:        func(*MockProductClient, service.IBasketService) {},
+			productID:      1,
+			wantQuantities: map[uint]int{1: 3, 2: 1},
+		},
+		{
+			name:           "product not saved",
+			prepare:        func(*MockProductClient, service.IBasketService) {},
+			productID:      2,
+			wantErr:        service.ErrItemNotInWishlist,
+			wantQuantities: map[uint]int{1: 1, 2: 1},
+			wantSaved:      1,
+		},
+		{
+			name: "product no longer sold",
+			prepare: func(catalog *MockProductClient, _ service.IBasketService) {
+				catalog.products[1].IsActive = false
+			},
+			productID:      1,
+			wantErr:        service.ErrProductInactive,
+			wantQuantities: map[uint]int{1: 1, 2: 1},
+			wantSaved:      1,
+		},
+		{
+			name: "not enough stock",
+			prepare: func(catalog *MockProductClient, _ service.IBasketService) {
+				catalog.products[1].Stock = 2
+			},
+			productID:      1,
+			wantErr:        service.ErrInsufficientStock,
+			wantQuantities: map[uint]int{1: 1, 2: 1},
+			wantSaved:      1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup: 2 keyboards are saved and 1 keyboard is in the basket again
+			ctx := context.Background()
+			catalog, basketService := newWishlistBasket(t)
+			basketService.MoveToWishlist(ctx, 1, 1)
+			basketService.AddItemToBasket(ctx, 1, 1, 1)
+			tt.prepare(catalog, basketService)
+
+			// Execute
+			err := basketService.MoveToBasket(ctx, 1, tt.productID)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MoveToBasket() error = %v, want %v", err, tt.wantErr)
+			}
+			catalog.products[1].IsActive = true
+			basket, _ := basketService.GetBasket(ctx, 1)
+			got := quantities(basket)
+			if len(got) != len(tt.wantQuantities) || got[1] != tt.wantQuantities[1] || got[2] != tt.wantQuantities[2] {
+				t.Errorf("basket quantities = %v, want %v", got, tt.wantQuantities)
+			}
+			wishlist, _ := basketService.GetWishlist(ctx, 1)
+			if len(wishlist.Items) != tt.wantSaved {
+				t.Errorf("wishlist has %d items, want %d", len(wishlist.Items), tt.wantSaved)
+			}
+		})
+	}
+}
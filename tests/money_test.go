@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"gomicro/internal/money"
+)
+
+func TestMoneyFromMajor(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		currency string
+		want     int64
+		wantStr  string
+		wantErr  error
+	}{
+		{name: "two decimals", amount: 12.5, currency: "TRY", want: 1250, wantStr: "12.50 TRY"},
+		{name: "rounds half away from zero", amount: 0.125, currency: "EUR", want: 13, wantStr: "0.13 EUR"},
+		{name: "float noise", amount: 0.1 + 0.2, currency: "USD", want: 30, wantStr: "0.30 USD"},
+		{name: "zero decimals", amount: 1234.5, currency: "JPY", want: 1235, wantStr: "1235 JPY"},
+		{name: "three decimals", amount: 1.2345, currency: "BHD", want: 1235, wantStr: "1.235 BHD"},
+		{name: "negative", amount: -0.05, currency: "TRY", want: -5, wantStr: "-0.05 TRY"},
+		{name: "unknown currency", amount: 1, currency: "XYZ", wantErr: money.ErrUnknownCurrency},
+		{name: "lowercase code", amount: 1, currency: "try", wantErr: money.ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			m, err := money.FromMajor(tt.amount, tt.currency)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FromMajor() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if m.MinorUnits != tt.want {
+				t.Errorf("FromMajor() minor units = %d, want %d", m.MinorUnits, tt.want)
+			}
+			if m.String() != tt.wantStr {
+				t.Errorf("String() = %q, want %q", m.String(), tt.wantStr)
+			}
+		})
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    money.Money
+		wantErr bool
+	}{
+		{name: "object", data: `{"minor_units":1250,"currency":"EUR"}`, want: money.Money{MinorUnits: 1250, Currency: "EUR"}},
+		{name: "legacy amount", data: `12.5`, want: money.Money{MinorUnits: 1250, Currency: "TRY"}},
+		{name: "negative legacy amount", data: `-0.05`, want: money.Money{MinorUnits: -5, Currency: "TRY"}},
+		{name: "null", data: `null`},
+		{name: "string", data: `"12.50"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			var m money.Money
+			err := json.Unmarshal([]byte(tt.data), &m)
+
+			// Assert
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, want error %v", tt.data, err, tt.wantErr)
+			}
+			if m != tt.want {
+				t.Errorf("Unmarshal(%s) = %+v, want %+v", tt.data, m, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	price := tryAmount(1999)
+
+	total, err := price.Mul(3).Add(tryAmount(1))
+	if err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	if total != tryAmount(5998) {
+		t.Errorf("Add() = %v, want %v", total, tryAmount(5998))
+	}
+
+	if _, err := total.Sub(money.Money{MinorUnits: 1, Currency: "EUR"}); !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Errorf("Sub() error = %v, want %v", err, money.ErrCurrencyMismatch)
+	}
+	if _, err := money.New(100, ""); !errors.Is(err, money.ErrUnknownCurrency) {
+		t.Errorf("New() error = %v, want %v", err, money.ErrUnknownCurrency)
+	}
+}
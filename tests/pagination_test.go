@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"gomicro/internal/pagination"
+)
+
+func TestPaginationFetch(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	list := func(after int) func(limit int) ([]int, error) {
+		return func(limit int) ([]int, error) {
+			var page []int
+			for _, item := range items {
+				if item > after && len(page) < limit {
+					page = append(page, item)
+				}
+			}
+			return page, nil
+		}
+	}
+	cursor := func(last int) interface{} { return struct{ After int }{last} }
+
+	// Execute: page two items at a time, following the tokens
+	var seen []int
+	after := 0
+	for page := 0; ; page++ {
+		if page > len(items) {
+			t.Fatal("Fetch() did not terminate")
+		}
+		result, token, err := pagination.Fetch(2, list(after), cursor)
+		if err != nil {
+			t.Fatalf("Fetch() unexpected error: %v", err)
+		}
+		seen = append(seen, result...)
+		if token == "" {
+			break
+		}
+		var next struct{ After int }
+		if err := pagination.DecodeToken(token, &next); err != nil {
+			t.Fatalf("DecodeToken() unexpected error: %v", err)
+		}
+		after = next.After
+	}
+
+	// Assert
+	if len(seen) != len(items) {
+		t.Fatalf("Fetch() paged through %v, want %v", seen, items)
+	}
+	for i := range items {
+		if seen[i] != items[i] {
+			t.Fatalf("Fetch() paged through %v, want %v", seen, items)
+		}
+	}
+}
+
+func TestPaginationToken(t *testing.T) {
+	var cursor struct{ ID uint }
+	for _, token := range []string{"not a token!", "bm90LWpzb24"} {
+		if err := pagination.DecodeToken(token, &cursor); !errors.Is(err, pagination.ErrInvalidToken) {
+			t.Errorf("DecodeToken(%q) error = %v, want %v", token, err, pagination.ErrInvalidToken)
+		}
+	}
+
+	for requested, want := range map[int]int{0: pagination.DefaultSize, -1: pagination.DefaultSize, 7: 7, 500: pagination.MaxSize} {
+		if got := pagination.Size(requested); got != want {
+			t.Errorf("Size(%d) = %d, want %d", requested, got, want)
+		}
+	}
+}
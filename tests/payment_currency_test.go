@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gomicro/internal/basket/model"
+	"gomicro/internal/basket/service"
+	"gomicro/internal/money"
+	paymentservice "gomicro/internal/payment/service"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	rates := testRates(t)
+
+	tests := []struct {
+		name    string
+		amount  money.Money
+		to      string
+		want    money.Money
+		wantErr error
+	}{
+		{name: "same currency", amount: tryAmount(1234), to: "TRY", want: tryAmount(1234)},
+		{name: "from base", amount: tryAmount(10000), to: "EUR", want: money.Money{MinorUnits: 250, Currency: "EUR"}},
+		{name: "to base", amount: money.Money{MinorUnits: 250, Currency: "EUR"}, to: "TRY", want: tryAmount(10000)},
+		{name: "cross rate", amount: money.Money{MinorUnits: 300, Currency: "USD"}, to: "EUR", want: money.Money{MinorUnits: 250, Currency: "EUR"}},
+		{name: "zero decimal target", amount: tryAmount(1050), to: "JPY", want: money.Money{MinorUnits: 42, Currency: "JPY"}},
+		{name: "zero decimal source", amount: money.Money{MinorUnits: 42, Currency: "JPY"}, to: "TRY", want: tryAmount(1050)},
+		{name: "no rate", amount: tryAmount(100), to: "GBP", wantErr: money.ErrRateUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			got, _, err := money.Exchange(context.Background(), rates, tt.amount, tt.to)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Exchange() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Exchange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessPaymentSettlement(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      money.Money
+		wantErr     error
+		wantSettled money.Money
+		wantRate    float64
+	}{
+		{
+			name:        "base currency",
+			amount:      tryAmount(5000),
+			wantSettled: tryAmount(5000),
+			wantRate:    1,
+		},
+		{
+			name:        "foreign currency",
+			amount:      money.Money{MinorUnits: 1000, Currency: "EUR"},
+			wantSettled: tryAmount(40000),
+			wantRate:    40,
+		},
+		{
+			name:    "currency without a rate",
+			amount:  money.Money{MinorUnits: 1000, Currency: "GBP"},
+			wantErr: money.ErrRateUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo := NewMockPaymentRepository()
+			paymentService := paymentservice.NewPaymentService(repo, paymentservice.NewSimulatedProvider(), NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
+
+			// Execute
+			payment, err := paymentService.ProcessPayment(context.Background(), 1, tt.amount, "credit_card", "")
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ProcessPayment() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(repo.payments) != 0 {
+					t.Errorf("ProcessPayment() created %d payments, want 0", len(repo.payments))
+				}
+				return
+			}
+			if payment.Amount != tt.amount {
+				t.Errorf("ProcessPayment() charged = %v, want %v", payment.Amount, tt.amount)
+			}
+			if payment.SettledAmount != tt.wantSettled {
+				t.Errorf("ProcessPayment() settled = %v, want %v", payment.SettledAmount, tt.wantSettled)
+			}
+			if payment.ExchangeRate != tt.wantRate {
+				t.Errorf("ProcessPayment() rate = %v, want %v", payment.ExchangeRate, tt.wantRate)
+			}
+		})
+	}
+}
+
+func TestBasketConvertTotal(t *testing.T) {
+	basketService := service.NewBasketService(NewMockBasketRepository(), testRates(t), NewMockProductClient(), NewMockCouponRepository())
+
+	tests := []struct {
+		name     string
+		total    money.Money
+		currency string
+		want     money.Money
+		wantErr  error
+	}{
+		{name: "converted", total: tryAmount(20000), currency: "USD", want: money.Money{MinorUnits: 600, Currency: "USD"}},
+		{name: "same currency", total: tryAmount(20000), currency: "TRY", want: tryAmount(20000)},
+		{name: "empty basket", currency: "EUR", want: money.Money{Currency: "EUR"}},
+		{name: "unknown currency", total: tryAmount(20000), currency: "XYZ", wantErr: money.ErrUnknownCurrency},
+		{name: "no rate", total: tryAmount(20000), currency: "GBP", wantErr: money.ErrRateUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			got, _, err := basketService.ConvertTotal(context.Background(), &model.Basket{UserID: 1, Total: tt.total}, tt.currency)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConvertTotal() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ConvertTotal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "gomicro/api/proto"
+	"gomicro/internal/money"
+	"gomicro/internal/payment/handler"
+	"gomicro/internal/payment/model"
+	"gomicro/internal/payment/repository"
+	"gomicro/internal/payment/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// seedPayments creates payments one minute apart, oldest first
+func seedPayments(repo *MockPaymentRepository, base time.Time, payments ...*model.Payment) {
+	for i, p := range payments {
+		repo.Create(context.Background(), p)
+		p.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+	}
+}
+
+func TestListPayments(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := NewMockPaymentRepository()
+	seedPayments(repo, base,
+		&model.Payment{UserID: 1, Amount: tryAmount(1000), Status: model.StatusCaptured},
+		&model.Payment{UserID: 1, Amount: money.Money{MinorUnits: 2000, Currency: "EUR"}, Status: model.StatusFailed},
+		&model.Payment{UserID: 2, Amount: tryAmount(3000), Status: model.StatusCaptured},
+		&model.Payment{UserID: 1, Amount: tryAmount(4000), Status: model.StatusRefunded},
+		&model.Payment{UserID: 1, Amount: tryAmount(5000), Status: model.StatusCaptured},
+	)
+	paymentService := service.NewPaymentService(repo, service.NewSimulatedProvider(), NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
+
+	tests := []struct {
+		name   string
+		filter repository.PaymentFilter
+		wantID []uint
+	}{
+		{
+			name:   "by user newest first",
+			filter: repository.PaymentFilter{UserID: 1},
+			wantID: []uint{5, 4, 2, 1},
+		},
+		{
+			name:   "by status",
+			filter: repository.PaymentFilter{Status: model.StatusCaptured},
+			wantID: []uint{5, 3, 1},
+		},
+		{
+			name:   "by currency",
+			filter: repository.PaymentFilter{Currency: "EUR"},
+			wantID: []uint{2},
+		},
+		{
+			name:   "by created range",
+			filter: repository.PaymentFilter{CreatedFrom: base.Add(time.Minute), CreatedTo: base.Add(3 * time.Minute)},
+			wantID: []uint{3, 2},
+		},
+		{
+			name:   "by amount range",
+			filter: repository.PaymentFilter{UserID: 1, MinAmount: 1500, MaxAmount: 4000},
+			wantID: []uint{4, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments, next, err := paymentService.ListPayments(context.Background(), tt.filter, 0, "")
+			if err != nil {
+				t.Fatalf("ListPayments() unexpected error: %v", err)
+			}
+			if next != "" {
+				t.Errorf("ListPayments() next page token = %q, want empty", next)
+			}
+			if len(payments) != len(tt.wantID) {
+				t.Fatalf("ListPayments() returned %d payments, want %d", len(payments), len(tt.wantID))
+			}
+			for i, p := range payments {
+				if p.ID != tt.wantID[i] {
+					t.Errorf("ListPayments()[%d] ID = %d, want %d", i, p.ID, tt.wantID[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListPaymentsPagination(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := NewMockPaymentRepository()
+	var payments []*model.Payment
+	for i := 0; i < 7; i++ {
+		payments = append(payments, &model.Payment{UserID: 1, Amount: tryAmount(1000), Status: model.StatusCaptured})
+	}
+	seedPayments(repo, base, payments...)
+	paymentService := service.NewPaymentService(repo, service.NewSimulatedProvider(), NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
+
+	var seen []uint
+	token := ""
+	for page := 0; ; page++ {
+		if page > 5 {
+			t.Fatal("ListPayments() did not terminate")
+		}
+		result, next, err := paymentService.ListPayments(context.Background(), repository.PaymentFilter{UserID: 1}, 3, token)
+		if err != nil {
+			t.Fatalf("ListPayments() unexpected error: %v", err)
+		}
+		for _, p := range result {
+			seen = append(seen, p.ID)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	want := []uint{7, 6, 5, 4, 3, 2, 1}
+	if len(seen) != len(want) {
+		t.Fatalf("ListPayments() paged through %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("ListPayments() paged through %v, want %v", seen, want)
+		}
+	}
+
+	if _, _, err := paymentService.ListPayments(context.Background(), repository.PaymentFilter{}, 3, "not-a-token"); !errors.Is(err, service.ErrInvalidPageToken) {
+		t.Errorf("ListPayments() invalid token error = %v, want %v", err, service.ErrInvalidPageToken)
+	}
+}
+
+func TestListPaymentsAmountRequiresCurrency(t *testing.T) {
+	repo := NewMockPaymentRepository()
+	seedPayments(repo, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		&model.Payment{UserID: 1, Amount: money.Money{MinorUnits: 1000, Currency: "JPY"}, Status: model.StatusCaptured},
+		&model.Payment{UserID: 1, Amount: money.Money{MinorUnits: 900, Currency: "USD"}, Status: model.StatusCaptured},
+	)
+	paymentService := service.NewPaymentService(repo, service.NewSimulatedProvider(), NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
+	paymentHandler := handler.NewPaymentHandler(paymentService)
+
+	tests := []struct {
+		name     string
+		req      *pb.ListPaymentsRequest
+		wantCode codes.Code
+		wantID   []uint
+	}{
+		{name: "min amount without currency", req: &pb.ListPaymentsRequest{MinAmount: 950}, wantCode: codes.InvalidArgument},
+		{name: "max amount without currency", req: &pb.ListPaymentsRequest{MaxAmount: 950}, wantCode: codes.InvalidArgument},
+		{name: "amount with currency", req: &pb.ListPaymentsRequest{Currency: "USD", MaxAmount: 950}, wantCode: codes.OK, wantID: []uint{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := paymentHandler.ListPayments(context.Background(), tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("ListPayments() error = %v, want code %v", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if len(resp.Payments) != len(tt.wantID) || resp.Payments[0].PaymentId != uint32(tt.wantID[0]) {
+				t.Errorf("ListPayments() returned %v, want IDs %v", resp.Payments, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"os"
+	"testing"
+
+	"gomicro/internal/payment/model"
+	"gomicro/internal/payment/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestMigrateLegacyAmounts runs against the database in
+// PAYMENT_TEST_POSTGRES_DSN and recreates the payment tables as they were
+// before amounts were stored in minor units
+func TestMigrateLegacyAmounts(t *testing.T) {
+	dsn := os.Getenv("PAYMENT_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("PAYMENT_TEST_POSTGRES_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Setup
+	legacy := []string{
+		"DROP TABLE IF EXISTS refund_items, refunds, payment_items, payment_events, outbox_events, payments",
+		"CREATE TABLE payments (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, user_id bigint NOT NULL, amount double precision NOT NULL, currency text NOT NULL, status text NOT NULL, payment_method text NOT NULL, refunded_amount double precision NOT NULL DEFAULT 0)",
+		"CREATE TABLE payment_items (id bigserial PRIMARY KEY, payment_id bigint NOT NULL, product_id bigint NOT NULL, quantity bigint NOT NULL, unit_price double precision NOT NULL)",
+		"CREATE TABLE refunds (id bigserial PRIMARY KEY, created_at timestamptz, payment_id bigint NOT NULL, amount double precision NOT NULL, reason text, provider_ref text)",
+		"INSERT INTO payments (user_id, amount, currency, status, payment_method, refunded_amount) VALUES (1, 59.97, 'TRY', 'refunded', 'card', 19.99), (2, 1500, 'JPY', 'completed', 'card', 0)",
+		"INSERT INTO payment_items (payment_id, product_id, quantity, unit_price) VALUES (1, 1, 3, 19.99), (2, 2, 1, 1500)",
+		"INSERT INTO refunds (payment_id, amount, reason) VALUES (1, 19.99, 'damaged')",
+	}
+	for _, sql := range legacy {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatalf("Failed to create legacy schema: %v", err)
+		}
+	}
+
+	// Execute: migrating twice must be a no-op the second time
+	for i := 0; i < 2; i++ {
+		if err := repository.MigrateLegacyAmounts(db); err != nil {
+			t.Fatalf("MigrateLegacyAmounts() unexpected error: %v", err)
+		}
+	}
+	if err := db.AutoMigrate(&model.Payment{}, &model.PaymentItem{}, &model.Refund{}, &model.RefundItem{}, &model.PaymentEvent{}, &model.OutboxEvent{}); err != nil {
+		t.Fatalf("AutoMigrate() after the legacy migration error: %v", err)
+	}
+
+	// Assert
+	var payments []model.Payment
+	if err := db.Preload("Items").Preload("Refunds").Order("id").Find(&payments).Error; err != nil {
+		t.Fatalf("Failed to load payments: %v", err)
+	}
+	if len(payments) != 2 {
+		t.Fatalf("migrated %d payments, want 2", len(payments))
+	}
+	try, jpy := payments[0], payments[1]
+	if try.Amount != tryAmount(5997) || try.SettledAmount != tryAmount(5997) || try.RefundedAmount != tryAmount(1999) {
+		t.Errorf("TRY payment amounts = %v, %v, %v; want 59.97, 59.97, 19.99 TRY", try.Amount, try.SettledAmount, try.RefundedAmount)
+	}
+	if try.Items[0].UnitPrice != tryAmount(1999) || try.Refunds[0].Amount != tryAmount(1999) || try.Refunds[0].Status != model.RefundSucceeded {
+		t.Errorf("TRY payment item %v and refund %+v, want 19.99 TRY and a succeeded refund", try.Items[0].UnitPrice, try.Refunds[0])
+	}
+	if jpy.Amount.MinorUnits != 1500 || jpy.Amount.Currency != "JPY" || jpy.Items[0].UnitPrice.MinorUnits != 1500 {
+		t.Errorf("JPY payment amount %v and item %v, want 1500 JPY", jpy.Amount, jpy.Items[0].UnitPrice)
+	}
+	for _, column := range []string{"amount", "currency", "refunded_amount"} {
+		if db.Migrator().HasColumn("payments", column) {
+			t.Errorf("payments.%s was not dropped", column)
+		}
+	}
+}
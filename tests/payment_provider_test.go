@@ -0,0 +1,322 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	pb "gomicro/api/proto"
+	"gomicro/internal/money"
+	"gomicro/internal/payment/model"
+	"gomicro/internal/payment/service"
+)
+
+// CaptureFailingProvider approves authorizations but declines every capture
+type CaptureFailingProvider struct {
+	*service.SimulatedProvider
+}
+
+func (p *CaptureFailingProvider) Capture(ctx context.Context, reference string, amount money.Money) (*service.ProviderResult, error) {
+	return &service.ProviderResult{Status: service.ProviderDeclined, Reference: reference}, nil
+}
+
+func TestProcessPaymentProviderOutcomes(t *testing.T) {
+	rules := append(service.DefaultSimulatorRules(),
+		service.SimulatorRule{Amount: tryAmount(1337), Outcome: service.SimulateDecline},
+		service.SimulatorRule{CardNumber: "4242424242424242", Amount: tryAmount(50000), Outcome: service.SimulateRequire3DS},
+	)
+
+	tests := []struct {
+		name          string
+		provider      service.PaymentProvider
+		amount        int64
+		paymentMethod string
+		wantStatus    string
+		wantReason    bool
+		wantNextURL   bool
+	}{
+		{
+			name:          "approved",
+			provider:      service.NewSimulatedProvider(rules...),
+			amount:        10000,
+			paymentMethod: "4242424242424242",
+			wantStatus:    model.StatusCaptured,
+		},
+		{
+			name:          "declined by card number",
+			provider:      service.NewSimulatedProvider(rules...),
+			amount:        10000,
+			paymentMethod: "4000000000000002",
+			wantStatus:    model.StatusFailed,
+			wantReason:    true,
+		},
+		{
+			name:          "declined by amount",
+			provider:      service.NewSimulatedProvider(rules...),
+			amount:        1337,
+			paymentMethod: "4242424242424242",
+			wantStatus:    model.StatusFailed,
+			wantReason:    true,
+		},
+		{
+			name:          "provider timeout",
+			provider:      service.NewSimulatedProvider(rules...),
+			amount:        10000,
+			paymentMethod: "4000000000000119",
+			wantStatus:    model.StatusFailed,
+			wantReason:    true,
+		},
+		{
+			name:          "3-D Secure by card number",
+			provider:      service.NewSimulatedProvider(rules...),
+			amount:        10000,
+			paymentMethod: "4000000000003220",
+			wantStatus:    model.StatusRequiresAction,
+			wantReason:    true,
+			wantNextURL:   true,
+		},
+		{
+			name:          "3-D Secure by card number and amount",
+			provider:      service.NewSimulatedProvider(rules...),
+			amount:        50000,
+			paymentMethod: "4242424242424242",
+			wantStatus:    model.StatusRequiresAction,
+			wantReason:    true,
+			wantNextURL:   true,
+		},
+		{
+			name:          "capture declined voids authorization",
+			provider:      &CaptureFailingProvider{service.NewSimulatedProvider()},
+			amount:        10000,
+			paymentMethod: "4242424242424242",
+			wantStatus:    model.StatusVoided,
+			wantReason:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo := NewMockPaymentRepository()
+			paymentService := service.NewPaymentService(repo, tt.provider, NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
+
+			// Execute
+			payment, err := paymentService.ProcessPayment(context.Background(), 1, tryAmount(tt.amount), tt.paymentMethod, "")
+
+			// Assert
+			if err != nil {
+				t.Fatalf("ProcessPayment() unexpected error: %v", err)
+			}
+			if payment.Status != tt.wantStatus {
+				t.Errorf("ProcessPayment() status = %v, want %v", payment.Status, tt.wantStatus)
+			}
+			if (payment.FailureReason != "") != tt.wantReason {
+				t.Errorf("ProcessPayment() failure reason = %q", payment.FailureReason)
+			}
+			if (payment.NextActionURL != "") != tt.wantNextURL {
+				t.Errorf("ProcessPayment() next action url = %q", payment.NextActionURL)
+			}
+			if stored := repo.payments[payment.ID]; stored.Status != tt.wantStatus {
+				t.Errorf("stored status = %v, want %v", stored.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCheckoutNotCompletedKeepsBasket(t *testing.T) {
+	// Setup
+	repo := NewMockPaymentRepository()
+	basketClient := NewMockBasketClient()
+	basketClient.baskets[1] = &pb.Basket{UserId: 1, Items: []*pb.BasketItem{basketItem(1, 1, tryAmount(1000))}}
+	productClient := NewMockProductClient(&pb.Product{Id: 1, Price: tryAmount(1000).ToProto(), Stock: 5})
+	provider := service.NewSimulatedProvider(service.DefaultSimulatorRules()...)
+	paymentService := service.NewPaymentService(repo, provider, basketClient, productClient, testRates(t), "TRY")
+
+	// Execute
+	payment, err := paymentService.Checkout(context.Background(), 1, 0, "TRY", "4000000000000002")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Checkout() unexpected error: %v", err)
+	}
+	if payment.Status != model.StatusFailed {
+		t.Errorf("Checkout() status = %v, want %v", payment.Status, model.StatusFailed)
+	}
+	if len(basketClient.cleared) != 0 {
+		t.Error("Checkout() cleared basket for a declined payment")
+	}
+	if len(repo.outbox) != 0 {
+		t.Errorf("Checkout() enqueued %d events for a declined payment", len(repo.outbox))
+	}
+}
+
+func TestSimulatedProviderLifecycle(t *testing.T) {
+	ctx := context.Background()
+	provider := service.NewSimulatedProvider()
+
+	auth, err := provider.Authorize(ctx, &service.AuthorizeRequest{Amount: tryAmount(10000), PaymentMethod: "4242424242424242"})
+	if err != nil || auth.Status != service.ProviderApproved {
+		t.Fatalf("Authorize() = %+v, %v", auth, err)
+	}
+
+	if _, err := provider.Capture(ctx, auth.Reference, tryAmount(15000)); !errors.Is(err, service.ErrInvalidProviderOperation) {
+		t.Errorf("Capture() above authorized amount error = %v, want %v", err, service.ErrInvalidProviderOperation)
+	}
+	if _, err := provider.Capture(ctx, auth.Reference, tryAmount(10000)); err != nil {
+		t.Fatalf("Capture() unexpected error: %v", err)
+	}
+	if _, err := provider.Void(ctx, auth.Reference); !errors.Is(err, service.ErrInvalidProviderOperation) {
+		t.Errorf("Void() after capture error = %v, want %v", err, service.ErrInvalidProviderOperation)
+	}
+	if _, err := provider.Refund(ctx, auth.Reference, "refund_1", tryAmount(6000)); err != nil {
+		t.Fatalf("Refund() unexpected error: %v", err)
+	}
+	if _, err := provider.Refund(ctx, auth.Reference, "refund_1", tryAmount(6000)); err != nil {
+		t.Errorf("Refund() replayed with the same key unexpected error: %v", err)
+	}
+	if _, err := provider.Refund(ctx, auth.Reference, "refund_2", tryAmount(5000)); !errors.Is(err, service.ErrInvalidProviderOperation) {
+		t.Errorf("Refund() above captured amount error = %v, want %v", err, service.ErrInvalidProviderOperation)
+	}
+	if _, err := provider.Refund(ctx, auth.Reference, "refund_3", tryAmount(4000)); err != nil {
+		t.Errorf("Refund() of remaining amount unexpected error: %v", err)
+	}
+	if refund, err := provider.GetRefund(ctx, "refund_1"); err != nil || refund.Status != service.ProviderApproved {
+		t.Errorf("GetRefund() = %+v, %v, want an approved refund", refund, err)
+	}
+	if _, err := provider.GetRefund(ctx, "refund_2"); !errors.Is(err, service.ErrUnknownProviderReference) {
+		t.Errorf("GetRefund() of a rejected refund error = %v, want %v", err, service.ErrUnknownProviderReference)
+	}
+	if _, err := provider.Refund(ctx, auth.Reference, "refund_4", money.Money{MinorUnits: 1, Currency: "EUR"}); !errors.Is(err, service.ErrInvalidProviderOperation) {
+		t.Errorf("Refund() in another currency error = %v, want %v", err, service.ErrInvalidProviderOperation)
+	}
+	if _, err := provider.Capture(ctx, "sim_auth_unknown", tryAmount(100)); !errors.Is(err, service.ErrUnknownProviderReference) {
+		t.Errorf("Capture() unknown reference error = %v, want %v", err, service.ErrUnknownProviderReference)
+	}
+
+	voidable, _ := provider.Authorize(ctx, &service.AuthorizeRequest{Amount: tryAmount(1000)})
+	if _, err := provider.Void(ctx, voidable.Reference); err != nil {
+		t.Fatalf("Void() unexpected error: %v", err)
+	}
+	if _, err := provider.Capture(ctx, voidable.Reference, tryAmount(1000)); !errors.Is(err, service.ErrInvalidProviderOperation) {
+		t.Errorf("Capture() after void error = %v, want %v", err, service.ErrInvalidProviderOperation)
+	}
+}
+
+func TestConfirmPayment(t *testing.T) {
+	tests := []struct {
+		name          string
+		paymentMethod string
+		paymentID     uint
+		changeBasket  bool
+		wantStatus    string
+		wantEvents    int
+		wantReleased  bool
+		wantCleared   bool
+		wantInvalid   bool
+		wantErr       error
+	}{
+		{
+			name:          "challenge passed",
+			paymentMethod: "4000000000003220",
+			paymentID:     1,
+			wantStatus:    model.StatusCaptured,
+			wantEvents:    1,
+			wantCleared:   true,
+		},
+		{
+			name:          "basket changed during challenge",
+			paymentMethod: "4000000000003220",
+			paymentID:     1,
+			changeBasket:  true,
+			wantStatus:    model.StatusCaptured,
+			wantEvents:    1,
+		},
+		{
+			name:          "challenge failed",
+			paymentMethod: "4000008400001629",
+			paymentID:     1,
+			wantStatus:    model.StatusFailed,
+			wantReleased:  true,
+		},
+		{
+			name:          "not awaiting action",
+			paymentMethod: "4242424242424242",
+			paymentID:     1,
+			wantInvalid:   true,
+		},
+		{
+			name:          "missing payment",
+			paymentMethod: "4000000000003220",
+			paymentID:     999,
+			wantErr:       service.ErrPaymentNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo := NewMockPaymentRepository()
+			basketClient := NewMockBasketClient()
+			basketClient.baskets[1] = &pb.Basket{UserId: 1, Items: []*pb.BasketItem{basketItem(1, 2, tryAmount(1000))}}
+			productClient := NewMockProductClient(&pb.Product{Id: 1, Price: tryAmount(1000).ToProto(), Stock: 5})
+			provider := service.NewSimulatedProvider(service.DefaultSimulatorRules()...)
+			paymentService := service.NewPaymentService(repo, provider, basketClient, productClient, testRates(t), "TRY")
+			if _, err := paymentService.Checkout(context.Background(), 1, 0, "TRY", tt.paymentMethod); err != nil {
+				t.Fatalf("Checkout() unexpected error: %v", err)
+			}
+			outboxBefore := len(repo.outbox)
+			if tt.changeBasket {
+				basketClient.baskets[1].Version++
+			}
+
+			// Execute
+			payment, err := paymentService.ConfirmPayment(context.Background(), tt.paymentID)
+
+			// Assert
+			if tt.wantInvalid {
+				var transitionErr *model.InvalidTransitionError
+				if !errors.As(err, &transitionErr) {
+					t.Fatalf("ConfirmPayment() error = %v, want invalid transition", err)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ConfirmPayment() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConfirmPayment() unexpected error: %v", err)
+			}
+			if payment.Status != tt.wantStatus {
+				t.Errorf("ConfirmPayment() status = %v, want %v", payment.Status, tt.wantStatus)
+			}
+			if payment.NextActionURL != "" {
+				t.Errorf("ConfirmPayment() next action url = %q, want empty", payment.NextActionURL)
+			}
+			if got := len(repo.outbox) - outboxBefore; got != tt.wantEvents {
+				t.Errorf("ConfirmPayment() enqueued %d stock events, want %d", got, tt.wantEvents)
+			}
+			if released := len(productClient.released) == 1; released != tt.wantReleased {
+				t.Errorf("ConfirmPayment() released reservations %v, want released = %v", productClient.released, tt.wantReleased)
+			}
+			for _, outboxEvent := range repo.outbox[outboxBefore:] {
+				var event model.StockUpdateEvent
+				if err := json.Unmarshal([]byte(outboxEvent.Payload), &event); err != nil {
+					t.Fatalf("Failed to decode outbox payload: %v", err)
+				}
+				if event.ReservationID != "res_1" {
+					t.Errorf("ConfirmPayment() stock event %+v, want reservation res_1", event)
+				}
+			}
+			if cleared := len(basketClient.cleared) == 1; cleared != tt.wantCleared {
+				t.Errorf("ConfirmPayment() cleared basket = %v, want %v", cleared, tt.wantCleared)
+			}
+			if _, err := paymentService.ConfirmPayment(context.Background(), payment.ID); err == nil {
+				t.Error("ConfirmPayment() confirmed a settled payment twice")
+			}
+		})
+	}
+}
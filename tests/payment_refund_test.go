@@ -0,0 +1,406 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	pb "gomicro/api/proto"
+	"gomicro/internal/money"
+	"gomicro/internal/payment/model"
+	"gomicro/internal/payment/service"
+)
+
+// refundCall is one RefundPayment call; a zero amount refunds the remainder
+type refundCall struct {
+	amount money.Money
+	items  []model.RefundItem
+}
+
+// RefundOutcomeProvider fails or declines refunds on demand and otherwise
+// behaves like the simulator. With lostAnswer set, refunds are paid out but
+// the answer times out.
+type RefundOutcomeProvider struct {
+	*service.SimulatedProvider
+	refundErr  error
+	decline    bool
+	lostAnswer bool
+	lookupErr  error
+	refunds    int
+}
+
+func (p *RefundOutcomeProvider) Refund(ctx context.Context, reference, key string, amount money.Money) (*service.ProviderResult, error) {
+	p.refunds++
+	if p.lostAnswer {
+		if _, err := p.SimulatedProvider.Refund(ctx, reference, key, amount); err != nil {
+			return nil, err
+		}
+		return nil, service.ErrProviderTimeout
+	}
+	if p.refundErr != nil {
+		return nil, p.refundErr
+	}
+	if p.decline {
+		return &service.ProviderResult{Status: service.ProviderDeclined, Reason: "insufficient merchant balance"}, nil
+	}
+	return p.SimulatedProvider.Refund(ctx, reference, key, amount)
+}
+
+func (p *RefundOutcomeProvider) GetRefund(ctx context.Context, key string) (*service.ProviderResult, error) {
+	if p.lookupErr != nil {
+		return nil, p.lookupErr
+	}
+	return p.SimulatedProvider.GetRefund(ctx, key)
+}
+
+// setupRefundablePayment checks out a basket of 2 x product 1 (10.00) and
+// 1 x product 2 (5.00) for a total of 25.00
+func setupRefundablePayment(t *testing.T) (*MockPaymentRepository, service.PaymentService, *model.Payment) {
+	t.Helper()
+	return setupRefundablePaymentWith(t, service.NewSimulatedProvider())
+}
+
+// setupRefundablePaymentWith is setupRefundablePayment on the given provider
+func setupRefundablePaymentWith(t *testing.T, provider service.PaymentProvider) (*MockPaymentRepository, service.PaymentService, *model.Payment) {
+	t.Helper()
+	repo := NewMockPaymentRepository()
+	basketClient := NewMockBasketClient()
+	basketClient.baskets[1] = &pb.Basket{UserId: 1, Items: []*pb.BasketItem{
+		basketItem(1, 2, tryAmount(1000)),
+		basketItem(2, 1, tryAmount(500)),
+	}}
+	productClient := NewMockProductClient(
+		&pb.Product{Id: 1, Price: tryAmount(1000).ToProto(), Stock: 10},
+		&pb.Product{Id: 2, Price: tryAmount(500).ToProto(), Stock: 10},
+	)
+	paymentService := service.NewPaymentService(repo, provider, basketClient, productClient, testRates(t), "TRY")
+	payment, err := paymentService.Checkout(context.Background(), 1, 0, "TRY", "4242424242424242")
+	if err != nil || payment.Status != model.StatusCaptured {
+		t.Fatalf("Checkout() = %+v, %v", payment, err)
+	}
+	// Drop the checkout's own stock events so only refund events remain
+	repo.outbox = nil
+	return repo, paymentService, payment
+}
+
+func TestRefundPayment(t *testing.T) {
+	tests := []struct {
+		name         string
+		calls        []refundCall
+		wantErr      error
+		wantStatus   string
+		wantRefunded int64
+		wantRestock  map[uint]int
+	}{
+		{
+			name:         "full refund restocks everything",
+			calls:        []refundCall{{}},
+			wantStatus:   model.StatusRefunded,
+			wantRefunded: 2500,
+			wantRestock:  map[uint]int{1: 2, 2: 1},
+		},
+		{
+			name:         "partial refund without items",
+			calls:        []refundCall{{amount: tryAmount(500)}},
+			wantStatus:   model.StatusPartiallyRefunded,
+			wantRefunded: 500,
+			wantRestock:  map[uint]int{},
+		},
+		{
+			name:         "partial refund with returned item",
+			calls:        []refundCall{{amount: tryAmount(1000), items: []model.RefundItem{{ProductID: 1, Quantity: 1}}}},
+			wantStatus:   model.StatusPartiallyRefunded,
+			wantRefunded: 1000,
+			wantRestock:  map[uint]int{1: 1},
+		},
+		{
+			name: "partial refunds settling the payment restock the remainder",
+			calls: []refundCall{
+				{amount: tryAmount(1000), items: []model.RefundItem{{ProductID: 1, Quantity: 1}}},
+				{amount: tryAmount(1500)},
+			},
+			wantStatus:   model.StatusRefunded,
+			wantRefunded: 2500,
+			wantRestock:  map[uint]int{1: 2, 2: 1},
+		},
+		{
+			name:        "refund above charged amount",
+			calls:       []refundCall{{amount: tryAmount(2501)}},
+			wantErr:     model.ErrRefundExceedsPayment,
+			wantStatus:  model.StatusCaptured,
+			wantRestock: map[uint]int{},
+		},
+		{
+			name:         "cumulative refunds above charged amount",
+			calls:        []refundCall{{amount: tryAmount(2000)}, {amount: tryAmount(1000)}},
+			wantErr:      model.ErrRefundExceedsPayment,
+			wantStatus:   model.StatusPartiallyRefunded,
+			wantRefunded: 2000,
+			wantRestock:  map[uint]int{},
+		},
+		{
+			name:         "refund of fully refunded payment",
+			calls:        []refundCall{{}, {amount: tryAmount(100)}},
+			wantErr:      &model.InvalidTransitionError{},
+			wantStatus:   model.StatusRefunded,
+			wantRefunded: 2500,
+			wantRestock:  map[uint]int{1: 2, 2: 1},
+		},
+		{
+			name:        "refund in another currency",
+			calls:       []refundCall{{amount: money.Money{MinorUnits: 500, Currency: "EUR"}}},
+			wantErr:     money.ErrCurrencyMismatch,
+			wantStatus:  model.StatusCaptured,
+			wantRestock: map[uint]int{},
+		},
+		{
+			name:        "restock more than purchased",
+			calls:       []refundCall{{amount: tryAmount(500), items: []model.RefundItem{{ProductID: 2, Quantity: 2}}}},
+			wantErr:     service.ErrInvalidRefundItems,
+			wantStatus:  model.StatusCaptured,
+			wantRestock: map[uint]int{},
+		},
+		{
+			name:        "restock product not in payment",
+			calls:       []refundCall{{amount: tryAmount(500), items: []model.RefundItem{{ProductID: 3, Quantity: 1}}}},
+			wantErr:     service.ErrInvalidRefundItems,
+			wantStatus:  model.StatusCaptured,
+			wantRestock: map[uint]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo, paymentService, payment := setupRefundablePayment(t)
+
+			// Execute
+			var err error
+			for _, call := range tt.calls {
+				_, err = paymentService.RefundPayment(context.Background(), payment.ID, call.amount, "customer request", call.items)
+			}
+
+			// Assert
+			var transitionErr *model.InvalidTransitionError
+			if _, wantTransitionErr := tt.wantErr.(*model.InvalidTransitionError); wantTransitionErr {
+				if !errors.As(err, &transitionErr) {
+					t.Fatalf("RefundPayment() error = %v, want invalid transition", err)
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RefundPayment() error = %v, want %v", err, tt.wantErr)
+			}
+			stored := repo.payments[payment.ID]
+			if stored.Status != tt.wantStatus {
+				t.Errorf("RefundPayment() status = %v, want %v", stored.Status, tt.wantStatus)
+			}
+			if stored.RefundedAmount.MinorUnits != tt.wantRefunded {
+				t.Errorf("RefundPayment() refunded = %v, want %v", stored.RefundedAmount, tt.wantRefunded)
+			}
+
+			restocked := make(map[uint]int)
+			for _, event := range repo.outbox {
+				var stockEvent model.StockUpdateEvent
+				if err := json.Unmarshal([]byte(event.Payload), &stockEvent); err != nil {
+					t.Fatalf("Failed to decode outbox payload: %v", err)
+				}
+				if stockEvent.Quantity <= 0 {
+					t.Errorf("RefundPayment() emitted non-positive stock event %+v", stockEvent)
+				}
+				restocked[stockEvent.ProductID] += stockEvent.Quantity
+			}
+			if len(restocked) != len(tt.wantRestock) {
+				t.Fatalf("RefundPayment() restocked %v, want %v", restocked, tt.wantRestock)
+			}
+			for productID, quantity := range tt.wantRestock {
+				if restocked[productID] != quantity {
+					t.Errorf("RefundPayment() restocked %v, want %v", restocked, tt.wantRestock)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestRefundPaymentNotCaptured(t *testing.T) {
+	// Setup
+	repo := NewMockPaymentRepository()
+	provider := service.NewSimulatedProvider(service.DefaultSimulatorRules()...)
+	paymentService := service.NewPaymentService(repo, provider, NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
+	payment, _ := paymentService.ProcessPayment(context.Background(), 1, tryAmount(1000), "4000000000000002", "")
+
+	// Execute
+	_, err := paymentService.RefundPayment(context.Background(), payment.ID, money.Money{}, "", nil)
+	_, missingErr := paymentService.RefundPayment(context.Background(), 999, money.Money{}, "", nil)
+
+	// Assert
+	var transitionErr *model.InvalidTransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Errorf("RefundPayment() failed payment error = %v, want invalid transition", err)
+	}
+	if !errors.Is(missingErr, service.ErrPaymentNotFound) {
+		t.Errorf("RefundPayment() missing payment error = %v, want %v", missingErr, service.ErrPaymentNotFound)
+	}
+}
+
+func TestRefundPaymentPendingRefundHoldsAmount(t *testing.T) {
+	// Setup: the provider times out, so the outcome of the first refund is
+	// unknown and the money may have gone out
+	provider := &RefundOutcomeProvider{SimulatedProvider: service.NewSimulatedProvider(), refundErr: service.ErrProviderTimeout}
+	repo, paymentService, payment := setupRefundablePaymentWith(t, provider)
+	ctx := context.Background()
+
+	// Execute
+	_, pendingErr := paymentService.RefundPayment(ctx, payment.ID, tryAmount(2000), "", []model.RefundItem{{ProductID: 1, Quantity: 2}})
+	provider.refundErr = nil
+	_, amountErr := paymentService.RefundPayment(ctx, payment.ID, tryAmount(1000), "", nil)
+	_, itemsErr := paymentService.RefundPayment(ctx, payment.ID, tryAmount(500), "", []model.RefundItem{{ProductID: 1, Quantity: 1}})
+
+	// Assert
+	if !errors.Is(pendingErr, service.ErrProviderTimeout) {
+		t.Fatalf("RefundPayment() error = %v, want %v", pendingErr, service.ErrProviderTimeout)
+	}
+	if !errors.Is(amountErr, model.ErrRefundExceedsPayment) {
+		t.Errorf("RefundPayment() beyond the pending refund error = %v, want %v", amountErr, model.ErrRefundExceedsPayment)
+	}
+	if !errors.Is(itemsErr, service.ErrInvalidRefundItems) {
+		t.Errorf("RefundPayment() of pending items error = %v, want %v", itemsErr, service.ErrInvalidRefundItems)
+	}
+	if provider.refunds != 1 {
+		t.Errorf("provider refunded %d times, want 1", provider.refunds)
+	}
+	stored := repo.payments[payment.ID]
+	if len(stored.Refunds) != 1 || stored.Refunds[0].Status != model.RefundPending {
+		t.Fatalf("stored refunds = %+v, want one pending refund", stored.Refunds)
+	}
+	if stored.Status != model.StatusCaptured || !stored.RefundedAmount.IsZero() {
+		t.Errorf("stored payment = %s refunded %v, want captured with nothing refunded", stored.Status, stored.RefundedAmount)
+	}
+	if len(repo.outbox) != 0 {
+		t.Errorf("RefundPayment() enqueued %d stock events for a pending refund", len(repo.outbox))
+	}
+}
+
+func TestReconcileRefunds(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     *RefundOutcomeProvider
+		staleAfter   time.Duration
+		wantSettled  int
+		wantRefund   string
+		wantStatus   string
+		wantRefunded int64
+		wantRestock  int
+	}{
+		{
+			name:         "refund paid out before the timeout is completed",
+			provider:     &RefundOutcomeProvider{lostAnswer: true},
+			wantSettled:  1,
+			wantRefund:   model.RefundSucceeded,
+			wantStatus:   model.StatusPartiallyRefunded,
+			wantRefunded: 2000,
+			wantRestock:  2,
+		},
+		{
+			name:        "refund the provider never received is failed",
+			provider:    &RefundOutcomeProvider{refundErr: service.ErrProviderTimeout},
+			wantSettled: 1,
+			wantRefund:  model.RefundFailed,
+			wantStatus:  model.StatusCaptured,
+		},
+		{
+			name:       "refund the provider cannot answer for stays pending",
+			provider:   &RefundOutcomeProvider{lostAnswer: true, lookupErr: service.ErrProviderTimeout},
+			wantRefund: model.RefundPending,
+			wantStatus: model.StatusCaptured,
+		},
+		{
+			name:       "refund that may still be in flight stays pending",
+			provider:   &RefundOutcomeProvider{refundErr: service.ErrProviderTimeout},
+			staleAfter: time.Hour,
+			wantRefund: model.RefundPending,
+			wantStatus: model.StatusCaptured,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup: the first refund times out and is left pending
+			tt.provider.SimulatedProvider = service.NewSimulatedProvider()
+			repo, paymentService, payment := setupRefundablePaymentWith(t, tt.provider)
+			ctx := context.Background()
+			_, err := paymentService.RefundPayment(ctx, payment.ID, tryAmount(2000), "", []model.RefundItem{{ProductID: 1, Quantity: 2}})
+			if !errors.Is(err, service.ErrProviderTimeout) {
+				t.Fatalf("RefundPayment() error = %v, want %v", err, service.ErrProviderTimeout)
+			}
+
+			// Execute
+			settled, err := service.NewRefundReconciler(paymentService, time.Minute, tt.staleAfter).ReconcilePending(ctx)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("ReconcilePending() unexpected error: %v", err)
+			}
+			if settled != tt.wantSettled {
+				t.Errorf("ReconcilePending() settled %d refunds, want %d", settled, tt.wantSettled)
+			}
+			stored := repo.payments[payment.ID]
+			if refund := stored.Refunds[0]; refund.Status != tt.wantRefund {
+				t.Errorf("reconciled refund status = %s, want %s", refund.Status, tt.wantRefund)
+			} else if tt.wantRefund == model.RefundSucceeded && refund.ProviderRef == "" {
+				t.Error("reconciled refund has no provider reference")
+			}
+			if stored.Status != tt.wantStatus || stored.RefundedAmount.MinorUnits != tt.wantRefunded {
+				t.Errorf("stored payment = %s refunded %v, want %s refunded %d", stored.Status, stored.RefundedAmount, tt.wantStatus, tt.wantRefunded)
+			}
+			restocked := 0
+			for _, event := range repo.outbox {
+				var stockEvent model.StockUpdateEvent
+				if err := json.Unmarshal([]byte(event.Payload), &stockEvent); err != nil {
+					t.Fatalf("Failed to decode outbox payload: %v", err)
+				}
+				if stockEvent.ProductID != 1 {
+					t.Errorf("ReconcilePending() restocked product %d, want 1", stockEvent.ProductID)
+				}
+				restocked += stockEvent.Quantity
+			}
+			if restocked != tt.wantRestock {
+				t.Errorf("ReconcilePending() restocked %d items, want %d", restocked, tt.wantRestock)
+			}
+			if want := 2500 - tt.wantRefunded; tt.wantRefund != model.RefundPending && stored.AvailableRefund().MinorUnits != want {
+				t.Errorf("available refund = %v, want %d", stored.AvailableRefund(), want)
+			}
+		})
+	}
+}
+
+func TestRefundPaymentDeclinedReleasesAmount(t *testing.T) {
+	// Setup
+	provider := &RefundOutcomeProvider{SimulatedProvider: service.NewSimulatedProvider(), decline: true}
+	repo, paymentService, payment := setupRefundablePaymentWith(t, provider)
+	ctx := context.Background()
+
+	// Execute
+	_, declinedErr := paymentService.RefundPayment(ctx, payment.ID, money.Money{}, "", nil)
+	provider.decline = false
+	refunded, err := paymentService.RefundPayment(ctx, payment.ID, money.Money{}, "", nil)
+
+	// Assert
+	if declinedErr == nil {
+		t.Fatal("RefundPayment() declined refund returned no error")
+	}
+	if err != nil {
+		t.Fatalf("RefundPayment() after a declined refund unexpected error: %v", err)
+	}
+	if refunded.Status != model.StatusRefunded || refunded.RefundedAmount != tryAmount(2500) {
+		t.Errorf("RefundPayment() = %s refunded %v, want refunded 2500", refunded.Status, refunded.RefundedAmount)
+	}
+	stored := repo.payments[payment.ID]
+	if len(stored.Refunds) != 2 || stored.Refunds[0].Status != model.RefundFailed || stored.Refunds[1].Status != model.RefundSucceeded {
+		t.Errorf("stored refunds = %+v, want a failed and a succeeded refund", stored.Refunds)
+	}
+	if len(repo.outbox) != 2 {
+		t.Errorf("RefundPayment() enqueued %d stock events, want 2", len(repo.outbox))
+	}
+}
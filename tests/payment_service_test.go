@@ -2,16 +2,26 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 	"testing"
 	"time"
 
+	pb "gomicro/api/proto"
+	"gomicro/internal/money"
 	"gomicro/internal/payment/model"
+	"gomicro/internal/payment/repository"
 	"gomicro/internal/payment/service"
 )
 
-// MockPaymentRepository implements repository.PaymentRepository interface
+// MockPaymentRepository implements repository.PaymentRepository and
+// repository.OutboxRepository interfaces
 type MockPaymentRepository struct {
 	payments map[uint]*model.Payment
+	events   []*model.PaymentEvent
+	outbox   []*model.OutboxEvent
 }
 
 func NewMockPaymentRepository() *MockPaymentRepository {
@@ -35,6 +45,15 @@ func (m *MockPaymentRepository) GetByID(ctx context.Context, id uint) (*model.Pa
 	return nil, nil
 }
 
+func (m *MockPaymentRepository) GetByIdempotencyKey(ctx context.Context, key string) (*model.Payment, error) {
+	for _, payment := range m.payments {
+		if payment.IdempotencyKey != nil && *payment.IdempotencyKey == key {
+			return payment, nil
+		}
+	}
+	return nil, nil
+}
+
 func (m *MockPaymentRepository) Update(ctx context.Context, payment *model.Payment) error {
 	if _, exists := m.payments[payment.ID]; exists {
 		payment.UpdatedAt = time.Now()
@@ -44,9 +63,147 @@ func (m *MockPaymentRepository) Update(ctx context.Context, payment *model.Payme
 	return nil
 }
 
+func (m *MockPaymentRepository) UpdateStatus(ctx context.Context, payment *model.Payment, event *model.PaymentEvent, outbox []*model.OutboxEvent) error {
+	if err := m.Update(ctx, payment); err != nil {
+		return err
+	}
+	m.addEvent(event)
+	m.addOutbox(outbox)
+	return nil
+}
+
+func (m *MockPaymentRepository) List(ctx context.Context, filter repository.PaymentFilter, after *repository.PaymentCursor, limit int) ([]*model.Payment, error) {
+	var payments []*model.Payment
+	for _, p := range m.payments {
+		if (filter.UserID != 0 && p.UserID != filter.UserID) ||
+			(filter.Status != "" && p.Status != filter.Status) ||
+			(filter.Currency != "" && p.Amount.Currency != filter.Currency) ||
+			(!filter.CreatedFrom.IsZero() && p.CreatedAt.Before(filter.CreatedFrom)) ||
+			(!filter.CreatedTo.IsZero() && !p.CreatedAt.Before(filter.CreatedTo)) ||
+			(filter.MinAmount > 0 && p.Amount.MinorUnits < filter.MinAmount) ||
+			(filter.MaxAmount > 0 && p.Amount.MinorUnits > filter.MaxAmount) {
+			continue
+		}
+		if after != nil && !(p.CreatedAt.Before(after.CreatedAt) || (p.CreatedAt.Equal(after.CreatedAt) && p.ID < after.ID)) {
+			continue
+		}
+		payments = append(payments, p)
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		if !payments[i].CreatedAt.Equal(payments[j].CreatedAt) {
+			return payments[i].CreatedAt.After(payments[j].CreatedAt)
+		}
+		return payments[i].ID > payments[j].ID
+	})
+	if len(payments) > limit {
+		payments = payments[:limit]
+	}
+	return payments, nil
+}
+
+func (m *MockPaymentRepository) GetEvents(ctx context.Context, paymentID uint) ([]*model.PaymentEvent, error) {
+	var events []*model.PaymentEvent
+	for _, event := range m.events {
+		if event.PaymentID == paymentID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockPaymentRepository) addEvent(event *model.PaymentEvent) {
+	event.ID = uint(len(m.events) + 1)
+	event.CreatedAt = time.Now()
+	m.events = append(m.events, event)
+}
+
+func (m *MockPaymentRepository) addOutbox(outbox []*model.OutboxEvent) {
+	for _, event := range outbox {
+		event.ID = uint(len(m.outbox) + 1)
+		event.CreatedAt = time.Now()
+		m.outbox = append(m.outbox, event)
+	}
+}
+
+func (m *MockPaymentRepository) ReserveRefund(ctx context.Context, refund *model.Refund) error {
+	payment, exists := m.payments[refund.PaymentID]
+	if !exists {
+		return errors.New("payment not found")
+	}
+	if err := payment.CheckRefund(refund); err != nil {
+		return err
+	}
+	refund.ID = uint(len(payment.Refunds) + 1)
+	refund.CreatedAt = time.Now()
+	refund.Status = model.RefundPending
+	payment.Refunds = append(payment.Refunds, *refund)
+	return nil
+}
+
+func (m *MockPaymentRepository) CompleteRefund(ctx context.Context, refund *model.Refund, outbox []*model.OutboxEvent) (*model.Payment, error) {
+	payment := m.payments[refund.PaymentID]
+	event, err := payment.ApplyRefund(refund.Amount, refund.Reason)
+	if err != nil {
+		return nil, err
+	}
+	refund.Status = model.RefundSucceeded
+	payment.Refunds[refund.ID-1].Status = refund.Status
+	payment.Refunds[refund.ID-1].ProviderRef = refund.ProviderRef
+	m.addEvent(event)
+	m.addOutbox(outbox)
+	return payment, nil
+}
+
+func (m *MockPaymentRepository) GetPendingRefunds(ctx context.Context, before time.Time, limit int) ([]*model.Refund, error) {
+	var refunds []*model.Refund
+	for _, payment := range m.payments {
+		for _, refund := range payment.Refunds {
+			if refund.Status == model.RefundPending && refund.CreatedAt.Before(before) {
+				refund := refund
+				refunds = append(refunds, &refund)
+			}
+		}
+	}
+	sort.Slice(refunds, func(i, j int) bool { return refunds[i].CreatedAt.Before(refunds[j].CreatedAt) })
+	if len(refunds) > limit {
+		refunds = refunds[:limit]
+	}
+	return refunds, nil
+}
+
+func (m *MockPaymentRepository) FailRefund(ctx context.Context, refund *model.Refund) error {
+	refund.Status = model.RefundFailed
+	m.payments[refund.PaymentID].Refunds[refund.ID-1].Status = refund.Status
+	return nil
+}
+
+func (m *MockPaymentRepository) GetPending(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+	for _, event := range m.outbox {
+		if event.Status == model.OutboxStatusPending && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockPaymentRepository) MarkSent(ctx context.Context, id uint) error {
+	now := time.Now()
+	m.outbox[id-1].Status = model.OutboxStatusSent
+	m.outbox[id-1].PublishedAt = &now
+	return nil
+}
+
+func (m *MockPaymentRepository) MarkFailed(ctx context.Context, id uint, reason string) error {
+	m.outbox[id-1].Attempts++
+	m.outbox[id-1].LastError = reason
+	return nil
+}
+
 // MockRabbitMQPublisher implements RabbitMQ publisher interface
 type MockRabbitMQPublisher struct {
 	messages []*model.StockUpdateEvent
+	failures int
 }
 
 func NewMockRabbitMQPublisher() *MockRabbitMQPublisher {
@@ -56,17 +213,135 @@ func NewMockRabbitMQPublisher() *MockRabbitMQPublisher {
 }
 
 func (m *MockRabbitMQPublisher) SendStockUpdateEvent(event *model.StockUpdateEvent) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("connection closed")
+	}
 	m.messages = append(m.messages, event)
 	return nil
 }
 
 func (m *MockRabbitMQPublisher) Close() {}
 
+// MockBasketClient implements service.IBasketClient
+type MockBasketClient struct {
+	baskets map[uint32]*pb.Basket
+	cleared []uint32
+}
+
+func NewMockBasketClient() *MockBasketClient {
+	return &MockBasketClient{
+		baskets: make(map[uint32]*pb.Basket),
+	}
+}
+
+func (m *MockBasketClient) GetBasket(ctx context.Context, userID uint32) (*pb.Basket, error) {
+	if basket, exists := m.baskets[userID]; exists {
+		return basket, nil
+	}
+	return &pb.Basket{UserId: userID}, nil
+}
+
+func (m *MockBasketClient) LockBasketForCheckout(ctx context.Context, userID uint32, version int64) (*pb.Basket, error) {
+	basket, _ := m.GetBasket(ctx, userID)
+	if basket.Version != version {
+		return nil, service.ErrBasketChanged
+	}
+	return basket, nil
+}
+
+func (m *MockBasketClient) CompleteCheckout(ctx context.Context, userID uint32, version int64) error {
+	if basket, _ := m.GetBasket(ctx, userID); basket.Version != version {
+		return service.ErrBasketChanged
+	}
+	m.cleared = append(m.cleared, userID)
+	delete(m.baskets, userID)
+	return nil
+}
+
+// basketItem returns a basket line as the basket service prices it
+func basketItem(productID uint32, quantity int32, price money.Money) *pb.BasketItem {
+	return &pb.BasketItem{ProductId: productID, Quantity: quantity, Price: price.ToProto(), CurrentPrice: price.ToProto()}
+}
+
+// MockProductClient implements the payment and basket services' IProductClient
+type MockProductClient struct {
+	products     map[uint32]*pb.Product
+	err          error
+	reserveErr   error
+	reservations map[string][]*pb.StockReservationItem
+	released     []string
+}
+
+func NewMockProductClient(products ...*pb.Product) *MockProductClient {
+	m := &MockProductClient{
+		products:     make(map[uint32]*pb.Product),
+		reservations: make(map[string][]*pb.StockReservationItem),
+	}
+	for _, p := range products {
+		m.products[p.Id] = p
+	}
+	return m
+}
+
+func (m *MockProductClient) GetProduct(ctx context.Context, productID uint32) (*pb.Product, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.products[productID], nil
+}
+
+func (m *MockProductClient) GetProducts(ctx context.Context, productIDs []uint32) ([]*pb.Product, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var products []*pb.Product
+	for _, id := range productIDs {
+		if p, exists := m.products[id]; exists {
+			products = append(products, p)
+		}
+	}
+	return products, nil
+}
+
+func (m *MockProductClient) ReserveStock(ctx context.Context, items []*pb.StockReservationItem, ttl time.Duration) (string, error) {
+	if m.reserveErr != nil {
+		return "", m.reserveErr
+	}
+	reservationID := fmt.Sprintf("res_%d", len(m.reservations)+1)
+	m.reservations[reservationID] = items
+	return reservationID, nil
+}
+
+func (m *MockProductClient) ReleaseStock(ctx context.Context, reservationID string) error {
+	if _, exists := m.reservations[reservationID]; !exists {
+		return errors.New("reservation not found")
+	}
+	delete(m.reservations, reservationID)
+	m.released = append(m.released, reservationID)
+	return nil
+}
+
+// testRates quotes the fixed rates in testdata/exchange_rates.json
+func testRates(t *testing.T) money.RateProvider {
+	t.Helper()
+	rates, err := money.LoadStaticRates("testdata/exchange_rates.json")
+	if err != nil {
+		t.Fatalf("LoadStaticRates() unexpected error: %v", err)
+	}
+	return rates
+}
+
+// tryAmount returns an amount of Turkish lira in kuruş
+func tryAmount(minorUnits int64) money.Money {
+	return money.Money{MinorUnits: minorUnits, Currency: "TRY"}
+}
+
 func TestProcessPayment(t *testing.T) {
 	tests := []struct {
 		name          string
 		userID        uint
-		amount        float64
+		amount        int64
 		currency      string
 		paymentMethod string
 		wantErr       bool
@@ -74,11 +349,34 @@ func TestProcessPayment(t *testing.T) {
 		{
 			name:          "successful payment",
 			userID:        1,
-			amount:        100.0,
+			amount:        10000,
 			currency:      "TRY",
 			paymentMethod: "credit_card",
 			wantErr:       false,
 		},
+		{
+			name:          "zero decimal currency",
+			userID:        1,
+			amount:        1500,
+			currency:      "JPY",
+			paymentMethod: "credit_card",
+			wantErr:       false,
+		},
+		{
+			name:          "unknown currency",
+			userID:        1,
+			amount:        10000,
+			currency:      "XYZ",
+			paymentMethod: "credit_card",
+			wantErr:       true,
+		},
+		{
+			name:          "missing currency",
+			userID:        1,
+			amount:        10000,
+			paymentMethod: "credit_card",
+			wantErr:       true,
+		},
 		{
 			name:          "invalid amount",
 			userID:        1,
@@ -90,7 +388,7 @@ func TestProcessPayment(t *testing.T) {
 		{
 			name:          "negative amount",
 			userID:        1,
-			amount:        -50.0,
+			amount:        -5000,
 			currency:      "TRY",
 			paymentMethod: "credit_card",
 			wantErr:       true,
@@ -101,11 +399,11 @@ func TestProcessPayment(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup
 			repo := NewMockPaymentRepository()
-			publisher := NewMockRabbitMQPublisher()
-			paymentService := service.NewPaymentService(repo, publisher)
+			paymentService := service.NewPaymentService(repo, service.NewSimulatedProvider(), NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
 
 			// Execute
-			payment, err := paymentService.ProcessPayment(context.Background(), tt.userID, tt.amount, tt.currency, tt.paymentMethod)
+			amount := money.Money{MinorUnits: tt.amount, Currency: tt.currency}
+			payment, err := paymentService.ProcessPayment(context.Background(), tt.userID, amount, tt.paymentMethod, "")
 
 			// Assert
 			if tt.wantErr {
@@ -129,31 +427,19 @@ func TestProcessPayment(t *testing.T) {
 			if payment.UserID != tt.userID {
 				t.Errorf("ProcessPayment() userID = %v, want %v", payment.UserID, tt.userID)
 			}
-			if payment.Amount != tt.amount {
-				t.Errorf("ProcessPayment() amount = %v, want %v", payment.Amount, tt.amount)
-			}
-			if payment.Currency != tt.currency {
-				t.Errorf("ProcessPayment() currency = %v, want %v", payment.Currency, tt.currency)
+			if payment.Amount != amount {
+				t.Errorf("ProcessPayment() amount = %v, want %v", payment.Amount, amount)
 			}
 			if payment.PaymentMethod != tt.paymentMethod {
 				t.Errorf("ProcessPayment() paymentMethod = %v, want %v", payment.PaymentMethod, tt.paymentMethod)
 			}
-			if payment.Status != "completed" {
-				t.Errorf("ProcessPayment() status = %v, want %v", payment.Status, "completed")
+			if payment.Status != model.StatusCaptured {
+				t.Errorf("ProcessPayment() status = %v, want %v", payment.Status, model.StatusCaptured)
 			}
 
-			// Verify RabbitMQ message
-			if len(publisher.messages) != 1 {
-				t.Errorf("Expected 1 RabbitMQ message, got %d", len(publisher.messages))
-				return
-			}
-
-			event := publisher.messages[0]
-			if event.ProductID != 1 {
-				t.Errorf("Expected ProductID = 1, got %d", event.ProductID)
-			}
-			if event.Quantity != -1 {
-				t.Errorf("Expected Quantity = -1, got %d", event.Quantity)
+			// A raw payment carries no items, so no stock is moved
+			if len(repo.outbox) != 0 {
+				t.Errorf("Expected 0 outbox events, got %d", len(repo.outbox))
 			}
 		})
 	}
@@ -162,15 +448,13 @@ func TestProcessPayment(t *testing.T) {
 func TestGetPayment(t *testing.T) {
 	// Setup
 	repo := NewMockPaymentRepository()
-	publisher := NewMockRabbitMQPublisher()
-	paymentService := service.NewPaymentService(repo, publisher)
+	paymentService := service.NewPaymentService(repo, service.NewSimulatedProvider(), NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
 
 	// Create a test payment
 	testPayment := &model.Payment{
 		UserID:        1,
-		Amount:        100.0,
-		Currency:      "TRY",
-		Status:        "completed",
+		Amount:        tryAmount(10000),
+		Status:        model.StatusCaptured,
 		PaymentMethod: "credit_card",
 	}
 	repo.Create(context.Background(), testPayment)
@@ -225,4 +509,327 @@ func TestGetPayment(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+}
+
+func TestProcessPaymentIdempotency(t *testing.T) {
+	tests := []struct {
+		name         string
+		amount       int64
+		key          string
+		wantErr      error
+		wantPayments int
+	}{
+		{
+			name:         "replay with same parameters",
+			amount:       10000,
+			key:          "order-1",
+			wantPayments: 1,
+		},
+		{
+			name:         "replay with different amount",
+			amount:       15000,
+			key:          "order-1",
+			wantErr:      service.ErrIdempotencyKeyConflict,
+			wantPayments: 1,
+		},
+		{
+			name:         "different key",
+			amount:       10000,
+			key:          "order-2",
+			wantPayments: 2,
+		},
+		{
+			name:         "no key",
+			amount:       10000,
+			wantPayments: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo := NewMockPaymentRepository()
+			paymentService := service.NewPaymentService(repo, service.NewSimulatedProvider(), NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
+			first, err := paymentService.ProcessPayment(context.Background(), 1, tryAmount(10000), "credit_card", "order-1")
+			if err != nil {
+				t.Fatalf("ProcessPayment() unexpected error: %v", err)
+			}
+
+			// Execute
+			payment, err := paymentService.ProcessPayment(context.Background(), 1, tryAmount(tt.amount), "credit_card", tt.key)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ProcessPayment() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(repo.payments) != tt.wantPayments {
+				t.Errorf("ProcessPayment() created %d payments, want %d", len(repo.payments), tt.wantPayments)
+			}
+			if tt.wantErr == nil && tt.key == "order-1" && payment.ID != first.ID {
+				t.Errorf("ProcessPayment() replay returned payment %d, want %d", payment.ID, first.ID)
+			}
+		})
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	products := []*pb.Product{
+		{Id: 1, Name: "Keyboard", Price: tryAmount(4999).ToProto(), Stock: 10},
+		{Id: 2, Name: "Mouse", Price: tryAmount(1995).ToProto(), Stock: 1},
+		{Id: 3, Name: "Headset", Price: &pb.Money{MinorUnits: 2500, Currency: "EUR"}, Stock: 5},
+		{Id: 5, Name: "Cable", Price: &pb.Money{MinorUnits: 500, Currency: "GBP"}, Stock: 5},
+	}
+
+	tests := []struct {
+		name       string
+		items      []*pb.BasketItem
+		discounts  []*pb.DiscountLine
+		productErr error
+		wantErr    bool
+		wantAmount money.Money
+	}{
+		{
+			name: "basket charged at locked prices",
+			items: []*pb.BasketItem{
+				{ProductId: 1, Quantity: 2, Price: tryAmount(1).ToProto(), CurrentPrice: tryAmount(4999).ToProto()},
+				basketItem(2, 1, tryAmount(1995)),
+			},
+			wantAmount: tryAmount(11993),
+		},
+		{
+			name:       "coupon discount deducted",
+			items:      []*pb.BasketItem{basketItem(1, 2, tryAmount(4999))},
+			discounts:  []*pb.DiscountLine{{Code: "SAVE10", Amount: tryAmount(1000).ToProto()}},
+			wantAmount: tryAmount(8998),
+		},
+		{
+			name:       "discount in another currency is converted",
+			items:      []*pb.BasketItem{basketItem(1, 2, tryAmount(4999))},
+			discounts:  []*pb.DiscountLine{{Code: "EURO1", Amount: &pb.Money{MinorUnits: 100, Currency: "EUR"}}},
+			wantAmount: tryAmount(5998),
+		},
+		{
+			name:    "product no longer sold",
+			items:   []*pb.BasketItem{{ProductId: 1, Quantity: 1, CurrentPrice: tryAmount(4999).ToProto(), Unavailable: true}},
+			wantErr: true,
+		},
+		{
+			name:    "empty basket",
+			wantErr: true,
+		},
+		{
+			name:    "unknown product",
+			items:   []*pb.BasketItem{basketItem(4, 1, tryAmount(100))},
+			wantErr: true,
+		},
+		{
+			name:       "product priced in another currency is converted",
+			items:      []*pb.BasketItem{basketItem(1, 1, tryAmount(4999)), basketItem(3, 1, money.Money{MinorUnits: 2500, Currency: "EUR"})},
+			wantAmount: tryAmount(104999),
+		},
+		{
+			name:    "product priced in a currency without a rate",
+			items:   []*pb.BasketItem{basketItem(5, 1, money.Money{MinorUnits: 500, Currency: "GBP"})},
+			wantErr: true,
+		},
+		{
+			name:    "insufficient stock",
+			items:   []*pb.BasketItem{basketItem(2, 2, tryAmount(1995))},
+			wantErr: true,
+		},
+		{
+			name:       "product service unavailable",
+			items:      []*pb.BasketItem{basketItem(1, 1, tryAmount(4999))},
+			productErr: errors.New("unavailable"),
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo := NewMockPaymentRepository()
+			basketClient := NewMockBasketClient()
+			basketClient.baskets[1] = &pb.Basket{UserId: 1, Items: tt.items, Discounts: tt.discounts}
+			productClient := NewMockProductClient(products...)
+			productClient.err = tt.productErr
+			paymentService := service.NewPaymentService(repo, service.NewSimulatedProvider(), basketClient, productClient, testRates(t), "TRY")
+
+			// Execute
+			payment, err := paymentService.Checkout(context.Background(), 1, 0, "TRY", "credit_card")
+
+			// Assert
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Checkout() expected error but got none")
+				}
+				if len(repo.payments) != 0 {
+					t.Errorf("Checkout() created %d payments, want 0", len(repo.payments))
+				}
+				if len(basketClient.cleared) != 0 {
+					t.Error("Checkout() cleared basket on failure")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Checkout() unexpected error: %v", err)
+				return
+			}
+
+			if payment.Amount != tt.wantAmount {
+				t.Errorf("Checkout() amount = %v, want %v", payment.Amount, tt.wantAmount)
+			}
+			if payment.Status != model.StatusCaptured {
+				t.Errorf("Checkout() status = %v, want %v", payment.Status, model.StatusCaptured)
+			}
+			if len(payment.Items) != len(tt.items) {
+				t.Errorf("Checkout() items = %d, want %d", len(payment.Items), len(tt.items))
+			}
+
+			// Verify one stock event per line item
+			if len(repo.outbox) != len(tt.items) {
+				t.Fatalf("Expected %d outbox events, got %d", len(tt.items), len(repo.outbox))
+			}
+			for i, item := range tt.items {
+				var event model.StockUpdateEvent
+				if err := json.Unmarshal([]byte(repo.outbox[i].Payload), &event); err != nil {
+					t.Fatalf("Failed to decode outbox payload: %v", err)
+				}
+				if event.ProductID != uint(item.ProductId) || event.Quantity != -int(item.Quantity) || event.ReservationID != "res_1" {
+					t.Errorf("Event %d = %+v, want product %d quantity %d of reservation res_1", i, event, item.ProductId, -item.Quantity)
+				}
+			}
+
+			if len(basketClient.cleared) != 1 || basketClient.cleared[0] != 1 {
+				t.Errorf("Checkout() cleared baskets = %v, want [1]", basketClient.cleared)
+			}
+		})
+	}
+}
+
+func TestCheckoutBasketVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int64
+		wantErr error
+	}{
+		{name: "version the customer saw", version: 3},
+		{name: "current version", version: 0},
+		{name: "basket changed since", version: 2, wantErr: service.ErrBasketChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo := NewMockPaymentRepository()
+			basketClient := NewMockBasketClient()
+			basketClient.baskets[1] = &pb.Basket{UserId: 1, Version: 3, Items: []*pb.BasketItem{basketItem(1, 1, tryAmount(500))}}
+			productClient := NewMockProductClient(&pb.Product{Id: 1, Price: tryAmount(500).ToProto(), Stock: 1})
+			paymentService := service.NewPaymentService(repo, service.NewSimulatedProvider(), basketClient, productClient, testRates(t), "TRY")
+
+			// Execute
+			_, err := paymentService.Checkout(context.Background(), 1, tt.version, "TRY", "credit_card")
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Checkout() error = %v, want %v", err, tt.wantErr)
+			}
+			wantPayments := 1
+			if tt.wantErr != nil {
+				wantPayments = 0
+			}
+			if len(repo.payments) != wantPayments {
+				t.Errorf("Checkout() created %d payments, want %d", len(repo.payments), wantPayments)
+			}
+		})
+	}
+}
+
+func TestOutboxRelay(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		wantStatus   string
+		wantAttempts int
+		wantMessages int
+	}{
+		{
+			name:         "published on first try",
+			wantStatus:   model.OutboxStatusSent,
+			wantMessages: 1,
+		},
+		{
+			name:         "published after retries",
+			failures:     2,
+			wantStatus:   model.OutboxStatusSent,
+			wantMessages: 1,
+		},
+		{
+			name:         "left pending when broker is down",
+			failures:     3,
+			wantStatus:   model.OutboxStatusPending,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo := NewMockPaymentRepository()
+			repo.Create(context.Background(), &model.Payment{UserID: 1, Amount: tryAmount(1000), Status: model.StatusCaptured})
+			repo.addOutbox([]*model.OutboxEvent{
+				{PaymentID: 1, EventType: model.EventTypeStockUpdate, Payload: `{"product_id":7,"quantity":-2}`, Status: model.OutboxStatusPending},
+			})
+			publisher := NewMockRabbitMQPublisher()
+			publisher.failures = tt.failures
+			relay := service.NewOutboxRelay(repo, publisher, time.Second)
+
+			// Execute
+			if err := relay.RelayPending(context.Background()); err != nil {
+				t.Fatalf("RelayPending() unexpected error: %v", err)
+			}
+
+			// Assert
+			event := repo.outbox[0]
+			if event.Status != tt.wantStatus {
+				t.Errorf("RelayPending() status = %v, want %v", event.Status, tt.wantStatus)
+			}
+			if event.Attempts != tt.wantAttempts {
+				t.Errorf("RelayPending() attempts = %v, want %v", event.Attempts, tt.wantAttempts)
+			}
+			if len(publisher.messages) != tt.wantMessages {
+				t.Fatalf("Expected %d RabbitMQ messages, got %d", tt.wantMessages, len(publisher.messages))
+			}
+			if tt.wantMessages > 0 && (publisher.messages[0].ProductID != 7 || publisher.messages[0].Quantity != -2 || publisher.messages[0].EventID != event.ID) {
+				t.Errorf("Published event = %+v, want product 7 quantity -2 as event %d", publisher.messages[0], event.ID)
+			}
+		})
+	}
+}
+
+func TestOutboxRelayRecoversAfterOutage(t *testing.T) {
+	// Setup
+	repo := NewMockPaymentRepository()
+	basketClient := NewMockBasketClient()
+	basketClient.baskets[1] = &pb.Basket{UserId: 1, Items: []*pb.BasketItem{basketItem(1, 1, tryAmount(500))}}
+	paymentService := service.NewPaymentService(repo, service.NewSimulatedProvider(), basketClient, NewMockProductClient(&pb.Product{Id: 1, Price: tryAmount(500).ToProto(), Stock: 1}), testRates(t), "TRY")
+	if _, err := paymentService.Checkout(context.Background(), 1, 0, "TRY", "credit_card"); err != nil {
+		t.Fatalf("Checkout() unexpected error: %v", err)
+	}
+	publisher := NewMockRabbitMQPublisher()
+	publisher.failures = 3
+	relay := service.NewOutboxRelay(repo, publisher, time.Second)
+
+	// Execute: the first run fails every retry, the second succeeds
+	relay.RelayPending(context.Background())
+	relay.RelayPending(context.Background())
+
+	// Assert
+	if len(publisher.messages) != 1 {
+		t.Fatalf("Expected 1 RabbitMQ message, got %d", len(publisher.messages))
+	}
+	if repo.outbox[0].Status != model.OutboxStatusSent {
+		t.Errorf("outbox status = %v, want %v", repo.outbox[0].Status, model.OutboxStatusSent)
+	}
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gomicro/internal/money"
+	"gomicro/internal/payment/model"
+	"gomicro/internal/payment/service"
+)
+
+func TestPaymentTransition(t *testing.T) {
+	tests := []struct {
+		from    string
+		to      string
+		allowed bool
+	}{
+		{model.StatusPending, model.StatusAuthorized, true},
+		{model.StatusPending, model.StatusRequiresAction, true},
+		{model.StatusPending, model.StatusFailed, true},
+		{model.StatusPending, model.StatusCaptured, false},
+		{model.StatusRequiresAction, model.StatusAuthorized, true},
+		{model.StatusRequiresAction, model.StatusCaptured, false},
+		{model.StatusAuthorized, model.StatusCaptured, true},
+		{model.StatusAuthorized, model.StatusVoided, true},
+		{model.StatusAuthorized, model.StatusRefunded, false},
+		{model.StatusCaptured, model.StatusPartiallyRefunded, true},
+		{model.StatusCaptured, model.StatusRefunded, true},
+		{model.StatusCaptured, model.StatusVoided, false},
+		{model.StatusPartiallyRefunded, model.StatusPartiallyRefunded, true},
+		{model.StatusPartiallyRefunded, model.StatusRefunded, true},
+		{model.StatusRefunded, model.StatusPartiallyRefunded, false},
+		{model.StatusFailed, model.StatusAuthorized, false},
+		{model.StatusVoided, model.StatusCaptured, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			payment := &model.Payment{ID: 1, Status: tt.from}
+
+			event, err := payment.Transition(tt.to, "test")
+
+			if !tt.allowed {
+				var transitionErr *model.InvalidTransitionError
+				if !errors.As(err, &transitionErr) {
+					t.Fatalf("Transition() error = %v, want invalid transition", err)
+				}
+				if payment.Status != tt.from {
+					t.Errorf("Transition() changed status to %v on rejected transition", payment.Status)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Transition() unexpected error: %v", err)
+			}
+			if payment.Status != tt.to {
+				t.Errorf("Transition() status = %v, want %v", payment.Status, tt.to)
+			}
+			if event.FromStatus != tt.from || event.ToStatus != tt.to || event.PaymentID != 1 {
+				t.Errorf("Transition() event = %+v", event)
+			}
+		})
+	}
+}
+
+func TestGetPaymentHistory(t *testing.T) {
+	tests := []struct {
+		name          string
+		paymentMethod string
+		refund        bool
+		wantStatuses  []string
+	}{
+		{
+			name:          "captured",
+			paymentMethod: "4242424242424242",
+			wantStatuses:  []string{model.StatusAuthorized, model.StatusCaptured},
+		},
+		{
+			name:          "captured and refunded",
+			paymentMethod: "4242424242424242",
+			refund:        true,
+			wantStatuses:  []string{model.StatusAuthorized, model.StatusCaptured, model.StatusRefunded},
+		},
+		{
+			name:          "declined",
+			paymentMethod: "4000000000000002",
+			wantStatuses:  []string{model.StatusFailed},
+		},
+		{
+			name:          "3-D Secure",
+			paymentMethod: "4000000000003220",
+			wantStatuses:  []string{model.StatusRequiresAction},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo := NewMockPaymentRepository()
+			provider := service.NewSimulatedProvider(service.DefaultSimulatorRules()...)
+			paymentService := service.NewPaymentService(repo, provider, NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
+			payment, err := paymentService.ProcessPayment(context.Background(), 1, tryAmount(5000), tt.paymentMethod, "")
+			if err != nil {
+				t.Fatalf("ProcessPayment() unexpected error: %v", err)
+			}
+			if tt.refund {
+				if _, err := paymentService.RefundPayment(context.Background(), payment.ID, money.Money{}, "returned", nil); err != nil {
+					t.Fatalf("RefundPayment() unexpected error: %v", err)
+				}
+			}
+
+			// Execute
+			history, err := paymentService.GetPaymentHistory(context.Background(), payment.ID)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("GetPaymentHistory() unexpected error: %v", err)
+			}
+			if len(history) != len(tt.wantStatuses) {
+				t.Fatalf("GetPaymentHistory() returned %d events, want %d", len(history), len(tt.wantStatuses))
+			}
+			from := model.StatusPending
+			for i, event := range history {
+				if event.FromStatus != from || event.ToStatus != tt.wantStatuses[i] {
+					t.Errorf("event %d = %s -> %s, want %s -> %s", i, event.FromStatus, event.ToStatus, from, tt.wantStatuses[i])
+				}
+				from = event.ToStatus
+			}
+		})
+	}
+
+	t.Run("unknown payment", func(t *testing.T) {
+		paymentService := service.NewPaymentService(NewMockPaymentRepository(), service.NewSimulatedProvider(), NewMockBasketClient(), NewMockProductClient(), testRates(t), "TRY")
+		if _, err := paymentService.GetPaymentHistory(context.Background(), 999); !errors.Is(err, service.ErrPaymentNotFound) {
+			t.Errorf("GetPaymentHistory() error = %v, want %v", err, service.ErrPaymentNotFound)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gomicro/internal/product/handler"
+)
+
+func TestProductRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantField  string
+	}{
+		{name: "get product", path: "/products/1", wantStatus: http.StatusOK, wantField: "version"},
+		{name: "list products", path: "/products/", wantStatus: http.StatusOK, wantField: "products"},
+		{name: "missing product", path: "/products/999", wantStatus: http.StatusNotFound, wantField: "error"},
+		{name: "unknown route", path: "/users/1", wantStatus: http.StatusNotFound},
+	}
+
+	// Setup: the router the product service serves
+	_, productService := newEditableProduct()
+	server := httptest.NewServer(handler.NewRouter(productService))
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			resp, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("GET %s unexpected error: %v", tt.path, err)
+			}
+			defer resp.Body.Close()
+
+			// Assert
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantField == "" {
+				return
+			}
+			var body map[string]json.RawMessage
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("GET %s returned invalid JSON: %v", tt.path, err)
+			}
+			if _, ok := body[tt.wantField]; !ok {
+				t.Errorf("GET %s body %v has no %q", tt.path, body, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gomicro/internal/money"
+	"gomicro/internal/product/model"
+	"gomicro/internal/product/repository"
+	"gomicro/internal/product/service"
+)
+
+// seedProducts creates products one minute apart, oldest first
+func seedProducts(repo *MockProductRepository, base time.Time, products ...*model.Product) {
+	for i, p := range products {
+		repo.Create(context.Background(), p)
+		p.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+	}
+}
+
+func TestListProducts(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := NewMockProductRepository()
+	seedProducts(repo, base,
+		&model.Product{Name: "Mechanical Keyboard", Description: "Brown switches", Category: "peripherals", Price: tryAmount(250000), Stock: 5, IsActive: true},
+		&model.Product{Name: "Mouse", Description: "Wireless", Category: "peripherals", Price: tryAmount(40000), Stock: 0, IsActive: true},
+		&model.Product{Name: "Monitor", Description: "27 inch", Category: "displays", Price: tryAmount(900000), Stock: 3, IsActive: true},
+		&model.Product{Name: "Keycaps", Description: "PBT set for a keyboard", Category: "peripherals", Price: money.Money{MinorUnits: 3000, Currency: "EUR"}, Stock: 8, IsActive: true},
+		&model.Product{Name: "Old Webcam", Description: "720p", Category: "peripherals", Price: tryAmount(20000), Stock: 2, IsActive: false},
+	)
+	productService := service.NewProductService(repo)
+	active, inactive := true, false
+
+	tests := []struct {
+		name   string
+		filter repository.ProductFilter
+		sort   string
+		wantID []uint
+	}{
+		{
+			name:   "active newest first",
+			wantID: []uint{4, 3, 2, 1},
+		},
+		{
+			name:   "including inactive",
+			filter: repository.ProductFilter{IncludeInactive: true},
+			wantID: []uint{5, 4, 3, 2, 1},
+		},
+		{
+			name:   "query matches name or description ignoring case",
+			filter: repository.ProductFilter{Query: "KEYBOARD"},
+			wantID: []uint{4, 1},
+		},
+		{
+			name:   "by category",
+			filter: repository.ProductFilter{Category: "displays"},
+			wantID: []uint{3},
+		},
+		{
+			name:   "by price range",
+			filter: repository.ProductFilter{Currency: "TRY", MinPrice: 30000, MaxPrice: 250000},
+			wantID: []uint{2, 1},
+		},
+		{
+			name:   "in stock only",
+			filter: repository.ProductFilter{InStockOnly: true},
+			wantID: []uint{4, 3, 1},
+		},
+		{
+			name:   "active only",
+			filter: repository.ProductFilter{IsActive: &active},
+			wantID: []uint{4, 3, 2, 1},
+		},
+		{
+			name:   "inactive only",
+			filter: repository.ProductFilter{IsActive: &inactive},
+			wantID: []uint{5},
+		},
+		{
+			name:   "inactive only wins over including inactive",
+			filter: repository.ProductFilter{IsActive: &inactive, IncludeInactive: true},
+			wantID: []uint{5},
+		},
+		{
+			name:   "by name",
+			sort:   repository.SortName,
+			wantID: []uint{4, 1, 3, 2},
+		},
+		{
+			name:   "by price ascending",
+			filter: repository.ProductFilter{Currency: "TRY"},
+			sort:   repository.SortPriceAsc,
+			wantID: []uint{2, 1, 3},
+		},
+		{
+			name:   "by price descending",
+			filter: repository.ProductFilter{Currency: "TRY", IsActive: &active},
+			sort:   repository.SortPriceDesc,
+			wantID: []uint{3, 1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products, next, err := productService.ListProducts(context.Background(), tt.filter, tt.sort, 0, "")
+			if err != nil {
+				t.Fatalf("ListProducts() unexpected error: %v", err)
+			}
+			if next != "" {
+				t.Errorf("ListProducts() next page token = %q, want empty", next)
+			}
+			if len(products) != len(tt.wantID) {
+				t.Fatalf("ListProducts() returned %d products, want %d", len(products), len(tt.wantID))
+			}
+			for i, p := range products {
+				if p.ID != tt.wantID[i] {
+					t.Errorf("ListProducts()[%d] ID = %d, want %d", i, p.ID, tt.wantID[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListProductsPagination(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := NewMockProductRepository()
+	// Prices tie in pairs so pages must break ties by ID
+	var products []*model.Product
+	for i := 0; i < 7; i++ {
+		products = append(products, &model.Product{Name: "Cable", Price: tryAmount(int64(1000 * (i / 2))), Stock: 1, IsActive: true})
+	}
+	seedProducts(repo, base, products...)
+	productService := service.NewProductService(repo)
+	filter := repository.ProductFilter{Currency: "TRY"}
+
+	tests := []struct {
+		sort   string
+		wantID []uint
+	}{
+		{sort: repository.SortNewest, wantID: []uint{7, 6, 5, 4, 3, 2, 1}},
+		{sort: repository.SortName, wantID: []uint{1, 2, 3, 4, 5, 6, 7}},
+		{sort: repository.SortPriceAsc, wantID: []uint{1, 2, 3, 4, 5, 6, 7}},
+		{sort: repository.SortPriceDesc, wantID: []uint{7, 6, 5, 4, 3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			var seen []uint
+			token := ""
+			for page := 0; ; page++ {
+				if page > 5 {
+					t.Fatal("ListProducts() did not terminate")
+				}
+				result, next, err := productService.ListProducts(context.Background(), filter, tt.sort, 3, token)
+				if err != nil {
+					t.Fatalf("ListProducts() unexpected error: %v", err)
+				}
+				for _, p := range result {
+					seen = append(seen, p.ID)
+				}
+				if next == "" {
+					break
+				}
+				token = next
+			}
+
+			if len(seen) != len(tt.wantID) {
+				t.Fatalf("ListProducts() paged through %v, want %v", seen, tt.wantID)
+			}
+			for i := range tt.wantID {
+				if seen[i] != tt.wantID[i] {
+					t.Fatalf("ListProducts() paged through %v, want %v", seen, tt.wantID)
+				}
+			}
+		})
+	}
+
+	_, next, err := productService.ListProducts(context.Background(), filter, repository.SortName, 3, "")
+	if err != nil {
+		t.Fatalf("ListProducts() unexpected error: %v", err)
+	}
+	if _, _, err := productService.ListProducts(context.Background(), filter, repository.SortPriceAsc, 3, next); !errors.Is(err, service.ErrInvalidPageToken) {
+		t.Errorf("ListProducts() token from another sort error = %v, want %v", err, service.ErrInvalidPageToken)
+	}
+	if _, _, err := productService.ListProducts(context.Background(), repository.ProductFilter{}, "", 3, "not-a-token"); !errors.Is(err, service.ErrInvalidPageToken) {
+		t.Errorf("ListProducts() invalid token error = %v, want %v", err, service.ErrInvalidPageToken)
+	}
+	if _, _, err := productService.ListProducts(context.Background(), repository.ProductFilter{}, "popularity", 3, ""); !errors.Is(err, service.ErrInvalidSort) {
+		t.Errorf("ListProducts() unknown sort error = %v, want %v", err, service.ErrInvalidSort)
+	}
+}
+
+func TestListProductsPriceRequiresCurrency(t *testing.T) {
+	productService := service.NewProductService(NewMockProductRepository())
+
+	tests := []struct {
+		name   string
+		filter repository.ProductFilter
+		sort   string
+	}{
+		{name: "minimum price", filter: repository.ProductFilter{MinPrice: 1000}},
+		{name: "maximum price", filter: repository.ProductFilter{MaxPrice: 1000}},
+		{name: "price ascending", sort: repository.SortPriceAsc},
+		{name: "price descending", sort: repository.SortPriceDesc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			_, _, err := productService.ListProducts(context.Background(), tt.filter, tt.sort, 0, "")
+
+			// Assert
+			if !errors.Is(err, service.ErrInvalidPriceFilter) {
+				t.Errorf("ListProducts() without currency error = %v, want %v", err, service.ErrInvalidPriceFilter)
+			}
+		})
+	}
+}
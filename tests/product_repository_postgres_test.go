@@ -0,0 +1,381 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gomicro/internal/money"
+	"gomicro/internal/product/model"
+	"gomicro/internal/product/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openPostgresProductRepository uses the database in
+// PRODUCT_TEST_POSTGRES_DSN and skips the test without one. Every test
+// starts from freshly created product tables holding the productCatalog.
+func openPostgresProductRepository(t *testing.T) repository.ProductRepository {
+	repo, _ := openPostgresProductDB(t)
+	return repo
+}
+
+// openPostgresProductDB is openPostgresProductRepository that also returns
+// the database
+func openPostgresProductDB(t *testing.T) (repository.ProductRepository, *gorm.DB) {
+	dsn := os.Getenv("PRODUCT_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("PRODUCT_TEST_POSTGRES_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	for _, sql := range []string{"CREATE EXTENSION IF NOT EXISTS pg_trgm", "DROP TABLE IF EXISTS processed_stock_updates, stock_reservations, products"} {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatalf("Failed to prepare database: %v", err)
+		}
+	}
+	if err := db.AutoMigrate(&model.Product{}, &model.StockReservation{}, &model.ProcessedStockUpdate{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	repo := repository.NewProductRepository(db)
+	for _, product := range productCatalog() {
+		if _, err := repo.Create(context.Background(), product); err != nil {
+			t.Fatalf("Failed to create product %q: %v", product.Name, err)
+		}
+	}
+	return repo, db
+}
+
+// productCatalog returns the products created by
+// openPostgresProductRepository, with IDs 1 to 7 in order. Products 2 and 3,
+// and 6 and 7, were created at the same time; products 2 and 7 share their
+// name and price.
+func productCatalog() []*model.Product {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	return []*model.Product{
+		{Name: "Mechanical Keyboard", Category: "peripherals", Description: "Hot-swappable brown switches", Price: tryAmount(250000), Stock: 5, IsActive: true, CreatedAt: base},
+		{Name: "Wireless Mouse", Category: "peripherals", Description: "Pairs with any keyboard", Price: tryAmount(40000), Stock: 9, IsActive: true, CreatedAt: base.Add(time.Hour)},
+		{Name: "Keyboard Stand", Category: "accessories", Description: "Aluminium stand that tilts your keyboard", Price: tryAmount(90000), Stock: 2, IsActive: true, CreatedAt: base.Add(time.Hour)},
+		{Name: "Monitor", Category: "displays", Description: "27 inch IPS", Price: money.Money{MinorUnits: 30000, Currency: "EUR"}, Stock: 1, IsActive: true, CreatedAt: base.Add(2 * time.Hour)},
+		{Name: "Vintage Keyboard", Category: "peripherals", Description: "Retired model", Price: tryAmount(10000), Stock: 1, IsActive: false, CreatedAt: base.Add(2 * time.Hour)},
+		{Name: "Wrist Rest", Category: "keyboards", Description: "Memory foam", Price: tryAmount(50000), Stock: 0, IsActive: true, CreatedAt: base.Add(3 * time.Hour)},
+		{Name: "Wireless Mouse", Category: "peripherals", Description: "Silent clicks", Price: tryAmount(40000), Stock: 3, IsActive: true, CreatedAt: base.Add(3 * time.Hour)},
+	}
+}
+
+// activeSearch returns a query for the terms over active products
+func activeSearch(fuzzy bool, terms ...string) repository.SearchQuery {
+	active := true
+	return repository.SearchQuery{Terms: terms, Fuzzy: fuzzy, Filter: repository.ProductFilter{IsActive: &active}}
+}
+
+// hitIDs returns the product IDs of the hits in order
+func hitIDs(hits []*repository.SearchHit) []uint {
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.Product.ID
+	}
+	return ids
+}
+
+func TestProductRepositorySearch(t *testing.T) {
+	repo := openPostgresProductRepository(t)
+
+	tests := []struct {
+		name   string
+		query  repository.SearchQuery
+		wantID []uint
+	}{
+		{
+			// Product 3 matches in its name and description, 1 in its name,
+			// 6 in its category and 2 in its description
+			name:   "name matches rank above category and description matches",
+			query:  activeSearch(false, "keyb"),
+			wantID: []uint{3, 1, 6, 2},
+		},
+		{
+			name:   "every term must match",
+			query:  activeSearch(false, "mech", "key"),
+			wantID: []uint{1},
+		},
+		{
+			name:   "typos do not match the full text",
+			query:  activeSearch(false, "keybord"),
+			wantID: []uint{},
+		},
+		{
+			// Both names are equally similar, so the ID breaks the tie
+			name:   "fuzzy mode matches typos in names",
+			query:  activeSearch(true, "keybord"),
+			wantID: []uint{3, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute
+			hits, err := repo.Search(context.Background(), tt.query, nil, 10)
+			if err != nil {
+				t.Fatalf("Search() unexpected error: %v", err)
+			}
+
+			// Assert
+			if got := hitIDs(hits); !reflect.DeepEqual(got, tt.wantID) {
+				t.Fatalf("Search() hits = %v, want %v", got, tt.wantID)
+			}
+
+			// Paging one hit at a time from the (rank, id) cursor of the
+			// previous page must return the same hits
+			var paged []*repository.SearchHit
+			var after *repository.SearchCursor
+			for i := 0; i <= len(tt.wantID); i++ {
+				page, err := repo.Search(context.Background(), tt.query, after, 1)
+				if err != nil {
+					t.Fatalf("Search() page %d unexpected error: %v", i, err)
+				}
+				if len(page) == 0 {
+					break
+				}
+				paged = append(paged, page...)
+				after = &repository.SearchCursor{Rank: page[0].Rank, ID: page[0].Product.ID}
+			}
+			if got := hitIDs(paged); !reflect.DeepEqual(got, tt.wantID) {
+				t.Errorf("Search() paged through %v, want %v", got, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestProductRepositorySearchHighlights(t *testing.T) {
+	repo := openPostgresProductRepository(t)
+
+	// Execute
+	hits, err := repo.Search(context.Background(), activeSearch(false, "keyb"), nil, 1)
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %v", err)
+	}
+
+	// Assert
+	if len(hits) != 1 || hits[0].Product.ID != 3 {
+		t.Fatalf("Search() hits = %v, want product 3", hitIDs(hits))
+	}
+	if want := "<mark>Keyboard</mark> Stand"; hits[0].NameHighlight != want {
+		t.Errorf("Search() name highlight = %q, want %q", hits[0].NameHighlight, want)
+	}
+	if !strings.Contains(hits[0].Snippet, "tilts your <mark>keyboard</mark>") {
+		t.Errorf("Search() snippet = %q, want the description with keyboard highlighted", hits[0].Snippet)
+	}
+	if hits[0].Product.Name != "Keyboard Stand" || hits[0].Product.Price != tryAmount(90000) {
+		t.Errorf("Search() product = %+v, want the Keyboard Stand at 900 TRY", hits[0].Product)
+	}
+}
+
+func TestProductRepositorySearchFacets(t *testing.T) {
+	repo := openPostgresProductRepository(t)
+	q := activeSearch(false, "keyb")
+
+	// Execute
+	categories, err := repo.SearchCategoryCounts(context.Background(), q)
+	if err != nil {
+		t.Fatalf("SearchCategoryCounts() unexpected error: %v", err)
+	}
+	q.Filter.Currency = "TRY"
+	prices, err := repo.SearchPriceCounts(context.Background(), q, []int64{50000, 100000})
+	if err != nil {
+		t.Fatalf("SearchPriceCounts() unexpected error: %v", err)
+	}
+
+	// Assert: categories are ordered by count, then name; product 6 costs
+	// exactly 500 TRY and falls in the bucket starting there
+	wantCategories := []repository.CategoryCount{{Category: "peripherals", Count: 2}, {Category: "accessories", Count: 1}, {Category: "keyboards", Count: 1}}
+	if !reflect.DeepEqual(categories, wantCategories) {
+		t.Errorf("SearchCategoryCounts() = %v, want %v", categories, wantCategories)
+	}
+	if want := []int64{1, 2, 1}; !reflect.DeepEqual(prices, want) {
+		t.Errorf("SearchPriceCounts() = %v, want %v", prices, want)
+	}
+}
+
+func TestProductRepositoryList(t *testing.T) {
+	repo := openPostgresProductRepository(t)
+
+	tests := []struct {
+		sort   string
+		wantID []uint
+	}{
+		{sort: repository.SortNewest, wantID: []uint{7, 6, 5, 3, 2, 1}},
+		{sort: repository.SortName, wantID: []uint{3, 1, 5, 2, 7, 6}},
+		{sort: repository.SortPriceAsc, wantID: []uint{5, 2, 7, 6, 3, 1}},
+		{sort: repository.SortPriceDesc, wantID: []uint{1, 3, 6, 7, 2, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			// Execute: page two products at a time, so the ties between
+			// products 2 and 3, 2 and 7, and 6 and 7 straddle page boundaries
+			var seen []uint
+			var after *repository.ProductCursor
+			for page := 0; ; page++ {
+				if page > len(tt.wantID) {
+					t.Fatal("List() did not terminate")
+				}
+				products, err := repo.List(context.Background(), repository.ProductFilter{Currency: "TRY"}, tt.sort, after, 2)
+				if err != nil {
+					t.Fatalf("List() unexpected error: %v", err)
+				}
+				if len(products) == 0 {
+					break
+				}
+				for _, p := range products {
+					seen = append(seen, p.ID)
+				}
+				after = repository.NewProductCursor(products[len(products)-1])
+			}
+
+			// Assert
+			if !reflect.DeepEqual(seen, tt.wantID) {
+				t.Errorf("List() paged through %v, want %v", seen, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestProductRepositoryVersionedWrites(t *testing.T) {
+	repo := openPostgresProductRepository(t)
+	ctx := context.Background()
+
+	// Setup
+	product, err := repo.GetByID(ctx, 1)
+	if err != nil || product == nil {
+		t.Fatalf("GetByID() = %v, %v; want product 1", product, err)
+	}
+	stale := *product
+	product.Name = "Tactile Keyboard"
+
+	// Execute
+	updated, err := repo.Update(ctx, product)
+	if err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+
+	// Assert
+	if updated.Version != 2 {
+		t.Errorf("Update() version = %d, want 2", updated.Version)
+	}
+	stored, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() unexpected error: %v", err)
+	}
+	if stored.Name != "Tactile Keyboard" || stored.Version != 2 || !stored.CreatedAt.Equal(stale.CreatedAt) {
+		t.Errorf("GetByID() after Update() = %+v, want the new name at version 2 with the original creation time", stored)
+	}
+	hits, err := repo.Search(ctx, activeSearch(false, "tact"), nil, 10)
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %v", err)
+	}
+	if got := hitIDs(hits); !reflect.DeepEqual(got, []uint{1}) {
+		t.Errorf("Search() for the new name = %v, want [1]", got)
+	}
+
+	stale.Name = "Lost Update"
+	if _, err := repo.Update(ctx, &stale); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Update() at a stale version error = %v, want %v", err, repository.ErrVersionConflict)
+	}
+	missing := *stored
+	missing.ID = 999
+	if _, err := repo.Update(ctx, &missing); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Errorf("Update() of a missing product error = %v, want %v", err, repository.ErrProductNotFound)
+	}
+
+	if err := repo.Delete(ctx, 1, 1); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Delete() at a stale version error = %v, want %v", err, repository.ErrVersionConflict)
+	}
+	if err := repo.Delete(ctx, 1, 2); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	if err := repo.Delete(ctx, 1, 2); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Errorf("Delete() of a deleted product error = %v, want %v", err, repository.ErrProductNotFound)
+	}
+	if deleted, err := repo.GetByID(ctx, 1); err != nil || deleted != nil {
+		t.Errorf("GetByID() after Delete() = %v, %v; want nothing", deleted, err)
+	}
+}
+
+func TestProductRepositoryApplyStockUpdate(t *testing.T) {
+	repo, db := openPostgresProductDB(t)
+	reservations := repository.NewReservationRepository(db)
+	ctx := context.Background()
+
+	// Setup: a checkout holds 2 units of product 1 and 1 unit of product 2
+	expiresAt := time.Now().Add(time.Minute)
+	err := reservations.Reserve(ctx, []*model.StockReservation{
+		{ReservationID: "res_1", ProductID: 1, Quantity: 2, ExpiresAt: expiresAt},
+		{ReservationID: "res_1", ProductID: 2, Quantity: 1, ExpiresAt: expiresAt},
+	})
+	if err != nil {
+		t.Fatalf("Reserve() unexpected error: %v", err)
+	}
+
+	// Execute
+	err = repo.ApplyStockUpdate(ctx, &model.StockUpdateEvent{ProductID: 1, Quantity: -2, ReservationID: "res_1"})
+
+	// Assert: the decrement releases only the product's hold
+	if err != nil {
+		t.Fatalf("ApplyStockUpdate() unexpected error: %v", err)
+	}
+	if product, _ := repo.GetByID(ctx, 1); product.Stock != 3 {
+		t.Errorf("stock after ApplyStockUpdate() = %d, want 3", product.Stock)
+	}
+	reserved, err := reservations.ReservedQuantities(ctx, []uint{1, 2})
+	if err != nil {
+		t.Fatalf("ReservedQuantities() unexpected error: %v", err)
+	}
+	if want := map[uint]int{2: 1}; !reflect.DeepEqual(reserved, want) {
+		t.Errorf("ReservedQuantities() after ApplyStockUpdate() = %v, want %v", reserved, want)
+	}
+
+	if err := repo.ApplyStockUpdate(ctx, &model.StockUpdateEvent{ProductID: 2, Quantity: -10, ReservationID: "res_1"}); !errors.Is(err, repository.ErrInsufficientStock) {
+		t.Errorf("ApplyStockUpdate() beyond the stock error = %v, want %v", err, repository.ErrInsufficientStock)
+	}
+	if reserved, _ := reservations.ReservedQuantities(ctx, []uint{2}); reserved[2] != 1 {
+		t.Errorf("ReservedQuantities() after a rejected update = %v, want the hold kept", reserved)
+	}
+}
+
+func TestProductRepositoryApplyStockUpdateOnce(t *testing.T) {
+	repo := openPostgresProductRepository(t)
+	ctx := context.Background()
+	event := &model.StockUpdateEvent{EventID: 1, ProductID: 2, Quantity: -3}
+
+	// Execute: the event is published twice
+	first := repo.ApplyStockUpdate(ctx, event)
+	second := repo.ApplyStockUpdate(ctx, event)
+
+	// Assert
+	if first != nil {
+		t.Fatalf("ApplyStockUpdate() unexpected error: %v", first)
+	}
+	if !errors.Is(second, repository.ErrStockUpdateApplied) {
+		t.Errorf("ApplyStockUpdate() again error = %v, want %v", second, repository.ErrStockUpdateApplied)
+	}
+	if product, _ := repo.GetByID(ctx, 2); product.Stock != 6 {
+		t.Errorf("stock after a duplicate event = %d, want 6", product.Stock)
+	}
+
+	// A rejected event is not recorded, so it can be applied later
+	if err := repo.ApplyStockUpdate(ctx, &model.StockUpdateEvent{EventID: 2, ProductID: 2, Quantity: -7}); !errors.Is(err, repository.ErrInsufficientStock) {
+		t.Fatalf("ApplyStockUpdate() beyond the stock error = %v, want %v", err, repository.ErrInsufficientStock)
+	}
+	repo.UpdateStock(ctx, 2, 1)
+	if err := repo.ApplyStockUpdate(ctx, &model.StockUpdateEvent{EventID: 2, ProductID: 2, Quantity: -7}); err != nil {
+		t.Errorf("ApplyStockUpdate() after a restock unexpected error: %v", err)
+	}
+}
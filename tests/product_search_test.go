@@ -0,0 +1,269 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gomicro/internal/money"
+	"gomicro/internal/product/model"
+	"gomicro/internal/product/repository"
+	"gomicro/internal/product/service"
+)
+
+// searchHit returns a stubbed hit on a product with the given ID
+func searchHit(id uint, rank float64) *repository.SearchHit {
+	return &repository.SearchHit{Product: &model.Product{ID: id, Name: "USB Cable", Price: tryAmount(1000), IsActive: true}, Rank: rank}
+}
+
+func TestSearchProducts(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		filter      repository.ProductFilter
+		exact       []*repository.SearchHit
+		fuzzy       []*repository.SearchHit
+		wantTerms   []string
+		wantID      []uint
+		wantFuzzy   bool
+		wantQueries int
+	}{
+		{
+			name:        "exact hits are returned as ranked",
+			query:       "keyboard",
+			exact:       []*repository.SearchHit{searchHit(3, 0.6), searchHit(1, 0.6), searchHit(2, 0.2)},
+			fuzzy:       []*repository.SearchHit{searchHit(4, 0.5)},
+			wantTerms:   []string{"keyboard"},
+			wantID:      []uint{3, 1, 2},
+			wantQueries: 1,
+		},
+		{
+			name:        "words are lower-cased and split on punctuation",
+			query:       "Mech, KEY! a|b",
+			exact:       []*repository.SearchHit{searchHit(1, 0.6)},
+			wantTerms:   []string{"mech", "key", "a", "b"},
+			wantID:      []uint{1},
+			wantQueries: 1,
+		},
+		{
+			name:        "search query and active filter are overridden",
+			query:       "keyboard",
+			filter:      repository.ProductFilter{Query: "mouse", Category: "accessories", IsActive: new(bool)},
+			exact:       []*repository.SearchHit{searchHit(3, 0.6)},
+			wantTerms:   []string{"keyboard"},
+			wantID:      []uint{3},
+			wantQueries: 1,
+		},
+		{
+			name:        "no exact hits fall back to fuzzy hits",
+			query:       "keybord",
+			fuzzy:       []*repository.SearchHit{searchHit(3, 0.8), searchHit(1, 0.5)},
+			wantTerms:   []string{"keybord"},
+			wantID:      []uint{3, 1},
+			wantFuzzy:   true,
+			wantQueries: 2,
+		},
+		{
+			name:        "no match at all",
+			query:       "toaster",
+			wantTerms:   []string{"toaster"},
+			wantFuzzy:   true,
+			wantQueries: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo := NewMockProductRepository()
+			repo.searchHits, repo.fuzzyHits = tt.exact, tt.fuzzy
+			productService := service.NewProductService(repo)
+
+			// Execute
+			result, err := productService.SearchProducts(context.Background(), tt.query, tt.filter, nil, 0, "")
+
+			// Assert
+			if err != nil {
+				t.Fatalf("SearchProducts() unexpected error: %v", err)
+			}
+			if result.Fuzzy != tt.wantFuzzy {
+				t.Errorf("SearchProducts() fuzzy = %v, want %v", result.Fuzzy, tt.wantFuzzy)
+			}
+			if result.NextPageToken != "" {
+				t.Errorf("SearchProducts() next page token = %q, want empty", result.NextPageToken)
+			}
+			if len(result.Hits) != len(tt.wantID) {
+				t.Fatalf("SearchProducts() returned %d hits, want %d", len(result.Hits), len(tt.wantID))
+			}
+			for i, hit := range result.Hits {
+				if hit.Product.ID != tt.wantID[i] {
+					t.Errorf("SearchProducts()[%d] ID = %d, want %d", i, hit.Product.ID, tt.wantID[i])
+				}
+			}
+			if len(repo.searches) != tt.wantQueries {
+				t.Fatalf("SearchProducts() searched %d times, want %d", len(repo.searches), tt.wantQueries)
+			}
+			for i, q := range repo.searches {
+				if !reflect.DeepEqual(q.Terms, tt.wantTerms) {
+					t.Errorf("Search() terms = %q, want %q", q.Terms, tt.wantTerms)
+				}
+				if q.Fuzzy != (i > 0) {
+					t.Errorf("Search() call %d fuzzy = %v, want %v", i, q.Fuzzy, i > 0)
+				}
+				if q.Filter.Query != "" || q.Filter.IsActive == nil || !*q.Filter.IsActive || q.Filter.Category != tt.filter.Category {
+					t.Errorf("Search() filter = %+v, want active products in %q only", q.Filter, tt.filter.Category)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchProductsFacets(t *testing.T) {
+	// Setup
+	repo := NewMockProductRepository()
+	repo.searchHits = []*repository.SearchHit{searchHit(1, 0.6)}
+	repo.categoryCounts = []repository.CategoryCount{{Category: "peripherals", Count: 2}, {Category: "accessories", Count: 1}}
+	repo.priceCounts = []int64{0, 0, 1, 1, 0, 1}
+	productService := service.NewProductService(repo)
+	filter := repository.ProductFilter{Category: "peripherals", Currency: "TRY", MinPrice: 50000}
+
+	// Execute
+	result, err := productService.SearchProducts(context.Background(), "keyboard", filter, nil, 0, "")
+	if err != nil {
+		t.Fatalf("SearchProducts() unexpected error: %v", err)
+	}
+
+	// Assert: the facets ignore their own filter but keep the others
+	if !reflect.DeepEqual(result.Categories, repo.categoryCounts) {
+		t.Errorf("SearchProducts() categories = %v, want %v", result.Categories, repo.categoryCounts)
+	}
+	if q := repo.categoryQueries[0]; q.Filter.Category != "" || q.Filter.MinPrice != 50000 || q.Filter.Currency != "TRY" {
+		t.Errorf("SearchCategoryCounts() filter = %+v, want the price filter without the category", q.Filter)
+	}
+	if q := repo.priceQueries[0]; q.Filter.Category != "peripherals" || q.Filter.MinPrice != 0 || q.Filter.Currency != "TRY" {
+		t.Errorf("SearchPriceCounts() filter = %+v, want the category filter without the price range", q.Filter)
+	}
+	wantBounds := []int64{10000, 25000, 50000, 100000, 250000}
+	if !reflect.DeepEqual(repo.priceBounds, wantBounds) {
+		t.Errorf("SearchPriceCounts() bounds = %v, want %v", repo.priceBounds, wantBounds)
+	}
+	wantBuckets := []service.PriceBucket{
+		{Min: 0, Max: 10000, Count: 0},
+		{Min: 10000, Max: 25000, Count: 0},
+		{Min: 25000, Max: 50000, Count: 1},
+		{Min: 50000, Max: 100000, Count: 1},
+		{Min: 100000, Max: 250000, Count: 0},
+		{Min: 250000, Max: 0, Count: 1},
+	}
+	if !reflect.DeepEqual(result.PriceBuckets, wantBuckets) {
+		t.Errorf("SearchProducts() price buckets = %v, want %v", result.PriceBuckets, wantBuckets)
+	}
+
+	filter.Currency = "JPY"
+	if _, err := productService.SearchProducts(context.Background(), "keyboard", filter, nil, 0, ""); err != nil {
+		t.Fatalf("SearchProducts() unexpected error: %v", err)
+	}
+	if wantBounds := []int64{100, 250, 500, 1000, 2500}; !reflect.DeepEqual(repo.priceBounds, wantBounds) {
+		t.Errorf("SearchPriceCounts() JPY bounds = %v, want %v", repo.priceBounds, wantBounds)
+	}
+
+	repo.priceCounts = []int64{1, 2}
+	result, err = productService.SearchProducts(context.Background(), "keyboard", filter, []int64{50000}, 0, "")
+	if err != nil {
+		t.Fatalf("SearchProducts() unexpected error: %v", err)
+	}
+	if want := []service.PriceBucket{{Min: 0, Max: 50000, Count: 1}, {Min: 50000, Count: 2}}; !reflect.DeepEqual(result.PriceBuckets, want) {
+		t.Errorf("SearchProducts() custom price buckets = %v, want %v", result.PriceBuckets, want)
+	}
+
+	calls := len(repo.priceQueries)
+	result, err = productService.SearchProducts(context.Background(), "keyboard", repository.ProductFilter{}, nil, 0, "")
+	if err != nil {
+		t.Fatalf("SearchProducts() unexpected error: %v", err)
+	}
+	if result.PriceBuckets != nil || len(repo.priceQueries) != calls {
+		t.Errorf("SearchProducts() price buckets without currency = %v, want none", result.PriceBuckets)
+	}
+}
+
+func TestSearchProductsInvalidRequest(t *testing.T) {
+	productService := service.NewProductService(NewMockProductRepository())
+
+	tests := []struct {
+		name    string
+		query   string
+		filter  repository.ProductFilter
+		bounds  []int64
+		token   string
+		wantErr error
+	}{
+		{name: "no words", query: " -- ", wantErr: service.ErrInvalidSearchQuery},
+		{name: "descending bounds", query: "keyboard", filter: repository.ProductFilter{Currency: "TRY"}, bounds: []int64{500, 100}, wantErr: service.ErrInvalidPriceBuckets},
+		{name: "zero bound", query: "keyboard", filter: repository.ProductFilter{Currency: "TRY"}, bounds: []int64{0, 100}, wantErr: service.ErrInvalidPriceBuckets},
+		{name: "unknown currency", query: "keyboard", filter: repository.ProductFilter{Currency: "XYZ"}, wantErr: money.ErrUnknownCurrency},
+		{name: "bad page token", query: "keyboard", token: "not-a-token", wantErr: service.ErrInvalidPageToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := productService.SearchProducts(context.Background(), tt.query, tt.filter, tt.bounds, 0, tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SearchProducts() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSearchProductsPagination(t *testing.T) {
+	var hits []*repository.SearchHit
+	for id := uint(5); id > 0; id-- {
+		hits = append(hits, searchHit(id, 0.5))
+	}
+
+	for _, fuzzy := range []bool{false, true} {
+		t.Run(map[bool]string{false: "exact", true: "fuzzy"}[fuzzy], func(t *testing.T) {
+			// Setup
+			repo := NewMockProductRepository()
+			if fuzzy {
+				repo.fuzzyHits = hits
+			} else {
+				repo.searchHits = hits
+			}
+			productService := service.NewProductService(repo)
+
+			// Execute
+			var seen []uint
+			token := ""
+			for page := 0; ; page++ {
+				if page > 5 {
+					t.Fatal("SearchProducts() did not terminate")
+				}
+				result, err := productService.SearchProducts(context.Background(), "cable", repository.ProductFilter{}, nil, 2, token)
+				if err != nil {
+					t.Fatalf("SearchProducts() unexpected error: %v", err)
+				}
+				if result.Fuzzy != fuzzy {
+					t.Fatalf("SearchProducts() page %d fuzzy = %v", page, result.Fuzzy)
+				}
+				for _, hit := range result.Hits {
+					seen = append(seen, hit.Product.ID)
+				}
+				if result.NextPageToken == "" {
+					break
+				}
+				token = result.NextPageToken
+			}
+
+			// Assert
+			if want := []uint{5, 4, 3, 2, 1}; !reflect.DeepEqual(seen, want) {
+				t.Fatalf("SearchProducts() paged through %v, want %v", seen, want)
+			}
+			for _, q := range repo.searches[1:] {
+				if q.Fuzzy != fuzzy {
+					t.Errorf("Search() on a later page fuzzy = %v, want %v", q.Fuzzy, fuzzy)
+				}
+			}
+		})
+	}
+}
@@ -3,16 +3,43 @@ package tests
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"gomicro/internal/money"
 	"gomicro/internal/product/model"
+	"gomicro/internal/product/repository"
 	"gomicro/internal/product/service"
 )
 
 // MockProductRepository implements repository.ProductRepository interface
 type MockProductRepository struct {
 	products map[uint]*model.Product
+
+	// Search results are stubbed and the queries recorded
+	searchHits      []*repository.SearchHit
+	fuzzyHits       []*repository.SearchHit
+	categoryCounts  []repository.CategoryCount
+	priceCounts     []int64
+	searches        []repository.SearchQuery
+	categoryQueries []repository.SearchQuery
+	priceQueries    []repository.SearchQuery
+	priceBounds     []int64
+
+	// stockUpdates records the events applied by ApplyStockUpdate
+	stockUpdates []*model.StockUpdateEvent
+}
+
+// applied reports whether an event with the ID was applied before
+func (m *MockProductRepository) applied(eventID uint) bool {
+	for _, event := range m.stockUpdates {
+		if eventID != 0 && event.EventID == eventID {
+			return true
+		}
+	}
+	return false
 }
 
 func NewMockProductRepository() *MockProductRepository {
@@ -21,12 +48,13 @@ func NewMockProductRepository() *MockProductRepository {
 	}
 }
 
-func (m *MockProductRepository) Create(ctx context.Context, product *model.Product) error {
+func (m *MockProductRepository) Create(ctx context.Context, product *model.Product) (*model.Product, error) {
 	product.ID = uint(len(m.products) + 1)
 	product.CreatedAt = time.Now()
 	product.UpdatedAt = time.Now()
+	product.Version = 1
 	m.products[product.ID] = product
-	return nil
+	return product, nil
 }
 
 func (m *MockProductRepository) GetByID(ctx context.Context, id uint) (*model.Product, error) {
@@ -36,31 +64,116 @@ func (m *MockProductRepository) GetByID(ctx context.Context, id uint) (*model.Pr
 	return nil, nil
 }
 
-func (m *MockProductRepository) Update(ctx context.Context, product *model.Product) error {
-	if _, exists := m.products[product.ID]; exists {
-		product.UpdatedAt = time.Now()
-		m.products[product.ID] = product
-		return nil
+func (m *MockProductRepository) Update(ctx context.Context, product *model.Product) (*model.Product, error) {
+	if current, exists := m.products[product.ID]; exists {
+		if current.Version != product.Version {
+			return nil, repository.ErrVersionConflict
+		}
+		updated := *product
+		updated.UpdatedAt = time.Now()
+		updated.Version++
+		m.products[product.ID] = &updated
+		return &updated, nil
 	}
-	return nil
+	return nil, repository.ErrProductNotFound
 }
 
-func (m *MockProductRepository) Delete(ctx context.Context, id uint) error {
-	if _, exists := m.products[id]; exists {
+func (m *MockProductRepository) Delete(ctx context.Context, id uint, version int64) error {
+	if current, exists := m.products[id]; exists {
+		if current.Version != version {
+			return repository.ErrVersionConflict
+		}
 		delete(m.products, id)
 		return nil
 	}
-	return nil
+	return repository.ErrProductNotFound
 }
 
-func (m *MockProductRepository) List(ctx context.Context) ([]*model.Product, error) {
+func (m *MockProductRepository) List(ctx context.Context, filter repository.ProductFilter, sortBy string, after *repository.ProductCursor, limit int) ([]*model.Product, error) {
 	products := make([]*model.Product, 0, len(m.products))
-	for _, product := range m.products {
-		products = append(products, product)
+	for _, p := range m.products {
+		if !productMatches(p, filter) {
+			continue
+		}
+		if after != nil && !productAfter(sortBy, repository.NewProductCursor(p), after) {
+			continue
+		}
+		products = append(products, p)
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return productAfter(sortBy, repository.NewProductCursor(products[j]), repository.NewProductCursor(products[i]))
+	})
+	if len(products) > limit {
+		products = products[:limit]
 	}
 	return products, nil
 }
 
+// productMatches reports whether a product passes the filter
+func productMatches(p *model.Product, filter repository.ProductFilter) bool {
+	query := strings.ToLower(filter.Query)
+	return (query == "" || strings.Contains(strings.ToLower(p.Name), query) || strings.Contains(strings.ToLower(p.Description), query)) &&
+		(filter.Category == "" || p.Category == filter.Category) &&
+		(filter.Currency == "" || p.Price.Currency == filter.Currency) &&
+		(filter.MinPrice <= 0 || p.Price.MinorUnits >= filter.MinPrice) &&
+		(filter.MaxPrice <= 0 || p.Price.MinorUnits <= filter.MaxPrice) &&
+		(!filter.InStockOnly || p.Stock > 0) &&
+		(filter.IsActive == nil || p.IsActive == *filter.IsActive)
+}
+
+// productAfter reports whether a comes after b in the sort order
+func productAfter(sortBy string, a, b *repository.ProductCursor) bool {
+	switch {
+	case sortBy == repository.SortName && a.Name != b.Name:
+		return a.Name > b.Name
+	case sortBy == repository.SortPriceAsc && a.Price != b.Price:
+		return a.Price > b.Price
+	case sortBy == repository.SortPriceDesc && a.Price != b.Price:
+		return a.Price < b.Price
+	case sortBy == repository.SortNewest && !a.CreatedAt.Equal(b.CreatedAt):
+		return a.CreatedAt.Before(b.CreatedAt)
+	case sortBy == repository.SortNewest || sortBy == repository.SortPriceDesc:
+		return a.ID < b.ID
+	default:
+		return a.ID > b.ID
+	}
+}
+
+// Search returns the stubbed hits of the query's mode that come after the
+// cursor. The stubs must be in (rank, id) descending order; the ranking
+// itself is PostgreSQL's and is tested in product_repository_postgres_test.go.
+func (m *MockProductRepository) Search(ctx context.Context, q repository.SearchQuery, after *repository.SearchCursor, limit int) ([]*repository.SearchHit, error) {
+	m.searches = append(m.searches, q)
+	stubbed := m.searchHits
+	if q.Fuzzy {
+		stubbed = m.fuzzyHits
+	}
+	var hits []*repository.SearchHit
+	for _, hit := range stubbed {
+		if after != nil && !(hit.Rank < after.Rank || (hit.Rank == after.Rank && hit.Product.ID < after.ID)) {
+			continue
+		}
+		if len(hits) == limit {
+			break
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+func (m *MockProductRepository) SearchCategoryCounts(ctx context.Context, q repository.SearchQuery) ([]repository.CategoryCount, error) {
+	m.categoryQueries = append(m.categoryQueries, q)
+	return m.categoryCounts, nil
+}
+
+func (m *MockProductRepository) SearchPriceCounts(ctx context.Context, q repository.SearchQuery, bounds []int64) ([]int64, error) {
+	m.priceQueries = append(m.priceQueries, q)
+	m.priceBounds = bounds
+	counts := make([]int64, len(bounds)+1)
+	copy(counts, m.priceCounts)
+	return counts, nil
+}
+
 func (m *MockProductRepository) GetAll(ctx context.Context, offset int, limit int) ([]*model.Product, error) {
 	var products []*model.Product
 	count := 0
@@ -85,53 +198,102 @@ func (m *MockProductRepository) GetByCategory(ctx context.Context, category stri
 	return products, nil
 }
 
+func (m *MockProductRepository) ApplyStockUpdate(ctx context.Context, event *model.StockUpdateEvent) error {
+	if m.applied(event.EventID) {
+		return repository.ErrStockUpdateApplied
+	}
+	if err := m.UpdateStock(ctx, event.ProductID, event.Quantity); err != nil {
+		return err
+	}
+	m.stockUpdates = append(m.stockUpdates, event)
+	return nil
+}
+
 func (m *MockProductRepository) UpdateStock(ctx context.Context, id uint, quantity int) error {
 	if p, ok := m.products[id]; ok {
-		p.Stock += quantity
-		if p.Stock < 0 {
-			p.Stock = 0
+		if p.Stock+quantity < 0 {
+			return repository.ErrInsufficientStock
 		}
+		p.Stock += quantity
+		p.Version++
 		m.products[id] = p
 		return nil
 	}
-	return errors.New("product not found")
+	return repository.ErrProductNotFound
 }
 
 func TestCreateProduct(t *testing.T) {
 	tests := []struct {
 		name        string
-		product     *model.Product
+		input       service.ProductInput
 		wantErr     bool
 		checkFields bool
 	}{
 		{
 			name: "valid product",
-			product: &model.Product{
+			input: service.ProductInput{
 				Name:        "Test Product",
 				Description: "Test Description",
-				Price:       100.0,
+				Price:       tryAmount(10000),
 				Stock:       10,
 			},
 			wantErr:     false,
 			checkFields: true,
 		},
+		{
+			name: "categorised inactive product",
+			input: service.ProductInput{
+				Name:        "Hidden Product",
+				Description: "Test Description",
+				Price:       tryAmount(10000),
+				Stock:       10,
+				Category:    "peripherals",
+				ImageURL:    "https://cdn.example.com/hidden.png",
+				IsActive:    new(bool),
+			},
+			wantErr:     false,
+			checkFields: true,
+		},
+		{
+			name: "relative image URL",
+			input: service.ProductInput{
+				Name:        "Relative Image Product",
+				Description: "Test Description",
+				Price:       tryAmount(10000),
+				Stock:       10,
+				ImageURL:    "/images/product.png",
+			},
+			wantErr:     true,
+			checkFields: false,
+		},
 		{
 			name: "zero price",
-			product: &model.Product{
+			input: service.ProductInput{
 				Name:        "Zero Price Product",
 				Description: "Test Description",
-				Price:       0,
+				Price:       tryAmount(0),
 				Stock:       10,
 			},
-			wantErr:     false,
+			wantErr:     true,
+			checkFields: false,
+		},
+		{
+			name: "unknown currency",
+			input: service.ProductInput{
+				Name:        "Unknown Currency Product",
+				Description: "Test Description",
+				Price:       money.Money{MinorUnits: 10000, Currency: "XYZ"},
+				Stock:       10,
+			},
+			wantErr:     true,
 			checkFields: false,
 		},
 		{
 			name: "negative stock",
-			product: &model.Product{
+			input: service.ProductInput{
 				Name:        "Negative Stock Product",
 				Description: "Test Description",
-				Price:       100.0,
+				Price:       tryAmount(10000),
 				Stock:       -5,
 			},
 			wantErr:     true,
@@ -146,12 +308,12 @@ func TestCreateProduct(t *testing.T) {
 			productService := service.NewProductService(repo)
 
 			// Execute
-			err := productService.CreateProduct(context.Background(), tt.product)
+			created, err := productService.CreateProduct(context.Background(), tt.input)
 
 			// Assert
 			if tt.wantErr {
-				if err == nil {
-					t.Errorf("CreateProduct() expected error but got none")
+				if !errors.Is(err, service.ErrInvalidProduct) {
+					t.Errorf("CreateProduct() error = %v, want %v", err, service.ErrInvalidProduct)
 				}
 				return
 			}
@@ -163,7 +325,7 @@ func TestCreateProduct(t *testing.T) {
 
 			if tt.checkFields {
 				// Verify product was created
-				created, err := repo.GetByID(context.Background(), tt.product.ID)
+				created, err = repo.GetByID(context.Background(), created.ID)
 				if err != nil {
 					t.Errorf("Failed to get created product: %v", err)
 					return
@@ -175,17 +337,27 @@ func TestCreateProduct(t *testing.T) {
 				}
 
 				// Verify fields
-				if created.Name != tt.product.Name {
-					t.Errorf("CreateProduct() name = %v, want %v", created.Name, tt.product.Name)
+				if created.Name != tt.input.Name {
+					t.Errorf("CreateProduct() name = %v, want %v", created.Name, tt.input.Name)
 				}
-				if created.Description != tt.product.Description {
-					t.Errorf("CreateProduct() description = %v, want %v", created.Description, tt.product.Description)
+				if created.Description != tt.input.Description {
+					t.Errorf("CreateProduct() description = %v, want %v", created.Description, tt.input.Description)
 				}
-				if created.Price != tt.product.Price {
-					t.Errorf("CreateProduct() price = %v, want %v", created.Price, tt.product.Price)
+				if created.Price != tt.input.Price {
+					t.Errorf("CreateProduct() price = %v, want %v", created.Price, tt.input.Price)
 				}
-				if created.Stock != tt.product.Stock {
-					t.Errorf("CreateProduct() stock = %v, want %v", created.Stock, tt.product.Stock)
+				if created.Stock != tt.input.Stock {
+					t.Errorf("CreateProduct() stock = %v, want %v", created.Stock, tt.input.Stock)
+				}
+				if created.Category != tt.input.Category {
+					t.Errorf("CreateProduct() category = %v, want %v", created.Category, tt.input.Category)
+				}
+				if created.ImageURL != tt.input.ImageURL {
+					t.Errorf("CreateProduct() image URL = %v, want %v", created.ImageURL, tt.input.ImageURL)
+				}
+				// Products are active unless created otherwise
+				if wantActive := tt.input.IsActive == nil || *tt.input.IsActive; created.IsActive != wantActive {
+					t.Errorf("CreateProduct() active = %v, want %v", created.IsActive, wantActive)
 				}
 			}
 		})
@@ -201,7 +373,7 @@ func TestGetProduct(t *testing.T) {
 	testProduct := &model.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       100.0,
+		Price:       tryAmount(10000),
 		Stock:       10,
 	}
 	repo.Create(context.Background(), testProduct)
@@ -267,45 +439,77 @@ func TestUpdateProduct(t *testing.T) {
 	testProduct := &model.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       100.0,
+		Price:       tryAmount(10000),
 		Stock:       10,
+		IsActive:    true,
 	}
 	repo.Create(context.Background(), testProduct)
 
 	tests := []struct {
-		name    string
-		product *model.Product
-		wantErr bool
+		name       string
+		id         uint
+		input      service.ProductInput
+		wantActive bool
+		wantErr    bool
 	}{
 		{
 			name: "valid update",
-			product: &model.Product{
-				ID:          1,
+			id:   1,
+			input: service.ProductInput{
+				Name:        "Updated Product",
+				Description: "Updated Description",
+				Price:       tryAmount(15000),
+				Stock:       20,
+			},
+			wantActive: true,
+			wantErr:    false,
+		},
+		{
+			name: "deactivate and categorise",
+			id:   1,
+			input: service.ProductInput{
+				Name:        "Updated Product",
+				Description: "Updated Description",
+				Price:       tryAmount(15000),
+				Stock:       20,
+				Category:    "peripherals",
+				ImageURL:    "https://cdn.example.com/product.png",
+				IsActive:    new(bool),
+			},
+			wantActive: false,
+			wantErr:    false,
+		},
+		{
+			name: "unset active flag is kept",
+			id:   1,
+			input: service.ProductInput{
 				Name:        "Updated Product",
 				Description: "Updated Description",
-				Price:       150.0,
+				Price:       tryAmount(15000),
 				Stock:       20,
+				Category:    "keyboards",
 			},
-			wantErr: false,
+			wantActive: false,
+			wantErr:    false,
 		},
 		{
 			name: "non-existing product",
-			product: &model.Product{
-				ID:          999,
+			id:   999,
+			input: service.ProductInput{
 				Name:        "Non-existing Product",
 				Description: "Test Description",
-				Price:       100.0,
+				Price:       tryAmount(10000),
 				Stock:       10,
 			},
 			wantErr: true,
 		},
 		{
 			name: "invalid price",
-			product: &model.Product{
-				ID:          1,
+			id:   1,
+			input: service.ProductInput{
 				Name:        "Invalid Price Product",
 				Description: "Test Description",
-				Price:       -50.0,
+				Price:       tryAmount(-5000),
 				Stock:       10,
 			},
 			wantErr: true,
@@ -314,8 +518,14 @@ func TestUpdateProduct(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			// Setup: each case updates the version left by the previous one
+			version := int64(1)
+			if current, _ := repo.GetByID(context.Background(), tt.id); current != nil {
+				version = current.Version
+			}
+
 			// Execute
-			err := productService.UpdateProduct(context.Background(), tt.product)
+			_, err := productService.UpdateProduct(context.Background(), tt.id, version, tt.input, nil)
 
 			// Assert
 			if tt.wantErr {
@@ -330,9 +540,9 @@ func TestUpdateProduct(t *testing.T) {
 				return
 			}
 
-			if tt.product.ID == 1 {
+			if tt.id == 1 {
 				// Verify product was updated
-				updated, err := repo.GetByID(context.Background(), tt.product.ID)
+				updated, err := repo.GetByID(context.Background(), tt.id)
 				if err != nil {
 					t.Errorf("Failed to get updated product: %v", err)
 					return
@@ -344,17 +554,26 @@ func TestUpdateProduct(t *testing.T) {
 				}
 
 				// Verify fields
-				if updated.Name != tt.product.Name {
-					t.Errorf("UpdateProduct() name = %v, want %v", updated.Name, tt.product.Name)
+				if updated.Name != tt.input.Name {
+					t.Errorf("UpdateProduct() name = %v, want %v", updated.Name, tt.input.Name)
+				}
+				if updated.Description != tt.input.Description {
+					t.Errorf("UpdateProduct() description = %v, want %v", updated.Description, tt.input.Description)
+				}
+				if updated.Price != tt.input.Price {
+					t.Errorf("UpdateProduct() price = %v, want %v", updated.Price, tt.input.Price)
+				}
+				if updated.Stock != tt.input.Stock {
+					t.Errorf("UpdateProduct() stock = %v, want %v", updated.Stock, tt.input.Stock)
 				}
-				if updated.Description != tt.product.Description {
-					t.Errorf("UpdateProduct() description = %v, want %v", updated.Description, tt.product.Description)
+				if updated.Category != tt.input.Category {
+					t.Errorf("UpdateProduct() category = %v, want %v", updated.Category, tt.input.Category)
 				}
-				if updated.Price != tt.product.Price {
-					t.Errorf("UpdateProduct() price = %v, want %v", updated.Price, tt.product.Price)
+				if updated.ImageURL != tt.input.ImageURL {
+					t.Errorf("UpdateProduct() image URL = %v, want %v", updated.ImageURL, tt.input.ImageURL)
 				}
-				if updated.Stock != tt.product.Stock {
-					t.Errorf("UpdateProduct() stock = %v, want %v", updated.Stock, tt.product.Stock)
+				if updated.IsActive != tt.wantActive {
+					t.Errorf("UpdateProduct() active = %v, want %v", updated.IsActive, tt.wantActive)
 				}
 			}
 		})
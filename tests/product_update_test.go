@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gomicro/internal/money"
+	"gomicro/internal/product/handler"
+	"gomicro/internal/product/model"
+	"gomicro/internal/product/repository"
+	"gomicro/internal/product/service"
+)
+
+// newEditableProduct returns a product service whose catalog holds product 1,
+// an active keyboard
+func newEditableProduct() (*MockProductRepository, service.ProductService) {
+	repo := NewMockProductRepository()
+	repo.Create(context.Background(), &model.Product{
+		Name:        "Keyboard",
+		Description: "Brown switches",
+		Price:       tryAmount(4999),
+		Stock:       10,
+		Category:    "peripherals",
+		IsActive:    true,
+	})
+	return repo, service.NewProductService(repo)
+}
+
+func TestUpdateProductFields(t *testing.T) {
+	inactive := false
+
+	tests := []struct {
+		name    string
+		id      uint
+		input   service.ProductInput
+		fields  []string
+		want    model.Product
+		wantErr error
+	}{
+		{
+			name:   "only named fields change",
+			id:     1,
+			input:  service.ProductInput{Price: tryAmount(3999)},
+			fields: []string{service.FieldPrice},
+			want:   model.Product{Name: "Keyboard", Description: "Brown switches", Price: tryAmount(3999), Stock: 10, Category: "peripherals", IsActive: true},
+		},
+		{
+			name:   "named fields can be cleared",
+			id:     1,
+			input:  service.ProductInput{Stock: 0},
+			fields: []string{service.FieldDescription, service.FieldStock},
+			want:   model.Product{Name: "Keyboard", Price: tryAmount(4999), Category: "peripherals", IsActive: true},
+		},
+		{
+			name:   "deactivate",
+			id:     1,
+			input:  service.ProductInput{IsActive: &inactive},
+			fields: []string{service.FieldIsActive},
+			want:   model.Product{Name: "Keyboard", Description: "Brown switches", Price: tryAmount(4999), Stock: 10, Category: "peripherals", IsActive: false},
+		},
+		{
+			name:    "unknown field",
+			id:      1,
+			input:   service.ProductInput{},
+			fields:  []string{"reserved_stock"},
+			wantErr: service.ErrInvalidFieldMask,
+		},
+		{
+			name:    "invalid result",
+			id:      1,
+			input:   service.ProductInput{},
+			fields:  []string{service.FieldPrice},
+			wantErr: money.ErrUnknownCurrency,
+		},
+		{
+			name:    "missing product",
+			id:      999,
+			input:   service.ProductInput{Name: "Mouse"},
+			fields:  []string{service.FieldName},
+			wantErr: repository.ErrProductNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo, productService := newEditableProduct()
+
+			// Execute
+			_, err := productService.UpdateProduct(context.Background(), tt.id, 1, tt.input, tt.fields)
+
+			// Assert
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("UpdateProduct() error = %v, want %v", err, tt.wantErr)
+				}
+				if stored, _ := repo.GetByID(context.Background(), 1); stored.Name != "Keyboard" || stored.Price != tryAmount(4999) {
+					t.Errorf("UpdateProduct() changed the product on error: %+v", stored)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateProduct() unexpected error: %v", err)
+			}
+
+			stored, _ := repo.GetByID(context.Background(), tt.id)
+			got := model.Product{
+				Name:        stored.Name,
+				Description: stored.Description,
+				Price:       stored.Price,
+				Stock:       stored.Stock,
+				Category:    stored.Category,
+				IsActive:    stored.IsActive,
+			}
+			if got != tt.want {
+				t.Errorf("UpdateProduct() stored %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchProduct(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		want       model.Product
+	}{
+		{
+			name:       "nested price member",
+			path:       "/products/1",
+			body:       `{"price": {"minor_units": 3999}}`,
+			wantStatus: http.StatusOK,
+			want:       model.Product{Name: "Keyboard", Description: "Brown switches", Price: tryAmount(3999), Stock: 10, Category: "peripherals", IsActive: true},
+		},
+		{
+			name:       "null clears a field",
+			path:       "/products/1",
+			body:       `{"description": null, "is_active": false}`,
+			wantStatus: http.StatusOK,
+			want:       model.Product{Name: "Keyboard", Price: tryAmount(4999), Stock: 10, Category: "peripherals", IsActive: false},
+		},
+		{
+			name:       "unknown field",
+			path:       "/products/1",
+			body:       `{"reserved_stock": 3}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "not an object",
+			path:       "/products/1",
+			body:       `[{"name": "Mouse"}]`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing product",
+			path:       "/products/999",
+			body:       `{"name": "Mouse"}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			_, productService := newEditableProduct()
+			router := gin.New()
+			handler.NewProductHTTPHandler(productService).RegisterRoutes(router)
+
+			// Execute
+			req := httptest.NewRequest(http.MethodPatch, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			req.Header.Set("If-Match", `"1"`)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("PATCH %s status = %d, want %d: %s", tt.path, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var patched model.Product
+			if err := json.Unmarshal(rec.Body.Bytes(), &patched); err != nil {
+				t.Fatalf("PATCH %s returned invalid JSON: %v", tt.path, err)
+			}
+			got := model.Product{
+				Name:        patched.Name,
+				Description: patched.Description,
+				Price:       patched.Price,
+				Stock:       patched.Stock,
+				Category:    patched.Category,
+				IsActive:    patched.IsActive,
+			}
+			if got != tt.want {
+				t.Errorf("PATCH %s returned %+v, want %+v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	pb "gomicro/api/proto"
+	"gomicro/internal/product/handler"
+	"gomicro/internal/product/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInvalidProductHTTPStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "create with negative stock", method: http.MethodPost, path: "/products/", body: `{"name": "Mouse", "price": {"minor_units": 1999, "currency": "TRY"}, "stock": -1}`},
+		{name: "create with zero price", method: http.MethodPost, path: "/products/", body: `{"name": "Mouse", "price": {"minor_units": 0, "currency": "TRY"}, "stock": 1}`},
+		{name: "replace with relative image URL", method: http.MethodPut, path: "/products/1", body: `{"name": "Keyboard", "price": {"minor_units": 4999, "currency": "TRY"}, "stock": 10, "image_url": "/keyboard.png"}`},
+		{name: "patch with negative stock", method: http.MethodPatch, path: "/products/1", body: `{"stock": -1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo, productService := newEditableProduct()
+			router := handler.NewRouter(productService)
+
+			// Execute
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", `"1"`)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("%s %s status = %d, want %d: %s", tt.method, tt.path, rec.Code, http.StatusBadRequest, rec.Body.String())
+			}
+			if len(repo.products) != 1 || repo.products[1].Version != 1 {
+				t.Errorf("%s %s changed the catalog", tt.method, tt.path)
+			}
+		})
+	}
+}
+
+func TestInvalidProductGRPCCode(t *testing.T) {
+	// Setup
+	repo, productService := newEditableProduct()
+	productHandler := handler.NewProductGRPCHandler(productService, service.NewReservationService(NewMockReservationRepository(repo)))
+	price := &pb.Money{MinorUnits: 4999, Currency: "TRY"}
+
+	// Execute
+	_, createErr := productHandler.CreateProduct(context.Background(), &pb.CreateProductRequest{Name: "Mouse", Price: price, Stock: -1})
+	_, updateErr := productHandler.UpdateProduct(context.Background(), &pb.UpdateProductRequest{Id: 1, Version: 1, ImageUrl: "ftp://cdn.example.com/keyboard.png"})
+	_, currencyErr := productHandler.CreateProduct(context.Background(), &pb.CreateProductRequest{Name: "Mouse", Price: &pb.Money{MinorUnits: 4999, Currency: "XYZ"}, Stock: 1})
+	_, missingErr := productHandler.GetProduct(context.Background(), &pb.GetProductRequest{ProductId: 999})
+
+	// Assert
+	for name, err := range map[string]error{"CreateProduct()": createErr, "UpdateProduct()": updateErr, "CreateProduct() with an unknown currency": currencyErr} {
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s error = %v, want code %v", name, err, codes.InvalidArgument)
+		}
+	}
+	if status.Code(missingErr) != codes.NotFound {
+		t.Errorf("GetProduct() of a missing product error = %v, want code %v", missingErr, codes.NotFound)
+	}
+}
@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gomicro/internal/product/handler"
+	"gomicro/internal/product/repository"
+	"gomicro/internal/product/service"
+)
+
+func TestUpdateProductConcurrentEdits(t *testing.T) {
+	// Setup: two admins read the keyboard at version 1
+	repo, productService := newEditableProduct()
+	first := service.ProductInput{Price: tryAmount(3999)}
+	second := service.ProductInput{Stock: 25}
+
+	// Execute
+	updated, err := productService.UpdateProduct(context.Background(), 1, 1, first, []string{service.FieldPrice})
+	if err != nil {
+		t.Fatalf("UpdateProduct() unexpected error: %v", err)
+	}
+	_, err = productService.UpdateProduct(context.Background(), 1, 1, second, []string{service.FieldStock})
+
+	// Assert
+	if updated.Version != 2 {
+		t.Errorf("UpdateProduct() version = %d, want 2", updated.Version)
+	}
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("UpdateProduct() with a stale version error = %v, want %v", err, repository.ErrVersionConflict)
+	}
+	stored, _ := repo.GetByID(context.Background(), 1)
+	if stored.Price != tryAmount(3999) || stored.Stock != 10 || stored.Version != 2 {
+		t.Errorf("UpdateProduct() stored %+v, want the first edit only", stored)
+	}
+}
+
+func TestUpdateProductVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      uint
+		version int64
+		setup   func(repo *MockProductRepository)
+		wantErr error
+	}{
+		{
+			name:    "current version",
+			id:      1,
+			version: 1,
+		},
+		{
+			name:    "missing version",
+			id:      1,
+			version: 0,
+			wantErr: service.ErrVersionRequired,
+		},
+		{
+			name:    "stock changed since",
+			id:      1,
+			version: 1,
+			setup: func(repo *MockProductRepository) {
+				repo.UpdateStock(context.Background(), 1, -1)
+			},
+			wantErr: repository.ErrVersionConflict,
+		},
+		{
+			name:    "missing product",
+			id:      999,
+			version: 1,
+			wantErr: repository.ErrProductNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo, productService := newEditableProduct()
+			if tt.setup != nil {
+				tt.setup(repo)
+			}
+
+			// Execute
+			input := service.ProductInput{Name: "Mouse"}
+			_, err := productService.UpdateProduct(context.Background(), tt.id, tt.version, input, []string{service.FieldName})
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateProduct() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeleteProductVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         uint
+		version    int64
+		wantErr    error
+		wantExists bool
+	}{
+		{name: "current version", id: 1, version: 1},
+		{name: "missing version", id: 1, version: 0, wantErr: service.ErrVersionRequired, wantExists: true},
+		{name: "stale version", id: 1, version: 2, wantErr: repository.ErrVersionConflict, wantExists: true},
+		{name: "missing product", id: 999, version: 1, wantErr: repository.ErrProductNotFound, wantExists: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo, productService := newEditableProduct()
+
+			// Execute
+			err := productService.DeleteProduct(context.Background(), tt.id, tt.version)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("DeleteProduct() error = %v, want %v", err, tt.wantErr)
+			}
+			if stored, _ := repo.GetByID(context.Background(), 1); (stored != nil) != tt.wantExists {
+				t.Errorf("DeleteProduct() product 1 exists = %v, want %v", stored != nil, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestProductETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		ifMatch    string
+		wantStatus int
+		wantETag   string
+	}{
+		{name: "get", method: http.MethodGet, wantStatus: http.StatusOK, wantETag: `"1"`},
+		{name: "put", method: http.MethodPut, body: `{"name": "Mouse", "price": {"minor_units": 999, "currency": "TRY"}, "stock": 3}`, ifMatch: `"1"`, wantStatus: http.StatusOK, wantETag: `"2"`},
+		{name: "patch", method: http.MethodPatch, body: `{"stock": 3}`, ifMatch: `"1"`, wantStatus: http.StatusOK, wantETag: `"2"`},
+		{name: "delete", method: http.MethodDelete, ifMatch: `"1"`, wantStatus: http.StatusNoContent},
+		{name: "put without If-Match", method: http.MethodPut, body: `{"name": "Mouse"}`, wantStatus: http.StatusPreconditionRequired},
+		{name: "patch with any tag", method: http.MethodPatch, body: `{"stock": 3}`, ifMatch: "*", wantStatus: http.StatusPreconditionRequired},
+		{name: "patch with stale tag", method: http.MethodPatch, body: `{"stock": 3}`, ifMatch: `"2"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "put with stale tag", method: http.MethodPut, body: `{"name": "Mouse", "price": {"minor_units": 999, "currency": "TRY"}, "stock": 3}`, ifMatch: `"2"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "delete with weak tag", method: http.MethodDelete, ifMatch: `W/"1"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "delete without If-Match", method: http.MethodDelete, wantStatus: http.StatusPreconditionRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			_, productService := newEditableProduct()
+			router := gin.New()
+			handler.NewProductHTTPHandler(productService).RegisterRoutes(router)
+
+			// Execute
+			req := httptest.NewRequest(tt.method, "/products/1", strings.NewReader(tt.body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s /products/1 status = %d, want %d: %s", tt.method, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("%s /products/1 ETag = %q, want %q", tt.method, got, tt.wantETag)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"gomicro/internal/product/model"
+	"gomicro/internal/product/service"
+)
+
+// FakeBroker is an in-process stand-in for RabbitMQ implementing service.IRabbitMQConsumer.
+// Requeued messages are redelivered and rejected messages land in the dead-letter list.
+type FakeBroker struct {
+	mu          sync.Mutex
+	deliveries  chan amqp.Delivery
+	nextTag     uint64
+	inFlight    map[uint64]amqp.Delivery
+	acked       [][]byte
+	deadLetters [][]byte
+	settled     chan struct{}
+}
+
+func NewFakeBroker() *FakeBroker {
+	return &FakeBroker{
+		deliveries: make(chan amqp.Delivery, 16),
+		inFlight:   make(map[uint64]amqp.Delivery),
+		settled:    make(chan struct{}, 16),
+	}
+}
+
+func (b *FakeBroker) Consume() (<-chan amqp.Delivery, error) {
+	return b.deliveries, nil
+}
+
+func (b *FakeBroker) Close() {}
+
+func (b *FakeBroker) Publish(body []byte) {
+	b.deliver(body, false)
+}
+
+func (b *FakeBroker) PublishEvent(event *model.StockUpdateEvent) {
+	body, _ := json.Marshal(event)
+	b.Publish(body)
+}
+
+func (b *FakeBroker) deliver(body []byte, redelivered bool) {
+	b.mu.Lock()
+	b.nextTag++
+	d := amqp.Delivery{
+		Acknowledger: b,
+		DeliveryTag:  b.nextTag,
+		Redelivered:  redelivered,
+		Body:         body,
+	}
+	b.inFlight[d.DeliveryTag] = d
+	b.mu.Unlock()
+	b.deliveries <- d
+}
+
+func (b *FakeBroker) Ack(tag uint64, multiple bool) error {
+	b.mu.Lock()
+	d := b.inFlight[tag]
+	delete(b.inFlight, tag)
+	b.acked = append(b.acked, d.Body)
+	b.mu.Unlock()
+	b.settled <- struct{}{}
+	return nil
+}
+
+func (b *FakeBroker) Nack(tag uint64, multiple bool, requeue bool) error {
+	b.mu.Lock()
+	d := b.inFlight[tag]
+	delete(b.inFlight, tag)
+	if !requeue {
+		b.deadLetters = append(b.deadLetters, d.Body)
+	}
+	b.mu.Unlock()
+	if requeue {
+		go b.deliver(d.Body, true)
+		return nil
+	}
+	b.settled <- struct{}{}
+	return nil
+}
+
+func (b *FakeBroker) Reject(tag uint64, requeue bool) error {
+	return b.Nack(tag, false, requeue)
+}
+
+// waitSettled blocks until n messages have been acked or dead-lettered
+func (b *FakeBroker) waitSettled(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-b.settled:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for message %d of %d to settle", i+1, n)
+		}
+	}
+}
+
+// FlakyProductRepository fails ApplyStockUpdate with a transient error a fixed number of times
+type FlakyProductRepository struct {
+	*MockProductRepository
+	failures int
+}
+
+func (r *FlakyProductRepository) ApplyStockUpdate(ctx context.Context, event *model.StockUpdateEvent) error {
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("connection reset")
+	}
+	return r.MockProductRepository.ApplyStockUpdate(ctx, event)
+}
+
+func startStockConsumer(t *testing.T, consumer *service.StockUpdateConsumer) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		consumer.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestStockUpdateConsumer(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           []byte
+		failures       int
+		wantStock      int
+		wantAcked      int
+		wantDeadLetter int
+	}{
+		{
+			name:      "decrease stock",
+			body:      []byte(`{"product_id":1,"quantity":-3}`),
+			wantStock: 7,
+			wantAcked: 1,
+		},
+		{
+			name:      "increase stock",
+			body:      []byte(`{"product_id":1,"quantity":5}`),
+			wantStock: 15,
+			wantAcked: 1,
+		},
+		{
+			name:           "malformed json",
+			body:           []byte(`{"product_id":`),
+			wantStock:      10,
+			wantDeadLetter: 1,
+		},
+		{
+			name:           "missing product id",
+			body:           []byte(`{"quantity":-1}`),
+			wantStock:      10,
+			wantDeadLetter: 1,
+		},
+		{
+			name:           "unknown product",
+			body:           []byte(`{"product_id":999,"quantity":-1}`),
+			wantStock:      10,
+			wantDeadLetter: 1,
+		},
+		{
+			name:           "insufficient stock",
+			body:           []byte(`{"product_id":1,"quantity":-11}`),
+			wantStock:      10,
+			wantDeadLetter: 1,
+		},
+		{
+			name:      "transient failure is retried",
+			body:      []byte(`{"product_id":1,"quantity":-1}`),
+			failures:  1,
+			wantStock: 9,
+			wantAcked: 1,
+		},
+		{
+			name:           "repeated transient failure is dead-lettered",
+			body:           []byte(`{"product_id":1,"quantity":-1}`),
+			failures:       2,
+			wantStock:      10,
+			wantDeadLetter: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockRepo := NewMockProductRepository()
+			mockRepo.Create(context.Background(), &model.Product{Name: "Test Product", Price: tryAmount(10000), Stock: 10})
+			repo := &FlakyProductRepository{MockProductRepository: mockRepo, failures: tt.failures}
+			broker := NewFakeBroker()
+			startStockConsumer(t, service.NewStockUpdateConsumer(repo, broker))
+
+			// Execute
+			broker.Publish(tt.body)
+			broker.waitSettled(t, 1)
+
+			// Assert
+			broker.mu.Lock()
+			defer broker.mu.Unlock()
+			if len(broker.acked) != tt.wantAcked {
+				t.Errorf("acked = %d, want %d", len(broker.acked), tt.wantAcked)
+			}
+			if len(broker.deadLetters) != tt.wantDeadLetter {
+				t.Errorf("dead-lettered = %d, want %d", len(broker.deadLetters), tt.wantDeadLetter)
+			}
+			if stock := mockRepo.products[1].Stock; stock != tt.wantStock {
+				t.Errorf("stock = %d, want %d", stock, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestStockUpdateConsumerMultipleEvents(t *testing.T) {
+	// Setup
+	repo := NewMockProductRepository()
+	repo.Create(context.Background(), &model.Product{Name: "First", Price: tryAmount(1000), Stock: 5})
+	repo.Create(context.Background(), &model.Product{Name: "Second", Price: tryAmount(2000), Stock: 5})
+	broker := NewFakeBroker()
+	startStockConsumer(t, service.NewStockUpdateConsumer(repo, broker))
+
+	// Execute
+	broker.PublishEvent(&model.StockUpdateEvent{ProductID: 1, Quantity: -2})
+	broker.PublishEvent(&model.StockUpdateEvent{ProductID: 2, Quantity: -1})
+	broker.PublishEvent(&model.StockUpdateEvent{ProductID: 1, Quantity: -1})
+	broker.waitSettled(t, 3)
+
+	// Assert
+	if stock := repo.products[1].Stock; stock != 2 {
+		t.Errorf("product 1 stock = %d, want 2", stock)
+	}
+	if stock := repo.products[2].Stock; stock != 4 {
+		t.Errorf("product 2 stock = %d, want 4", stock)
+	}
+}
+
+func TestStockUpdateConsumerSkipsDuplicates(t *testing.T) {
+	// Setup
+	repo := NewMockProductRepository()
+	repo.Create(context.Background(), &model.Product{Name: "Test Product", Price: tryAmount(1000), Stock: 5})
+	broker := NewFakeBroker()
+	startStockConsumer(t, service.NewStockUpdateConsumer(repo, broker))
+
+	// Execute: the relay republishes event 1 after failing to mark it sent
+	broker.PublishEvent(&model.StockUpdateEvent{EventID: 1, ProductID: 1, Quantity: -2})
+	broker.PublishEvent(&model.StockUpdateEvent{EventID: 1, ProductID: 1, Quantity: -2})
+	broker.PublishEvent(&model.StockUpdateEvent{EventID: 2, ProductID: 1, Quantity: -1})
+	broker.waitSettled(t, 3)
+
+	// Assert
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.acked) != 3 || len(broker.deadLetters) != 0 {
+		t.Errorf("acked = %d, dead-lettered = %d, want all 3 acked", len(broker.acked), len(broker.deadLetters))
+	}
+	if stock := repo.products[1].Stock; stock != 2 {
+		t.Errorf("stock = %d, want 2", stock)
+	}
+}
+
+func TestStockUpdateConsumerReleasesReservation(t *testing.T) {
+	// Setup
+	repo := NewMockProductRepository()
+	repo.Create(context.Background(), &model.Product{Name: "Test Product", Price: tryAmount(1000), Stock: 5})
+	broker := NewFakeBroker()
+	startStockConsumer(t, service.NewStockUpdateConsumer(repo, broker))
+
+	// Execute
+	broker.Publish([]byte(`{"product_id":1,"quantity":-2,"reservation_id":"res_1"}`))
+	broker.waitSettled(t, 1)
+
+	// Assert
+	if len(repo.stockUpdates) != 1 || repo.stockUpdates[0].ReservationID != "res_1" {
+		t.Fatalf("ApplyStockUpdate() events = %+v, want the decrement of reservation res_1", repo.stockUpdates)
+	}
+	if stock := repo.products[1].Stock; stock != 3 {
+		t.Errorf("stock = %d, want 3", stock)
+	}
+}
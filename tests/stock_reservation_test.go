@@ -0,0 +1,332 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "gomicro/api/proto"
+	paymentmodel "gomicro/internal/payment/model"
+	paymentservice "gomicro/internal/payment/service"
+	"gomicro/internal/product/model"
+	"gomicro/internal/product/repository"
+	"gomicro/internal/product/service"
+)
+
+// MockReservationRepository implements repository.ReservationRepository
+// against the stock of a MockProductRepository
+type MockReservationRepository struct {
+	products     *MockProductRepository
+	reservations []*model.StockReservation
+}
+
+func NewMockReservationRepository(products *MockProductRepository) *MockReservationRepository {
+	return &MockReservationRepository{products: products}
+}
+
+func (m *MockReservationRepository) Reserve(ctx context.Context, reservations []*model.StockReservation) error {
+	reserved, _ := m.ReservedQuantities(ctx, nil)
+	for _, r := range reservations {
+		product, exists := m.products.products[r.ProductID]
+		if !exists {
+			return repository.ErrProductNotFound
+		}
+		if product.Stock-reserved[r.ProductID] < r.Quantity {
+			return repository.ErrInsufficientStock
+		}
+	}
+	m.reservations = append(m.reservations, reservations...)
+	return nil
+}
+
+func (m *MockReservationRepository) Release(ctx context.Context, reservationID string) error {
+	var kept []*model.StockReservation
+	released := false
+	for _, r := range m.reservations {
+		if r.ReservationID == reservationID && r.ExpiresAt.After(time.Now()) {
+			released = true
+			continue
+		}
+		kept = append(kept, r)
+	}
+	if !released {
+		return repository.ErrReservationNotFound
+	}
+	m.reservations = kept
+	return nil
+}
+
+// ReservedQuantities sums active reservations; nil productIDs means all products
+func (m *MockReservationRepository) ReservedQuantities(ctx context.Context, productIDs []uint) (map[uint]int, error) {
+	wanted := make(map[uint]bool, len(productIDs))
+	for _, id := range productIDs {
+		wanted[id] = true
+	}
+	reserved := make(map[uint]int)
+	for _, r := range m.reservations {
+		if r.ExpiresAt.After(time.Now()) && (productIDs == nil || wanted[r.ProductID]) {
+			reserved[r.ProductID] += r.Quantity
+		}
+	}
+	return reserved, nil
+}
+
+func (m *MockReservationRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var kept []*model.StockReservation
+	for _, r := range m.reservations {
+		if r.ExpiresAt.After(now) {
+			kept = append(kept, r)
+		}
+	}
+	deleted := int64(len(m.reservations) - len(kept))
+	m.reservations = kept
+	return deleted, nil
+}
+
+// setupReservations creates product 1 with 5 units in stock, 3 of which are
+// held by an existing reservation
+func setupReservations(t *testing.T) (*MockReservationRepository, service.ReservationService) {
+	t.Helper()
+	products := NewMockProductRepository()
+	products.Create(context.Background(), &model.Product{Name: "Keyboard", Price: tryAmount(4999), Stock: 5})
+	products.Create(context.Background(), &model.Product{Name: "Mouse", Price: tryAmount(1995), Stock: 10})
+	repo := NewMockReservationRepository(products)
+	reservationService := service.NewReservationService(repo)
+	if _, _, err := reservationService.ReserveStock(context.Background(), []service.ReservationItem{{ProductID: 1, Quantity: 3}}, 0); err != nil {
+		t.Fatalf("ReserveStock() unexpected error: %v", err)
+	}
+	return repo, reservationService
+}
+
+func TestReserveStock(t *testing.T) {
+	tests := []struct {
+		name         string
+		items        []service.ReservationItem
+		ttl          time.Duration
+		wantErr      error
+		wantReserved map[uint]int
+		wantTTL      time.Duration
+	}{
+		{
+			name:         "available stock",
+			items:        []service.ReservationItem{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 4}},
+			wantReserved: map[uint]int{1: 5, 2: 4},
+			wantTTL:      service.DefaultReservationTTL,
+		},
+		{
+			name:         "repeated products are merged",
+			items:        []service.ReservationItem{{ProductID: 2, Quantity: 6}, {ProductID: 2, Quantity: 4}},
+			ttl:          time.Minute,
+			wantReserved: map[uint]int{1: 3, 2: 10},
+			wantTTL:      time.Minute,
+		},
+		{
+			name:         "ttl is capped",
+			items:        []service.ReservationItem{{ProductID: 2, Quantity: 1}},
+			ttl:          24 * time.Hour,
+			wantReserved: map[uint]int{1: 3, 2: 1},
+			wantTTL:      service.MaxReservationTTL,
+		},
+		{
+			name:         "stock held by another reservation",
+			items:        []service.ReservationItem{{ProductID: 2, Quantity: 1}, {ProductID: 1, Quantity: 3}},
+			wantErr:      repository.ErrInsufficientStock,
+			wantReserved: map[uint]int{1: 3},
+		},
+		{
+			name:         "unknown product",
+			items:        []service.ReservationItem{{ProductID: 99, Quantity: 1}},
+			wantErr:      repository.ErrProductNotFound,
+			wantReserved: map[uint]int{1: 3},
+		},
+		{
+			name:         "non-positive quantity",
+			items:        []service.ReservationItem{{ProductID: 2, Quantity: 0}},
+			wantErr:      service.ErrInvalidReservation,
+			wantReserved: map[uint]int{1: 3},
+		},
+		{
+			name:         "no items",
+			wantErr:      service.ErrInvalidReservation,
+			wantReserved: map[uint]int{1: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			_, reservationService := setupReservations(t)
+
+			// Execute
+			reservationID, expiresAt, err := reservationService.ReserveStock(context.Background(), tt.items, tt.ttl)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReserveStock() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				if reservationID == "" {
+					t.Error("ReserveStock() returned empty reservation ID")
+				}
+				if ttl := time.Until(expiresAt); ttl > tt.wantTTL || ttl < tt.wantTTL-time.Minute {
+					t.Errorf("ReserveStock() expires in %v, want %v", ttl, tt.wantTTL)
+				}
+			}
+			reserved, _ := reservationService.ReservedStock(context.Background(), []uint{1, 2})
+			if len(reserved) != len(tt.wantReserved) {
+				t.Fatalf("ReservedStock() = %v, want %v", reserved, tt.wantReserved)
+			}
+			for productID, quantity := range tt.wantReserved {
+				if reserved[productID] != quantity {
+					t.Errorf("ReservedStock() = %v, want %v", reserved, tt.wantReserved)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestReleaseStock(t *testing.T) {
+	// Setup
+	_, reservationService := setupReservations(t)
+	reservationID, _, err := reservationService.ReserveStock(context.Background(), []service.ReservationItem{{ProductID: 1, Quantity: 2}}, 0)
+	if err != nil {
+		t.Fatalf("ReserveStock() unexpected error: %v", err)
+	}
+
+	// Execute
+	releaseErr := reservationService.ReleaseStock(context.Background(), reservationID)
+	secondErr := reservationService.ReleaseStock(context.Background(), reservationID)
+
+	// Assert
+	if releaseErr != nil {
+		t.Fatalf("ReleaseStock() unexpected error: %v", releaseErr)
+	}
+	if !errors.Is(secondErr, repository.ErrReservationNotFound) {
+		t.Errorf("ReleaseStock() twice error = %v, want %v", secondErr, repository.ErrReservationNotFound)
+	}
+	if _, _, err := reservationService.ReserveStock(context.Background(), []service.ReservationItem{{ProductID: 1, Quantity: 2}}, 0); err != nil {
+		t.Errorf("ReserveStock() after release unexpected error: %v", err)
+	}
+}
+
+func TestExpiredReservationsAreReleased(t *testing.T) {
+	// Setup
+	repo, reservationService := setupReservations(t)
+	repo.reservations[0].ExpiresAt = time.Now().Add(-time.Second)
+
+	// Execute
+	reserved, _ := reservationService.ReservedStock(context.Background(), []uint{1})
+	_, _, reserveErr := reservationService.ReserveStock(context.Background(), []service.ReservationItem{{ProductID: 1, Quantity: 5}}, 0)
+	deleted, reapErr := service.NewReservationReaper(repo, time.Minute).ReapExpired(context.Background())
+
+	// Assert
+	if reserved[1] != 0 {
+		t.Errorf("ReservedStock() counted expired reservation: %v", reserved)
+	}
+	if reserveErr != nil {
+		t.Errorf("ReserveStock() over expired reservation unexpected error: %v", reserveErr)
+	}
+	if reapErr != nil || deleted != 1 {
+		t.Errorf("ReapExpired() = %d, %v, want 1 deleted", deleted, reapErr)
+	}
+	if len(repo.reservations) != 1 {
+		t.Errorf("ReapExpired() left %d reservations, want 1", len(repo.reservations))
+	}
+}
+
+func TestCheckoutHoldsStock(t *testing.T) {
+	tests := []struct {
+		name          string
+		paymentMethod string
+		reserveErr    error
+		wantErr       bool
+		wantStatus    string
+		wantReleased  bool
+	}{
+		{
+			name:          "captured payment keeps stock held for the decrement",
+			paymentMethod: "4242424242424242",
+			wantStatus:    paymentmodel.StatusCaptured,
+			wantReleased:  false,
+		},
+		{
+			name:          "declined payment releases its reservation",
+			paymentMethod: "4000000000000002",
+			wantStatus:    paymentmodel.StatusFailed,
+			wantReleased:  true,
+		},
+		{
+			name:          "3-D Secure challenge keeps stock held",
+			paymentMethod: "4000000000003220",
+			wantStatus:    paymentmodel.StatusRequiresAction,
+			wantReleased:  false,
+		},
+		{
+			name:          "stock taken by another checkout",
+			paymentMethod: "4242424242424242",
+			reserveErr:    errors.New("insufficient stock"),
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			repo := NewMockPaymentRepository()
+			basketClient := NewMockBasketClient()
+			basketClient.baskets[1] = &pb.Basket{UserId: 1, Items: []*pb.BasketItem{basketItem(1, 2, tryAmount(1000))}}
+			productClient := NewMockProductClient(&pb.Product{Id: 1, Price: tryAmount(1000).ToProto(), Stock: 5})
+			productClient.reserveErr = tt.reserveErr
+			provider := paymentservice.NewSimulatedProvider(paymentservice.DefaultSimulatorRules()...)
+			paymentService := paymentservice.NewPaymentService(repo, provider, basketClient, productClient, testRates(t), "TRY")
+
+			// Execute
+			payment, err := paymentService.Checkout(context.Background(), 1, 0, "TRY", tt.paymentMethod)
+
+			// Assert
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Checkout() expected error but got none")
+				}
+				if len(repo.payments) != 0 {
+					t.Errorf("Checkout() created %d payments, want 0", len(repo.payments))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Checkout() unexpected error: %v", err)
+			}
+			if payment.Status != tt.wantStatus {
+				t.Errorf("Checkout() status = %v, want %v", payment.Status, tt.wantStatus)
+			}
+			if released := len(productClient.released) == 1; released != tt.wantReleased {
+				t.Errorf("Checkout() released reservations %v, want released %v", productClient.released, tt.wantReleased)
+			}
+			if held := len(productClient.reservations) == 1; held == tt.wantReleased {
+				t.Errorf("Checkout() held reservations %v", productClient.reservations)
+			}
+		})
+	}
+}
+
+func TestCheckoutRejectsReservedStock(t *testing.T) {
+	// Setup: 5 in stock but 4 held by other checkouts
+	repo := NewMockPaymentRepository()
+	basketClient := NewMockBasketClient()
+	basketClient.baskets[1] = &pb.Basket{UserId: 1, Items: []*pb.BasketItem{basketItem(1, 2, tryAmount(1000))}}
+	productClient := NewMockProductClient(&pb.Product{Id: 1, Price: tryAmount(1000).ToProto(), Stock: 5, ReservedStock: 4})
+	paymentService := paymentservice.NewPaymentService(repo, paymentservice.NewSimulatedProvider(), basketClient, productClient, testRates(t), "TRY")
+
+	// Execute
+	_, err := paymentService.Checkout(context.Background(), 1, 0, "TRY", "4242424242424242")
+
+	// Assert
+	if err == nil {
+		t.Fatal("Checkout() expected error but got none")
+	}
+	if len(productClient.reservations) != 0 || len(repo.payments) != 0 {
+		t.Errorf("Checkout() reserved %v and created %d payments, want none", productClient.reservations, len(repo.payments))
+	}
+}